@@ -14,6 +14,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/Conversly/lightning-response/internal/config"
+	"github.com/Conversly/lightning-response/internal/lifecycle"
 	"github.com/Conversly/lightning-response/internal/loaders"
 	"github.com/Conversly/lightning-response/internal/middleware"
 	"github.com/Conversly/lightning-response/internal/routes"
@@ -74,14 +75,15 @@ func main() {
 		utils.Zlog.Error("Failed to create database client", zap.Error(err))
 		os.Exit(1)
 	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			utils.Zlog.Error("Error closing database connection", zap.Error(err))
-		}
-	}()
+
+	// Components are stopped in reverse registration order: the server stops accepting
+	// requests first and the pool is closed last
+	lm := lifecycle.NewManager()
+	lm.Register("postgres pool", func(context.Context) error { return db.Close() })
 
 	// Start periodic refresh of API key/domain mappings every 2 minutes
 	utils.GetApiKeyManager().StartAutoRefresh(context.Background(), db, 2*time.Minute)
+	lm.RegisterFunc("api key refresh", utils.GetApiKeyManager().StopAutoRefresh)
 
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -95,7 +97,7 @@ func main() {
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
-	routes.SetupRoutes(router, db, cfg)
+	routes.SetupRoutes(router, db, cfg, lm)
 
 	srv := &http.Server{
 		Addr:         "0.0.0.0:" + cfg.Port,
@@ -104,6 +106,10 @@ func main() {
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	// In-flight graph runs and the message saves they spawn finish before the workers
+	// they feed are stopped
+	lm.Register("in-flight tasks", lm.WaitTasks)
+	lm.Register("http server", srv.Shutdown)
 
 	go func() {
 		utils.Zlog.Info("Starting HTTP server", zap.String("addr", srv.Addr))
@@ -117,16 +123,14 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	utils.Zlog.Info("Shutting down server...")
-
-	// Stop background refreshers
-	utils.GetApiKeyManager().StopAutoRefresh()
+	utils.Zlog.Info("Shutting down server...", zap.Duration("deadline", cfg.ShutdownTimeout))
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		utils.Zlog.Error("Server forced to shutdown", zap.Error(err))
+	if err := lm.Shutdown(ctx); err != nil {
+		utils.Zlog.Error("Unclean shutdown", zap.Error(err))
+		cleanup()
 		os.Exit(1)
 	}
 
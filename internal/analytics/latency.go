@@ -0,0 +1,50 @@
+package analytics
+
+import "github.com/Conversly/lightning-response/internal/loaders"
+
+// MergeHistograms adds latency histograms bucket by bucket
+func MergeHistograms(histograms ...[]int64) []int64 {
+	merged := make([]int64, len(loaders.LatencyBucketsMS)+1)
+	for _, h := range histograms {
+		for i := 0; i < len(h) && i < len(merged); i++ {
+			merged[i] += h[i]
+		}
+	}
+	return merged
+}
+
+// Percentile estimates the q-th quantile (0 < q <= 1) of the latencies in a histogram
+// of loaders.LatencyBucketsMS, interpolating linearly within the bucket it falls in.
+// Latencies past the last bound are reported as that bound. It returns nil for an empty
+// histogram.
+func Percentile(histogram []int64, q float64) *float64 {
+	var total int64
+	for _, n := range histogram {
+		total += n
+	}
+	if total == 0 {
+		return nil
+	}
+
+	bounds := loaders.LatencyBucketsMS
+	rank := q * float64(total)
+	var seen int64
+	for i, n := range histogram {
+		if n == 0 || float64(seen+n) < rank {
+			seen += n
+			continue
+		}
+		if i >= len(bounds) {
+			break
+		}
+		lower := 0.0
+		if i > 0 {
+			lower = float64(bounds[i-1])
+		}
+		upper := float64(bounds[i])
+		v := lower + (upper-lower)*(rank-float64(seen))/float64(n)
+		return &v
+	}
+	v := float64(bounds[len(bounds)-1])
+	return &v
+}
@@ -0,0 +1,83 @@
+package analytics
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+)
+
+// histogram returns an empty histogram with the given bucket counts set
+func histogram(counts map[int]int64) []int64 {
+	h := make([]int64, len(loaders.LatencyBucketsMS)+1)
+	for i, n := range counts {
+		h[i] = n
+	}
+	return h
+}
+
+func TestPercentile(t *testing.T) {
+	overflow := len(loaders.LatencyBucketsMS)
+	last := float64(loaders.LatencyBucketsMS[overflow-1])
+	tests := []struct {
+		name      string
+		histogram []int64
+		q         float64
+		want      *float64
+	}{
+		{"nil histogram", nil, 0.5, nil},
+		{"empty histogram", histogram(nil), 0.95, nil},
+		// Ten latencies under 100ms are spread evenly over [0, 100)
+		{"first bucket median", histogram(map[int]int64{0: 10}), 0.5, ptr(50)},
+		{"first bucket q=1", histogram(map[int]int64{0: 10}), 1, ptr(100)},
+		// The median is the last latency of bucket 0; q=1 is the top of bucket 3, [300, 500)
+		{"median at bucket end", histogram(map[int]int64{0: 2, 3: 2}), 0.5, ptr(100)},
+		{"q=1 skips empty buckets", histogram(map[int]int64{0: 2, 3: 2}), 1, ptr(500)},
+		{"p95 interpolated", histogram(map[int]int64{1: 100}), 0.95, ptr(195)},
+		// Latencies past the last bound report the last bound
+		{"overflow only", histogram(map[int]int64{overflow: 4}), 0.5, ptr(last)},
+		{"q=1 in overflow", histogram(map[int]int64{0: 9, overflow: 1}), 1, ptr(last)},
+		{"below overflow", histogram(map[int]int64{0: 9, overflow: 1}), 0.9, ptr(100)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Percentile(tt.histogram, tt.q)
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("Percentile(%v) = %v, want nil", tt.q, *got)
+			case tt.want != nil && got == nil:
+				t.Errorf("Percentile(%v) = nil, want %v", tt.q, *tt.want)
+			case tt.want != nil && math.Abs(*got-*tt.want) > 1e-9:
+				t.Errorf("Percentile(%v) = %v, want %v", tt.q, *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeHistograms(t *testing.T) {
+	overflow := len(loaders.LatencyBucketsMS)
+	tests := []struct {
+		name       string
+		histograms [][]int64
+		want       []int64
+	}{
+		{"none", nil, histogram(nil)},
+		{"empty", [][]int64{{}, histogram(nil)}, histogram(nil)},
+		{"bucket by bucket",
+			[][]int64{histogram(map[int]int64{0: 1, 5: 2}), histogram(map[int]int64{5: 3, overflow: 4})},
+			histogram(map[int]int64{0: 1, 5: 5, overflow: 4})},
+		// Histograms with fewer or more buckets than the current bounds keep the overlap
+		{"short histogram", [][]int64{{1, 2}}, histogram(map[int]int64{0: 1, 1: 2})},
+		{"long histogram", [][]int64{append(histogram(map[int]int64{overflow: 1}), 7)}, histogram(map[int]int64{overflow: 1})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MergeHistograms(tt.histograms...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeHistograms = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr(v float64) *float64 { return &v }
@@ -0,0 +1,105 @@
+// Package analytics maintains per-chatbot daily rollups of conversations, feedback, topics,
+// tool calls and response latency, and derives dashboard figures from them.
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+const (
+	// rollupBatchSize is how many dirty days are listed at a time
+	rollupBatchSize = 500
+	// maxBatchesPerRun caps the work of one run so a backlog drains over several
+	maxBatchesPerRun = 20
+)
+
+// Roller periodically recomputes the rollups of the days that gained messages or
+// feedback since they were last rolled up. Instances take an advisory lock for each run,
+// so only one rolls up at a time.
+type Roller struct {
+	db       *loaders.PostgresClient
+	interval time.Duration
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewRoller creates a roller running every interval
+func NewRoller(db *loaders.PostgresClient, interval time.Duration) *Roller {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Roller{db: db, interval: interval, ctx: ctx, cancel: cancel}
+}
+
+// Start runs a rollup now and then every interval until Stop
+func (r *Roller) Start() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := r.Run(r.ctx); err != nil && r.ctx.Err() == nil {
+				utils.Zlog.Error("Analytics rollup failed", zap.Error(err))
+			}
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels a rollup in progress (days already rolled up stay so) and waits for it
+func (r *Roller) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// Run rolls up dirty days and returns how many. It does nothing when another instance
+// holds the lock.
+func (r *Roller) Run(ctx context.Context) (int, error) {
+	release, ok, err := r.db.TryAnalyticsLock(ctx)
+	if err != nil || !ok {
+		return 0, err
+	}
+	defer release()
+
+	start := time.Now()
+	rolled := 0
+	defer func() {
+		if rolled > 0 {
+			utils.Zlog.Info("Rolled up analytics",
+				zap.Int("days", rolled),
+				zap.Duration("elapsed", time.Since(start)))
+		}
+	}()
+
+	for range maxBatchesPerRun {
+		days, err := r.db.ListDirtyDays(ctx, rollupBatchSize)
+		if err != nil {
+			return rolled, err
+		}
+		for i := range days {
+			if err := r.db.RollupDay(ctx, &days[i]); err != nil {
+				return rolled, fmt.Errorf("chatbot %s, %s: %w",
+					days[i].ChatbotID, days[i].Day.Format(time.DateOnly), err)
+			}
+			rolled++
+		}
+		if len(days) < rollupBatchSize {
+			break
+		}
+	}
+	return rolled, nil
+}
@@ -0,0 +1,56 @@
+package analytics
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/types"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+type Controller struct {
+	svc *Service
+}
+
+func NewController(svc *Service) *Controller {
+	return &Controller{svc: svc}
+}
+
+// Daily returns the time series of the chatbot in the path for the from and to query
+// parameters
+func (c *Controller) Daily(ctx *gin.Context) {
+	res, err := c.svc.Daily(ctx.Request.Context(), ctx.Param("chatbotId"), ctx.Query("from"), ctx.Query("to"))
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	res.BaseResponse = types.BaseResponse{Success: true}
+	res.RequestID = utils.RequestID(ctx)
+	ctx.JSON(http.StatusOK, res)
+}
+
+// Summary returns the totals and breakdowns of the chatbot in the path for the from and
+// to query parameters
+func (c *Controller) Summary(ctx *gin.Context) {
+	res, err := c.svc.Summary(ctx.Request.Context(), ctx.Param("chatbotId"), ctx.Query("from"), ctx.Query("to"))
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	res.BaseResponse = types.BaseResponse{Success: true}
+	res.RequestID = utils.RequestID(ctx)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *Controller) fail(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrInvalidRequest):
+		utils.WriteError(ctx, http.StatusBadRequest, "analytics_error", err)
+	default:
+		utils.Zlog.Error("analytics request failed", zap.String("chatbot_id", ctx.Param("chatbotId")), zap.Error(err))
+		utils.WriteError(ctx, http.StatusInternalServerError, "analytics_error", err)
+	}
+}
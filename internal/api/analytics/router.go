@@ -0,0 +1,26 @@
+package analytics
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/Conversly/lightning-response/internal/analytics"
+	"github.com/Conversly/lightning-response/internal/config"
+	"github.com/Conversly/lightning-response/internal/lifecycle"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/middleware"
+)
+
+// RegisterRoutes registers the admin analytics endpoints and starts the rollup job they
+// read from, registering it with lm
+func RegisterRoutes(router *gin.Engine, db *loaders.PostgresClient, cfg *config.Config, lm *lifecycle.Manager) {
+	roller := analytics.NewRoller(db, cfg.AnalyticsRollupInterval)
+	roller.Start()
+	lm.RegisterFunc("analytics rollup", roller.Stop)
+
+	svc := NewService(db)
+	ctrl := NewController(svc)
+
+	admin := router.Group("/admin", middleware.AdminAuth(cfg.AdminAPIToken))
+	admin.GET("/chatbots/:chatbotId/analytics/daily", ctrl.Daily)
+	admin.GET("/chatbots/:chatbotId/analytics/summary", ctrl.Summary)
+}
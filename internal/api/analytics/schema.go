@@ -0,0 +1,71 @@
+package analytics
+
+import (
+	"github.com/Conversly/lightning-response/internal/types"
+)
+
+// Stats are a chatbot's figures over a period, summed from daily rollups that outlive
+// purged and erased messages. Messages are counted on the day they were written (UTC),
+// and feedback on the day of the message it rates.
+type Stats struct {
+	// Conversations counts each conversation once per day it has messages on, so one
+	// spanning several days counts several times in summary totals
+	Conversations     int64 `json:"conversations"`
+	Messages          int64 `json:"messages"`
+	UserMessages      int64 `json:"userMessages"`
+	AssistantMessages int64 `json:"assistantMessages"`
+	Likes             int64 `json:"likes"`
+	Dislikes          int64 `json:"dislikes"`
+	Neutral           int64 `json:"neutral"`
+	// LikeRatio is likes / (likes + dislikes), null without either
+	LikeRatio *float64 `json:"likeRatio"`
+	// AvgTurns is the user messages per conversation day, null without conversations
+	AvgTurns  *float64 `json:"avgTurns"`
+	ToolCalls int64    `json:"toolCalls"`
+	// Response latency percentiles, estimated from a histogram and null without
+	// measured responses
+	LatencyP50MS *float64 `json:"latencyP50Ms"`
+	LatencyP95MS *float64 `json:"latencyP95Ms"`
+}
+
+// DayStats are the figures of one day
+type DayStats struct {
+	Date string `json:"date"` // YYYY-MM-DD
+	Stats
+}
+
+// DailyResponse is a chatbot's time series, one entry per day from From to To
+// including days without messages
+type DailyResponse struct {
+	types.BaseResponse
+	ChatbotID string     `json:"chatbotId"`
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	Days      []DayStats `json:"days"`
+}
+
+// TopicShare is how many user messages of the period were about a topic
+type TopicShare struct {
+	TopicID  string  `json:"topicId"`
+	Name     string  `json:"name,omitempty"` // empty when the topic was deleted
+	Messages int64   `json:"messages"`
+	Share    float64 `json:"share"` // of the user messages tagged with a topic
+}
+
+// ToolUsage is how many times a tool was called in the period
+type ToolUsage struct {
+	Tool  string `json:"tool"`
+	Calls int64  `json:"calls"`
+}
+
+// SummaryResponse totals a chatbot's figures over a period and breaks them down by
+// topic and tool
+type SummaryResponse struct {
+	types.BaseResponse
+	ChatbotID string       `json:"chatbotId"`
+	From      string       `json:"from"`
+	To        string       `json:"to"`
+	Totals    Stats        `json:"totals"`
+	Topics    []TopicShare `json:"topics"`
+	Tools     []ToolUsage  `json:"tools"`
+}
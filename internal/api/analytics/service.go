@@ -0,0 +1,174 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Conversly/lightning-response/internal/analytics"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+const (
+	defaultRangeDays = 30
+	maxRangeDays     = 366
+)
+
+type Service struct {
+	db *loaders.PostgresClient
+}
+
+func NewService(db *loaders.PostgresClient) *Service {
+	return &Service{db: db}
+}
+
+// Daily returns a chatbot's figures for each day of the range
+func (s *Service) Daily(ctx context.Context, chatbotID, from, to string) (*DailyResponse, error) {
+	first, last, err := parseRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	rollups, err := s.db.GetDailyRollups(ctx, chatbotID, first, last)
+	if err != nil {
+		return nil, err
+	}
+
+	byDay := make(map[string]*loaders.DailyRollup, len(rollups))
+	for i := range rollups {
+		byDay[rollups[i].Day.Format(time.DateOnly)] = &rollups[i]
+	}
+	res := &DailyResponse{
+		ChatbotID: chatbotID,
+		From:      first.Format(time.DateOnly),
+		To:        last.Format(time.DateOnly),
+		Days:      []DayStats{},
+	}
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		ds := DayStats{Date: day.Format(time.DateOnly)}
+		var histogram []int64
+		if r, ok := byDay[ds.Date]; ok {
+			ds.add(r)
+			histogram = r.LatencyHistogram
+		}
+		ds.derive(histogram)
+		res.Days = append(res.Days, ds)
+	}
+	return res, nil
+}
+
+// Summary returns a chatbot's totals over the range with its topic and tool breakdowns.
+// Every figure is summed from the daily rollups, so a conversation counts once per day
+// it has messages on.
+func (s *Service) Summary(ctx context.Context, chatbotID, from, to string) (*SummaryResponse, error) {
+	first, last, err := parseRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	rollups, err := s.db.GetDailyRollups(ctx, chatbotID, first, last)
+	if err != nil {
+		return nil, err
+	}
+	topics, err := s.db.GetTopicTotals(ctx, chatbotID, first, last)
+	if err != nil {
+		return nil, err
+	}
+	tools, err := s.db.GetToolTotals(ctx, chatbotID, first, last)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &SummaryResponse{
+		ChatbotID: chatbotID,
+		From:      first.Format(time.DateOnly),
+		To:        last.Format(time.DateOnly),
+		Topics:    make([]TopicShare, len(topics)),
+		Tools:     make([]ToolUsage, len(tools)),
+	}
+	histograms := make([][]int64, len(rollups))
+	for i := range rollups {
+		res.Totals.add(&rollups[i])
+		histograms[i] = rollups[i].LatencyHistogram
+	}
+	res.Totals.derive(analytics.MergeHistograms(histograms...))
+
+	var tagged int64
+	for _, t := range topics {
+		tagged += t.Messages
+	}
+	for i, t := range topics {
+		res.Topics[i] = TopicShare{TopicID: t.TopicID, Messages: t.Messages}
+		if t.Name != nil {
+			res.Topics[i].Name = *t.Name
+		}
+		if tagged > 0 {
+			res.Topics[i].Share = float64(t.Messages) / float64(tagged)
+		}
+	}
+	for i, t := range tools {
+		res.Tools[i] = ToolUsage{Tool: t.Tool, Calls: t.Calls}
+	}
+	return res, nil
+}
+
+// add counts a day's rollup in st
+func (st *Stats) add(r *loaders.DailyRollup) {
+	st.Conversations += int64(r.Conversations)
+	st.UserMessages += int64(r.UserMessages)
+	st.AssistantMessages += int64(r.AssistantMessages)
+	st.Messages += int64(r.UserMessages + r.AssistantMessages)
+	st.Likes += int64(r.Likes)
+	st.Dislikes += int64(r.Dislikes)
+	st.Neutral += int64(r.Neutral)
+	st.ToolCalls += int64(r.ToolCalls)
+}
+
+// derive sets the ratios and latency percentiles of st from its counts and the
+// latency histogram of the same period
+func (st *Stats) derive(histogram []int64) {
+	if rated := st.Likes + st.Dislikes; rated > 0 {
+		ratio := float64(st.Likes) / float64(rated)
+		st.LikeRatio = &ratio
+	}
+	st.AvgTurns = avgTurns(st.UserMessages, st.Conversations)
+	st.LatencyP50MS = analytics.Percentile(histogram, 0.5)
+	st.LatencyP95MS = analytics.Percentile(histogram, 0.95)
+}
+
+// avgTurns is the user messages per conversation, nil without conversations
+func avgTurns(userMessages, conversations int64) *float64 {
+	if conversations == 0 {
+		return nil
+	}
+	turns := float64(userMessages) / float64(conversations)
+	return &turns
+}
+
+// parseRange parses the YYYY-MM-DD days of a range, both included. The range ends today
+// (UTC) and spans defaultRangeDays unless given.
+func parseRange(from, to string) (time.Time, time.Time, error) {
+	last := time.Now().UTC().Truncate(24 * time.Hour)
+	if to != "" {
+		t, err := time.Parse(time.DateOnly, to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: to must be YYYY-MM-DD", utils.ErrInvalidRequest)
+		}
+		last = t
+	}
+	first := last.AddDate(0, 0, 1-defaultRangeDays)
+	if from != "" {
+		t, err := time.Parse(time.DateOnly, from)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: from must be YYYY-MM-DD", utils.ErrInvalidRequest)
+		}
+		first = t
+	}
+
+	if first.After(last) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: from is after to", utils.ErrInvalidRequest)
+	}
+	if last.Sub(first) >= maxRangeDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: at most %d days", utils.ErrInvalidRequest, maxRangeDays)
+	}
+	return first, last, nil
+}
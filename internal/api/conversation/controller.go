@@ -0,0 +1,198 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/export"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/types"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+type Controller struct {
+	svc *Service
+}
+
+func NewController(svc *Service) *Controller {
+	return &Controller{svc: svc}
+}
+
+// List returns a page of the conversations of the chatbot in the path
+func (c *Controller) List(ctx *gin.Context) {
+	limit, ok := intQuery(ctx, "limit")
+	if !ok {
+		return
+	}
+	conversations, next, err := c.svc.List(ctx.Request.Context(), ctx.Param("chatbotId"), ctx.Query("cursor"), limit)
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	res := ListResponse{
+		BaseResponse:  types.BaseResponse{Success: true},
+		Conversations: conversations,
+		NextCursor:    next,
+	}
+	res.RequestID = utils.RequestID(ctx)
+	ctx.JSON(http.StatusOK, res)
+}
+
+// Get returns the transcript of one conversation of the chatbot in the path
+func (c *Controller) Get(ctx *gin.Context) {
+	limit, ok := intQuery(ctx, "limit")
+	if !ok {
+		return
+	}
+	convID := ctx.Param("conversationId")
+	messages, truncated, err := c.svc.Transcript(ctx.Request.Context(), ctx.Param("chatbotId"), convID, limit)
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	// The transcript is still useful without its classification
+	classification, err := c.svc.Classification(ctx.Request.Context(), ctx.Param("chatbotId"), convID)
+	if err != nil {
+		utils.Zlog.Warn("failed to load conversation classification",
+			zap.String("chatbot_id", ctx.Param("chatbotId")),
+			zap.String("conversation_id", convID),
+			zap.Error(err))
+		classification = nil
+	}
+	c.respond(ctx, convID, messages, truncated, classification)
+}
+
+// History returns the calling widget's own conversation
+func (c *Controller) History(ctx *gin.Context) {
+	var req HistoryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.WriteError(ctx, http.StatusBadRequest, "bad_request", err)
+		return
+	}
+	convID, messages, truncated, err := c.svc.History(ctx.Request.Context(), &req)
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	c.respond(ctx, convID, messages, truncated, nil)
+}
+
+// StartExport starts exporting the messages of the chatbot in the path
+func (c *Controller) StartExport(ctx *gin.Context) {
+	var req ExportRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.WriteError(ctx, http.StatusBadRequest, "bad_request", err)
+		return
+	}
+	job, err := c.svc.StartExport(ctx.Request.Context(), ctx.Param("chatbotId"), &req)
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, exportResponse(ctx, job))
+}
+
+// GetExport reports the progress of an export
+func (c *Controller) GetExport(ctx *gin.Context) {
+	job, err := c.svc.GetExport(ctx.Request.Context(), ctx.Param("exportId"))
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, exportResponse(ctx, job))
+}
+
+// DownloadExport sends the file of a completed export. Range requests are honoured so
+// large downloads can resume.
+func (c *Controller) DownloadExport(ctx *gin.Context) {
+	job, f, err := c.svc.OpenExport(ctx.Request.Context(), ctx.Param("exportId"))
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	defer f.Close()
+
+	name := fmt.Sprintf("messages-%s-%s.%s", job.ChatbotID, job.ID, job.Format)
+	ctx.Header("Content-Type", export.ContentType(job.Format))
+	ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name}))
+	modified := job.StartedAt
+	if job.FinishedAt != nil {
+		modified = *job.FinishedAt
+	}
+	http.ServeContent(ctx.Writer, ctx.Request, name, modified, f)
+}
+
+func exportResponse(ctx *gin.Context, job *loaders.ExportRecord) ExportResponse {
+	res := ExportResponse{
+		BaseResponse: types.BaseResponse{Success: true},
+		ExportID:     job.ID,
+		ChatbotID:    job.ChatbotID,
+		Format:       job.Format,
+		From:         job.From,
+		To:           job.To,
+		Status:       job.Status,
+		Rows:         job.Rows,
+		SizeBytes:    job.SizeBytes,
+		Error:        job.ErrorMessage,
+		StartedAt:    job.StartedAt,
+		FinishedAt:   job.FinishedAt,
+		ExpiresAt:    job.ExpiresAt,
+	}
+	if job.Status == loaders.ExportCompleted {
+		res.DownloadURL = "/admin/exports/" + job.ID + "/download"
+	}
+	res.RequestID = utils.RequestID(ctx)
+	return res
+}
+
+func (c *Controller) respond(ctx *gin.Context, convID string, messages []Message, truncated bool, classification *Classification) {
+	res := TranscriptResponse{
+		BaseResponse:   types.BaseResponse{Success: true},
+		ConversationID: convID,
+		Messages:       messages,
+		Truncated:      truncated,
+		Classification: classification,
+	}
+	res.RequestID = utils.RequestID(ctx)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *Controller) fail(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, loaders.ErrConversationNotFound), errors.Is(err, loaders.ErrExportNotFound):
+		utils.WriteError(ctx, http.StatusNotFound, "conversation_error", err)
+	case errors.Is(err, errInvalidCursor), errors.Is(err, errInvalidExport), errors.Is(err, utils.ErrInvalidRequest):
+		utils.WriteError(ctx, http.StatusBadRequest, "bad_request", err)
+	case errors.Is(err, errAccessDenied):
+		utils.Zlog.Warn("conversation history access denied", zap.Error(err))
+		utils.WriteError(ctx, http.StatusForbidden, "forbidden", err)
+	case errors.Is(err, export.ErrExportRunning), errors.Is(err, export.ErrExportNotReady):
+		utils.WriteError(ctx, http.StatusConflict, "export_error", err)
+	case errors.Is(err, export.ErrExportExpired):
+		utils.WriteError(ctx, http.StatusGone, "export_error", err)
+	case errors.Is(err, export.ErrExporterStopped):
+		utils.WriteError(ctx, http.StatusServiceUnavailable, "export_error", err)
+	default:
+		utils.Zlog.Error("conversation request failed", zap.String("chatbot_id", ctx.Param("chatbotId")), zap.Error(err))
+		utils.WriteError(ctx, http.StatusInternalServerError, "internal_error", err)
+	}
+}
+
+func intQuery(ctx *gin.Context, name string) (int, bool) {
+	raw := ctx.Query(name)
+	if raw == "" {
+		return 0, true
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil || v < 0 {
+		utils.WriteError(ctx, http.StatusBadRequest, "bad_request", errors.New("invalid "+name))
+		return 0, false
+	}
+	return v, true
+}
@@ -0,0 +1,45 @@
+package conversation
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/Conversly/lightning-response/internal/config"
+	"github.com/Conversly/lightning-response/internal/export"
+	"github.com/Conversly/lightning-response/internal/lifecycle"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/middleware"
+	"github.com/Conversly/lightning-response/internal/tagging"
+)
+
+// RegisterRoutes registers the admin transcript and export endpoints and the widget's
+// history endpoint, and starts conversation tagging unless it is disabled. The exporter and
+// tagger are registered with lm to be stopped on shutdown.
+func RegisterRoutes(router *gin.Engine, db *loaders.PostgresClient, cfg *config.Config, lm *lifecycle.Manager) {
+	exporter := export.NewExporter(db, cfg.ExportDir, cfg.ExportTTL, cfg.Hostname)
+	lm.RegisterFunc("message exporter", exporter.Stop)
+
+	if cfg.TaggingInterval > 0 {
+		tagger := tagging.NewTagger(db, tagging.Options{
+			IdleAfter:         cfg.ConversationIdleTimeout,
+			Interval:          cfg.TaggingInterval,
+			LLMModel:          cfg.TaggingLLMModel,
+			GeminiAPIKeys:     cfg.GeminiAPIKeys,
+			KeywordCorpusSize: cfg.KeywordCorpusSize,
+		})
+		tagger.Start()
+		lm.RegisterFunc("conversation tagger", tagger.Stop)
+	}
+
+	svc := NewService(db, exporter)
+	ctrl := NewController(svc)
+
+	// The widget authenticates like /response, with its web id and origin
+	router.POST("/conversations/history", ctrl.History)
+
+	admin := router.Group("/admin", middleware.AdminAuth(cfg.AdminAPIToken))
+	admin.GET("/chatbots/:chatbotId/conversations", ctrl.List)
+	admin.GET("/chatbots/:chatbotId/conversations/:conversationId", ctrl.Get)
+	admin.POST("/chatbots/:chatbotId/exports", ctrl.StartExport)
+	admin.GET("/exports/:exportId", ctrl.GetExport)
+	admin.GET("/exports/:exportId/download", ctrl.DownloadExport)
+}
@@ -0,0 +1,107 @@
+package conversation
+
+import (
+	"time"
+
+	"github.com/Conversly/lightning-response/internal/types"
+)
+
+// Summary is one conversation in a listing
+type Summary struct {
+	ConversationID string          `json:"conversationId"`
+	MessageCount   int             `json:"messageCount"`
+	StartedAt      time.Time       `json:"startedAt"`
+	LastActivity   time.Time       `json:"lastActivity"`
+	Topic          *Topic          `json:"topic,omitempty"`
+	Feedback       FeedbackSummary `json:"feedback"`
+}
+
+type Topic struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// FeedbackSummary counts the feedback left on a conversation's messages
+type FeedbackSummary struct {
+	Likes    int `json:"likes"`
+	Dislikes int `json:"dislikes"`
+	Neutral  int `json:"neutral"`
+}
+
+// ListResponse is a page of conversations. NextCursor is set when more remain and is
+// passed back as the cursor query parameter.
+type ListResponse struct {
+	types.BaseResponse
+	Conversations []Summary `json:"conversations"`
+	NextCursor    string    `json:"nextCursor,omitempty"`
+}
+
+// Message is one message of a transcript
+type Message struct {
+	ID              string    `json:"messageId"`
+	Role            string    `json:"role"` // user | assistant
+	Content         string    `json:"content"`
+	Citations       []string  `json:"citations"`
+	CreatedAt       time.Time `json:"createdAt"`
+	TopicID         *string   `json:"topicId,omitempty"`
+	Feedback        string    `json:"feedback,omitempty"` // like | dislike | neutral
+	FeedbackComment *string   `json:"feedbackComment,omitempty"`
+}
+
+// TranscriptResponse holds a conversation's latest messages, oldest first. Truncated is
+// set when older messages were left out.
+type TranscriptResponse struct {
+	types.BaseResponse
+	ConversationID string    `json:"conversationId"`
+	Messages       []Message `json:"messages"`
+	Truncated      bool      `json:"truncated"`
+	// Classification is set on admin transcripts of conversations that have been tagged
+	Classification *Classification `json:"classification,omitempty"`
+}
+
+// Classification is how a conversation was tagged after it went idle. It describes the
+// conversation up to ClassifiedAt; Sentiment and Resolution are omitted when unknown.
+type Classification struct {
+	TopicIDs     []string  `json:"topicIds"`
+	Tags         []string  `json:"tags"`
+	Sentiment    *string   `json:"sentiment,omitempty"`  // positive | neutral | negative
+	Resolution   *string   `json:"resolution,omitempty"` // resolved | unresolved | escalated
+	Method       string    `json:"method"`               // llm | heuristic
+	Model        *string   `json:"model,omitempty"`
+	ClassifiedAt time.Time `json:"classifiedAt"`
+}
+
+// HistoryRequest is sent by the widget to restore its own conversation. Access is
+// checked like /response: the web id must match the origin's domain, and only the
+// conversation of uniqueClientId is returned.
+type HistoryRequest struct {
+	User     types.RequestUser `json:"user"`
+	Metadata types.RequestMeta `json:"metadata"`
+	Limit    int               `json:"limit,omitempty"`
+}
+
+// ExportRequest starts an export of the messages created in [from, to). Format is
+// jsonl (the default) or csv.
+type ExportRequest struct {
+	Format string    `json:"format,omitempty"`
+	From   time.Time `json:"from" binding:"required"`
+	To     time.Time `json:"to" binding:"required"`
+}
+
+// ExportResponse reports an export job. DownloadURL is set once the file is ready.
+type ExportResponse struct {
+	types.BaseResponse
+	ExportID    string     `json:"exportId"`
+	ChatbotID   string     `json:"chatbotId"`
+	Format      string     `json:"format"`
+	From        time.Time  `json:"from"`
+	To          time.Time  `json:"to"`
+	Status      string     `json:"status"` // RUNNING | COMPLETED | FAILED | EXPIRED
+	Rows        int64      `json:"rows"`
+	SizeBytes   int64      `json:"sizeBytes"`
+	Error       *string    `json:"error,omitempty"`
+	StartedAt   time.Time  `json:"startedAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	DownloadURL string     `json:"downloadUrl,omitempty"`
+}
@@ -0,0 +1,201 @@
+package conversation
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	respapi "github.com/Conversly/lightning-response/internal/api/response"
+	"github.com/Conversly/lightning-response/internal/export"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+const (
+	defaultPageSize     = 50
+	maxPageSize         = 200
+	defaultMessageLimit = 200
+	maxMessageLimit     = 1000
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// errAccessDenied marks a widget request whose web id does not match its origin
+var errAccessDenied = errors.New("access denied")
+
+// errInvalidExport marks an export request with a bad format or range
+var errInvalidExport = errors.New("invalid export request")
+
+type Service struct {
+	db       *loaders.PostgresClient
+	exporter *export.Exporter
+}
+
+func NewService(db *loaders.PostgresClient, exporter *export.Exporter) *Service {
+	return &Service{db: db, exporter: exporter}
+}
+
+// List returns a page of a chatbot's conversations, most recently active first
+func (s *Service) List(ctx context.Context, chatbotID, cursor string, limit int) ([]Summary, string, error) {
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	limit = clamp(limit, defaultPageSize, maxPageSize)
+
+	rows, err := s.db.ListConversations(ctx, chatbotID, after, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	summaries := make([]Summary, len(rows))
+	for i, r := range rows {
+		summaries[i] = Summary{
+			ConversationID: r.ConversationID,
+			MessageCount:   r.MessageCount,
+			StartedAt:      r.StartedAt,
+			LastActivity:   r.LastActivity,
+			Feedback: FeedbackSummary{
+				Likes:    r.Feedback.Likes,
+				Dislikes: r.Feedback.Dislikes,
+				Neutral:  r.Feedback.Neutral,
+			},
+		}
+		if r.TopicID != nil {
+			summaries[i].Topic = &Topic{ID: *r.TopicID}
+			if r.TopicName != nil {
+				summaries[i].Topic.Name = *r.TopicName
+			}
+		}
+	}
+
+	next := ""
+	if len(rows) == limit {
+		last := rows[len(rows)-1]
+		next = encodeCursor(&loaders.ConversationCursor{LastActivity: last.LastActivity, ConversationID: last.ConversationID})
+	}
+	return summaries, next, nil
+}
+
+// Transcript returns the latest messages of one conversation
+func (s *Service) Transcript(ctx context.Context, chatbotID, conversationID string, limit int) ([]Message, bool, error) {
+	rows, truncated, err := s.db.GetConversationMessages(ctx, chatbotID, conversationID,
+		clamp(limit, defaultMessageLimit, maxMessageLimit))
+	if err != nil {
+		return nil, false, err
+	}
+
+	messages := make([]Message, len(rows))
+	for i, r := range rows {
+		messages[i] = Message{
+			ID:              r.ID,
+			Role:            r.Type,
+			Content:         r.Content,
+			Citations:       r.Citations,
+			CreatedAt:       r.CreatedAt,
+			TopicID:         r.TopicID,
+			Feedback:        loaders.FeedbackLabel(r.Feedback),
+			FeedbackComment: r.FeedbackComment,
+		}
+	}
+	return messages, truncated, nil
+}
+
+// Classification returns how a conversation was tagged, or nil when it has not been
+func (s *Service) Classification(ctx context.Context, chatbotID, conversationID string) (*Classification, error) {
+	tags, err := s.db.GetConversationTags(ctx, chatbotID, conversationID)
+	if err != nil || tags == nil {
+		return nil, err
+	}
+	return &Classification{
+		TopicIDs:     tags.TopicIDs,
+		Tags:         tags.Tags,
+		Sentiment:    tags.Sentiment,
+		Resolution:   tags.Resolution,
+		Method:       tags.Method,
+		Model:        tags.Model,
+		ClassifiedAt: tags.ClassifiedAt,
+	}, nil
+}
+
+// History returns the widget's own conversation after checking its web id and origin.
+// The conversation is always the one of the caller's uniqueClientId.
+func (s *Service) History(ctx context.Context, req *HistoryRequest) (string, []Message, bool, error) {
+	if req.User.UniqueClientID == "" {
+		return "", nil, false, fmt.Errorf("%w: user.uniqueClientId is required", utils.ErrInvalidRequest)
+	}
+	chatbotID, err := respapi.ValidateChatbotAccess(ctx, req.User.ConverslyWebID, req.Metadata.OriginURL)
+	if err != nil {
+		return "", nil, false, fmt.Errorf("%w: %v", errAccessDenied, err)
+	}
+	messages, truncated, err := s.Transcript(ctx, chatbotID, req.User.UniqueClientID, req.Limit)
+	if errors.Is(err, loaders.ErrConversationNotFound) {
+		// A visitor without history yet is not an error for the widget
+		return req.User.UniqueClientID, []Message{}, false, nil
+	}
+	return req.User.UniqueClientID, messages, truncated, err
+}
+
+// StartExport starts exporting a chatbot's messages in the requested range
+func (s *Service) StartExport(ctx context.Context, chatbotID string, req *ExportRequest) (*loaders.ExportRecord, error) {
+	format := strings.ToLower(req.Format)
+	if format == "" {
+		format = export.FormatJSONL
+	}
+	if format != export.FormatJSONL && format != export.FormatCSV {
+		return nil, fmt.Errorf("%w: format must be jsonl or csv", errInvalidExport)
+	}
+	if !req.From.Before(req.To) {
+		return nil, fmt.Errorf("%w: from must be before to", errInvalidExport)
+	}
+	return s.exporter.Start(ctx, chatbotID, format, req.From, req.To)
+}
+
+// GetExport reports an export job
+func (s *Service) GetExport(ctx context.Context, exportID string) (*loaders.ExportRecord, error) {
+	return s.exporter.Get(ctx, exportID)
+}
+
+// OpenExport returns a completed export and its file, which the caller must close
+func (s *Service) OpenExport(ctx context.Context, exportID string) (*loaders.ExportRecord, *os.File, error) {
+	return s.exporter.Open(ctx, exportID)
+}
+
+// Cursors are the last conversation's activity time and id, base64 encoded
+func encodeCursor(c *loaders.ConversationCursor) string {
+	raw := c.LastActivity.UTC().Format(time.RFC3339Nano) + "|" + c.ConversationID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (*loaders.ConversationCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, errInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	return &loaders.ConversationCursor{LastActivity: t, ConversationID: id}, nil
+}
+
+func clamp(v, def, max int) int {
+	if v <= 0 {
+		return def
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
@@ -0,0 +1,241 @@
+package datasource
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/ingestion"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/types"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+type Controller struct {
+	svc *Service
+}
+
+func NewController(svc *Service) *Controller {
+	return &Controller{svc: svc}
+}
+
+// Ingest schedules ingestion of a data source from its URL
+func (c *Controller) Ingest(ctx *gin.Context) {
+	var req IngestRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.Zlog.Warn("invalid ingest payload", zap.Error(err))
+		utils.WriteError(ctx, http.StatusBadRequest, "bad_request", err)
+		return
+	}
+	c.schedule(ctx, &req, nil)
+}
+
+// Upload schedules ingestion of an uploaded file (multipart field "file")
+func (c *Controller) Upload(ctx *gin.Context) {
+	var req IngestRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		utils.Zlog.Warn("invalid upload payload", zap.Error(err))
+		utils.WriteError(ctx, http.StatusBadRequest, "bad_request", err)
+		return
+	}
+
+	fh, err := ctx.FormFile("file")
+	if err != nil {
+		utils.WriteError(ctx, http.StatusBadRequest, "bad_request", err)
+		return
+	}
+	if fh.Size > utils.MaxDownloadSize {
+		utils.WriteError(ctx, http.StatusBadRequest, "bad_request", errUploadTooLarge)
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		utils.WriteError(ctx, http.StatusBadRequest, "bad_request", err)
+		return
+	}
+	defer f.Close()
+	path, err := c.svc.SpoolUpload(f)
+	if err != nil {
+		if errors.Is(err, errUploadTooLarge) {
+			utils.WriteError(ctx, http.StatusBadRequest, "bad_request", err)
+			return
+		}
+		utils.Zlog.Error("failed to spool upload", zap.Error(err))
+		utils.WriteError(ctx, http.StatusInternalServerError, "upload_error", errors.New("failed to store uploaded file"))
+		return
+	}
+
+	c.schedule(ctx, &req, &Upload{
+		Path:        path,
+		ContentType: fh.Header.Get("Content-Type"),
+		Filename:    fh.Filename,
+	})
+}
+
+// Crawl schedules a website crawl data source
+func (c *Controller) Crawl(ctx *gin.Context) {
+	var req CrawlRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.Zlog.Warn("invalid crawl payload", zap.Error(err))
+		utils.WriteError(ctx, http.StatusBadRequest, "bad_request", err)
+		return
+	}
+	c.respond(ctx, req.DataSourceID, c.svc.ScheduleCrawl(ctx.Request.Context(), &req))
+}
+
+// DeleteEmbeddings removes all embeddings of the data source in the path
+func (c *Controller) DeleteEmbeddings(ctx *gin.Context) {
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		utils.WriteError(ctx, http.StatusBadRequest, "bad_request", errors.New("invalid data source id"))
+		return
+	}
+
+	deleted, err := c.svc.DeleteEmbeddings(ctx.Request.Context(), id)
+	if err != nil {
+		utils.Zlog.Warn("failed to delete data source embeddings", zap.Int("data_source_id", id), zap.Error(err))
+		fail(ctx, err)
+		return
+	}
+
+	res := DeleteEmbeddingsResponse{
+		BaseResponse: types.BaseResponse{Success: true},
+		DataSourceID: id,
+		Deleted:      deleted,
+	}
+	res.RequestID = utils.RequestID(ctx)
+	ctx.JSON(http.StatusOK, res)
+}
+
+// StartReindex starts a background reindex of the chatbot in the path
+func (c *Controller) StartReindex(ctx *gin.Context) {
+	job, err := c.svc.StartReindex(ctx.Request.Context(), ctx.Param("chatbotId"))
+	if err != nil {
+		utils.Zlog.Warn("failed to start reindex", zap.String("chatbot_id", ctx.Param("chatbotId")), zap.Error(err))
+		fail(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, reindexResponse(ctx, job))
+}
+
+// GetReindexJob reports the progress of a reindex
+func (c *Controller) GetReindexJob(ctx *gin.Context) {
+	job, err := c.svc.GetReindexJob(ctx.Request.Context(), ctx.Param("jobId"))
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, reindexResponse(ctx, job))
+}
+
+func reindexResponse(ctx *gin.Context, job *loaders.ReindexJobRecord) ReindexJobResponse {
+	res := ReindexJobResponse{
+		BaseResponse:       types.BaseResponse{Success: true},
+		JobID:              job.ID,
+		ChatbotID:          job.ChatbotID,
+		Status:             job.Status,
+		TotalDocuments:     job.TotalDocuments,
+		ProcessedDocuments: job.ProcessedDocuments,
+		ChunksEmbedded:     job.ChunksEmbedded,
+		ChunksReused:       job.ChunksReused,
+		Error:              job.ErrorMessage,
+		StartedAt:          job.StartedAt,
+		FinishedAt:         job.FinishedAt,
+	}
+	res.RequestID = utils.RequestID(ctx)
+	return res
+}
+
+// StartEmbeddingMigration starts moving the chatbot in the path to another embedding model
+func (c *Controller) StartEmbeddingMigration(ctx *gin.Context) {
+	var req EmbeddingMigrationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.WriteError(ctx, http.StatusBadRequest, "bad_request", err)
+		return
+	}
+	migration, err := c.svc.StartEmbeddingMigration(ctx.Request.Context(), ctx.Param("chatbotId"), &req)
+	if err != nil {
+		utils.Zlog.Warn("failed to start embedding migration", zap.String("chatbot_id", ctx.Param("chatbotId")), zap.Error(err))
+		fail(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, migrationResponse(ctx, migration))
+}
+
+// GetEmbeddingMigration reports the progress of an embedding model migration
+func (c *Controller) GetEmbeddingMigration(ctx *gin.Context) {
+	migration, err := c.svc.GetEmbeddingMigration(ctx.Request.Context(), ctx.Param("migrationId"))
+	if err != nil {
+		fail(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, migrationResponse(ctx, migration))
+}
+
+func migrationResponse(ctx *gin.Context, m *loaders.EmbeddingMigrationRecord) EmbeddingMigrationResponse {
+	res := EmbeddingMigrationResponse{
+		BaseResponse:   types.BaseResponse{Success: true},
+		MigrationID:    m.ID,
+		ChatbotID:      m.ChatbotID,
+		Model:          m.Model,
+		Dimensions:     m.Dimensions,
+		Status:         m.Status,
+		TotalChunks:    m.TotalChunks,
+		EmbeddedChunks: m.EmbeddedChunks,
+		FailedChunks:   m.FailedChunks,
+		Error:          m.ErrorMessage,
+		StartedAt:      m.StartedAt,
+		FinishedAt:     m.FinishedAt,
+		ActivatedAt:    m.ActivatedAt,
+	}
+	res.RequestID = utils.RequestID(ctx)
+	return res
+}
+
+func (c *Controller) schedule(ctx *gin.Context, req *IngestRequest, upload *Upload) {
+	c.respond(ctx, req.DataSourceID, c.svc.ScheduleIngestion(ctx.Request.Context(), req, upload))
+}
+
+// respond writes the 202 acknowledgement, or the scheduling error
+func (c *Controller) respond(ctx *gin.Context, dataSourceID int, err error) {
+	if err != nil {
+		utils.Zlog.Warn("failed to schedule ingestion",
+			zap.Int("data_source_id", dataSourceID),
+			zap.Error(err))
+		fail(ctx, err)
+		return
+	}
+
+	res := IngestResponse{
+		BaseResponse: types.BaseResponse{Success: true},
+		DataSourceID: dataSourceID,
+		Status:       loaders.DataSourcePending,
+	}
+	res.RequestID = utils.RequestID(ctx)
+	ctx.JSON(http.StatusAccepted, res)
+}
+
+// fail maps service errors to HTTP statuses: only validation errors are the client's
+// fault
+func fail(ctx *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, utils.ErrInvalidRequest):
+		status = http.StatusBadRequest
+	case errors.Is(err, ingestion.ErrQueueFull), errors.Is(err, ingestion.ErrWorkerStopped):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, ingestion.ErrReindexRunning), errors.Is(err, ingestion.ErrIngestionRunning),
+		errors.Is(err, ingestion.ErrMigrationRunning),
+		errors.Is(err, loaders.ErrDataSourceBusy):
+		status = http.StatusConflict
+	case errors.Is(err, loaders.ErrDataSourceOwner):
+		status = http.StatusForbidden
+	case errors.Is(err, loaders.ErrDataSourceNotFound), errors.Is(err, loaders.ErrReindexJobNotFound),
+		errors.Is(err, loaders.ErrEmbeddingMigrationNotFound):
+		status = http.StatusNotFound
+	}
+	utils.WriteError(ctx, status, "ingestion_error", err)
+}
@@ -0,0 +1,57 @@
+package datasource
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/Conversly/lightning-response/internal/config"
+	"github.com/Conversly/lightning-response/internal/crawler"
+	"github.com/Conversly/lightning-response/internal/embedder"
+	"github.com/Conversly/lightning-response/internal/ingestion"
+	"github.com/Conversly/lightning-response/internal/lifecycle"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/middleware"
+	"github.com/Conversly/lightning-response/internal/splitter"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+// RegisterRoutes registers the admin data source endpoints and starts the ingestion
+// workers, registering them with lm to be stopped on shutdown
+func RegisterRoutes(router *gin.Engine, db *loaders.PostgresClient, cfg *config.Config, embedders *embedder.Registry, lm *lifecycle.Manager) {
+	chunking := splitter.Config{
+		Strategy:     cfg.ChunkStrategy,
+		ChunkSize:    cfg.ChunkSize,
+		ChunkOverlap: cfg.ChunkOverlap,
+		Unit:         cfg.ChunkUnit,
+	}
+	pipeline := ingestion.NewPipeline(db, embedders, utils.NewFileDownloader(), crawler.NewClient(), chunking)
+	worker := ingestion.NewWorker(pipeline, cfg.IngestionWorkers, cfg.IngestionQueueSize)
+	worker.Start()
+	lm.RegisterFunc("ingestion worker", worker.Stop)
+
+	reindexer := ingestion.NewReindexer(pipeline)
+	lm.RegisterFunc("reindexer", reindexer.Stop)
+	migrator := ingestion.NewModelMigrator(pipeline, reindexer)
+	lm.RegisterFunc("embedding model migrator", migrator.Stop)
+
+	svc := NewService(db, worker, reindexer, migrator, crawler.Config{
+		MaxDepth:     cfg.CrawlerMaxDepth,
+		MaxPages:     cfg.CrawlerMaxPages,
+		RequestDelay: time.Duration(cfg.CrawlerRequestDelayMs) * time.Millisecond,
+		UserAgent:    cfg.CrawlerUserAgent,
+	}, cfg.UploadDir)
+	ctrl := NewController(svc)
+
+	group := router.Group("/admin/data-sources", middleware.AdminAuth(cfg.AdminAPIToken))
+	group.POST("/ingest", ctrl.Ingest)
+	group.POST("/upload", ctrl.Upload)
+	group.POST("/crawl", ctrl.Crawl)
+	group.DELETE("/:id/embeddings", ctrl.DeleteEmbeddings)
+
+	admin := router.Group("/admin", middleware.AdminAuth(cfg.AdminAPIToken))
+	admin.POST("/chatbots/:chatbotId/reindex", ctrl.StartReindex)
+	admin.GET("/reindex-jobs/:jobId", ctrl.GetReindexJob)
+	admin.POST("/chatbots/:chatbotId/embedding-migrations", ctrl.StartEmbeddingMigration)
+	admin.GET("/embedding-migrations/:migrationId", ctrl.GetEmbeddingMigration)
+}
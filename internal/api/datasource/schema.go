@@ -0,0 +1,94 @@
+package datasource
+
+import (
+	"time"
+
+	"github.com/Conversly/lightning-response/internal/types"
+)
+
+// IngestRequest schedules ingestion of a data source. For uploads the same fields are
+// sent as multipart form values alongside a "file" part; otherwise URL is downloaded.
+type IngestRequest struct {
+	DataSourceID int    `json:"dataSourceId" form:"dataSourceId"`
+	ChatbotID    string `json:"chatbotId" form:"chatbotId"`
+	UserID       string `json:"userId" form:"userId"`
+	URL          string `json:"url,omitempty" form:"url"`
+	ContentType  string `json:"contentType,omitempty" form:"contentType"`
+	Citation     string `json:"citation,omitempty" form:"citation"`
+}
+
+// CrawlRequest schedules a website crawl. Limits above the server's configured maximums
+// are capped; zero values use the maximums.
+type CrawlRequest struct {
+	DataSourceID    int    `json:"dataSourceId"`
+	ChatbotID       string `json:"chatbotId"`
+	UserID          string `json:"userId"`
+	URL             string `json:"url"`
+	SitemapURL      string `json:"sitemapUrl,omitempty"`
+	UseSitemap      bool   `json:"useSitemap,omitempty"`
+	PathPrefix      string `json:"pathPrefix,omitempty"`
+	AllowSubdomains bool   `json:"allowSubdomains,omitempty"`
+	MaxDepth        int    `json:"maxDepth,omitempty"`
+	MaxPages        int    `json:"maxPages,omitempty"`
+}
+
+// Upload is a file received with an ingestion request, spooled to disk by SpoolUpload
+type Upload struct {
+	Path        string
+	ContentType string
+	Filename    string
+}
+
+// DeleteEmbeddingsResponse reports how many embeddings were removed
+type DeleteEmbeddingsResponse struct {
+	types.BaseResponse
+	DataSourceID int   `json:"dataSourceId"`
+	Deleted      int64 `json:"deleted"`
+}
+
+// ReindexJobResponse describes a chatbot reindex and its progress
+type ReindexJobResponse struct {
+	types.BaseResponse
+	JobID              string     `json:"jobId"`
+	ChatbotID          string     `json:"chatbotId"`
+	Status             string     `json:"status"`
+	TotalDocuments     int        `json:"totalDocuments"`
+	ProcessedDocuments int        `json:"processedDocuments"`
+	ChunksEmbedded     int        `json:"chunksEmbedded"`
+	ChunksReused       int        `json:"chunksReused"`
+	Error              *string    `json:"error,omitempty"`
+	StartedAt          time.Time  `json:"startedAt"`
+	FinishedAt         *time.Time `json:"finishedAt,omitempty"`
+}
+
+// EmbeddingMigrationRequest moves a chatbot to another embedding model. Dimensions of 0
+// uses the model's default size; the service's default model switches back to the
+// primary vectors immediately.
+type EmbeddingMigrationRequest struct {
+	Model      string `json:"model"`
+	Dimensions int    `json:"dimensions,omitempty"`
+}
+
+// EmbeddingMigrationResponse describes an embedding model migration and its progress
+type EmbeddingMigrationResponse struct {
+	types.BaseResponse
+	MigrationID    string     `json:"migrationId"`
+	ChatbotID      string     `json:"chatbotId"`
+	Model          string     `json:"model"`
+	Dimensions     int        `json:"dimensions"`
+	Status         string     `json:"status"`
+	TotalChunks    int        `json:"totalChunks"`
+	EmbeddedChunks int        `json:"embeddedChunks"`
+	FailedChunks   int        `json:"failedChunks"`
+	Error          *string    `json:"error,omitempty"`
+	StartedAt      time.Time  `json:"startedAt"`
+	FinishedAt     *time.Time `json:"finishedAt,omitempty"`
+	ActivatedAt    *time.Time `json:"activatedAt,omitempty"`
+}
+
+// IngestResponse acknowledges a scheduled ingestion
+type IngestResponse struct {
+	types.BaseResponse
+	DataSourceID int    `json:"dataSourceId"`
+	Status       string `json:"status"`
+}
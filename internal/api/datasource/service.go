@@ -0,0 +1,214 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Conversly/lightning-response/internal/crawler"
+	"github.com/Conversly/lightning-response/internal/ingestion"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+var errUploadTooLarge = errors.New("file exceeds maximum allowed size")
+
+type Service struct {
+	db          *loaders.PostgresClient
+	worker      *ingestion.Worker
+	reindexer   *ingestion.Reindexer
+	migrator    *ingestion.ModelMigrator
+	crawlLimits crawler.Config
+	uploadDir   string
+}
+
+// NewService creates the data source service. crawlLimits holds the maximum depth and
+// page count a crawl may request, plus the request delay and user agent to crawl with.
+// Uploads are spooled to uploadDir until their job runs.
+func NewService(db *loaders.PostgresClient, worker *ingestion.Worker, reindexer *ingestion.Reindexer, migrator *ingestion.ModelMigrator, crawlLimits crawler.Config, uploadDir string) *Service {
+	return &Service{db: db, worker: worker, reindexer: reindexer, migrator: migrator, crawlLimits: crawlLimits, uploadDir: uploadDir}
+}
+
+// SpoolUpload copies an uploaded file to the upload directory so queued jobs hold a
+// path rather than the file's bytes. It returns the path of the spooled file.
+func (s *Service) SpoolUpload(r io.Reader) (string, error) {
+	if err := os.MkdirAll(s.uploadDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	f, err := os.CreateTemp(s.uploadDir, "upload-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to spool upload: %w", err)
+	}
+
+	n, err := io.Copy(f, io.LimitReader(r, utils.MaxDownloadSize+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > utils.MaxDownloadSize {
+		err = errUploadTooLarge
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		if errors.Is(err, errUploadTooLarge) {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to spool upload: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// ScheduleIngestion marks the data source PENDING and queues it for the worker.
+// upload may be nil, in which case req.URL is downloaded. The spooled upload is removed
+// if the job cannot be queued; otherwise the job removes it when done.
+func (s *Service) ScheduleIngestion(ctx context.Context, req *IngestRequest, upload *Upload) (err error) {
+	if upload != nil {
+		defer func() {
+			if err != nil {
+				_ = os.Remove(upload.Path)
+			}
+		}()
+	}
+	if req == nil {
+		return fmt.Errorf("%w: nil request", utils.ErrInvalidRequest)
+	}
+	if req.DataSourceID <= 0 || req.ChatbotID == "" || req.UserID == "" {
+		return fmt.Errorf("%w: dataSourceId, chatbotId and userId are required", utils.ErrInvalidRequest)
+	}
+	if upload == nil && req.URL == "" {
+		return fmt.Errorf("%w: either a file or a url is required", utils.ErrInvalidRequest)
+	}
+
+	job := &ingestion.Job{
+		DataSourceID: req.DataSourceID,
+		ChatbotID:    req.ChatbotID,
+		UserID:       req.UserID,
+		SourceURL:    req.URL,
+		ContentType:  req.ContentType,
+		Citation:     req.Citation,
+	}
+	if upload != nil {
+		job.UploadPath = upload.Path
+		job.Filename = upload.Filename
+		if job.ContentType == "" {
+			job.ContentType = upload.ContentType
+		}
+		if job.Citation == "" {
+			job.Citation = upload.Filename
+		}
+	}
+
+	return s.enqueue(ctx, job)
+}
+
+// ScheduleCrawl marks a website data source PENDING and queues its crawl
+func (s *Service) ScheduleCrawl(ctx context.Context, req *CrawlRequest) error {
+	if req == nil {
+		return fmt.Errorf("%w: nil request", utils.ErrInvalidRequest)
+	}
+	if req.DataSourceID <= 0 || req.ChatbotID == "" || req.UserID == "" || req.URL == "" {
+		return fmt.Errorf("%w: dataSourceId, chatbotId, userId and url are required", utils.ErrInvalidRequest)
+	}
+
+	crawl := crawler.Config{
+		StartURL:        req.URL,
+		SitemapURL:      req.SitemapURL,
+		UseSitemap:      req.UseSitemap,
+		PathPrefix:      req.PathPrefix,
+		AllowSubdomains: req.AllowSubdomains,
+		MaxDepth:        capLimit(req.MaxDepth, s.crawlLimits.MaxDepth),
+		MaxPages:        capLimit(req.MaxPages, s.crawlLimits.MaxPages),
+		RequestDelay:    s.crawlLimits.RequestDelay,
+		UserAgent:       s.crawlLimits.UserAgent,
+	}
+	// Validate the URL now rather than failing in the worker
+	if _, err := crawler.New(crawl, nil); err != nil {
+		return fmt.Errorf("%w: %v", utils.ErrInvalidRequest, err)
+	}
+
+	return s.enqueue(ctx, &ingestion.Job{
+		DataSourceID: req.DataSourceID,
+		ChatbotID:    req.ChatbotID,
+		UserID:       req.UserID,
+		SourceURL:    req.URL,
+		Citation:     req.URL,
+		Crawl:        &crawl,
+	})
+}
+
+func (s *Service) enqueue(ctx context.Context, job *ingestion.Job) error {
+	if err := s.db.CheckDataSourceOwner(ctx, job.DataSourceID, job.ChatbotID, job.UserID); err != nil {
+		return err
+	}
+	// Rows written during a reindex would be dropped by its swap; a PENDING data source
+	// keeps a reindex from starting until the job is done
+	err := s.reindexer.Guard(ctx, job.ChatbotID, func() error {
+		return s.db.SetDataSourceStatus(ctx, job.DataSourceID, loaders.DataSourcePending, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.worker.Enqueue(job); err != nil {
+		msg := err.Error()
+		_ = s.db.SetDataSourceStatus(ctx, job.DataSourceID, loaders.DataSourceFailed, &msg)
+		return err
+	}
+	return nil
+}
+
+// DeleteEmbeddings removes every embedding of a data source
+func (s *Service) DeleteEmbeddings(ctx context.Context, dataSourceID int) (int64, error) {
+	if dataSourceID <= 0 {
+		return 0, fmt.Errorf("%w: invalid data source id", utils.ErrInvalidRequest)
+	}
+	chatbotID, err := s.db.GetDataSourceChatbotID(ctx, dataSourceID)
+	if err != nil {
+		return 0, err
+	}
+	// The reindex would publish rebuilt copies of the deleted rows
+	var deleted int64
+	err = s.reindexer.Guard(ctx, chatbotID, func() error {
+		deleted, err = s.db.DeleteDataSourceEmbeddings(ctx, dataSourceID)
+		return err
+	})
+	return deleted, err
+}
+
+// StartReindex starts a background reindex of a chatbot
+func (s *Service) StartReindex(ctx context.Context, chatbotID string) (*loaders.ReindexJobRecord, error) {
+	if chatbotID == "" {
+		return nil, fmt.Errorf("%w: chatbotId is required", utils.ErrInvalidRequest)
+	}
+	return s.reindexer.Start(ctx, chatbotID)
+}
+
+// GetReindexJob returns the progress of a reindex
+func (s *Service) GetReindexJob(ctx context.Context, jobID string) (*loaders.ReindexJobRecord, error) {
+	return s.db.GetReindexJob(ctx, jobID)
+}
+
+// StartEmbeddingMigration starts moving a chatbot to another embedding model
+func (s *Service) StartEmbeddingMigration(ctx context.Context, chatbotID string, req *EmbeddingMigrationRequest) (*loaders.EmbeddingMigrationRecord, error) {
+	if chatbotID == "" || req == nil || req.Model == "" {
+		return nil, fmt.Errorf("%w: chatbotId and model are required", utils.ErrInvalidRequest)
+	}
+	if req.Dimensions < 0 {
+		return nil, fmt.Errorf("%w: dimensions must not be negative", utils.ErrInvalidRequest)
+	}
+	return s.migrator.Start(ctx, chatbotID, req.Model, req.Dimensions)
+}
+
+// GetEmbeddingMigration returns the progress of an embedding model migration
+func (s *Service) GetEmbeddingMigration(ctx context.Context, migrationID string) (*loaders.EmbeddingMigrationRecord, error) {
+	return s.db.GetEmbeddingMigration(ctx, migrationID)
+}
+
+// capLimit returns requested capped at max; zero or negative requests get max
+func capLimit(requested, max int) int {
+	if requested <= 0 || (max > 0 && requested > max) {
+		return max
+	}
+	return requested
+}
@@ -0,0 +1,144 @@
+package faq
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/types"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+type Controller struct {
+	svc *Service
+}
+
+func NewController(svc *Service) *Controller {
+	return &Controller{svc: svc}
+}
+
+// Create adds a curated answer to the chatbot in the path
+func (c *Controller) Create(ctx *gin.Context) {
+	var req EntryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.WriteError(ctx, http.StatusBadRequest, "faq_error", err)
+		return
+	}
+	entry, err := c.svc.Create(ctx.Request.Context(), ctx.Param("chatbotId"), &req)
+	if err != nil {
+		utils.Zlog.Warn("failed to create faq entry", zap.String("chatbot_id", ctx.Param("chatbotId")), zap.Error(err))
+		utils.WriteError(ctx, statusFor(err), "faq_error", err)
+		return
+	}
+	c.respond(ctx, http.StatusCreated, entry)
+}
+
+// List returns the curated answers of the chatbot in the path
+func (c *Controller) List(ctx *gin.Context) {
+	entries, err := c.svc.List(ctx.Request.Context(), ctx.Param("chatbotId"))
+	if err != nil {
+		utils.WriteError(ctx, statusFor(err), "faq_error", err)
+		return
+	}
+	res := ListResponse{BaseResponse: types.BaseResponse{Success: true}, Entries: make([]Entry, len(entries))}
+	for i := range entries {
+		res.Entries[i] = toEntry(&entries[i])
+	}
+	res.RequestID = utils.RequestID(ctx)
+	ctx.JSON(http.StatusOK, res)
+}
+
+// Get returns one curated answer of the chatbot in the path
+func (c *Controller) Get(ctx *gin.Context) {
+	id, ok := entryID(ctx)
+	if !ok {
+		return
+	}
+	entry, err := c.svc.Get(ctx.Request.Context(), ctx.Param("chatbotId"), id)
+	if err != nil {
+		utils.WriteError(ctx, statusFor(err), "faq_error", err)
+		return
+	}
+	c.respond(ctx, http.StatusOK, entry)
+}
+
+// Update replaces a curated answer of the chatbot in the path
+func (c *Controller) Update(ctx *gin.Context) {
+	id, ok := entryID(ctx)
+	if !ok {
+		return
+	}
+	var req EntryRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.WriteError(ctx, http.StatusBadRequest, "faq_error", err)
+		return
+	}
+	entry, err := c.svc.Update(ctx.Request.Context(), ctx.Param("chatbotId"), id, &req)
+	if err != nil {
+		utils.Zlog.Warn("failed to update faq entry",
+			zap.String("chatbot_id", ctx.Param("chatbotId")), zap.Int64("faq_id", id), zap.Error(err))
+		utils.WriteError(ctx, statusFor(err), "faq_error", err)
+		return
+	}
+	c.respond(ctx, http.StatusOK, entry)
+}
+
+// Delete removes a curated answer of the chatbot in the path
+func (c *Controller) Delete(ctx *gin.Context) {
+	id, ok := entryID(ctx)
+	if !ok {
+		return
+	}
+	if err := c.svc.Delete(ctx.Request.Context(), ctx.Param("chatbotId"), id); err != nil {
+		utils.WriteError(ctx, statusFor(err), "faq_error", err)
+		return
+	}
+	res := DeleteResponse{BaseResponse: types.BaseResponse{Success: true}, ID: id}
+	res.RequestID = utils.RequestID(ctx)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *Controller) respond(ctx *gin.Context, status int, entry *loaders.FAQEntry) {
+	res := EntryResponse{BaseResponse: types.BaseResponse{Success: true}, Entry: toEntry(entry)}
+	res.RequestID = utils.RequestID(ctx)
+	ctx.JSON(status, res)
+}
+
+func toEntry(e *loaders.FAQEntry) Entry {
+	return Entry{
+		ID:        e.ID,
+		ChatbotID: e.ChatbotID,
+		Questions: e.Questions,
+		Answer:    e.Answer,
+		Citation:  e.Citation,
+		CreatedAt: e.CreatedAt,
+		UpdatedAt: e.UpdatedAt,
+	}
+}
+
+func entryID(ctx *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil {
+		utils.WriteError(ctx, http.StatusBadRequest, "faq_error", errors.New("invalid faq id"))
+		return 0, false
+	}
+	return id, true
+}
+
+// statusFor maps service errors to HTTP statuses: only validation errors are the
+// client's fault
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, utils.ErrInvalidRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, loaders.ErrFAQNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errEmbedding):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,23 @@
+package faq
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/Conversly/lightning-response/internal/config"
+	"github.com/Conversly/lightning-response/internal/embedder"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/middleware"
+)
+
+// RegisterRoutes registers the admin endpoints for curated FAQ answers
+func RegisterRoutes(router *gin.Engine, db *loaders.PostgresClient, cfg *config.Config, embedders *embedder.Registry) {
+	svc := NewService(db, embedders)
+	ctrl := NewController(svc)
+
+	admin := router.Group("/admin", middleware.AdminAuth(cfg.AdminAPIToken))
+	admin.POST("/chatbots/:chatbotId/faqs", ctrl.Create)
+	admin.GET("/chatbots/:chatbotId/faqs", ctrl.List)
+	admin.GET("/chatbots/:chatbotId/faqs/:id", ctrl.Get)
+	admin.PUT("/chatbots/:chatbotId/faqs/:id", ctrl.Update)
+	admin.DELETE("/chatbots/:chatbotId/faqs/:id", ctrl.Delete)
+}
@@ -0,0 +1,44 @@
+package faq
+
+import (
+	"time"
+
+	"github.com/Conversly/lightning-response/internal/types"
+)
+
+// EntryRequest creates or replaces a curated answer. Questions are the phrasings that
+// should get this answer; each is matched separately.
+type EntryRequest struct {
+	Questions []string `json:"questions"`
+	Answer    string   `json:"answer"`
+	Citation  string   `json:"citation,omitempty"`
+}
+
+// Entry is a curated answer as returned by the API
+type Entry struct {
+	ID        int64     `json:"id"`
+	ChatbotID string    `json:"chatbotId"`
+	Questions []string  `json:"questions"`
+	Answer    string    `json:"answer"`
+	Citation  *string   `json:"citation,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// EntryResponse wraps one entry
+type EntryResponse struct {
+	types.BaseResponse
+	Entry Entry `json:"entry"`
+}
+
+// ListResponse holds a chatbot's entries
+type ListResponse struct {
+	types.BaseResponse
+	Entries []Entry `json:"entries"`
+}
+
+// DeleteResponse acknowledges a deleted entry
+type DeleteResponse struct {
+	types.BaseResponse
+	ID int64 `json:"id"`
+}
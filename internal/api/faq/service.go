@@ -0,0 +1,144 @@
+package faq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Conversly/lightning-response/internal/embedder"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+const maxQuestions = 20
+
+// errEmbedding marks a failure of the embedding provider
+var errEmbedding = errors.New("failed to embed question")
+
+type Service struct {
+	db        *loaders.PostgresClient
+	embedders *embedder.Registry
+}
+
+func NewService(db *loaders.PostgresClient, embedders *embedder.Registry) *Service {
+	return &Service{db: db, embedders: embedders}
+}
+
+// Create stores a new curated answer for a chatbot
+func (s *Service) Create(ctx context.Context, chatbotID string, req *EntryRequest) (*loaders.FAQEntry, error) {
+	if chatbotID == "" {
+		return nil, fmt.Errorf("%w: chatbotId is required", utils.ErrInvalidRequest)
+	}
+	entry, err := s.entryFrom(req)
+	if err != nil {
+		return nil, err
+	}
+	entry.ChatbotID = chatbotID
+
+	vectors, err := s.embed(ctx, chatbotID, entry.Questions)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.CreateFAQ(ctx, entry, vectors); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Update replaces the questions, answer and citation of a chatbot's entry
+func (s *Service) Update(ctx context.Context, chatbotID string, id int64, req *EntryRequest) (*loaders.FAQEntry, error) {
+	// Check the entry exists before paying for embeddings
+	if _, err := s.db.GetFAQ(ctx, chatbotID, id); err != nil {
+		return nil, err
+	}
+	entry, err := s.entryFrom(req)
+	if err != nil {
+		return nil, err
+	}
+	entry.ID, entry.ChatbotID = id, chatbotID
+
+	vectors, err := s.embed(ctx, chatbotID, entry.Questions)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.UpdateFAQ(ctx, entry, vectors); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *Service) Get(ctx context.Context, chatbotID string, id int64) (*loaders.FAQEntry, error) {
+	return s.db.GetFAQ(ctx, chatbotID, id)
+}
+
+func (s *Service) List(ctx context.Context, chatbotID string) ([]loaders.FAQEntry, error) {
+	return s.db.ListFAQs(ctx, chatbotID)
+}
+
+func (s *Service) Delete(ctx context.Context, chatbotID string, id int64) error {
+	return s.db.DeleteFAQ(ctx, chatbotID, id)
+}
+
+// entryFrom validates a request and normalises its questions
+func (s *Service) entryFrom(req *EntryRequest) (*loaders.FAQEntry, error) {
+	if req == nil {
+		return nil, fmt.Errorf("%w: nil request", utils.ErrInvalidRequest)
+	}
+	entry := &loaders.FAQEntry{Answer: strings.TrimSpace(req.Answer)}
+	if entry.Answer == "" {
+		return nil, fmt.Errorf("%w: answer is required", utils.ErrInvalidRequest)
+	}
+	seen := make(map[string]bool)
+	for _, q := range req.Questions {
+		q = strings.TrimSpace(q)
+		if q == "" || seen[strings.ToLower(q)] {
+			continue
+		}
+		seen[strings.ToLower(q)] = true
+		entry.Questions = append(entry.Questions, q)
+	}
+	if len(entry.Questions) == 0 {
+		return nil, fmt.Errorf("%w: at least one question is required", utils.ErrInvalidRequest)
+	}
+	if len(entry.Questions) > maxQuestions {
+		return nil, fmt.Errorf("%w: at most %d questions are allowed", utils.ErrInvalidRequest, maxQuestions)
+	}
+	if c := strings.TrimSpace(req.Citation); c != "" {
+		entry.Citation = &c
+	}
+	return entry, nil
+}
+
+// embed embeds the questions with the default model, so the entry survives a rollback
+// to it, and with the model the chatbot currently retrieves with if that differs.
+// Questions are embedded as queries since they are matched against user questions.
+func (s *Service) embed(ctx context.Context, chatbotID string, questions []string) ([]loaders.FAQVectors, error) {
+	settings, err := s.db.GetChatbotSettings(ctx, chatbotID)
+	if err != nil {
+		return nil, err
+	}
+	active, err := s.embedders.ForSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	models := []embedder.Embedder{s.embedders.Default()}
+	if active.Model() != models[0].Model() {
+		models = append(models, active)
+	}
+
+	var vectors []loaders.FAQVectors
+	for _, emb := range models {
+		v := loaders.FAQVectors{Model: emb.Model()}
+		for _, q := range questions {
+			vec, err := emb.EmbedQuery(ctx, q)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", errEmbedding, err)
+			}
+			v.Vectors = append(v.Vectors, vec)
+		}
+		vectors = append(vectors, v)
+	}
+	return vectors, nil
+}
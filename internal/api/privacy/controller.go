@@ -0,0 +1,150 @@
+package privacy
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/types"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+type Controller struct {
+	svc *Service
+}
+
+func NewController(svc *Service) *Controller {
+	return &Controller{svc: svc}
+}
+
+// GetRetention returns the retention period of the chatbot in the path
+func (c *Controller) GetRetention(ctx *gin.Context) {
+	days, err := c.svc.GetRetention(ctx.Request.Context(), ctx.Param("chatbotId"))
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	c.respondRetention(ctx, days)
+}
+
+// SetRetention sets the retention period of the chatbot in the path
+func (c *Controller) SetRetention(ctx *gin.Context) {
+	var req RetentionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.WriteError(ctx, http.StatusBadRequest, "privacy_error", err)
+		return
+	}
+	days, err := c.svc.SetRetention(ctx.Request.Context(), ctx.Param("chatbotId"), &req)
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	c.respondRetention(ctx, days)
+}
+
+// Erase removes a visitor's messages and feedback from the chatbot in the path
+func (c *Controller) Erase(ctx *gin.Context) {
+	var req ErasureRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.WriteError(ctx, http.StatusBadRequest, "privacy_error", err)
+		return
+	}
+	run, err := c.svc.Erase(ctx.Request.Context(), ctx.Param("chatbotId"), &req)
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	c.respondRun(ctx, run)
+}
+
+// ListRuns returns the latest purge and erasure audit records
+func (c *Controller) ListRuns(ctx *gin.Context) {
+	limit := 0
+	if raw := ctx.Query("limit"); raw != "" {
+		v, err := strconv.Atoi(raw)
+		if err != nil || v < 0 {
+			utils.WriteError(ctx, http.StatusBadRequest, "privacy_error", errors.New("invalid limit"))
+			return
+		}
+		limit = v
+	}
+	runs, err := c.svc.ListRuns(ctx.Request.Context(), ctx.Query("kind"), ctx.Query("chatbotId"), limit)
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	res := RunListResponse{BaseResponse: types.BaseResponse{Success: true}, Runs: make([]Run, len(runs))}
+	for i := range runs {
+		res.Runs[i] = toRun(&runs[i])
+	}
+	res.RequestID = utils.RequestID(ctx)
+	ctx.JSON(http.StatusOK, res)
+}
+
+// GetRun returns one audit record
+func (c *Controller) GetRun(ctx *gin.Context) {
+	run, err := c.svc.GetRun(ctx.Request.Context(), ctx.Param("runId"))
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	c.respondRun(ctx, run)
+}
+
+func (c *Controller) respondRetention(ctx *gin.Context, days *int) {
+	res := RetentionResponse{
+		BaseResponse:  types.BaseResponse{Success: true},
+		ChatbotID:     ctx.Param("chatbotId"),
+		RetentionDays: days,
+	}
+	res.RequestID = utils.RequestID(ctx)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *Controller) respondRun(ctx *gin.Context, run *loaders.RetentionRunRecord) {
+	res := RunResponse{BaseResponse: types.BaseResponse{Success: true}, Run: toRun(run)}
+	res.RequestID = utils.RequestID(ctx)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *Controller) fail(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrInvalidRequest):
+		utils.WriteError(ctx, http.StatusBadRequest, "privacy_error", err)
+	case errors.Is(err, loaders.ErrRetentionRunNotFound):
+		utils.WriteError(ctx, http.StatusNotFound, "privacy_error", err)
+	default:
+		utils.Zlog.Error("privacy request failed", zap.String("chatbot_id", ctx.Param("chatbotId")), zap.Error(err))
+		utils.WriteError(ctx, http.StatusInternalServerError, "privacy_error", err)
+	}
+}
+
+// notErased lists what an erasure leaves behind. Leads are stored by the service that
+// captures them, not in this database, and have to be erased there.
+var notErased = []string{"leads"}
+
+func toRun(r *loaders.RetentionRunRecord) Run {
+	run := Run{
+		ID:                 r.ID,
+		Kind:               r.Kind,
+		ChatbotID:          r.ChatbotID,
+		SubjectHash:        r.SubjectHash,
+		Mode:               r.Mode,
+		RequestedBy:        r.RequestedBy,
+		Status:             r.Status,
+		MessagesDeleted:    r.MessagesDeleted,
+		MessagesAnonymized: r.MessagesAnonymized,
+		Details:            r.Details,
+		Error:              r.ErrorMessage,
+		StartedAt:          r.StartedAt,
+		FinishedAt:         r.FinishedAt,
+	}
+	if r.Kind == loaders.RetentionErasure {
+		run.NotErased = notErased
+	}
+	return run
+}
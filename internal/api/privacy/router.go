@@ -0,0 +1,29 @@
+package privacy
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/Conversly/lightning-response/internal/config"
+	"github.com/Conversly/lightning-response/internal/lifecycle"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/middleware"
+	"github.com/Conversly/lightning-response/internal/retention"
+)
+
+// RegisterRoutes registers the admin endpoints for retention periods, visitor erasure
+// and their audit trail, and starts the retention purge, registering it with lm
+func RegisterRoutes(router *gin.Engine, db *loaders.PostgresClient, cfg *config.Config, lm *lifecycle.Manager) {
+	purger := retention.NewPurger(db, cfg.RetentionPurgeInterval)
+	purger.Start()
+	lm.RegisterFunc("retention purger", purger.Stop)
+
+	svc := NewService(db)
+	ctrl := NewController(svc)
+
+	admin := router.Group("/admin", middleware.AdminAuth(cfg.AdminAPIToken))
+	admin.GET("/chatbots/:chatbotId/retention", ctrl.GetRetention)
+	admin.PUT("/chatbots/:chatbotId/retention", ctrl.SetRetention)
+	admin.POST("/chatbots/:chatbotId/erasures", ctrl.Erase)
+	admin.GET("/retention-runs", ctrl.ListRuns)
+	admin.GET("/retention-runs/:runId", ctrl.GetRun)
+}
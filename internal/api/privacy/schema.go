@@ -0,0 +1,58 @@
+package privacy
+
+import (
+	"time"
+
+	"github.com/Conversly/lightning-response/internal/types"
+)
+
+// RetentionRequest sets how many days a chatbot's messages are kept. Null or 0 keeps
+// them indefinitely.
+type RetentionRequest struct {
+	RetentionDays *int `json:"retentionDays"`
+}
+
+// RetentionResponse reports a chatbot's retention period
+type RetentionResponse struct {
+	types.BaseResponse
+	ChatbotID     string `json:"chatbotId"`
+	RetentionDays *int   `json:"retentionDays"`
+}
+
+// ErasureRequest removes a visitor's messages and feedback from a chatbot. Mode is
+// delete (the default) or anonymize, which keeps the messages without their content.
+type ErasureRequest struct {
+	UniqueClientID string `json:"uniqueClientId" binding:"required"`
+	Mode           string `json:"mode,omitempty"`
+	RequestedBy    string `json:"requestedBy,omitempty"`
+}
+
+// Run is the audit record of a purge or erasure
+type Run struct {
+	ID                 string           `json:"runId"`
+	Kind               string           `json:"kind"` // PURGE | ERASURE
+	ChatbotID          *string          `json:"chatbotId,omitempty"`
+	SubjectHash        *string          `json:"subjectHash,omitempty"`
+	Mode               *string          `json:"mode,omitempty"`
+	RequestedBy        *string          `json:"requestedBy,omitempty"`
+	Status             string           `json:"status"`
+	MessagesDeleted    int64            `json:"messagesDeleted"`
+	MessagesAnonymized int64            `json:"messagesAnonymized"`
+	Details            map[string]int64 `json:"details,omitempty"`
+	NotErased          []string         `json:"notErased,omitempty"` // ERASURE: data this service cannot erase
+	Error              *string          `json:"error,omitempty"`
+	StartedAt          time.Time        `json:"startedAt"`
+	FinishedAt         *time.Time       `json:"finishedAt,omitempty"`
+}
+
+// RunResponse wraps one run
+type RunResponse struct {
+	types.BaseResponse
+	Run Run `json:"run"`
+}
+
+// RunListResponse holds the latest runs, newest first
+type RunListResponse struct {
+	types.BaseResponse
+	Runs []Run `json:"runs"`
+}
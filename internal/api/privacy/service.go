@@ -0,0 +1,83 @@
+package privacy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/retention"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+const (
+	maxRetentionDays = 3650
+	defaultRunLimit  = 50
+	maxRunLimit      = 500
+)
+
+type Service struct {
+	db *loaders.PostgresClient
+}
+
+func NewService(db *loaders.PostgresClient) *Service {
+	return &Service{db: db}
+}
+
+// GetRetention returns a chatbot's retention period, nil when messages are kept
+func (s *Service) GetRetention(ctx context.Context, chatbotID string) (*int, error) {
+	settings, err := s.db.GetChatbotSettings(ctx, chatbotID)
+	if err != nil {
+		return nil, err
+	}
+	return settings.RetentionDays, nil
+}
+
+// SetRetention sets a chatbot's retention period. It is enforced by the next purge.
+func (s *Service) SetRetention(ctx context.Context, chatbotID string, req *RetentionRequest) (*int, error) {
+	days := req.RetentionDays
+	if days != nil && *days == 0 {
+		days = nil
+	}
+	if days != nil && (*days < 0 || *days > maxRetentionDays) {
+		return nil, fmt.Errorf("%w: retentionDays must be between 1 and %d", utils.ErrInvalidRequest, maxRetentionDays)
+	}
+	if err := s.db.SetRetentionDays(ctx, chatbotID, days); err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+// Erase deletes or anonymizes a visitor's messages and feedback
+func (s *Service) Erase(ctx context.Context, chatbotID string, req *ErasureRequest) (*loaders.RetentionRunRecord, error) {
+	mode := strings.ToUpper(req.Mode)
+	if mode == "" {
+		mode = loaders.ErasureDelete
+	}
+	if mode != loaders.ErasureDelete && mode != loaders.ErasureAnonymize {
+		return nil, fmt.Errorf("%w: mode must be delete or anonymize", utils.ErrInvalidRequest)
+	}
+	return retention.Erase(ctx, s.db, &retention.Erasure{
+		ChatbotID:   chatbotID,
+		ClientID:    req.UniqueClientID,
+		Mode:        mode,
+		RequestedBy: req.RequestedBy,
+	})
+}
+
+// ListRuns returns the latest audit records, optionally of one kind or chatbot
+func (s *Service) ListRuns(ctx context.Context, kind, chatbotID string, limit int) ([]loaders.RetentionRunRecord, error) {
+	kind = strings.ToUpper(kind)
+	if kind != "" && kind != loaders.RetentionPurge && kind != loaders.RetentionErasure {
+		return nil, fmt.Errorf("%w: kind must be purge or erasure", utils.ErrInvalidRequest)
+	}
+	if limit <= 0 {
+		limit = defaultRunLimit
+	}
+	return s.db.ListRetentionRuns(ctx, kind, chatbotID, min(limit, maxRunLimit))
+}
+
+// GetRun returns one audit record
+func (s *Service) GetRun(ctx context.Context, runID string) (*loaders.RetentionRunRecord, error) {
+	return s.db.GetRetentionRun(ctx, runID)
+}
@@ -2,11 +2,15 @@ package response
 
 import (
 	"context"
+	"expvar"
+	"fmt"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Conversly/lightning-response/internal/config"
 	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/outbox"
 	"github.com/Conversly/lightning-response/internal/utils"
 	"go.uber.org/zap"
 )
@@ -18,15 +22,23 @@ type MessageRecord struct {
 	Role           string // user | assistant
 	Citations      []string
 	MessageUID     string
+	TopicID        string   // topic of a user message, "" for none
+	LatencyMS      int64    // time taken to answer an assistant message
+	Tools          []string // tools called to answer an assistant message
 }
 
+// messageSaver persists conversation messages in batches. With an outbox, messages are
+// on local disk before a save returns and a replayer moves them to Postgres; the
+// in-memory channel then only takes messages the outbox refuses.
 type messageSaver struct {
 	db            *loaders.PostgresClient
 	ch            chan loaders.MessageRow
+	outbox        *outbox.Outbox
 	batchSize     int
 	flushInterval time.Duration
 	stopCh        chan struct{}
 	stoppedCh     chan struct{}
+	replayDone    chan struct{}
 }
 
 var (
@@ -34,13 +46,31 @@ var (
 	msgSaverOnce sync.Once
 )
 
+// Message saver counters, published on /debug/vars
+var (
+	msgRowsInserted  = expvar.NewInt("message_saver_rows_inserted")
+	msgRowsDuplicate = expvar.NewInt("message_saver_rows_duplicate")
+	msgRowsFailed    = expvar.NewInt("message_saver_rows_failed")
+	msgFlushes       = expvar.NewInt("message_saver_flushes")
+	msgFlushRetries  = expvar.NewInt("message_saver_flush_retries")
+	msgFlushErrors   = expvar.NewInt("message_saver_flush_errors")
+	msgFlushMillis   = expvar.NewInt("message_saver_flush_ms_total")
+	msgRowsDropped   = expvar.NewInt("message_saver_rows_dropped")
+)
+
 const (
 	defaultMsgBatchSize    = 1000
 	defaultFlushInterval   = 500 * time.Millisecond
 	defaultChannelCapacity = 10000
 )
 
-func initMessageSaver(db *loaders.PostgresClient) {
+// StartMessageSaver starts the message saver with the outbox configured in cfg, replaying
+// messages left in it by a previous run. It is a no-op once the saver is running.
+func StartMessageSaver(db *loaders.PostgresClient, cfg *config.Config) {
+	initMessageSaver(db, cfg)
+}
+
+func initMessageSaver(db *loaders.PostgresClient, cfg *config.Config) {
 	msgSaverOnce.Do(func() {
 		msgSaver = &messageSaver{
 			db:            db,
@@ -50,7 +80,14 @@ func initMessageSaver(db *loaders.PostgresClient) {
 			stopCh:        make(chan struct{}),
 			stoppedCh:     make(chan struct{}),
 		}
+		if cfg != nil && cfg.MessageOutboxDir != "" {
+			msgSaver.outbox = openMessageOutbox(cfg)
+		}
 		go msgSaver.run()
+		if msgSaver.outbox != nil {
+			msgSaver.replayDone = make(chan struct{})
+			go msgSaver.replay()
+		}
 	})
 }
 
@@ -64,15 +101,7 @@ func (w *messageSaver) run() {
 		if len(batch) == 0 {
 			return
 		}
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		if err := w.db.BatchInsertMessages(ctx, batch); err != nil {
-			utils.Zlog.Error("Failed to batch insert messages", zap.Error(err), zap.Int("count", len(batch)))
-			// Best-effort: retry once
-			if err2 := w.db.BatchInsertMessages(ctx, batch); err2 != nil {
-				utils.Zlog.Error("Retry failed for batch insert messages", zap.Error(err2), zap.Int("count", len(batch)))
-			}
-		}
+		w.flush(batch)
 		batch = batch[:0]
 	}
 
@@ -103,33 +132,89 @@ func (w *messageSaver) run() {
 	}
 }
 
+// flush writes a batch, retrying once if the batch as a whole could not be attempted.
+// Inserts are idempotent on the message id, so rows written by the first attempt are
+// skipped by the retry.
+func (w *messageSaver) flush(batch []loaders.MessageRow) {
+	start := time.Now()
+	res, err := insertMessages(w.db, batch, 5*time.Second)
+	if err != nil {
+		utils.Zlog.Error("Failed to batch insert messages", zap.Error(err), zap.Int("count", len(batch)))
+		msgFlushRetries.Add(1)
+		res, err = insertMessages(w.db, batch, 5*time.Second)
+	}
+	elapsed := time.Since(start)
+	msgFlushes.Add(1)
+	msgFlushMillis.Add(elapsed.Milliseconds())
+	if err != nil {
+		msgFlushErrors.Add(1)
+		msgRowsFailed.Add(int64(len(batch)))
+		utils.Zlog.Error("Retry failed for batch insert messages", zap.Error(err), zap.Int("count", len(batch)))
+		return
+	}
+
+	utils.Zlog.Debug("Flushed message batch",
+		zap.Int("count", len(batch)),
+		zap.Int("inserted", res.Inserted),
+		zap.Int("duplicates", res.Duplicates),
+		zap.Int("failed", len(res.Failed)),
+		zap.Duration("elapsed", elapsed),
+		zap.Float64("rows_per_sec", float64(len(batch))/max(elapsed.Seconds(), 1e-6)))
+}
+
+// insertMessages inserts rows and records the per-row outcome in the saver counters
+func insertMessages(db *loaders.PostgresClient, rows []loaders.MessageRow, timeout time.Duration) (*loaders.MessageInsertResult, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	res, err := db.BatchInsertMessages(ctx, rows)
+	if err != nil {
+		return nil, err
+	}
+	msgRowsInserted.Add(int64(res.Inserted))
+	msgRowsDuplicate.Add(int64(res.Duplicates))
+	msgRowsFailed.Add(int64(len(res.Failed)))
+	for _, f := range res.Failed {
+		utils.Zlog.Error("Failed to insert message",
+			zap.String("message_id", f.MessageID),
+			zap.String("chatbot_id", rows[f.Index].ChatbotID),
+			zap.Error(f.Err))
+	}
+	return res, nil
+}
+
+// StopMessageSaver flushes the in-memory queue and stops the replayer. Messages still in
+// the outbox are replayed on the next start.
 func StopMessageSaver() {
 	if msgSaver == nil {
 		return
 	}
 	close(msgSaver.stopCh)
 	<-msgSaver.stoppedCh
+	if msgSaver.outbox != nil {
+		<-msgSaver.replayDone
+		if err := msgSaver.outbox.Close(); err != nil {
+			utils.Zlog.Error("Failed to close message outbox", zap.Error(err))
+		}
+	}
 }
 
+// SaveConversationMessagesBackground queues messages for saving. It returns once they are
+// in the outbox, or queued in memory if the outbox is disabled or refuses them, and
+// fails only for messages that could not be queued at all.
 func SaveConversationMessagesBackground(ctx context.Context, db *loaders.PostgresClient, records ...MessageRecord) error {
 	if db == nil {
 		return nil
 	}
-	initMessageSaver(db)
+	initMessageSaver(db, nil)
 
+	rows := make([]loaders.MessageRow, 0, len(records))
 	for _, r := range records {
 		citations := r.Citations
 		if citations == nil {
 			citations = []string{}
 		}
 
-		// Determine topic ID for user messages
-		topicID := ""
-		if strings.ToLower(r.Role) == "user" {
-			topicID = determineTopicID(ctx, db, r.ChatbotID, r.Message)
-		}
-
-		row := loaders.MessageRow{
+		rows = append(rows, loaders.MessageRow{
 			ChatbotID:    r.ChatbotID,
 			Citations:    citations,
 			Type:         strings.ToLower(r.Role),
@@ -137,50 +222,34 @@ func SaveConversationMessagesBackground(ctx context.Context, db *loaders.Postgre
 			CreatedAt:    time.Now().UTC(),
 			UniqueConvID: r.UniqueClientID,
 			UniqueMsgID:  r.MessageUID,
-			TopicID:      topicID,
+			TopicID:      r.TopicID,
+			LatencyMS:    r.LatencyMS,
+			Tools:        r.Tools,
+		})
+	}
+
+	if msgSaver.outbox != nil {
+		err := msgSaver.appendOutbox(rows)
+		if err == nil {
+			return nil
 		}
+		utils.Zlog.Warn("Failed to write messages to outbox, queueing in memory",
+			zap.Int("count", len(rows)),
+			zap.Error(err))
+	}
 
+	dropped := 0
+	for _, row := range rows {
 		select {
 		case msgSaver.ch <- row:
 			// enqueued
 		default:
-			// queue full: fallback to direct insert asynchronously
-			go func(r loaders.MessageRow) {
-				ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-				defer cancel()
-				_ = db.BatchInsertMessages(ctx, []loaders.MessageRow{r})
-			}(row)
+			dropped++
 		}
 	}
-	return nil
-}
-
-// determineTopicID extracts keywords from message and matches to chatbot topics
-func determineTopicID(ctx context.Context, db *loaders.PostgresClient, chatbotID string, message string) string {
-	// Fetch chatbot topics
-	info, err := db.GetChatbotInfoWithTopics(ctx, chatbotID)
-	if err != nil {
-		utils.Zlog.Debug("Failed to fetch chatbot topics for topic tagging",
-			zap.String("chatbot_id", chatbotID),
-			zap.Error(err))
-		return ""
-	}
-
-	// If no topics configured, return empty string
-	if len(info.Topics) == 0 {
-		return ""
+	if dropped > 0 {
+		msgRowsDropped.Add(int64(dropped))
+		return fmt.Errorf("message queue full, dropped %d of %d messages", dropped, len(rows))
 	}
-
-	// Extract keywords from message
-	keywords := utils.ExtractKeywords(message, 4)
-
-	// Match keywords to topics (will use "other" topic as fallback)
-	topicID := utils.MatchTopicFromKeywords(keywords, info.Topics)
-
-	utils.Zlog.Debug("Message tagged with topic",
-		zap.String("chatbot_id", chatbotID),
-		zap.Strings("keywords", keywords),
-		zap.String("topic_id", topicID))
-
-	return topicID
+	return nil
 }
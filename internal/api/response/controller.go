@@ -1,9 +1,13 @@
 package response
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/Conversly/lightning-response/internal/rag"
 	"github.com/Conversly/lightning-response/internal/utils"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -88,3 +92,39 @@ func (c *Controller) PlaygroundResponse(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, result)
 }
+
+// InspectRetrieval runs the retriever of the chatbot in the path for a query and returns
+// every step, for debugging what the knowledge base returns
+func (c *Controller) InspectRetrieval(ctx *gin.Context) {
+	var req InspectRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.WriteError(ctx, http.StatusBadRequest, "bad_request", err)
+		return
+	}
+	var invalid error
+	switch {
+	case strings.TrimSpace(req.Query) == "":
+		invalid = errors.New("query is required")
+	case req.Mode != "" && req.Mode != rag.ModeSimilarity && req.Mode != rag.ModeMMR:
+		invalid = fmt.Errorf("mode must be %q or %q", rag.ModeSimilarity, rag.ModeMMR)
+	case req.TopK < 0 || req.TopK > maxInspectTopK:
+		invalid = fmt.Errorf("topK must be between 1 and %d, or 0 for the default", maxInspectTopK)
+	}
+	if invalid != nil {
+		utils.WriteError(ctx, http.StatusBadRequest, "bad_request", invalid)
+		return
+	}
+
+	result, err := c.graphService.InspectRetrieval(ctx.Request.Context(), ctx.Param("chatbotId"), &req)
+	if err != nil {
+		utils.Zlog.Error("retrieval inspection failed",
+			zap.String("chatbot_id", ctx.Param("chatbotId")),
+			zap.Error(err))
+		utils.WriteError(ctx, http.StatusInternalServerError, "internal_error", err)
+		return
+	}
+
+	result.Success = true
+	result.RequestID = utils.RequestID(ctx)
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,106 @@
+package response
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/embedder"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+// faqContextEntries is how many curated answers may be added as priority context
+const faqContextEntries = 3
+
+// generatedAnswer is the assistant reply before it is wrapped in a Response
+type generatedAnswer struct {
+	Content   string
+	Citations []string
+	FAQID     *int64   // set when Content is a curated FAQ answer
+	Tools     []string // tools called to generate it
+	// QueryVector is the user's question as embedded by the chatbot's embedder, nil when
+	// it was not embedded
+	QueryVector []float64
+}
+
+// applyFAQ matches the user's question against the chatbot's curated answers. A match
+// at or above the answer threshold is returned to be sent verbatim. Weaker matches above
+// the context threshold are placed in cfg as priority context for the graph. vec is the
+// question as embedded by emb; without it, or if the lookup fails, the question is
+// answered normally.
+func (s *GraphService) applyFAQ(ctx context.Context, cfg *ChatbotConfig, emb embedder.Embedder, vec []float64) *generatedAnswer {
+	if vec == nil {
+		return nil
+	}
+
+	matches, err := s.db.SearchFAQ(ctx, cfg.ChatbotID, emb.Model(), vec, faqContextEntries)
+	if err != nil {
+		utils.Zlog.Warn("FAQ lookup failed",
+			zap.String("chatbot_id", cfg.ChatbotID),
+			zap.Error(err))
+		return nil
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+
+	if best := matches[0]; best.Score >= s.cfg.FAQAnswerThreshold {
+		utils.Zlog.Info("Answered from curated FAQ",
+			zap.String("chatbot_id", cfg.ChatbotID),
+			zap.Int64("faq_id", best.FAQID),
+			zap.Float64("score", best.Score))
+		citations := []string{}
+		if best.Citation != nil && *best.Citation != "" {
+			citations = append(citations, *best.Citation)
+		}
+		return &generatedAnswer{Content: best.Answer, Citations: citations, FAQID: &best.FAQID}
+	}
+
+	var b strings.Builder
+	for _, m := range matches {
+		if m.Score < s.cfg.FAQContextThreshold {
+			break
+		}
+		if b.Len() == 0 {
+			b.WriteString("**Curated Answers**:\n")
+			b.WriteString("The chatbot owner has approved the answers below. They take priority over the knowledge base and your own knowledge: " +
+				"if one of them answers the user's question, base your reply on it and do not contradict it.\n")
+		}
+		fmt.Fprintf(&b, "\nQ: %s\nA: %s\n", m.Question, m.Answer)
+		if m.Citation != nil && *m.Citation != "" {
+			cfg.CuratedCitations = append(cfg.CuratedCitations, *m.Citation)
+		}
+	}
+	cfg.CuratedContext = b.String()
+	return nil
+}
+
+// matchFAQ returns the chatbot's curated answers closest to question, best first
+func (s *GraphService) matchFAQ(ctx context.Context, chatbotID string, emb embedder.Embedder, question string) ([]loaders.FAQMatch, error) {
+	vec, err := emb.EmbedQuery(ctx, question)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed question: %w", err)
+	}
+	return s.db.SearchFAQ(ctx, chatbotID, emb.Model(), vec, faqContextEntries)
+}
+
+// mergeCitations puts the curated citations first and drops duplicates
+func mergeCitations(curated, generated []string) []string {
+	if len(curated) == 0 {
+		return generated
+	}
+	seen := make(map[string]bool, len(curated)+len(generated))
+	merged := make([]string, 0, len(curated)+len(generated))
+	for _, list := range [][]string{curated, generated} {
+		for _, c := range list {
+			if !seen[c] {
+				seen[c] = true
+				merged = append(merged, c)
+			}
+		}
+	}
+	return merged
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/cloudwego/eino/components/tool"
 	"github.com/cloudwego/eino/compose"
@@ -13,6 +14,7 @@ import (
 	"github.com/Conversly/lightning-response/internal/embedder"
 	"github.com/Conversly/lightning-response/internal/llm"
 	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/rag"
 	"github.com/Conversly/lightning-response/internal/utils"
 )
 
@@ -26,6 +28,41 @@ type GraphState struct {
 	Citations       []string               // Collected citations from RAG tool
 }
 
+// toolUsageKey carries the toolUsage of a graph run in its context
+type toolUsageKey struct{}
+
+// toolUsage records the tools called during a graph run
+type toolUsage struct {
+	mu    sync.Mutex
+	names []string
+}
+
+// withToolUsage returns a context recording the tools a graph run with it calls
+func withToolUsage(ctx context.Context) (context.Context, *toolUsage) {
+	u := &toolUsage{names: []string{}}
+	return context.WithValue(ctx, toolUsageKey{}, u), u
+}
+
+// recordToolCalls adds calls to the run's tool usage, if it is recorded
+func recordToolCalls(ctx context.Context, calls []schema.ToolCall) {
+	u, ok := ctx.Value(toolUsageKey{}).(*toolUsage)
+	if !ok {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, call := range calls {
+		u.names = append(u.names, call.Function.Name)
+	}
+}
+
+// list returns the names of the tools called, in call order
+func (u *toolUsage) list() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string{}, u.names...)
+}
+
 type ChatbotConfig struct {
 	ChatbotID     string
 	SystemPrompt  string
@@ -35,12 +72,48 @@ type ChatbotConfig struct {
 	TopK          int32    // Gemini-specific: controls diversity (1-40)
 	ToolConfigs   []string // e.g., ["rag"] more tools can be added
 	GeminiAPIKeys []string // Multiple API keys for rate limit distribution
+
+	// Retrieval selection settings (see rag.RetrieverConfig)
+	RetrievalMode   string
+	MMRLambda       float64
+	MMRFetchK       int
+	DedupeThreshold float64
+
+	// Context expansion settings (service defaults overridden by chatbot_settings)
+	ContextExpansion  string
+	ContextNeighbours int
+	ContextCharBudget int
+
+	// Embedding model the chatbot was migrated to; empty for the service default
+	EmbeddingModel      string
+	EmbeddingDimensions int
+
+	// Curated FAQ answers close to the question, added to the system prompt with
+	// priority over retrieved context, and their citations
+	CuratedContext   string
+	CuratedCitations []string
+}
+
+// RetrieverConfig derives the retriever settings for this chatbot
+func (c *ChatbotConfig) RetrieverConfig() rag.RetrieverConfig {
+	return rag.RetrieverConfig{
+		ChatbotID:       c.ChatbotID,
+		TopK:            int(c.TopK),
+		Mode:            c.RetrievalMode,
+		MMRLambda:       c.MMRLambda,
+		FetchK:          c.MMRFetchK,
+		DedupeThreshold: c.DedupeThreshold,
+		Expansion:       c.ContextExpansion,
+		Neighbours:      c.ContextNeighbours,
+		CharBudget:      c.ContextCharBudget,
+		EmbeddingModel:  c.EmbeddingModel,
+	}
 }
 
 // GraphDependencies holds dependencies needed for graph building
 type GraphDependencies struct {
 	DB       *loaders.PostgresClient
-	Embedder *embedder.GeminiEmbedder
+	Embedder embedder.Embedder
 }
 
 // BuildChatbotGraph creates a new graph for each request
@@ -125,6 +198,9 @@ func BuildChatbotGraph(ctx context.Context, cfg *ChatbotConfig, deps *GraphDepen
 			finalMessages := make([]*schema.Message, 0, len(state.Messages)+1)
 
 			systemPromptContent := promptBuilder(cfg.SystemPrompt)
+			if cfg.CuratedContext != "" {
+				systemPromptContent += "\n\n" + cfg.CuratedContext
+			}
 			finalMessages = append(finalMessages, schema.SystemMessage(systemPromptContent))
 
 			// Add all conversation messages
@@ -173,6 +249,7 @@ func BuildChatbotGraph(ctx context.Context, cfg *ChatbotConfig, deps *GraphDepen
 		graph.AddToolsNode("tools", toolsNode,
 			compose.WithStatePreHandler(func(ctx context.Context, input *schema.Message, state *GraphState) (*schema.Message, error) {
 				state.ToolCallCount++
+				recordToolCalls(ctx, input.ToolCalls)
 				utils.Zlog.Info("Executing tool calls",
 					zap.String("chatbot_id", cfg.ChatbotID),
 					zap.Int("tool_call_count", state.ToolCallCount),
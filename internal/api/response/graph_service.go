@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cloudwego/eino/compose"
@@ -14,23 +15,45 @@ import (
 
 	"github.com/Conversly/lightning-response/internal/config"
 	"github.com/Conversly/lightning-response/internal/embedder"
+	"github.com/Conversly/lightning-response/internal/lifecycle"
 	"github.com/Conversly/lightning-response/internal/loaders"
 	"github.com/Conversly/lightning-response/internal/rag"
+	"github.com/Conversly/lightning-response/internal/topics"
 	"github.com/Conversly/lightning-response/internal/types"
 	"github.com/Conversly/lightning-response/internal/utils"
 )
 
+// settingsCacheTTL is how long a chatbot's settings are reused before they are reloaded,
+// so a change takes effect within it
+const settingsCacheTTL = 30 * time.Second
+
+// classifyTimeout bounds topic tagging of a saved user message, which may embed topics
+const classifyTimeout = 10 * time.Second
+
 type GraphService struct {
-	db       *loaders.PostgresClient
-	cfg      *config.Config
-	embedder *embedder.GeminiEmbedder
+	db        *loaders.PostgresClient
+	cfg       *config.Config
+	embedders *embedder.Registry
+	topics    *topics.Classifier
+	lifecycle *lifecycle.Manager
+
+	settingsMu sync.Mutex
+	settings   map[string]cachedSettings // chatbot id -> settings
+}
+
+type cachedSettings struct {
+	settings *types.ChatbotSettings
+	expires  time.Time
 }
 
-func NewGraphService(db *loaders.PostgresClient, cfg *config.Config, embedder *embedder.GeminiEmbedder) *GraphService {
+func NewGraphService(db *loaders.PostgresClient, cfg *config.Config, embedders *embedder.Registry, classifier *topics.Classifier, lm *lifecycle.Manager) *GraphService {
 	return &GraphService{
-		db:       db,
-		cfg:      cfg,
-		embedder: embedder,
+		db:        db,
+		cfg:       cfg,
+		embedders: embedders,
+		topics:    classifier,
+		lifecycle: lm,
+		settings:  make(map[string]cachedSettings),
 	}
 }
 
@@ -40,6 +63,98 @@ func (s *GraphService) Initialize(ctx context.Context) error {
 	return nil
 }
 
+// chatbotConfig returns the service defaults for a chatbot with its stored settings
+// applied. Callers fill in the prompt and model.
+func (s *GraphService) chatbotConfig(ctx context.Context, chatbotID string) *ChatbotConfig {
+	cfg := &ChatbotConfig{
+		ChatbotID:     chatbotID,
+		Temperature:   0.7,
+		Model:         "gemini-2.0-flash-lite",
+		MaxTokens:     1024,
+		TopK:          5,
+		ToolConfigs:   []string{"rag"},
+		GeminiAPIKeys: s.cfg.GeminiAPIKeys,
+
+		RetrievalMode:   s.cfg.RetrievalMode,
+		MMRLambda:       s.cfg.MMRLambda,
+		MMRFetchK:       s.cfg.MMRFetchK,
+		DedupeThreshold: s.cfg.DedupeThreshold,
+
+		ContextExpansion:  s.cfg.ContextExpansion,
+		ContextNeighbours: s.cfg.ContextNeighbours,
+		ContextCharBudget: s.cfg.ContextCharBudget,
+	}
+	s.applyChatbotSettings(ctx, cfg)
+	return cfg
+}
+
+// applyChatbotSettings overlays the chatbot's stored overrides on the service defaults.
+// Failures are logged and the defaults kept, so a settings outage never blocks a response.
+func (s *GraphService) applyChatbotSettings(ctx context.Context, cfg *ChatbotConfig) {
+	settings, err := s.chatbotSettings(ctx, cfg.ChatbotID)
+	if err != nil {
+		utils.Zlog.Warn("Failed to load chatbot settings, using defaults",
+			zap.String("chatbot_id", cfg.ChatbotID),
+			zap.Error(err))
+		return
+	}
+
+	if settings.ContextExpansion != nil && *settings.ContextExpansion != "" {
+		cfg.ContextExpansion = *settings.ContextExpansion
+	}
+	if settings.ContextNeighbours != nil {
+		cfg.ContextNeighbours = *settings.ContextNeighbours
+	}
+	if settings.ContextCharBudget != nil && *settings.ContextCharBudget > 0 {
+		cfg.ContextCharBudget = *settings.ContextCharBudget
+	}
+	if settings.EmbeddingModel != nil && !s.embedders.IsDefault(*settings.EmbeddingModel) {
+		cfg.EmbeddingModel = *settings.EmbeddingModel
+		if settings.EmbeddingDimensions != nil {
+			cfg.EmbeddingDimensions = *settings.EmbeddingDimensions
+		}
+	}
+}
+
+// chatbotSettings returns a chatbot's settings, loading them when the cached copy is
+// older than settingsCacheTTL
+func (s *GraphService) chatbotSettings(ctx context.Context, chatbotID string) (*types.ChatbotSettings, error) {
+	s.settingsMu.Lock()
+	cached, ok := s.settings[chatbotID]
+	s.settingsMu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.settings, nil
+	}
+
+	settings, err := s.db.GetChatbotSettings(ctx, chatbotID)
+	if err != nil {
+		return nil, err
+	}
+	s.settingsMu.Lock()
+	s.settings[chatbotID] = cachedSettings{settings: settings, expires: time.Now().Add(settingsCacheTTL)}
+	s.settingsMu.Unlock()
+	return settings, nil
+}
+
+// embedderFor returns the embedder matching the vectors the chatbot's retriever searches.
+// If the migrated model's embedder cannot be built, the chatbot falls back to the
+// primary column rather than failing the request.
+func (s *GraphService) embedderFor(cfg *ChatbotConfig) embedder.Embedder {
+	if cfg.EmbeddingModel == "" {
+		return s.embedders.Default()
+	}
+	emb, err := s.embedders.For(cfg.EmbeddingModel, cfg.EmbeddingDimensions)
+	if err != nil {
+		utils.Zlog.Warn("Failed to create chatbot embedder, using default model",
+			zap.String("chatbot_id", cfg.ChatbotID),
+			zap.String("model", cfg.EmbeddingModel),
+			zap.Error(err))
+		cfg.EmbeddingModel = ""
+		return s.embedders.Default()
+	}
+	return emb
+}
+
 // errorResponse creates a failed Response with the given error
 func errorResponse(err error) (*Response, error) {
 	return &Response{
@@ -51,6 +166,7 @@ func errorResponse(err error) (*Response, error) {
 
 func (s *GraphService) BuildAndRunGraph(ctx context.Context, req *Request) (*Response, error) {
 	startTime := time.Now()
+	defer s.lifecycle.Track("graph run")()
 
 	utils.Zlog.Info("Processing request with graph",
 		zap.String("web_id", req.User.ConverslyWebID),
@@ -66,34 +182,12 @@ func (s *GraphService) BuildAndRunGraph(ctx context.Context, req *Request) (*Res
 		return errorResponse(fmt.Errorf("failed to load chatbot config: %w", err))
 	}
 
-	cfg := &ChatbotConfig{
-		ChatbotID:     info.ID,
-		SystemPrompt:  info.SystemPrompt,
-		Temperature:   0.7,
-		Model:         "gemini-2.0-flash-lite",
-		MaxTokens:     1024,
-		TopK:          5,
-		ToolConfigs:   []string{"rag"},
-		GeminiAPIKeys: s.cfg.GeminiAPIKeys,
-	}
-
-	deps := &GraphDependencies{
-		DB:       s.db,
-		Embedder: s.embedder,
-	}
+	cfg := s.chatbotConfig(ctx, info.ID)
+	cfg.SystemPrompt = info.SystemPrompt
 
-	compiledGraph, err := BuildChatbotGraph(ctx, cfg, deps)
+	ans, err := s.answer(ctx, cfg, req.Query)
 	if err != nil {
-		return errorResponse(fmt.Errorf("failed to build chatbot graph: %w", err))
-	}
-
-	messages, err := ParseConversationMessages(req.Query)
-	if err != nil {
-		return errorResponse(fmt.Errorf("failed to parse conversation: %w", err))
-	}
-	result, citations, err := s.invokeGraph(ctx, compiledGraph, messages, cfg)
-	if err != nil {
-		return errorResponse(fmt.Errorf("graph execution failed: %w", err))
+		return errorResponse(err)
 	}
 
 	assistantUUID, err := uuid.NewV7()
@@ -102,14 +196,18 @@ func (s *GraphService) BuildAndRunGraph(ctx context.Context, req *Request) (*Res
 	}
 	assistantMsgID := assistantUUID.String()
 	response := &Response{
-		Response:     result.Content,
-		Citations:    citations,
+		Response:     ans.Content,
+		Citations:    ans.Citations,
 		BaseResponse: types.BaseResponse{Success: true},
 		MessageID:    assistantMsgID,
+		Curated:      ans.FAQID != nil,
+		FAQID:        ans.FAQID,
 	}
 
+	latencyMS := time.Since(startTime).Milliseconds()
+
 	// Step 7: Save messages in background (non-blocking)
-	go func() {
+	s.lifecycle.Go("save messages", func() {
 		saveCtx := context.Background()
 		userUUID, err := uuid.NewV7()
 		if err != nil {
@@ -117,13 +215,15 @@ func (s *GraphService) BuildAndRunGraph(ctx context.Context, req *Request) (*Res
 			return
 		}
 		userMsgID := userUUID.String()
+		userMessage := ExtractLastUserContent(req.Query)
 		if err := SaveConversationMessagesBackground(saveCtx, s.db, MessageRecord{
 			UniqueClientID: req.User.UniqueClientID,
 			ChatbotID:      info.ID,
-			Message:        ExtractLastUserContent(req.Query),
+			Message:        userMessage,
 			Role:           "user",
 			Citations:      []string{},
 			MessageUID:     userMsgID,
+			TopicID:        s.classify(saveCtx, cfg, userMessage, ans.QueryVector),
 		}, MessageRecord{
 			UniqueClientID: req.User.UniqueClientID,
 			ChatbotID:      info.ID,
@@ -131,12 +231,13 @@ func (s *GraphService) BuildAndRunGraph(ctx context.Context, req *Request) (*Res
 			Role:           "assistant",
 			Citations:      response.Citations,
 			MessageUID:     assistantMsgID,
+			LatencyMS:      latencyMS,
+			Tools:          ans.Tools,
 		}); err != nil {
 			utils.Zlog.Error("Failed to save messages in background", zap.Error(err))
 		}
-	}()
+	})
 
-	latencyMS := time.Since(startTime).Milliseconds()
 	utils.Zlog.Info("Request completed",
 		zap.String("chatbot_id", cfg.ChatbotID),
 		zap.Int64("latency_ms", latencyMS),
@@ -145,6 +246,68 @@ func (s *GraphService) BuildAndRunGraph(ctx context.Context, req *Request) (*Res
 	return response, nil
 }
 
+// answer produces the assistant reply: a curated FAQ answer when one matches closely,
+// otherwise the graph's generated answer
+func (s *GraphService) answer(ctx context.Context, cfg *ChatbotConfig, query string) (*generatedAnswer, error) {
+	emb := s.embedderFor(cfg)
+	vec := s.embedQuestion(ctx, cfg, emb, ExtractLastUserContent(query))
+	if curated := s.applyFAQ(ctx, cfg, emb, vec); curated != nil {
+		curated.QueryVector = vec
+		return curated, nil
+	}
+
+	deps := &GraphDependencies{
+		DB:       s.db,
+		Embedder: emb,
+	}
+
+	compiledGraph, err := BuildChatbotGraph(ctx, cfg, deps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build chatbot graph: %w", err)
+	}
+
+	messages, err := ParseConversationMessages(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse conversation: %w", err)
+	}
+
+	ctx, usage := withToolUsage(ctx)
+	result, citations, err := s.invokeGraph(ctx, compiledGraph, messages, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("graph execution failed: %w", err)
+	}
+	return &generatedAnswer{
+		Content:     result.Content,
+		Citations:   mergeCitations(cfg.CuratedCitations, citations),
+		Tools:       usage.list(),
+		QueryVector: vec,
+	}, nil
+}
+
+// embedQuestion embeds the user's question once for the FAQ lookup and topic tagging. It
+// returns nil, and both fall back, when the question is empty or cannot be embedded.
+func (s *GraphService) embedQuestion(ctx context.Context, cfg *ChatbotConfig, emb embedder.Embedder, question string) []float64 {
+	if question == "" || emb == nil {
+		return nil
+	}
+	vec, err := emb.EmbedQuery(ctx, question)
+	if err != nil {
+		utils.Zlog.Warn("Failed to embed question",
+			zap.String("chatbot_id", cfg.ChatbotID),
+			zap.Error(err))
+		return nil
+	}
+	return vec
+}
+
+// classify returns the topic of a saved user message. vec is the message as embedded for
+// the answer, if it was.
+func (s *GraphService) classify(ctx context.Context, cfg *ChatbotConfig, message string, vec []float64) string {
+	ctx, cancel := context.WithTimeout(ctx, classifyTimeout)
+	defer cancel()
+	return s.topics.Classify(ctx, cfg.ChatbotID, s.embedderFor(cfg), message, vec)
+}
+
 // invokeGraph executes the compiled graph with runtime configuration
 func (s *GraphService) invokeGraph(
 	ctx context.Context,
@@ -177,7 +340,7 @@ func (s *GraphService) invokeGraph(
 		}
 
 		if lastUser != "" {
-			retr := rag.NewPgVectorRetriever(s.db, s.embedder, cfg.ChatbotID, int(cfg.TopK))
+			retr := rag.NewPgVectorRetriever(s.db, s.embedderFor(cfg), cfg.RetrieverConfig())
 			docs, err := retr.Retrieve(ctx, lastUser)
 			if err != nil {
 				utils.Zlog.Debug("fallback retriever failed",
@@ -208,22 +371,17 @@ func (s *GraphService) invokeGraph(
 // BuildAndRunPlaygroundGraph executes the graph for playground requests (no validation)
 func (s *GraphService) BuildAndRunPlaygroundGraph(ctx context.Context, req *PlaygroundRequest) (*Response, error) {
 	startTime := time.Now()
+	defer s.lifecycle.Track("playground graph run")()
 
 	utils.Zlog.Info("Processing playground request with graph",
 		zap.String("chatbot_id", req.Chatbot.ChatbotId),
 		zap.String("client_id", req.User.UniqueClientID))
 
 	// Use playground chatbot configuration directly (no validation or DB fetch)
-	cfg := &ChatbotConfig{
-		ChatbotID:     req.Chatbot.ChatbotId,
-		SystemPrompt:  req.Chatbot.ChatbotSystemPrompt,
-		Temperature:   float32(req.Chatbot.ChatbotTemperature),
-		Model:         req.Chatbot.ChatbotModel,
-		MaxTokens:     1024,
-		TopK:          5,
-		ToolConfigs:   []string{"rag"},
-		GeminiAPIKeys: s.cfg.GeminiAPIKeys,
-	}
+	cfg := s.chatbotConfig(ctx, req.Chatbot.ChatbotId)
+	cfg.SystemPrompt = req.Chatbot.ChatbotSystemPrompt
+	cfg.Temperature = float32(req.Chatbot.ChatbotTemperature)
+	cfg.Model = req.Chatbot.ChatbotModel
 
 	// Set default model if not provided
 	if cfg.Model == "" {
@@ -235,24 +393,9 @@ func (s *GraphService) BuildAndRunPlaygroundGraph(ctx context.Context, req *Play
 		cfg.Temperature = 0.7
 	}
 
-	deps := &GraphDependencies{
-		DB:       s.db,
-		Embedder: s.embedder,
-	}
-
-	compiledGraph, err := BuildChatbotGraph(ctx, cfg, deps)
+	ans, err := s.answer(ctx, cfg, req.Query)
 	if err != nil {
-		return errorResponse(fmt.Errorf("failed to build chatbot graph: %w", err))
-	}
-
-	messages, err := ParseConversationMessages(req.Query)
-	if err != nil {
-		return errorResponse(fmt.Errorf("failed to parse conversation: %w", err))
-	}
-
-	result, citations, err := s.invokeGraph(ctx, compiledGraph, messages, cfg)
-	if err != nil {
-		return errorResponse(fmt.Errorf("graph execution failed: %w", err))
+		return errorResponse(err)
 	}
 
 	assistantUUID, err := uuid.NewV7()
@@ -261,14 +404,18 @@ func (s *GraphService) BuildAndRunPlaygroundGraph(ctx context.Context, req *Play
 	}
 	assistantMsgID := assistantUUID.String()
 	response := &Response{
-		Response:     result.Content,
-		Citations:    citations,
+		Response:     ans.Content,
+		Citations:    ans.Citations,
 		BaseResponse: types.BaseResponse{Success: true},
 		MessageID:    assistantMsgID,
+		Curated:      ans.FAQID != nil,
+		FAQID:        ans.FAQID,
 	}
 
+	latencyMS := time.Since(startTime).Milliseconds()
+
 	// Save messages in background (non-blocking)
-	go func() {
+	s.lifecycle.Go("save messages", func() {
 		saveCtx := context.Background()
 		userUUID, err := uuid.NewV7()
 		if err != nil {
@@ -276,13 +423,15 @@ func (s *GraphService) BuildAndRunPlaygroundGraph(ctx context.Context, req *Play
 			return
 		}
 		userMsgID := userUUID.String()
+		userMessage := ExtractLastUserContent(req.Query)
 		if err := SaveConversationMessagesBackground(saveCtx, s.db, MessageRecord{
 			UniqueClientID: req.User.UniqueClientID,
 			ChatbotID:      req.Chatbot.ChatbotId,
-			Message:        ExtractLastUserContent(req.Query),
+			Message:        userMessage,
 			Role:           "user",
 			Citations:      []string{},
 			MessageUID:     userMsgID,
+			TopicID:        s.classify(saveCtx, cfg, userMessage, ans.QueryVector),
 		}, MessageRecord{
 			UniqueClientID: req.User.UniqueClientID,
 			ChatbotID:      req.Chatbot.ChatbotId,
@@ -290,12 +439,13 @@ func (s *GraphService) BuildAndRunPlaygroundGraph(ctx context.Context, req *Play
 			Role:           "assistant",
 			Citations:      response.Citations,
 			MessageUID:     assistantMsgID,
+			LatencyMS:      latencyMS,
+			Tools:          ans.Tools,
 		}); err != nil {
 			utils.Zlog.Error("Failed to save playground messages in background", zap.Error(err))
 		}
-	}()
+	})
 
-	latencyMS := time.Since(startTime).Milliseconds()
 	utils.Zlog.Info("Playground request completed",
 		zap.String("chatbot_id", cfg.ChatbotID),
 		zap.Int64("latency_ms", latencyMS),
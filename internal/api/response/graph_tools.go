@@ -27,13 +27,13 @@ func GetEnabledTools(ctx context.Context, cfg *ChatbotConfig, deps *GraphDepende
 			ragTool := tools.NewRAGTool(
 				deps.DB,
 				deps.Embedder,
-				cfg.ChatbotID,
-				int(cfg.TopK),
+				cfg.RetrieverConfig(),
 			)
 			enabledTools = append(enabledTools, ragTool)
 			internalUtils.Zlog.Info("Registered RAG tool",
 				zap.String("chatbot_id", cfg.ChatbotID),
-				zap.Int("topK", int(cfg.TopK)))
+				zap.Int("topK", int(cfg.TopK)),
+				zap.String("mode", cfg.RetrievalMode))
 		default:
 			internalUtils.Zlog.Warn("Unknown tool configuration",
 				zap.String("chatbot_id", cfg.ChatbotID),
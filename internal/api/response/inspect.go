@@ -0,0 +1,119 @@
+package response
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Conversly/lightning-response/internal/embedder"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/rag"
+)
+
+// maxInspectTopK bounds the topK override of an inspection
+const maxInspectTopK = 50
+
+// InspectRetrieval runs the chatbot's retriever for a query with the settings a
+// conversation would use and reports every step, without calling the model
+func (s *GraphService) InspectRetrieval(ctx context.Context, chatbotID string, req *InspectRequest) (*InspectResponse, error) {
+	startTime := time.Now()
+
+	searchQuery := strings.TrimSpace(req.Query)
+	if strings.HasPrefix(searchQuery, "[") {
+		if last := ExtractLastUserContent(searchQuery); last != "" {
+			searchQuery = last
+		}
+	}
+
+	cfg := s.chatbotConfig(ctx, chatbotID)
+	emb := s.embedderFor(cfg)
+	retrCfg := cfg.RetrieverConfig()
+	if req.TopK > 0 {
+		retrCfg.TopK = req.TopK
+	}
+	if req.Mode != "" {
+		retrCfg.Mode = req.Mode
+	}
+	retrCfg.DataSourceIDs = req.DataSourceIDs
+
+	trace, err := rag.NewPgVectorRetriever(s.db, emb, retrCfg).Inspect(ctx, searchQuery)
+	if err != nil {
+		return nil, fmt.Errorf("retrieval failed: %w", err)
+	}
+
+	res := &InspectResponse{
+		ChatbotID:   chatbotID,
+		Query:       req.Query,
+		SearchQuery: trace.Query,
+		Settings: InspectSettings{
+			Mode:            trace.Config.Mode,
+			TopK:            trace.Config.TopK,
+			FetchK:          trace.Config.FetchK,
+			MMRLambda:       trace.Config.MMRLambda,
+			DedupeThreshold: trace.Config.DedupeThreshold,
+			Expansion:       trace.Config.Expansion,
+			Neighbours:      trace.Config.Neighbours,
+			CharBudget:      trace.Config.CharBudget,
+		},
+		Filters: InspectFilters{
+			EmbeddingModel: trace.Filter.Model,
+			DataSourceIDs:  trace.Filter.DataSourceIDs,
+		},
+		FAQ:        s.inspectFAQ(ctx, chatbotID, emb, searchQuery),
+		Candidates: make([]InspectChunk, len(trace.Candidates)),
+		Results:    make([]InspectChunk, len(trace.Results)),
+		Timings: InspectTimings{
+			EmbedMS:  trace.EmbedDuration.Milliseconds(),
+			SearchMS: trace.SearchDuration.Milliseconds(),
+		},
+	}
+	if res.Settings.Expansion == "" {
+		res.Settings.Expansion = rag.ExpandNone
+	}
+	if res.Filters.DataSourceIDs == nil {
+		res.Filters.DataSourceIDs = []int{}
+	}
+	for i, c := range trace.Candidates {
+		res.Candidates[i] = inspectChunk(&c.EmbeddingResult)
+		res.Candidates[i].Duplicate = c.Duplicate
+		res.Candidates[i].Rank = c.Rank
+	}
+	for i := range trace.Results {
+		res.Results[i] = inspectChunk(&trace.Results[i])
+	}
+	res.Timings.TotalMS = time.Since(startTime).Milliseconds()
+	return res, nil
+}
+
+// inspectFAQ reports the curated answers applyFAQ would consider for question
+func (s *GraphService) inspectFAQ(ctx context.Context, chatbotID string, emb embedder.Embedder, question string) InspectFAQ {
+	out := InspectFAQ{
+		AnswerThreshold:  s.cfg.FAQAnswerThreshold,
+		ContextThreshold: s.cfg.FAQContextThreshold,
+		Matches:          []InspectFAQMatch{},
+	}
+	matches, err := s.matchFAQ(ctx, chatbotID, emb, question)
+	if err != nil {
+		out.Error = err.Error()
+		return out
+	}
+	for _, m := range matches {
+		out.Matches = append(out.Matches, InspectFAQMatch{FAQID: m.FAQID, Question: m.Question, Score: m.Score})
+	}
+	if len(matches) > 0 && matches[0].Score >= s.cfg.FAQAnswerThreshold {
+		out.AnsweredBy = &matches[0].FAQID
+	}
+	return out
+}
+
+func inspectChunk(r *loaders.EmbeddingResult) InspectChunk {
+	return InspectChunk{
+		Text:       r.Text,
+		Score:      r.Score,
+		Citation:   r.Citation,
+		DocumentID: r.DocumentID,
+		ChunkIndex: r.ChunkIndex,
+		Section:    r.Section,
+	}
+}
@@ -0,0 +1,150 @@
+package response
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/config"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/outbox"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+const (
+	replayTimeout    = 10 * time.Second
+	replayMaxBackoff = 30 * time.Second
+)
+
+// openMessageOutbox opens the configured outbox and publishes its backlog. Without a
+// usable directory messages are kept in memory as before.
+func openMessageOutbox(cfg *config.Config) *outbox.Outbox {
+	ob, err := outbox.Open(cfg.MessageOutboxDir, outbox.Options{
+		SegmentBytes: cfg.MessageOutboxSegmentBytes,
+		MaxBytes:     cfg.MessageOutboxMaxBytes,
+	})
+	if err != nil {
+		utils.Zlog.Error("Failed to open message outbox, messages will be queued in memory only",
+			zap.String("dir", cfg.MessageOutboxDir),
+			zap.Error(err))
+		return nil
+	}
+
+	st := ob.Stats()
+	utils.Zlog.Info("Message outbox opened",
+		zap.String("dir", cfg.MessageOutboxDir),
+		zap.Int64("pending_records", st.PendingRecords),
+		zap.Int64("pending_bytes", st.PendingBytes))
+	expvar.Publish("message_outbox", expvar.Func(func() any {
+		st := ob.Stats()
+		return map[string]int64{
+			"pending_records": st.PendingRecords,
+			"pending_bytes":   st.PendingBytes,
+			"disk_bytes":      st.DiskBytes,
+			"segments":        int64(st.Segments),
+		}
+	}))
+	return ob
+}
+
+// appendOutbox writes rows to the outbox as one record each
+func (w *messageSaver) appendOutbox(rows []loaders.MessageRow) error {
+	records := make([][]byte, len(rows))
+	for i := range rows {
+		data, err := json.Marshal(&rows[i])
+		if err != nil {
+			return err
+		}
+		records[i] = data
+	}
+	return w.outbox.Append(records...)
+}
+
+// replay drains the outbox into the messages table. A batch is committed only once
+// Postgres has accepted it; while the database is unreachable the batch is retried with
+// backoff. Rows Postgres rejects as invalid are logged and skipped so they cannot block
+// the queue; any other failure leaves the whole batch to be retried.
+func (w *messageSaver) replay() {
+	defer close(w.replayDone)
+	var backoff time.Duration
+	for {
+		batch, err := w.outbox.Read(w.batchSize)
+		if err == nil && len(batch.Records) > 0 {
+			err = w.replayBatch(batch)
+		}
+		if err != nil {
+			backoff = min(max(2*backoff, w.flushInterval), replayMaxBackoff)
+			utils.Zlog.Error("Failed to replay message outbox",
+				zap.Duration("retry_in", backoff),
+				zap.Error(err))
+			if !w.sleep(backoff) {
+				return
+			}
+			continue
+		}
+		backoff = 0
+		if len(batch.Records) == w.batchSize {
+			continue
+		}
+
+		select {
+		case <-w.outbox.Notify():
+		case <-time.After(w.flushInterval):
+		case <-w.stopCh:
+			return
+		}
+	}
+}
+
+func (w *messageSaver) replayBatch(batch *outbox.Batch) error {
+	rows := make([]loaders.MessageRow, 0, len(batch.Records))
+	for _, rec := range batch.Records {
+		var row loaders.MessageRow
+		if err := json.Unmarshal(rec, &row); err != nil {
+			msgRowsFailed.Add(1)
+			utils.Zlog.Error("Skipping undecodable outbox message", zap.Error(err))
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) > 0 {
+		start := time.Now()
+		res, err := insertMessages(w.db, rows, replayTimeout)
+		msgFlushes.Add(1)
+		msgFlushMillis.Add(time.Since(start).Milliseconds())
+		if err != nil {
+			msgFlushErrors.Add(1)
+			return err
+		}
+		// Only rows that would be rejected again may be dropped with the batch
+		for _, f := range res.Failed {
+			if !loaders.IsRowError(f.Err) {
+				msgFlushErrors.Add(1)
+				return fmt.Errorf("message %s: %w", f.MessageID, f.Err)
+			}
+		}
+		utils.Zlog.Debug("Replayed message outbox batch",
+			zap.Int("count", len(rows)),
+			zap.Int("inserted", res.Inserted),
+			zap.Int("duplicates", res.Duplicates),
+			zap.Int("failed", len(res.Failed)),
+			zap.Duration("elapsed", time.Since(start)))
+	}
+	return w.outbox.Commit(batch)
+}
+
+// sleep waits for d, returning false if the saver is stopped first
+func (w *messageSaver) sleep(d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-w.stopCh:
+		return false
+	}
+}
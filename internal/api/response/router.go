@@ -5,26 +5,29 @@ import (
 
 	"github.com/Conversly/lightning-response/internal/config"
 	"github.com/Conversly/lightning-response/internal/embedder"
+	"github.com/Conversly/lightning-response/internal/lifecycle"
 	"github.com/Conversly/lightning-response/internal/loaders"
-	"github.com/Conversly/lightning-response/internal/utils"
+	"github.com/Conversly/lightning-response/internal/middleware"
+	"github.com/Conversly/lightning-response/internal/topics"
 	"github.com/gin-gonic/gin"
-	"go.uber.org/zap"
 )
 
-// RegisterRoutes registers the /response endpoint at the root level
-func RegisterRoutes(router *gin.Engine, db *loaders.PostgresClient, cfg *config.Config) {
+// RegisterRoutes registers the /response endpoints at the root level and the retrieval
+// inspection endpoint under /admin
+func RegisterRoutes(router *gin.Engine, db *loaders.PostgresClient, cfg *config.Config, embedders *embedder.Registry, classifier *topics.Classifier, lm *lifecycle.Manager) {
 	ctx := context.Background()
-	emb, err := embedder.NewGeminiEmbedder(cfg.GeminiAPIKeys)
-	if err != nil {
-		utils.Zlog.Error("failed to create embedder", zap.Error(err))
-	}
 
 	// Wire service
-	svc := NewGraphService(db, cfg, emb)
+	svc := NewGraphService(db, cfg, embedders, classifier, lm)
 	_ = svc.Initialize(ctx)
+	StartMessageSaver(db, cfg)
+	lm.RegisterFunc("message saver", StopMessageSaver)
 
 	// Controller
 	ctrl := NewController(svc)
 	router.POST("/response", ctrl.Respond)
 	router.POST("/playground/response", ctrl.PlaygroundResponse)
+
+	admin := router.Group("/admin", middleware.AdminAuth(cfg.AdminAPIToken))
+	admin.POST("/chatbots/:chatbotId/retrieval/inspect", ctrl.InspectRetrieval)
 }
@@ -43,6 +43,9 @@ type Response struct {
 	MessageID string   `json:"message_id,omitempty"`
 	Response  string   `json:"response"`
 	Citations []string `json:"citations"`
+	// Curated is set when Response is a tenant's pinned FAQ answer rather than generated
+	Curated bool   `json:"curated"`
+	FAQID   *int64 `json:"faq_id,omitempty"`
 }
 
 type Source struct {
@@ -57,3 +60,82 @@ type Usage struct {
 	TotalTokens      int   `json:"total_tokens,omitempty"`
 	LatencyMS        int64 `json:"latency_ms,omitempty"`
 }
+
+// InspectRequest runs the retriever for a query without generating an answer. Query is
+// either the text to search or a conversation in the /response format, whose last user
+// message is searched. The optional fields override the chatbot's settings for this run.
+type InspectRequest struct {
+	Query         string `json:"query"`
+	TopK          int    `json:"topK,omitempty"`
+	Mode          string `json:"mode,omitempty"` // similarity | mmr
+	DataSourceIDs []int  `json:"dataSourceIds,omitempty"`
+}
+
+// InspectResponse reports each step of a retrieval
+type InspectResponse struct {
+	types.BaseResponse
+	ChatbotID string `json:"chatbotId"`
+	Query     string `json:"query"`
+	// SearchQuery is the text that was embedded and searched
+	SearchQuery string          `json:"searchQuery"`
+	Settings    InspectSettings `json:"settings"`
+	Filters     InspectFilters  `json:"filters"`
+	FAQ         InspectFAQ      `json:"faq"`
+	Candidates  []InspectChunk  `json:"candidates"`
+	Results     []InspectChunk  `json:"results"`
+	Timings     InspectTimings  `json:"timings"`
+}
+
+// InspectSettings are the retriever settings in effect for the chatbot
+type InspectSettings struct {
+	Mode            string  `json:"mode"`
+	TopK            int     `json:"topK"`
+	FetchK          int     `json:"fetchK"`
+	MMRLambda       float64 `json:"mmrLambda"`
+	DedupeThreshold float64 `json:"dedupeThreshold"`
+	Expansion       string  `json:"expansion"`
+	Neighbours      int     `json:"neighbours"`
+	CharBudget      int     `json:"charBudget"`
+}
+
+// InspectFilters is what the vector search was restricted to
+type InspectFilters struct {
+	// EmbeddingModel is empty when the primary vectors were searched
+	EmbeddingModel string `json:"embeddingModel"`
+	DataSourceIDs  []int  `json:"dataSourceIds"`
+}
+
+// InspectFAQ lists the curated answers close to the query. AnsweredBy is set when the
+// best match would be sent instead of running retrieval at all.
+type InspectFAQ struct {
+	AnswerThreshold  float64           `json:"answerThreshold"`
+	ContextThreshold float64           `json:"contextThreshold"`
+	Matches          []InspectFAQMatch `json:"matches"`
+	AnsweredBy       *int64            `json:"answeredBy,omitempty"`
+	Error            string            `json:"error,omitempty"`
+}
+
+type InspectFAQMatch struct {
+	FAQID    int64   `json:"faqId"`
+	Question string  `json:"question"`
+	Score    float64 `json:"score"`
+}
+
+// InspectChunk is a retrieved chunk. Candidates carry Duplicate and Rank; Rank is the
+// 1-based position in the final top K, or 0 when the candidate was not picked.
+type InspectChunk struct {
+	Text       string  `json:"text"`
+	Score      float64 `json:"score"`
+	Citation   *string `json:"citation,omitempty"`
+	DocumentID *string `json:"documentId,omitempty"`
+	ChunkIndex *int    `json:"chunkIndex,omitempty"`
+	Section    *string `json:"section,omitempty"`
+	Duplicate  bool    `json:"duplicate,omitempty"`
+	Rank       int     `json:"rank,omitempty"`
+}
+
+type InspectTimings struct {
+	EmbedMS  int64 `json:"embedMs"`
+	SearchMS int64 `json:"searchMs"`
+	TotalMS  int64 `json:"totalMs"`
+}
@@ -0,0 +1,75 @@
+package topic
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/types"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+type Controller struct {
+	svc *Service
+}
+
+func NewController(svc *Service) *Controller {
+	return &Controller{svc: svc}
+}
+
+// List returns the topics of the chatbot in the path
+func (c *Controller) List(ctx *gin.Context) {
+	defs, err := c.svc.List(ctx.Request.Context(), ctx.Param("chatbotId"))
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	res := ListResponse{BaseResponse: types.BaseResponse{Success: true}, Topics: make([]Topic, len(defs))}
+	for i := range defs {
+		res.Topics[i] = toTopic(&defs[i])
+	}
+	res.RequestID = utils.RequestID(ctx)
+	ctx.JSON(http.StatusOK, res)
+}
+
+// Update replaces the description and examples of a topic of the chatbot in the path
+func (c *Controller) Update(ctx *gin.Context) {
+	var req UpdateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.WriteError(ctx, http.StatusBadRequest, "topic_error", err)
+		return
+	}
+	def, err := c.svc.Update(ctx.Request.Context(), ctx.Param("chatbotId"), ctx.Param("topicId"), &req)
+	if err != nil {
+		c.fail(ctx, err)
+		return
+	}
+	res := TopicResponse{BaseResponse: types.BaseResponse{Success: true}, Topic: toTopic(def)}
+	res.RequestID = utils.RequestID(ctx)
+	ctx.JSON(http.StatusOK, res)
+}
+
+func (c *Controller) fail(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, utils.ErrInvalidRequest):
+		utils.WriteError(ctx, http.StatusBadRequest, "topic_error", err)
+	case errors.Is(err, loaders.ErrTopicNotFound):
+		utils.WriteError(ctx, http.StatusNotFound, "topic_error", err)
+	default:
+		utils.Zlog.Error("topic request failed", zap.String("chatbot_id", ctx.Param("chatbotId")), zap.Error(err))
+		utils.WriteError(ctx, http.StatusInternalServerError, "topic_error", err)
+	}
+}
+
+func toTopic(d *loaders.TopicDefinition) Topic {
+	return Topic{
+		ID:          d.ID,
+		Name:        d.Name,
+		Color:       d.Color,
+		Description: d.Description,
+		Examples:    d.Examples,
+	}
+}
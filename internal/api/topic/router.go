@@ -0,0 +1,20 @@
+package topic
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/Conversly/lightning-response/internal/config"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/middleware"
+	"github.com/Conversly/lightning-response/internal/topics"
+)
+
+// RegisterRoutes registers the admin endpoints describing topics to the classifier
+func RegisterRoutes(router *gin.Engine, db *loaders.PostgresClient, cfg *config.Config, classifier *topics.Classifier) {
+	svc := NewService(db, classifier)
+	ctrl := NewController(svc)
+
+	admin := router.Group("/admin", middleware.AdminAuth(cfg.AdminAPIToken))
+	admin.GET("/chatbots/:chatbotId/topics", ctrl.List)
+	admin.PUT("/chatbots/:chatbotId/topics/:topicId", ctrl.Update)
+}
@@ -0,0 +1,33 @@
+package topic
+
+import (
+	"github.com/Conversly/lightning-response/internal/types"
+)
+
+// UpdateRequest replaces what the classifier is told about a topic: a short description
+// and example user messages that belong to it
+type UpdateRequest struct {
+	Description string   `json:"description"`
+	Examples    []string `json:"examples"`
+}
+
+// Topic is a chatbot topic as returned by the API
+type Topic struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Color       string   `json:"color,omitempty"`
+	Description *string  `json:"description,omitempty"`
+	Examples    []string `json:"examples"`
+}
+
+// TopicResponse wraps one topic
+type TopicResponse struct {
+	types.BaseResponse
+	Topic Topic `json:"topic"`
+}
+
+// ListResponse holds a chatbot's topics
+type ListResponse struct {
+	types.BaseResponse
+	Topics []Topic `json:"topics"`
+}
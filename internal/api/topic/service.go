@@ -0,0 +1,65 @@
+package topic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/topics"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+const (
+	maxDescriptionLength = 500
+	maxExamples          = 50
+	maxExampleLength     = 500
+)
+
+type Service struct {
+	db         *loaders.PostgresClient
+	classifier *topics.Classifier
+}
+
+func NewService(db *loaders.PostgresClient, classifier *topics.Classifier) *Service {
+	return &Service{db: db, classifier: classifier}
+}
+
+// List returns a chatbot's topics with their descriptions and examples
+func (s *Service) List(ctx context.Context, chatbotID string) ([]loaders.TopicDefinition, error) {
+	return s.db.ListTopicDefinitions(ctx, chatbotID)
+}
+
+// Update replaces a topic's description and examples. This instance classifies with
+// them from the next message; other instances once their cached topics expire.
+func (s *Service) Update(ctx context.Context, chatbotID, topicID string, req *UpdateRequest) (*loaders.TopicDefinition, error) {
+	var description *string
+	if d := strings.TrimSpace(req.Description); d != "" {
+		if len(d) > maxDescriptionLength {
+			return nil, fmt.Errorf("%w: description is longer than %d characters", utils.ErrInvalidRequest, maxDescriptionLength)
+		}
+		description = &d
+	}
+
+	examples := make([]string, 0, len(req.Examples))
+	for _, ex := range req.Examples {
+		ex = strings.TrimSpace(ex)
+		if ex == "" {
+			continue
+		}
+		if len(ex) > maxExampleLength {
+			return nil, fmt.Errorf("%w: examples must be at most %d characters", utils.ErrInvalidRequest, maxExampleLength)
+		}
+		examples = append(examples, ex)
+	}
+	if len(examples) > maxExamples {
+		return nil, fmt.Errorf("%w: at most %d examples are allowed", utils.ErrInvalidRequest, maxExamples)
+	}
+
+	topic, err := s.db.UpdateTopicDefinition(ctx, chatbotID, topicID, description, examples)
+	if err != nil {
+		return nil, err
+	}
+	s.classifier.Invalidate(chatbotID)
+	return topic, nil
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"os"
 	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -19,6 +20,84 @@ type Config struct {
 	Port           string
 	AllowedOrigins []string
 	GeminiAPIKeys  []string
+
+	// Retrieval defaults applied to every chatbot
+	RetrievalMode     string  // similarity (default) | mmr
+	MMRLambda         float64 // 1.0 = pure relevance, 0.0 = pure diversity
+	MMRFetchK         int     // candidates fetched before MMR selection
+	DedupeThreshold   float64 // word-shingle Jaccard above which chunks are collapsed
+	ContextExpansion  string  // none | neighbours | parent
+	ContextNeighbours int     // chunks on each side of a hit for neighbours expansion
+	ContextCharBudget int     // characters of expanded context per retrieval
+
+	// Curated FAQ matching: at or above FAQAnswerThreshold the curated answer is returned
+	// as is; at or above FAQContextThreshold it is given to the model as priority context
+	FAQAnswerThreshold  float64
+	FAQContextThreshold float64
+
+	// Topic tagging: a user message is tagged with the closest topic at or above
+	// TopicMatchThreshold, else with the "other" topic. Topic vectors are cached per
+	// chatbot for TopicCacheTTL.
+	TopicMatchThreshold float64
+	TopicCacheTTL       time.Duration
+	// How many of a chatbot's latest user messages keywords are scored against by TF-IDF,
+	// cached with the topics; 0 scores them by frequency alone
+	KeywordCorpusSize int
+
+	// Embedding model
+	EmbeddingProvider   string // gemini | local
+	EmbeddingModel      string
+	EmbeddingDimensions int
+
+	// Embedding cache
+	EmbeddingCacheSize       int  // in-process LRU entries
+	EmbeddingCacheShared     bool // also use the Postgres-backed shared tier
+	EmbeddingCacheSharedSize int  // max rows kept in the shared tier
+
+	// Admin endpoints and ingestion
+	AdminAPIToken      string
+	IngestionWorkers   int
+	IngestionQueueSize int
+	// Directory uploads are spooled to until their ingestion job runs
+	UploadDir string
+
+	// Website crawler limits; per-request values are capped at these
+	CrawlerMaxPages       int
+	CrawlerMaxDepth       int
+	CrawlerRequestDelayMs int
+	CrawlerUserAgent      string
+
+	// Chunking defaults, overridable per chatbot
+	ChunkStrategy string // recursive | markdown
+	ChunkSize     int
+	ChunkOverlap  int
+	ChunkUnit     string // chars | tokens
+
+	// Local outbox conversation messages are written to before they reach Postgres;
+	// an empty directory keeps messages in memory only
+	MessageOutboxDir          string
+	MessageOutboxMaxBytes     int64
+	MessageOutboxSegmentBytes int64
+
+	// Directory message exports are written to, and how long they can be downloaded. It
+	// must be storage shared by every instance, as a download may reach any of them.
+	ExportDir string
+	ExportTTL time.Duration
+
+	// How often chatbots' message retention periods are enforced
+	RetentionPurgeInterval time.Duration
+
+	// Conversation tagging: conversations idle for ConversationIdleTimeout are classified
+	// every TaggingInterval (0 disables), by TaggingLLMModel when set, else heuristically
+	ConversationIdleTimeout time.Duration
+	TaggingInterval         time.Duration
+	TaggingLLMModel         string
+
+	// How often analytics rollups are brought up to date with new messages and feedback
+	AnalyticsRollupInterval time.Duration
+
+	// ShutdownTimeout bounds the graceful shutdown; unfinished work is abandoned after it
+	ShutdownTimeout time.Duration
 }
 
 func LoadConfig() (*Config, error) {
@@ -105,6 +184,42 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
+	// MMR is opt-in so existing chatbots keep plain similarity ranking
+	retrievalMode := os.Getenv("RAG_RETRIEVAL_MODE")
+	if retrievalMode == "" {
+		retrievalMode = "similarity"
+	}
+
+	contextExpansion := os.Getenv("RAG_CONTEXT_EXPANSION")
+	if contextExpansion == "" {
+		contextExpansion = "none"
+	}
+
+	messageOutboxDir, ok := os.LookupEnv("MESSAGE_OUTBOX_DIR")
+	if !ok {
+		messageOutboxDir = "data/message-outbox"
+	}
+
+	uploadDir := os.Getenv("UPLOAD_DIR")
+	if uploadDir == "" {
+		uploadDir = "data/uploads"
+	}
+
+	exportDir := os.Getenv("EXPORT_DIR")
+	if exportDir == "" {
+		exportDir = "data/exports"
+	}
+
+	retentionPurgeMinutes := envInt("RETENTION_PURGE_INTERVAL_MINUTES", 60)
+	if retentionPurgeMinutes <= 0 {
+		return nil, errors.New("RETENTION_PURGE_INTERVAL_MINUTES must be positive")
+	}
+
+	analyticsRollupSeconds := envInt("ANALYTICS_ROLLUP_INTERVAL_SECONDS", 60)
+	if analyticsRollupSeconds <= 0 {
+		return nil, errors.New("ANALYTICS_ROLLUP_INTERVAL_SECONDS must be positive")
+	}
+
 	return &Config{
 		Port:           port,
 		AllowedOrigins: allowedOrigins,
@@ -118,5 +233,80 @@ func LoadConfig() (*Config, error) {
 		WorkerCount:    workerCount,
 		BatchSize:      batchSize,
 		GeminiAPIKeys:  geminiAPIKeys,
+
+		RetrievalMode:   retrievalMode,
+		MMRLambda:       envFloat("RAG_MMR_LAMBDA", 0.7),
+		MMRFetchK:       envInt("RAG_MMR_FETCH_K", 20),
+		DedupeThreshold: envFloat("RAG_DEDUPE_THRESHOLD", 0.9),
+
+		ContextExpansion:  contextExpansion,
+		ContextNeighbours: envInt("RAG_CONTEXT_NEIGHBOURS", 1),
+		ContextCharBudget: envInt("RAG_CONTEXT_CHAR_BUDGET", 6000),
+
+		FAQAnswerThreshold:  envFloat("FAQ_ANSWER_THRESHOLD", 0.92),
+		FAQContextThreshold: envFloat("FAQ_CONTEXT_THRESHOLD", 0.8),
+		TopicMatchThreshold: envFloat("TOPIC_MATCH_THRESHOLD", 0.6),
+		TopicCacheTTL:       time.Duration(envInt("TOPIC_CACHE_TTL_SECONDS", 300)) * time.Second,
+		KeywordCorpusSize:   envInt("KEYWORD_CORPUS_MESSAGES", 0),
+
+		EmbeddingProvider:   os.Getenv("EMBEDDING_PROVIDER"),
+		EmbeddingModel:      os.Getenv("EMBEDDING_MODEL"),
+		EmbeddingDimensions: envInt("EMBEDDING_DIMENSIONS", 768),
+
+		EmbeddingCacheSize:       envInt("EMBEDDING_CACHE_SIZE", 10000),
+		EmbeddingCacheShared:     os.Getenv("EMBEDDING_CACHE_SHARED") == "true",
+		EmbeddingCacheSharedSize: envInt("EMBEDDING_CACHE_SHARED_SIZE", 200000),
+
+		AdminAPIToken:      os.Getenv("ADMIN_API_TOKEN"),
+		IngestionWorkers:   envInt("INGESTION_WORKERS", 2),
+		IngestionQueueSize: envInt("INGESTION_QUEUE_SIZE", 100),
+		UploadDir:          uploadDir,
+
+		CrawlerMaxPages:       envInt("CRAWLER_MAX_PAGES", 500),
+		CrawlerMaxDepth:       envInt("CRAWLER_MAX_DEPTH", 3),
+		CrawlerRequestDelayMs: envInt("CRAWLER_REQUEST_DELAY_MS", 1000),
+		CrawlerUserAgent:      os.Getenv("CRAWLER_USER_AGENT"),
+
+		ChunkStrategy: os.Getenv("CHUNK_STRATEGY"),
+		ChunkSize:     envInt("CHUNK_SIZE", 1000),
+		ChunkOverlap:  envInt("CHUNK_OVERLAP", 150),
+		ChunkUnit:     os.Getenv("CHUNK_UNIT"),
+
+		MessageOutboxDir:          messageOutboxDir,
+		MessageOutboxMaxBytes:     int64(envInt("MESSAGE_OUTBOX_MAX_MB", 1024)) << 20,
+		MessageOutboxSegmentBytes: int64(envInt("MESSAGE_OUTBOX_SEGMENT_MB", 64)) << 20,
+
+		ExportDir: exportDir,
+		ExportTTL: time.Duration(envInt("EXPORT_TTL_HOURS", 24)) * time.Hour,
+
+		RetentionPurgeInterval: time.Duration(retentionPurgeMinutes) * time.Minute,
+
+		ConversationIdleTimeout: time.Duration(envInt("CONVERSATION_IDLE_MINUTES", 30)) * time.Minute,
+		TaggingInterval:         time.Duration(envInt("TAGGING_INTERVAL_MINUTES", 5)) * time.Minute,
+		TaggingLLMModel:         os.Getenv("TAGGING_LLM_MODEL"),
+
+		AnalyticsRollupInterval: time.Duration(analyticsRollupSeconds) * time.Second,
+
+		ShutdownTimeout: time.Duration(envInt("SHUTDOWN_TIMEOUT_SECONDS", 30)) * time.Second,
 	}, nil
 }
+
+// envInt reads an integer environment variable, falling back to def when unset or invalid
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := strconv.Atoi(v); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
+
+// envFloat reads a float environment variable, falling back to def when unset or invalid
+func envFloat(key string, def float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+			return parsed
+		}
+	}
+	return def
+}
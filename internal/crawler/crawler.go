@@ -0,0 +1,388 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+const (
+	DefaultMaxDepth     = 3
+	DefaultMaxPages     = 500
+	DefaultRequestDelay = time.Second
+	DefaultUserAgent    = "ConverslyBot/1.0 (+https://conversly.ai/bot)"
+
+	maxPageSize    = 10 * 1024 * 1024 // 10MB
+	requestTimeout = 30 * time.Second
+	maxRedirects   = 10
+)
+
+// Config describes one crawl
+type Config struct {
+	StartURL string
+	// SitemapURL seeds the crawl from a sitemap. When empty and UseSitemap is set, the
+	// sitemaps listed in robots.txt (or /sitemap.xml) are used.
+	SitemapURL string
+	UseSitemap bool
+	// PathPrefix restricts the crawl to URLs whose path starts with it. Defaults to the
+	// start URL's directory, so pointing at /docs/ stays inside /docs/.
+	PathPrefix      string
+	AllowSubdomains bool
+	MaxDepth        int // link hops from a seed; seeds are depth 0
+	MaxPages        int
+	RequestDelay    time.Duration // minimum interval between requests to the same host
+	UserAgent       string
+}
+
+// Page is a fetched page handed to the visit callback
+type Page struct {
+	URL         string // final URL after redirects, without fragment
+	Depth       int
+	Content     []byte
+	ContentType string
+}
+
+// Stats summarises a finished crawl
+type Stats struct {
+	Visited int // pages handed to the visit callback
+	Skipped int // URLs skipped by robots.txt, noindex, unsupported content or redirects out of scope
+	Failed  int // fetch errors and callback errors
+	// Retryable counts the failures that may succeed later: transport errors, 5xx and 429
+	Retryable int
+	// Gone lists the URLs that answered 404 or 410; they are not counted as failed
+	Gone []string
+	// Truncated is set when MaxPages stopped the crawl before the frontier was exhausted
+	Truncated bool
+}
+
+// StatusError is returned for pages that answer with a status other than 200
+type StatusError struct {
+	Code int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("status code %d", e.Code)
+}
+
+// retryable reports whether a fetch error may go away on a later crawl
+func retryable(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return true
+	}
+	return statusErr.Code >= 500 || statusErr.Code == http.StatusTooManyRequests
+}
+
+// VisitFunc receives each crawled page. Returning an error counts the page as failed
+// but does not stop the crawl; cancel ctx to stop.
+type VisitFunc func(ctx context.Context, page *Page) error
+
+// Crawler walks a website breadth-first within the scope of its Config
+type Crawler struct {
+	cfg     Config
+	client  *http.Client
+	start   *neturl.URL
+	prefix  string
+	robots  map[string]*robotsRules
+	limiter *hostLimiter
+}
+
+type queued struct {
+	url   string
+	depth int
+}
+
+// NewClient returns an HTTP client suitable for crawling, to be shared by crawls
+func NewClient() *http.Client {
+	return &http.Client{Timeout: requestTimeout}
+}
+
+// New validates cfg and creates a crawler. client may be nil to use a default client;
+// tests pass an httptest server's client. The crawler uses a copy of client whose
+// redirect policy keeps page fetches within the crawl's scope.
+func New(cfg Config, client *http.Client) (*Crawler, error) {
+	start, err := neturl.Parse(strings.TrimSpace(cfg.StartURL))
+	if err != nil || (start.Scheme != "http" && start.Scheme != "https") || start.Host == "" {
+		return nil, fmt.Errorf("invalid start URL: %q", cfg.StartURL)
+	}
+	start = normalize(start)
+
+	if cfg.MaxDepth < 0 {
+		cfg.MaxDepth = 0
+	} else if cfg.MaxDepth == 0 {
+		cfg.MaxDepth = DefaultMaxDepth
+	}
+	if cfg.MaxPages <= 0 {
+		cfg.MaxPages = DefaultMaxPages
+	}
+	if cfg.RequestDelay < 0 {
+		cfg.RequestDelay = 0
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = DefaultUserAgent
+	}
+	if client == nil {
+		client = NewClient()
+	}
+
+	prefix := cfg.PathPrefix
+	if prefix == "" {
+		prefix = start.Path[:strings.LastIndex(start.Path, "/")+1]
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+
+	c := &Crawler{
+		cfg:     cfg,
+		start:   start,
+		prefix:  prefix,
+		robots:  make(map[string]*robotsRules),
+		limiter: newHostLimiter(cfg.RequestDelay),
+	}
+	scoped := *client
+	scoped.CheckRedirect = c.checkRedirect
+	c.client = &scoped
+	return c, nil
+}
+
+// pageRequestKey marks the context of page fetches, whose redirects must stay in scope.
+// robots.txt and sitemap fetches may redirect anywhere.
+type pageRequestKey struct{}
+
+var errOutOfScope = errors.New("redirected out of the crawl's scope")
+
+func (c *Crawler) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if page, _ := req.Context().Value(pageRequestKey{}).(bool); page {
+		if _, ok := c.inScope(req.URL.String()); !ok {
+			return errOutOfScope
+		}
+	}
+	return nil
+}
+
+// Crawl visits pages until the frontier is exhausted, MaxPages pages have been visited
+// or ctx is done. Only a cancelled context is returned as an error.
+func (c *Crawler) Crawl(ctx context.Context, visit VisitFunc) (Stats, error) {
+	var stats Stats
+	seen := make(map[string]bool)
+	var frontier []queued
+
+	enqueue := func(raw string, depth int) {
+		u, ok := c.inScope(raw)
+		if !ok || seen[u] {
+			return
+		}
+		seen[u] = true
+		frontier = append(frontier, queued{url: u, depth: depth})
+	}
+
+	enqueue(c.start.String(), 0)
+	if c.cfg.UseSitemap || c.cfg.SitemapURL != "" {
+		for _, u := range c.sitemapSeeds(ctx) {
+			enqueue(u, 0)
+		}
+	}
+
+	for len(frontier) > 0 && stats.Visited < c.cfg.MaxPages {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		item := frontier[0]
+		frontier = frontier[1:]
+
+		target, _ := neturl.Parse(item.url)
+		if !c.rulesFor(ctx, target).allowed(target) {
+			stats.Skipped++
+			continue
+		}
+
+		page, links, index, err := c.fetch(ctx, item)
+		if err != nil {
+			if ctx.Err() != nil {
+				return stats, ctx.Err()
+			}
+			var statusErr *StatusError
+			switch {
+			case errors.Is(err, errUnsupported) || errors.Is(err, errOutOfScope):
+				stats.Skipped++
+			case errors.As(err, &statusErr) && (statusErr.Code == http.StatusNotFound || statusErr.Code == http.StatusGone):
+				stats.Gone = append(stats.Gone, item.url)
+			default:
+				stats.Failed++
+				if retryable(err) {
+					stats.Retryable++
+				}
+				utils.Zlog.Debug("Crawl fetch failed", zap.String("url", item.url), zap.Error(err))
+			}
+			continue
+		}
+		// Redirects can land on an already visited or out-of-scope URL
+		if page.URL != item.url {
+			final, ok := c.inScope(page.URL)
+			if !ok || seen[final] {
+				stats.Skipped++
+				continue
+			}
+			seen[final] = true
+		}
+
+		if item.depth < c.cfg.MaxDepth {
+			for _, link := range links {
+				enqueue(link, item.depth+1)
+			}
+		}
+		if !index {
+			stats.Skipped++
+			continue
+		}
+
+		stats.Visited++
+		if err := visit(ctx, page); err != nil {
+			if ctx.Err() != nil {
+				return stats, ctx.Err()
+			}
+			stats.Failed++
+			utils.Zlog.Warn("Crawled page could not be processed", zap.String("url", page.URL), zap.Error(err))
+		}
+	}
+
+	// URLs are only left behind when MaxPages was reached
+	stats.Truncated = len(frontier) > 0
+	return stats, nil
+}
+
+var errUnsupported = errors.New("unsupported content type")
+
+// fetch downloads one page. For HTML it also returns the page's links and whether the
+// page allows indexing (meta robots).
+func (c *Crawler) fetch(ctx context.Context, item queued) (*Page, []string, bool, error) {
+	resp, err := c.get(context.WithValue(ctx, pageRequestKey{}, true), item.url)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, false, &StatusError{Code: resp.StatusCode}
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !crawlable(contentType) {
+		return nil, nil, false, errUnsupported
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize+1))
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to read body: %w", err)
+	}
+	if len(body) > maxPageSize {
+		return nil, nil, false, fmt.Errorf("page exceeds %d bytes", maxPageSize)
+	}
+
+	final := normalize(resp.Request.URL)
+	page := &Page{
+		URL:         final.String(),
+		Depth:       item.depth,
+		Content:     body,
+		ContentType: contentType,
+	}
+
+	if !isHTML(contentType) {
+		return page, nil, true, nil
+	}
+	links, meta := extractLinks(body, final)
+	if meta.nofollow {
+		links = nil
+	}
+	return page, links, !meta.noindex, nil
+}
+
+// get performs a rate-limited GET
+func (c *Crawler) get(ctx context.Context, rawURL string) (*http.Response, error) {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.limiter.wait(ctx, u.Host); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.cfg.UserAgent)
+	return c.client.Do(req)
+}
+
+// inScope resolves raw against the start URL and reports whether it may be crawled,
+// returning its normalised form
+func (c *Crawler) inScope(raw string) (string, bool) {
+	u, err := c.start.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+	u = normalize(u)
+
+	host, startHost := u.Hostname(), c.start.Hostname()
+	if host != startHost && !(c.cfg.AllowSubdomains && strings.HasSuffix(host, "."+startHost)) {
+		return "", false
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	if !strings.HasPrefix(path, c.prefix) && path+"/" != c.prefix {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// normalize drops the fragment and default port and lowercases scheme and host so the
+// same page is only visited once
+func normalize(u *neturl.URL) *neturl.URL {
+	n := *u
+	n.Fragment = ""
+	n.RawFragment = ""
+	n.Scheme = strings.ToLower(n.Scheme)
+	host := strings.ToLower(n.Host)
+	if (n.Scheme == "http" && strings.HasSuffix(host, ":80")) || (n.Scheme == "https" && strings.HasSuffix(host, ":443")) {
+		host = host[:strings.LastIndex(host, ":")]
+	}
+	n.Host = host
+	if n.Path == "" {
+		n.Path = "/"
+	}
+	return &n
+}
+
+func crawlable(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType == ""
+	}
+	switch mediaType {
+	case "text/html", "application/xhtml+xml", "text/plain", "text/markdown", "application/pdf":
+		return true
+	}
+	return false
+}
+
+func isHTML(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml" || contentType == ""
+}
@@ -0,0 +1,194 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// testSite serves a small site:
+//
+//	/docs/ -> /docs/a -> /docs/b -> /docs/c
+//	/docs/ -> /docs/private (disallowed), /blog/post (outside /docs/),
+//	          /docs/moved (redirects to /blog/moved), /docs/removed (404), another host
+type testSite struct {
+	*httptest.Server
+	mu   sync.Mutex
+	hits map[string]int
+}
+
+func newTestSite(t *testing.T, robots string) *testSite {
+	site := &testSite{hits: make(map[string]int)}
+	pages := map[string]string{
+		"/docs/":        `<a href="a">A</a> <a href="/docs/private">P</a> <a href="/blog/post">B</a> <a href="moved">M</a> <a href="removed">R</a> <a href="http://other.invalid/docs/">O</a>`,
+		"/docs/a":       `<a href="b">B</a>`,
+		"/docs/b":       `<a href="c">C</a>`,
+		"/docs/c":       `end`,
+		"/docs/private": `secret`,
+		"/blog/post":    `blog`,
+		"/blog/moved":   `moved`,
+	}
+	site.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site.mu.Lock()
+		site.hits[r.URL.Path]++
+		site.mu.Unlock()
+
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, robots)
+			return
+		case "/docs/moved":
+			http.Redirect(w, r, "/blog/moved", http.StatusMovedPermanently)
+			return
+		}
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<html><body>%s</body></html>", body)
+	}))
+	t.Cleanup(site.Close)
+	return site
+}
+
+func (s *testSite) hit(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits[path]
+}
+
+// crawl runs a crawl from /docs/ and returns the visited paths in order
+func (s *testSite) crawl(t *testing.T, cfg Config) ([]string, Stats) {
+	t.Helper()
+	cfg.StartURL = s.URL + "/docs/"
+	c, err := New(cfg, s.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var visited []string
+	stats, err := c.Crawl(context.Background(), func(ctx context.Context, page *Page) error {
+		u, err := neturl.Parse(page.URL)
+		if err != nil {
+			return err
+		}
+		visited = append(visited, u.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return visited, stats
+}
+
+func TestCrawlScopeAndRobots(t *testing.T) {
+	site := newTestSite(t, "User-agent: *\nDisallow: /docs/private\n")
+	visited, stats := site.crawl(t, Config{MaxDepth: 10})
+
+	sort.Strings(visited)
+	want := []string{"/docs/", "/docs/a", "/docs/b", "/docs/c"}
+	if fmt.Sprint(visited) != fmt.Sprint(want) {
+		t.Errorf("visited %v, want %v", visited, want)
+	}
+	if n := site.hit("/docs/private"); n != 0 {
+		t.Errorf("disallowed page fetched %d times", n)
+	}
+	if n := site.hit("/blog/post"); n != 0 {
+		t.Errorf("page outside the path prefix fetched %d times", n)
+	}
+	if n := site.hit("/blog/moved"); n != 0 {
+		t.Errorf("redirect out of scope followed %d times", n)
+	}
+	// the disallowed page and the out-of-scope redirect
+	if stats.Skipped != 2 || stats.Failed != 0 {
+		t.Errorf("stats = %+v, want 2 skipped and none failed", stats)
+	}
+	if len(stats.Gone) != 1 || stats.Gone[0] != site.URL+"/docs/removed" {
+		t.Errorf("gone = %v, want /docs/removed", stats.Gone)
+	}
+	if stats.Truncated {
+		t.Error("crawl reported truncated")
+	}
+}
+
+func TestCrawlRobotsUserAgent(t *testing.T) {
+	// The group naming our product token applies regardless of case, and "*" does not
+	site := newTestSite(t, "User-agent: *\nDisallow: /\n\nUser-agent: CONVERSLYBOT\nAllow: /\n")
+	visited, _ := site.crawl(t, Config{MaxDepth: -1, UserAgent: "ConverslyBot/1.0"})
+	if len(visited) != 1 {
+		t.Errorf("visited %v, want the start page", visited)
+	}
+
+	// A group naming only part of the token does not apply
+	site = newTestSite(t, "User-agent: bot\nDisallow: /\n")
+	visited, _ = site.crawl(t, Config{MaxDepth: -1, UserAgent: "ConverslyBot/1.0"})
+	if len(visited) != 1 {
+		t.Errorf("visited %v, want the start page", visited)
+	}
+}
+
+func TestCrawlMaxDepth(t *testing.T) {
+	site := newTestSite(t, "")
+	visited, _ := site.crawl(t, Config{MaxDepth: 1})
+
+	sort.Strings(visited)
+	want := []string{"/docs/", "/docs/a", "/docs/private"}
+	if fmt.Sprint(visited) != fmt.Sprint(want) {
+		t.Errorf("visited %v, want %v", visited, want)
+	}
+	if n := site.hit("/docs/b"); n != 0 {
+		t.Errorf("page beyond max depth fetched %d times", n)
+	}
+}
+
+func TestCrawlMaxPages(t *testing.T) {
+	site := newTestSite(t, "")
+	visited, stats := site.crawl(t, Config{MaxDepth: 10, MaxPages: 2})
+	if len(visited) != 2 || stats.Visited != 2 || !stats.Truncated {
+		t.Errorf("visited %v (stats %+v), want 2 pages and a truncated crawl", visited, stats)
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	body := []byte(`
+User-agent: ConverslyBot
+Disallow: /private
+Allow: /private/public
+Crawl-delay: 2
+
+User-agent: *
+Disallow: /
+
+Sitemap: https://example.com/sitemap.xml
+`)
+	rules := parseRobots(body, "converslybot/1.0")
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/", true},
+		{"/private", false},
+		{"/private/page", false},
+		{"/private/public/page", true},
+	}
+	for _, tt := range tests {
+		u, _ := neturl.Parse("https://example.com" + tt.path)
+		if got := rules.allowed(u); got != tt.want {
+			t.Errorf("allowed(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	if rules.crawlDelay.Seconds() != 2 {
+		t.Errorf("crawl delay = %v, want 2s", rules.crawlDelay)
+	}
+	if len(rules.sitemaps) != 1 {
+		t.Errorf("sitemaps = %v, want one", rules.sitemaps)
+	}
+}
@@ -0,0 +1,81 @@
+package crawler
+
+import (
+	"bytes"
+	neturl "net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// robotsMeta holds the directives of a page's <meta name="robots"> tags
+type robotsMeta struct {
+	noindex  bool
+	nofollow bool
+}
+
+// extractLinks returns the absolute targets of a page's links, honouring <base href>
+// and skipping rel="nofollow" anchors, along with the page's meta robots directives
+func extractLinks(body []byte, pageURL *neturl.URL) ([]string, robotsMeta) {
+	var links []string
+	var meta robotsMeta
+	base := pageURL
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return links, meta
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+
+		tok := z.Token()
+		switch tok.DataAtom {
+		case atom.Base:
+			if href := tokenAttr(tok, "href"); href != "" {
+				if u, err := pageURL.Parse(href); err == nil {
+					base = u
+				}
+			}
+		case atom.Meta:
+			if !strings.EqualFold(tokenAttr(tok, "name"), "robots") {
+				continue
+			}
+			for _, directive := range strings.Split(strings.ToLower(tokenAttr(tok, "content")), ",") {
+				switch strings.TrimSpace(directive) {
+				case "noindex":
+					meta.noindex = true
+				case "nofollow":
+					meta.nofollow = true
+				case "none":
+					meta.noindex, meta.nofollow = true, true
+				}
+			}
+		case atom.A, atom.Area:
+			href := strings.TrimSpace(tokenAttr(tok, "href"))
+			if href == "" || strings.HasPrefix(href, "#") {
+				continue
+			}
+			if rel := strings.ToLower(tokenAttr(tok, "rel")); strings.Contains(rel, "nofollow") {
+				continue
+			}
+			u, err := base.Parse(href)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				continue
+			}
+			links = append(links, u.String())
+		}
+	}
+}
+
+func tokenAttr(tok html.Token, key string) string {
+	for _, a := range tok.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
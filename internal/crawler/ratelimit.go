@@ -0,0 +1,59 @@
+package crawler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// hostLimiter spaces requests to the same host at least delay apart
+type hostLimiter struct {
+	mu     sync.Mutex
+	delay  time.Duration
+	delays map[string]time.Duration // per-host overrides (robots.txt Crawl-delay)
+	next   map[string]time.Time
+}
+
+func newHostLimiter(delay time.Duration) *hostLimiter {
+	return &hostLimiter{
+		delay:  delay,
+		delays: make(map[string]time.Duration),
+		next:   make(map[string]time.Time),
+	}
+}
+
+// setDelay raises the interval for one host; it never lowers it below the default
+func (l *hostLimiter) setDelay(host string, delay time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if delay > l.delay {
+		l.delays[host] = delay
+	}
+}
+
+// wait blocks until a request to host is allowed and reserves the slot
+func (l *hostLimiter) wait(ctx context.Context, host string) error {
+	l.mu.Lock()
+	delay := l.delay
+	if d, ok := l.delays[host]; ok {
+		delay = d
+	}
+	now := time.Now()
+	at := l.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	l.next[host] = at.Add(delay)
+	l.mu.Unlock()
+
+	if wait := time.Until(at); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
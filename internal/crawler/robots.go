@@ -0,0 +1,215 @@
+package crawler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxRobotsSize = 512 * 1024
+
+// robotsRules are the robots.txt rules that apply to our user agent on one host
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	sitemaps   []string
+	disallowed bool // robots.txt was unreachable (5xx): crawl nothing
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// rulesFor returns the cached robots.txt rules for u's host, fetching them on first use.
+// A Crawl-delay longer than the configured delay slows the host's rate limit down.
+func (c *Crawler) rulesFor(ctx context.Context, u *neturl.URL) *robotsRules {
+	key := u.Scheme + "://" + u.Host
+	if rules, ok := c.robots[key]; ok {
+		return rules
+	}
+
+	rules := c.fetchRobots(ctx, key+"/robots.txt")
+	c.robots[key] = rules
+	if rules.crawlDelay > 0 {
+		c.limiter.setDelay(u.Host, rules.crawlDelay)
+	}
+	return rules
+}
+
+// fetchRobots follows the usual conventions: a missing robots.txt (4xx) allows
+// everything, while a server error or network failure disallows the whole host
+func (c *Crawler) fetchRobots(ctx context.Context, robotsURL string) *robotsRules {
+	resp, err := c.get(ctx, robotsURL)
+	if err != nil {
+		return &robotsRules{disallowed: true}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return &robotsRules{disallowed: true}
+	case resp.StatusCode != http.StatusOK:
+		return &robotsRules{}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+	if err != nil {
+		return &robotsRules{disallowed: true}
+	}
+	return parseRobots(body, c.cfg.UserAgent)
+}
+
+// parseRobots extracts the group that best matches userAgent (falling back to "*")
+// and every Sitemap line
+func parseRobots(body []byte, userAgent string) *robotsRules {
+	token := strings.ToLower(userAgent)
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+
+	var groups []*robotsGroup
+	var current *robotsGroup
+	lastWasAgent := false
+	result := &robotsRules{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if current == nil || !lastWasAgent {
+				current = &robotsGroup{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastWasAgent = true
+			continue
+		case "allow", "disallow":
+			if current != nil && value != "" {
+				current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if current != nil {
+				if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+					current.crawlDelay = time.Duration(secs * float64(time.Second))
+				}
+			}
+		case "sitemap":
+			if value != "" {
+				result.sitemaps = append(result.sitemaps, value)
+			}
+		}
+		lastWasAgent = false
+	}
+
+	// RFC 9309: the group naming our product token, compared case-insensitively, wins
+	// over "*"; rules of several groups naming the same agent are combined
+	var matched, wildcard []*robotsGroup
+	for _, g := range groups {
+		switch {
+		case token != "" && g.names(token):
+			matched = append(matched, g)
+		case g.names("*"):
+			wildcard = append(wildcard, g)
+		}
+	}
+	if len(matched) == 0 {
+		matched = wildcard
+	}
+	for _, g := range matched {
+		result.rules = append(result.rules, g.rules...)
+		if result.crawlDelay == 0 {
+			result.crawlDelay = g.crawlDelay
+		}
+	}
+
+	// Longest pattern first; on equal length Allow wins
+	sort.SliceStable(result.rules, func(i, j int) bool {
+		if len(result.rules[i].pattern) != len(result.rules[j].pattern) {
+			return len(result.rules[i].pattern) > len(result.rules[j].pattern)
+		}
+		return result.rules[i].allow && !result.rules[j].allow
+	})
+	return result
+}
+
+// names reports whether the group's User-agent lines name agent
+func (g *robotsGroup) names(agent string) bool {
+	for _, a := range g.agents {
+		if strings.EqualFold(a, agent) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowed applies the most specific matching rule to u's path and query
+func (r *robotsRules) allowed(u *neturl.URL) bool {
+	if r.disallowed {
+		return false
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	for _, rule := range r.rules {
+		if matchRobotsPattern(rule.pattern, path) {
+			return rule.allow
+		}
+	}
+	return true
+}
+
+// matchRobotsPattern matches a robots.txt path pattern supporting "*" (any sequence)
+// and a trailing "$" (end of path)
+func matchRobotsPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		if last && anchored {
+			return strings.HasSuffix(path[pos:], part)
+		}
+		idx := strings.Index(path[pos:], part)
+		if idx < 0 {
+			return false
+		}
+		pos += idx + len(part)
+	}
+	return !anchored || pos == len(path)
+}
@@ -0,0 +1,110 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+const (
+	maxSitemapSize = 50 * 1024 * 1024 // protocol limit for an uncompressed sitemap
+	// maxSitemapFiles bounds how many sitemaps a sitemap index can pull in
+	maxSitemapFiles = 50
+)
+
+// sitemapDocument covers both <urlset> and <sitemapindex> documents
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapLoc `xml:"url"`
+	Sitemaps []sitemapLoc `xml:"sitemap"`
+}
+
+type sitemapLoc struct {
+	Loc string `xml:"loc"`
+}
+
+// sitemapSeeds returns the page URLs listed in the configured sitemap, or in the
+// sitemaps advertised by robots.txt, falling back to /sitemap.xml. Nested sitemap
+// indexes are followed. Failures are logged and yield no seeds.
+func (c *Crawler) sitemapSeeds(ctx context.Context) []string {
+	var pending []string
+	if c.cfg.SitemapURL != "" {
+		pending = append(pending, c.cfg.SitemapURL)
+	} else {
+		pending = append(pending, c.rulesFor(ctx, c.start).sitemaps...)
+		if len(pending) == 0 {
+			pending = append(pending, c.start.Scheme+"://"+c.start.Host+"/sitemap.xml")
+		}
+	}
+
+	var pages []string
+	fetched := make(map[string]bool)
+	for len(pending) > 0 && len(fetched) < maxSitemapFiles && len(pages) < c.cfg.MaxPages*4 {
+		sitemapURL := pending[0]
+		pending = pending[1:]
+		if fetched[sitemapURL] {
+			continue
+		}
+		fetched[sitemapURL] = true
+
+		doc, err := c.fetchSitemap(ctx, sitemapURL)
+		if err != nil {
+			utils.Zlog.Info("Sitemap could not be read", zap.String("url", sitemapURL), zap.Error(err))
+			continue
+		}
+		for _, s := range doc.Sitemaps {
+			if loc := strings.TrimSpace(s.Loc); loc != "" {
+				pending = append(pending, loc)
+			}
+		}
+		for _, u := range doc.URLs {
+			if loc := strings.TrimSpace(u.Loc); loc != "" {
+				pages = append(pages, loc)
+			}
+		}
+	}
+	return pages
+}
+
+func (c *Crawler) fetchSitemap(ctx context.Context, sitemapURL string) (*sitemapDocument, error) {
+	resp, err := c.get(ctx, sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSitemapSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sitemap: %w", err)
+	}
+	// Sitemaps are often served pre-compressed (sitemap.xml.gz)
+	if len(body) > 2 && body[0] == 0x1f && body[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap: %w", err)
+		}
+		body, err = io.ReadAll(io.LimitReader(zr, maxSitemapSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress sitemap: %w", err)
+		}
+	}
+
+	var doc sitemapDocument
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap: %w", err)
+	}
+	return &doc, nil
+}
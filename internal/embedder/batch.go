@@ -0,0 +1,249 @@
+package embedder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/types"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+const (
+	// maxBatchSize is the provider limit on requests per batchEmbedContents call
+	maxBatchSize = 100
+
+	maxBatchAttempts = 4
+)
+
+// baseRetryDelay is the first backoff of a failed batch; tests shorten it
+var baseRetryDelay = 500 * time.Millisecond
+
+// BatchError reports which inputs of an EmbedDocuments call failed. The vectors
+// returned alongside it are valid for every index not in Failed (failed ones are nil),
+// so callers can keep the successful part and retry or report the rest.
+type BatchError struct {
+	Total  int
+	Failed map[int]error
+}
+
+func (e *BatchError) Error() string {
+	indices := e.FailedIndices()
+	first := e.Failed[indices[0]]
+	return fmt.Sprintf("failed to embed %d of %d texts (first failure at index %d: %v)", len(indices), e.Total, indices[0], first)
+}
+
+// FailedIndices returns the failed input positions in ascending order
+func (e *BatchError) FailedIndices() []int {
+	indices := make([]int, 0, len(e.Failed))
+	for i := range e.Failed {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	return indices
+}
+
+// statusError is a non-200 response from the embedding API
+type statusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("API returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// EmbedDocuments embeds passages with the RETRIEVAL_DOCUMENT task type using
+// batchEmbedContents. Inputs are split into sub-batches of at most maxBatchSize that run
+// in parallel, bounded by the embedder's rate limiter, and each sub-batch is retried on
+// transient errors. If some inputs fail, the returned error is a *BatchError and the
+// vectors for the remaining indices are still returned.
+//
+// Documents bypass the embedding cache: ingestion volumes would otherwise evict the
+// query entries the cache exists for.
+func (g *GeminiEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("no texts provided")
+	}
+
+	vectors := make([][]float64, len(texts))
+	failed := make(map[int]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	var indices []int
+	flush := func(batch []int) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			batchTexts := make([]string, len(batch))
+			for i, idx := range batch {
+				batchTexts[i] = texts[idx]
+			}
+
+			vecs, err := g.batchEmbedWithRetry(ctx, batchTexts, TaskRetrievalDocument)
+
+			mu.Lock()
+			defer mu.Unlock()
+			for i, idx := range batch {
+				switch {
+				case err != nil:
+					failed[idx] = err
+				case len(vecs[i]) != g.dimensions:
+					failed[idx] = fmt.Errorf("expected %d dimensions, got %d", g.dimensions, len(vecs[i]))
+				default:
+					vectors[idx] = normalize(vecs[i])
+				}
+			}
+		}()
+	}
+
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			failed[i] = errEmptyText
+			continue
+		}
+		indices = append(indices, i)
+		if len(indices) == maxBatchSize {
+			flush(indices)
+			indices = nil
+		}
+	}
+	if len(indices) > 0 {
+		flush(indices)
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return vectors, &BatchError{Total: len(texts), Failed: failed}
+	}
+	return vectors, nil
+}
+
+// batchEmbedWithRetry calls batchEmbedContents, retrying transient failures with
+// exponential backoff. The rate limiter slot is released while backing off.
+func (g *GeminiEmbedder) batchEmbedWithRetry(ctx context.Context, texts []string, taskType string) ([][]float64, error) {
+	var lastErr error
+	for attempt := 0; attempt < maxBatchAttempts; attempt++ {
+		if attempt > 0 {
+			delay := baseRetryDelay << (attempt - 1)
+			utils.Zlog.Warn("Retrying embedding batch",
+				zap.Int("attempt", attempt+1),
+				zap.Int("batch_size", len(texts)),
+				zap.Duration("delay", delay),
+				zap.Error(lastErr))
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		vecs, err := g.batchEmbedContents(ctx, texts, taskType)
+		if err == nil {
+			return vecs, nil
+		}
+		lastErr = err
+		if !isTransient(err) {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// batchEmbedContents performs a single batchEmbedContents request
+func (g *GeminiEmbedder) batchEmbedContents(ctx context.Context, texts []string, taskType string) ([][]float64, error) {
+	select {
+	case g.rateLimiter <- struct{}{}:
+		defer func() { <-g.rateLimiter }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	reqBody := types.BatchEmbeddingRequest{
+		Requests: make([]types.EmbeddingRequest, len(texts)),
+	}
+	for i, text := range texts {
+		reqBody.Requests[i] = types.EmbeddingRequest{
+			Model: "models/" + g.model,
+			Content: types.EmbeddingContent{
+				Parts: []types.Part{
+					{Text: text},
+				},
+			},
+			TaskType:             taskType,
+			OutputDimensionality: g.dimensions,
+		}
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	apiKey := g.getNextKey()
+	url := fmt.Sprintf("%s/%s:batchEmbedContents?key=%s", g.baseURL, g.model, apiKey)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &statusError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var batchResp types.BatchEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batchResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(batchResp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(batchResp.Embeddings))
+	}
+
+	vectors := make([][]float64, len(batchResp.Embeddings))
+	for i, e := range batchResp.Embeddings {
+		vectors[i] = e.Values
+	}
+	return vectors, nil
+}
+
+// isTransient reports whether a failed call is worth retrying
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var se *statusError
+	if errors.As(err, &se) {
+		switch se.StatusCode {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,213 @@
+package embedder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/types"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	utils.Zlog = zap.NewNop()
+	baseRetryDelay = time.Millisecond
+	os.Exit(m.Run())
+}
+
+// batchAPI serves batchEmbedContents for texts named "t<index>". Each vector encodes
+// the index of its text as values[1]/values[0], which normalizing keeps. fail picks the
+// status of a call from its texts and how many calls came before; 0 answers normally.
+type batchAPI struct {
+	mu    sync.Mutex
+	calls int
+	sizes []int
+	fail  func(call int, texts []string) int
+}
+
+func (a *batchAPI) embedder(t *testing.T) *GeminiEmbedder {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, ":batchEmbedContents") {
+			http.Error(w, "unexpected path "+r.URL.Path, http.StatusNotFound)
+			return
+		}
+		var req types.BatchEmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		texts := make([]string, len(req.Requests))
+		for i, one := range req.Requests {
+			texts[i] = one.Content.Parts[0].Text
+		}
+
+		a.mu.Lock()
+		call := a.calls
+		a.calls++
+		a.sizes = append(a.sizes, len(texts))
+		a.mu.Unlock()
+		if a.fail != nil {
+			if status := a.fail(call, texts); status != 0 {
+				http.Error(w, "failed", status)
+				return
+			}
+		}
+
+		var res types.BatchEmbeddingResponse
+		for i, text := range texts {
+			idx, err := strconv.Atoi(strings.TrimPrefix(text, "t"))
+			if err != nil {
+				http.Error(w, "unexpected text "+text, http.StatusBadRequest)
+				return
+			}
+			values := make([]float64, req.Requests[i].OutputDimensionality)
+			values[0], values[1] = 1, float64(idx)
+			res.Embeddings = append(res.Embeddings, types.Embedding{Values: values})
+		}
+		_ = json.NewEncoder(w).Encode(res)
+	}))
+	t.Cleanup(srv.Close)
+
+	g, err := NewGeminiEmbedder([]string{"key"}, "text-embedding-004", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.baseURL = srv.URL
+	return g
+}
+
+func inputs(n int) []string {
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("t%d", i)
+	}
+	return texts
+}
+
+// checkVector fails unless vec is the embedding of text index i
+func checkVector(t *testing.T, vecs [][]float64, i int) {
+	t.Helper()
+	if len(vecs[i]) != 8 {
+		t.Fatalf("vector %d has %d dimensions, want 8", i, len(vecs[i]))
+	}
+	if got := math.Round(vecs[i][1] / vecs[i][0]); got != float64(i) {
+		t.Errorf("vector %d embeds text %v", i, got)
+	}
+}
+
+func TestEmbedDocumentsSplitsIntoSubBatches(t *testing.T) {
+	api := &batchAPI{}
+	vecs, err := api.embedder(t).EmbedDocuments(context.Background(), inputs(250))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Ints(api.sizes)
+	if want := []int{50, 100, 100}; !reflect.DeepEqual(api.sizes, want) {
+		t.Errorf("sub-batch sizes %v, want %v", api.sizes, want)
+	}
+	for i := range vecs {
+		checkVector(t, vecs, i)
+	}
+}
+
+func TestEmbedDocumentsRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		failCalls int // calls answered with status before the API recovers
+		wantCalls int
+		wantErr   bool
+	}{
+		{"rate limited once", http.StatusTooManyRequests, 1, 2, false},
+		{"unavailable twice", http.StatusServiceUnavailable, 2, 3, false},
+		{"server error throughout", http.StatusInternalServerError, maxBatchAttempts, maxBatchAttempts, true},
+		{"bad request not retried", http.StatusBadRequest, 1, 1, true},
+		{"forbidden not retried", http.StatusForbidden, 1, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &batchAPI{fail: func(call int, texts []string) int {
+				if call < tt.failCalls {
+					return tt.status
+				}
+				return 0
+			}}
+			vecs, err := api.embedder(t).EmbedDocuments(context.Background(), inputs(3))
+			if api.calls != tt.wantCalls {
+				t.Errorf("API called %d times, want %d", api.calls, tt.wantCalls)
+			}
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := range vecs {
+					checkVector(t, vecs, i)
+				}
+				return
+			}
+			var batchErr *BatchError
+			if !errors.As(err, &batchErr) {
+				t.Fatalf("error = %v, want a *BatchError", err)
+			}
+			if got := batchErr.FailedIndices(); !reflect.DeepEqual(got, []int{0, 1, 2}) {
+				t.Errorf("failed indices %v, want [0 1 2]", got)
+			}
+		})
+	}
+}
+
+func TestEmbedDocumentsFailedIndicesReferToInput(t *testing.T) {
+	texts := inputs(250)
+	// The blank text is skipped, so the sub-batches hold inputs 0-100, 101-200 and
+	// 201-249; the second one is rejected
+	texts[3] = " "
+	api := &batchAPI{fail: func(call int, batch []string) int {
+		for _, text := range batch {
+			if text == "t150" {
+				return http.StatusBadRequest
+			}
+		}
+		return 0
+	}}
+
+	vecs, err := api.embedder(t).EmbedDocuments(context.Background(), texts)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("error = %v, want a *BatchError", err)
+	}
+	if batchErr.Total != len(texts) {
+		t.Errorf("total = %d, want %d", batchErr.Total, len(texts))
+	}
+
+	want := []int{3}
+	for i := 101; i <= 200; i++ {
+		want = append(want, i)
+	}
+	if got := batchErr.FailedIndices(); !reflect.DeepEqual(got, want) {
+		t.Errorf("failed indices %v, want %v", got, want)
+	}
+	for i := range vecs {
+		if _, failed := batchErr.Failed[i]; failed {
+			if vecs[i] != nil {
+				t.Errorf("failed input %d has a vector", i)
+			}
+			continue
+		}
+		checkVector(t, vecs, i)
+	}
+}
@@ -0,0 +1,190 @@
+package embedder
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"expvar"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+// SharedEmbeddingStore is a cache tier shared between service instances (e.g. Postgres)
+type SharedEmbeddingStore interface {
+	GetCachedEmbedding(ctx context.Context, key string) ([]float64, bool, error)
+	PutCachedEmbedding(ctx context.Context, key, model, taskType string, vector []float64) error
+	PruneEmbeddingCache(ctx context.Context, maxEntries int) (int64, error)
+}
+
+// Process-wide cache counters, exposed on /debug/vars
+var (
+	cacheLocalHits  = expvar.NewInt("embedding_cache_local_hits")
+	cacheSharedHits = expvar.NewInt("embedding_cache_shared_hits")
+	cacheMisses     = expvar.NewInt("embedding_cache_misses")
+	cacheEvictions  = expvar.NewInt("embedding_cache_evictions")
+)
+
+const (
+	sharedCacheTimeout = 200 * time.Millisecond
+	// pruneEvery is how many shared-tier writes happen between prunes
+	pruneEvery = 1000
+)
+
+type cacheEntry struct {
+	key    string
+	vector []float64
+}
+
+// EmbeddingCache is an in-process LRU of embeddings with an optional shared tier.
+// Keys combine model, dimensions, task type and normalised text so different embedding
+// spaces never collide.
+type EmbeddingCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+
+	shared           SharedEmbeddingStore
+	maxSharedEntries int
+	sharedWrites     uint64
+}
+
+// CacheStats is a snapshot of the process-wide cache counters
+type CacheStats struct {
+	Entries    int
+	LocalHits  int64
+	SharedHits int64
+	Misses     int64
+	Evictions  int64
+}
+
+// NewEmbeddingCache creates an LRU holding up to maxEntries vectors. shared may be nil
+// to disable the shared tier; maxSharedEntries bounds its size (0 = unbounded).
+func NewEmbeddingCache(maxEntries int, shared SharedEmbeddingStore, maxSharedEntries int) *EmbeddingCache {
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	return &EmbeddingCache{
+		maxEntries:       maxEntries,
+		ll:               list.New(),
+		items:            make(map[string]*list.Element),
+		shared:           shared,
+		maxSharedEntries: maxSharedEntries,
+	}
+}
+
+// CacheKey builds the cache key for a model at a vector size, task type and text. The
+// registry runs one embedder per model@dims over the same shared tier, so the size is
+// part of the key.
+func CacheKey(model string, dimensions int, taskType, text string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + strconv.Itoa(dimensions) + "\x00" + taskType + "\x00" + normalizeForCache(text)))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeForCache lowercases text and collapses whitespace so trivially different
+// spellings of the same question share an entry
+func normalizeForCache(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// Get returns a copy of the cached vector, consulting the shared tier on a local miss
+func (c *EmbeddingCache) Get(ctx context.Context, key string) ([]float64, bool) {
+	c.mu.Lock()
+	if el, ok := c.items[key]; ok {
+		c.ll.MoveToFront(el)
+		vec := copyVector(el.Value.(*cacheEntry).vector)
+		c.mu.Unlock()
+		cacheLocalHits.Add(1)
+		return vec, true
+	}
+	c.mu.Unlock()
+
+	if c.shared != nil {
+		sharedCtx, cancel := context.WithTimeout(ctx, sharedCacheTimeout)
+		defer cancel()
+		vec, ok, err := c.shared.GetCachedEmbedding(sharedCtx, key)
+		if err != nil {
+			utils.Zlog.Debug("Shared embedding cache lookup failed", zap.Error(err))
+		} else if ok {
+			cacheSharedHits.Add(1)
+			c.putLocal(key, vec)
+			return copyVector(vec), true
+		}
+	}
+
+	cacheMisses.Add(1)
+	return nil, false
+}
+
+// Put stores a vector locally and, asynchronously, in the shared tier
+func (c *EmbeddingCache) Put(key, model, taskType string, vector []float64) {
+	c.putLocal(key, copyVector(vector))
+
+	if c.shared == nil {
+		return
+	}
+	writes := atomic.AddUint64(&c.sharedWrites, 1)
+	vec := copyVector(vector)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := c.shared.PutCachedEmbedding(ctx, key, model, taskType, vec); err != nil {
+			utils.Zlog.Debug("Shared embedding cache write failed", zap.Error(err))
+			return
+		}
+		if c.maxSharedEntries > 0 && writes%pruneEvery == 0 {
+			if pruned, err := c.shared.PruneEmbeddingCache(ctx, c.maxSharedEntries); err != nil {
+				utils.Zlog.Warn("Shared embedding cache prune failed", zap.Error(err))
+			} else if pruned > 0 {
+				utils.Zlog.Info("Pruned shared embedding cache", zap.Int64("removed", pruned))
+			}
+		}
+	}()
+}
+
+func (c *EmbeddingCache) putLocal(key string, vector []float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*cacheEntry).vector = vector
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&cacheEntry{key: key, vector: vector})
+	for c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheEntry).key)
+		cacheEvictions.Add(1)
+	}
+}
+
+// Stats returns the current entry count and process-wide counters
+func (c *EmbeddingCache) Stats() CacheStats {
+	c.mu.Lock()
+	entries := c.ll.Len()
+	c.mu.Unlock()
+	return CacheStats{
+		Entries:    entries,
+		LocalHits:  cacheLocalHits.Value(),
+		SharedHits: cacheSharedHits.Value(),
+		Misses:     cacheMisses.Value(),
+		Evictions:  cacheEvictions.Value(),
+	}
+}
+
+func copyVector(vec []float64) []float64 {
+	out := make([]float64, len(vec))
+	copy(out, vec)
+	return out
+}
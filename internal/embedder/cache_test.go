@@ -0,0 +1,122 @@
+package embedder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Conversly/lightning-response/internal/types"
+)
+
+// memoryStore is a SharedEmbeddingStore backed by a map
+type memoryStore struct {
+	mu      sync.Mutex
+	vectors map[string][]float64
+}
+
+func (s *memoryStore) GetCachedEmbedding(ctx context.Context, key string) ([]float64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vec, ok := s.vectors[key]
+	return vec, ok, nil
+}
+
+func (s *memoryStore) PutCachedEmbedding(ctx context.Context, key, model, taskType string, vector []float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.vectors[key] = vector
+	return nil
+}
+
+func (s *memoryStore) PruneEmbeddingCache(ctx context.Context, maxEntries int) (int64, error) {
+	return 0, nil
+}
+
+func TestCacheKeySeparatesDimensions(t *testing.T) {
+	a := CacheKey("text-embedding-004", 768, TaskRetrievalQuery, "Reset  my password")
+	if b := CacheKey("text-embedding-004", 768, TaskRetrievalQuery, "reset my password"); a != b {
+		t.Error("keys differ for texts that only differ in case and spacing")
+	}
+	if b := CacheKey("text-embedding-004", 1536, TaskRetrievalQuery, "reset my password"); a == b {
+		t.Error("keys collide across dimensions")
+	}
+	if b := CacheKey("text-embedding-004", 768, TaskRetrievalDocument, "reset my password"); a == b {
+		t.Error("keys collide across task types")
+	}
+}
+
+// fakeEmbedAPI serves embedContent and batchEmbedContents with vectors of the
+// requested size
+func fakeEmbedAPI(t *testing.T, calls *int64) *httptest.Server {
+	t.Helper()
+	vector := func(req types.EmbeddingRequest) types.Embedding {
+		values := make([]float64, req.OutputDimensionality)
+		values[0] = 1
+		return types.Embedding{Values: values}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(calls, 1)
+		if strings.HasSuffix(r.URL.Path, ":batchEmbedContents") {
+			var req types.BatchEmbeddingRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			var res types.BatchEmbeddingResponse
+			for _, one := range req.Requests {
+				res.Embeddings = append(res.Embeddings, vector(one))
+			}
+			_ = json.NewEncoder(w).Encode(res)
+			return
+		}
+		var req types.EmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(types.EmbeddingResponse{Embedding: vector(req)})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestEmbedQueryDiscardsCachedVectorOfOtherSize(t *testing.T) {
+	var calls int64
+	srv := fakeEmbedAPI(t, &calls)
+
+	g, err := NewGeminiEmbedder([]string{"key"}, "text-embedding-004", 768)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.baseURL = srv.URL
+
+	// A shared tier holding a vector of the wrong size under this embedder's key
+	shared := &memoryStore{vectors: map[string][]float64{
+		CacheKey("text-embedding-004", 768, TaskRetrievalQuery, "hello"): make([]float64, 1536),
+	}}
+	g.UseCache(NewEmbeddingCache(10, shared, 0))
+
+	vec, err := g.EmbedQuery(context.Background(), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vec) != 768 {
+		t.Fatalf("got %d dimensions, want 768", len(vec))
+	}
+	if calls != 1 {
+		t.Fatalf("API called %d times, want 1", calls)
+	}
+
+	// The fresh vector replaced the bad entry locally
+	if _, err := g.EmbedQuery(context.Background(), "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("API called %d times after a cache hit, want 1", calls)
+	}
+}
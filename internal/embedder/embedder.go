@@ -0,0 +1,63 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Conversly/lightning-response/internal/config"
+)
+
+// Task types understood by the embedding API. Queries and documents are embedded
+// asymmetrically so short questions land close to the passages that answer them.
+const (
+	TaskRetrievalQuery    = "RETRIEVAL_QUERY"
+	TaskRetrievalDocument = "RETRIEVAL_DOCUMENT"
+
+	DefaultModel      = "text-embedding-004"
+	DefaultDimensions = 768
+)
+
+// errEmptyText rejects texts with nothing to embed
+var errEmptyText = errors.New("text cannot be empty")
+
+// Embedder turns text into vectors for retrieval
+type Embedder interface {
+	// EmbedQuery embeds a search query. A blank query is an error.
+	EmbedQuery(ctx context.Context, text string) ([]float64, error)
+	// EmbedDocuments embeds passages to be stored and searched. Blank passages fail on
+	// their own: the error is a *BatchError and the other vectors are still returned.
+	EmbedDocuments(ctx context.Context, texts []string) ([][]float64, error)
+	// Model identifies the embedding space; vectors from different models are not comparable
+	Model() string
+	// Dimensions is the length of every returned vector
+	Dimensions() int
+}
+
+// NewFromConfig builds the embedder selected by EMBEDDING_PROVIDER. The shared store,
+// if non-nil and enabled in config, backs the embedding cache's shared tier.
+func NewFromConfig(cfg *config.Config, shared SharedEmbeddingStore) (Embedder, error) {
+	return newForModel(cfg, cfg.EmbeddingModel, cfg.EmbeddingDimensions, shared)
+}
+
+// newForModel builds an embedder of the configured provider for a specific model
+func newForModel(cfg *config.Config, model string, dimensions int, shared SharedEmbeddingStore) (Embedder, error) {
+	switch cfg.EmbeddingProvider {
+	case "local":
+		return NewLocalEmbedder(model, dimensions), nil
+	case "", "gemini":
+		emb, err := NewGeminiEmbedder(cfg.GeminiAPIKeys, model, dimensions)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.EmbeddingCacheSize > 0 {
+			if !cfg.EmbeddingCacheShared {
+				shared = nil
+			}
+			emb.UseCache(NewEmbeddingCache(cfg.EmbeddingCacheSize, shared, cfg.EmbeddingCacheSharedSize))
+		}
+		return emb, nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider: %s", cfg.EmbeddingProvider)
+	}
+}
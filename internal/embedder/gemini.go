@@ -8,6 +8,7 @@ import (
 	"io"
 	"math"
 	"net/http"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -20,13 +21,23 @@ type GeminiEmbedder struct {
 	baseURL     string
 	keyIndex    uint64        // atomic counter for round-robin key selection
 	rateLimiter chan struct{} // global rate limiter across all workers
+	cache       *EmbeddingCache
+	model       string
+	dimensions  int
 }
 
-// NewGeminiEmbedder creates a new embedder with API keys
-func NewGeminiEmbedder(keys []string) (*GeminiEmbedder, error) {
+// NewGeminiEmbedder creates a new embedder with API keys. An empty model or zero
+// dimensions fall back to text-embedding-004 at 768 dimensions.
+func NewGeminiEmbedder(keys []string, model string, dimensions int) (*GeminiEmbedder, error) {
 	if len(keys) == 0 {
 		return nil, fmt.Errorf("at least one API key is required")
 	}
+	if model == "" {
+		model = DefaultModel
+	}
+	if dimensions <= 0 {
+		dimensions = DefaultDimensions
+	}
 	maxConcurrentRequests := 5
 
 	return &GeminiEmbedder{
@@ -35,9 +46,19 @@ func NewGeminiEmbedder(keys []string) (*GeminiEmbedder, error) {
 		baseURL:     "https://generativelanguage.googleapis.com/v1beta/models",
 		keyIndex:    0,
 		rateLimiter: make(chan struct{}, maxConcurrentRequests),
+		model:       model,
+		dimensions:  dimensions,
 	}, nil
 }
 
+func (g *GeminiEmbedder) Model() string   { return g.model }
+func (g *GeminiEmbedder) Dimensions() int { return g.dimensions }
+
+// UseCache puts an embedding cache in front of the embedding calls. Pass nil to disable caching.
+func (g *GeminiEmbedder) UseCache(cache *EmbeddingCache) {
+	g.cache = cache
+}
+
 // getNextKey returns the next API key using round-robin selection
 // Thread-safe: uses atomic operations to ensure fair distribution across goroutines
 func (g *GeminiEmbedder) getNextKey() string {
@@ -74,11 +95,37 @@ func normalize(vec []float64) []float64 {
 	return normalized
 }
 
-func (g *GeminiEmbedder) EmbedText(ctx context.Context, text string) ([]float64, error) {
-	if text == "" {
-		return nil, fmt.Errorf("text cannot be empty")
+// EmbedQuery embeds a search query with the RETRIEVAL_QUERY task type
+func (g *GeminiEmbedder) EmbedQuery(ctx context.Context, text string) ([]float64, error) {
+	return g.embedText(ctx, text, TaskRetrievalQuery)
+}
+
+// embedText embeds a single text, consulting the cache first when one is configured
+func (g *GeminiEmbedder) embedText(ctx context.Context, text string, taskType string) ([]float64, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, errEmptyText
+	}
+
+	if g.cache == nil {
+		return g.embedContent(ctx, text, taskType)
+	}
+
+	key := CacheKey(g.model, g.dimensions, taskType, text)
+	// A vector of another size would fail in pgvector, so treat it as a miss
+	if vec, ok := g.cache.Get(ctx, key); ok && len(vec) == g.dimensions {
+		return vec, nil
 	}
 
+	vec, err := g.embedContent(ctx, text, taskType)
+	if err != nil {
+		return nil, err
+	}
+	g.cache.Put(key, g.model, taskType, vec)
+	return vec, nil
+}
+
+// embedContent calls the embedContent endpoint for a single text
+func (g *GeminiEmbedder) embedContent(ctx context.Context, text string, taskType string) ([]float64, error) {
 	select {
 	case g.rateLimiter <- struct{}{}:
 		defer func() { <-g.rateLimiter }()
@@ -87,14 +134,14 @@ func (g *GeminiEmbedder) EmbedText(ctx context.Context, text string) ([]float64,
 	}
 
 	reqBody := types.EmbeddingRequest{
-		Model: "text-embedding-004",
+		Model: g.model,
 		Content: types.EmbeddingContent{
 			Parts: []types.Part{
 				{Text: text},
 			},
 		},
-		TaskType:            "RETRIEVAL_DOCUMENT",
-		OutputDimensionality: 768,
+		TaskType:             taskType,
+		OutputDimensionality: g.dimensions,
 	}
 
 	jsonBody, err := json.Marshal(reqBody)
@@ -103,7 +150,7 @@ func (g *GeminiEmbedder) EmbedText(ctx context.Context, text string) ([]float64,
 	}
 
 	apiKey := g.getNextKey()
-	url := fmt.Sprintf("%s/text-embedding-004:embedContent?key=%s", g.baseURL, apiKey)
+	url := fmt.Sprintf("%s/%s:embedContent?key=%s", g.baseURL, g.model, apiKey)
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
 	if err != nil {
@@ -132,28 +179,12 @@ func (g *GeminiEmbedder) EmbedText(ctx context.Context, text string) ([]float64,
 		return nil, fmt.Errorf("no embeddings returned from API")
 	}
 
-	if len(embeddingResp.Embedding.Values) != 768 {
-		return nil, fmt.Errorf("expected 768 dimensions, got %d", len(embeddingResp.Embedding.Values))
+	if len(embeddingResp.Embedding.Values) != g.dimensions {
+		return nil, fmt.Errorf("expected %d dimensions, got %d", g.dimensions, len(embeddingResp.Embedding.Values))
 	}
 
 	normalized := normalize(embeddingResp.Embedding.Values)
 	return normalized, nil
 }
 
-func (g *GeminiEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
-	if len(texts) == 0 {
-		return nil, fmt.Errorf("no texts provided")
-	}
-
-	embeddings := make([][]float64, len(texts))
-
-	for i, text := range texts {
-		embedding, err := g.EmbedText(ctx, text)
-		if err != nil {
-			return nil, fmt.Errorf("failed to embed text at index %d: %w", i, err)
-		}
-		embeddings[i] = embedding
-	}
-
-	return embeddings, nil
-}
+var _ Embedder = (*GeminiEmbedder)(nil)
@@ -0,0 +1,88 @@
+package embedder
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// LocalEmbedder is a deterministic, offline embedder based on feature hashing of words
+// and character trigrams. Texts sharing vocabulary get similar vectors, which is enough
+// for tests and local development without API keys. It is not a semantic model.
+type LocalEmbedder struct {
+	model      string
+	dimensions int
+}
+
+// NewLocalEmbedder creates a hashing embedder producing vectors of the given size
+func NewLocalEmbedder(model string, dimensions int) *LocalEmbedder {
+	if model == "" {
+		model = "local-hash"
+	}
+	if dimensions <= 0 {
+		dimensions = DefaultDimensions
+	}
+	return &LocalEmbedder{model: model, dimensions: dimensions}
+}
+
+func (l *LocalEmbedder) Model() string   { return l.model }
+func (l *LocalEmbedder) Dimensions() int { return l.dimensions }
+
+func (l *LocalEmbedder) EmbedQuery(ctx context.Context, text string) ([]float64, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, errEmptyText
+	}
+	return l.embed(text), nil
+}
+
+func (l *LocalEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("no texts provided")
+	}
+	vectors := make([][]float64, len(texts))
+	failed := make(map[int]error)
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			failed[i] = errEmptyText
+			continue
+		}
+		vectors[i] = l.embed(text)
+	}
+	if len(failed) > 0 {
+		return vectors, &BatchError{Total: len(texts), Failed: failed}
+	}
+	return vectors, nil
+}
+
+func (l *LocalEmbedder) embed(text string) []float64 {
+	vec := make([]float64, l.dimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	for _, w := range words {
+		l.addFeature(vec, "w:"+w, 1.0)
+		runes := []rune("#" + w + "#")
+		for i := 0; i+3 <= len(runes); i++ {
+			l.addFeature(vec, "c:"+string(runes[i:i+3]), 0.5)
+		}
+	}
+	return normalize(vec)
+}
+
+// addFeature hashes a feature into a bucket, using a second hash bit for the sign so
+// collisions cancel out on average
+func (l *LocalEmbedder) addFeature(vec []float64, feature string, weight float64) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(feature))
+	sum := h.Sum64()
+	idx := int(sum % uint64(l.dimensions))
+	if (sum>>63)&1 == 1 {
+		weight = -weight
+	}
+	vec[idx] += weight
+}
+
+var _ Embedder = (*LocalEmbedder)(nil)
@@ -0,0 +1,110 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func dot(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func TestLocalEmbedderDeterministic(t *testing.T) {
+	ctx := context.Background()
+	e := NewLocalEmbedder("", 64)
+	if e.Model() != "local-hash" || e.Dimensions() != 64 {
+		t.Fatalf("got model %q, dimensions %d", e.Model(), e.Dimensions())
+	}
+
+	a, err := e.EmbedQuery(ctx, "How do I reset my password?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewLocalEmbedder("", 64).EmbedQuery(ctx, "How do I reset my password?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 64 {
+		t.Fatalf("got %d dimensions, want 64", len(a))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("vectors differ at %d: %v != %v", i, a[i], b[i])
+		}
+	}
+	if norm := math.Sqrt(dot(a, a)); math.Abs(norm-1) > 1e-9 {
+		t.Errorf("norm = %v, want 1", norm)
+	}
+}
+
+func TestLocalEmbedderSimilarity(t *testing.T) {
+	ctx := context.Background()
+	e := NewLocalEmbedder("", 256)
+	vecs, err := e.EmbedDocuments(ctx, []string{
+		"reset your password from the account page",
+		"how do I reset my password",
+		"shipping to Canada takes five days",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	related, unrelated := dot(vecs[0], vecs[1]), dot(vecs[0], vecs[2])
+	if related <= unrelated {
+		t.Errorf("related similarity %v <= unrelated %v", related, unrelated)
+	}
+}
+
+func TestLocalEmbedderDefaultDimensions(t *testing.T) {
+	if got := NewLocalEmbedder("", 0).Dimensions(); got != DefaultDimensions {
+		t.Errorf("dimensions = %d, want %d", got, DefaultDimensions)
+	}
+}
+
+// Both embedders reject a blank query and fail only the blank passages of a batch
+func TestEmbeddersRejectEmptyAlike(t *testing.T) {
+	var calls int64
+	srv := fakeEmbedAPI(t, &calls)
+	gemini, err := NewGeminiEmbedder([]string{"key"}, "text-embedding-004", 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gemini.baseURL = srv.URL
+
+	ctx := context.Background()
+	for _, e := range []Embedder{NewLocalEmbedder("", 64), gemini} {
+		t.Run(e.Model(), func(t *testing.T) {
+			for _, text := range []string{"", "  \n"} {
+				if _, err := e.EmbedQuery(ctx, text); !errors.Is(err, errEmptyText) {
+					t.Errorf("EmbedQuery(%q) error = %v, want %v", text, err, errEmptyText)
+				}
+			}
+
+			vecs, err := e.EmbedDocuments(ctx, []string{"a", "", "b", " "})
+			var batchErr *BatchError
+			if !errors.As(err, &batchErr) {
+				t.Fatalf("EmbedDocuments error = %v, want a *BatchError", err)
+			}
+			if got := batchErr.FailedIndices(); !reflect.DeepEqual(got, []int{1, 3}) {
+				t.Errorf("failed indices = %v, want [1 3]", got)
+			}
+			if len(vecs) != 4 || len(vecs[0]) != 64 || vecs[1] != nil || len(vecs[2]) != 64 || vecs[3] != nil {
+				t.Errorf("got vectors of sizes %v, want 64 for the non-blank texts only", sizes(vecs))
+			}
+		})
+	}
+}
+
+func sizes(vecs [][]float64) []int {
+	out := make([]int, len(vecs))
+	for i, v := range vecs {
+		out[i] = len(v)
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package embedder
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Conversly/lightning-response/internal/config"
+	"github.com/Conversly/lightning-response/internal/types"
+)
+
+// Registry hands out embedders by model. The default embedder produces the primary
+// embeddings.vector column; other models serve chatbots that were migrated to them and
+// the backfills that migrate them. Embedders are created once per model and shared, so
+// each model has a single rate limiter and cache.
+type Registry struct {
+	cfg    *config.Config
+	shared SharedEmbeddingStore
+	def    Embedder
+
+	mu      sync.Mutex
+	byModel map[string]Embedder
+}
+
+// NewRegistry builds the default embedder from config
+func NewRegistry(cfg *config.Config, shared SharedEmbeddingStore) (*Registry, error) {
+	def, err := NewFromConfig(cfg, shared)
+	if err != nil {
+		return nil, err
+	}
+	return &Registry{
+		cfg:     cfg,
+		shared:  shared,
+		def:     def,
+		byModel: make(map[string]Embedder),
+	}, nil
+}
+
+// Default returns the embedder of the primary vector column
+func (r *Registry) Default() Embedder {
+	if r == nil {
+		return nil
+	}
+	return r.def
+}
+
+// IsDefault reports whether model (empty meaning unset) is served by the primary column
+func (r *Registry) IsDefault(model string) bool {
+	return r == nil || model == "" || model == r.def.Model()
+}
+
+// For returns the embedder for model at the given dimensions (0 = provider default)
+func (r *Registry) For(model string, dimensions int) (Embedder, error) {
+	if r == nil {
+		return nil, fmt.Errorf("no embedder configured")
+	}
+	if r.IsDefault(model) && (dimensions <= 0 || dimensions == r.def.Dimensions()) {
+		return r.def, nil
+	}
+
+	key := fmt.Sprintf("%s@%d", model, dimensions)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if emb, ok := r.byModel[key]; ok {
+		return emb, nil
+	}
+	emb, err := newForModel(r.cfg, model, dimensions, r.shared)
+	if err != nil {
+		return nil, err
+	}
+	r.byModel[key] = emb
+	return emb, nil
+}
+
+// ForSettings returns the embedder of the model a chatbot retrieves with
+func (r *Registry) ForSettings(settings *types.ChatbotSettings) (Embedder, error) {
+	if settings == nil || settings.EmbeddingModel == nil || r.IsDefault(*settings.EmbeddingModel) {
+		return r.Default(), nil
+	}
+	dimensions := 0
+	if settings.EmbeddingDimensions != nil {
+		dimensions = *settings.EmbeddingDimensions
+	}
+	return r.For(*settings.EmbeddingModel, dimensions)
+}
@@ -0,0 +1,315 @@
+// Package export writes a chatbot's messages to downloadable JSONL or CSV files.
+package export
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+var (
+	ErrExportRunning   = errors.New("an export is already running for this chatbot")
+	ErrExporterStopped = errors.New("exporter is stopped")
+	ErrExportNotReady  = errors.New("export is not complete")
+	ErrExportExpired   = errors.New("export has expired")
+)
+
+const (
+	defaultExportTimeout = 2 * time.Hour
+	janitorInterval      = 10 * time.Minute
+	// progressInterval is how many rows are written between progress updates
+	progressInterval = 50000
+	partExt          = ".part"
+)
+
+// Exporter runs export jobs in the background. Files are written to a directory shared
+// by every instance, so an export can be downloaded from any of them, and deleted by a
+// janitor once they expire.
+type Exporter struct {
+	db   *loaders.PostgresClient
+	dir  string
+	ttl  time.Duration
+	host string
+
+	mu      sync.Mutex
+	running map[string]string // chatbot id -> export id
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// NewExporter creates an exporter writing to dir, cleans up after a previous run of this
+// instance and starts the janitor. Exports are kept for ttl after they complete; host
+// identifies this instance in the job records, so it must differ between instances.
+func NewExporter(db *loaders.PostgresClient, dir string, ttl time.Duration, host string) *Exporter {
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &Exporter{
+		db:      db,
+		dir:     dir,
+		ttl:     ttl,
+		host:    host,
+		running: make(map[string]string),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+	e.recover()
+	e.wg.Add(1)
+	go e.janitor()
+	return e
+}
+
+// Start records a new export of chatbotID's messages created in [from, to) and runs it
+// in the background
+func (e *Exporter) Start(ctx context.Context, chatbotID, format string, from, to time.Time) (*loaders.ExportRecord, error) {
+	if format != FormatJSONL && format != FormatCSV {
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+
+	e.mu.Lock()
+	if _, ok := e.running[chatbotID]; ok {
+		e.mu.Unlock()
+		return nil, ErrExportRunning
+	}
+	if e.ctx.Err() != nil {
+		e.mu.Unlock()
+		return nil, ErrExporterStopped
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		e.mu.Unlock()
+		return nil, fmt.Errorf("failed to generate export id: %w", err)
+	}
+	e.running[chatbotID] = id.String()
+	e.mu.Unlock()
+
+	job := &loaders.ExportRecord{
+		ID:        id.String(),
+		ChatbotID: chatbotID,
+		Format:    format,
+		From:      from.UTC(),
+		To:        to.UTC(),
+		Status:    loaders.ExportRunning,
+		Host:      e.host,
+		StartedAt: time.Now().UTC(),
+	}
+	if err := e.db.CreateExport(ctx, job); err != nil {
+		e.finish(chatbotID)
+		return nil, err
+	}
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		defer e.finish(chatbotID)
+
+		jobCtx, cancel := context.WithTimeout(e.ctx, defaultExportTimeout)
+		defer cancel()
+		e.run(jobCtx, job)
+	}()
+
+	utils.Zlog.Info("Export started",
+		zap.String("export_id", job.ID),
+		zap.String("chatbot_id", chatbotID),
+		zap.String("format", format),
+		zap.Time("from", job.From),
+		zap.Time("to", job.To))
+	return job, nil
+}
+
+// Get loads an export job
+func (e *Exporter) Get(ctx context.Context, exportID string) (*loaders.ExportRecord, error) {
+	return e.db.GetExport(ctx, exportID)
+}
+
+// Open returns a completed export and its file, which the caller must close
+func (e *Exporter) Open(ctx context.Context, exportID string) (*loaders.ExportRecord, *os.File, error) {
+	job, err := e.db.GetExport(ctx, exportID)
+	if err != nil {
+		return nil, nil, err
+	}
+	switch {
+	case job.Status == loaders.ExportExpired,
+		job.ExpiresAt != nil && time.Now().After(*job.ExpiresAt):
+		return job, nil, ErrExportExpired
+	case job.Status != loaders.ExportCompleted || job.FilePath == nil:
+		return job, nil, ErrExportNotReady
+	}
+	// The directory may be mounted elsewhere on the instance that wrote the file
+	f, err := os.Open(e.path(job))
+	if errors.Is(err, os.ErrNotExist) {
+		return job, nil, ErrExportExpired
+	}
+	if err != nil {
+		return job, nil, fmt.Errorf("failed to open export file: %w", err)
+	}
+	return job, f, nil
+}
+
+// Stop cancels running exports (they are recorded as failed and their partial files
+// removed), stops the janitor and waits for both
+func (e *Exporter) Stop() {
+	e.cancel()
+	e.wg.Wait()
+}
+
+func (e *Exporter) finish(chatbotID string) {
+	e.mu.Lock()
+	delete(e.running, chatbotID)
+	e.mu.Unlock()
+}
+
+// run writes the export to a partial file and renames it into place once complete, so
+// a file under its final name is always whole
+func (e *Exporter) run(ctx context.Context, job *loaders.ExportRecord) {
+	path := e.path(job)
+	size, err := e.write(ctx, job, path+partExt)
+
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	job.Status = loaders.ExportCompleted
+	if err == nil {
+		err = os.Rename(path+partExt, path)
+	}
+	if err != nil {
+		msg := err.Error()
+		job.Status = loaders.ExportFailed
+		job.ErrorMessage = &msg
+		if rmErr := os.Remove(path + partExt); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
+			utils.Zlog.Warn("Failed to remove partial export", zap.String("export_id", job.ID), zap.Error(rmErr))
+		}
+		utils.Zlog.Error("Export failed", zap.String("export_id", job.ID), zap.String("chatbot_id", job.ChatbotID), zap.Error(err))
+	} else {
+		expires := finished.Add(e.ttl)
+		job.FilePath = &path
+		job.SizeBytes = size
+		job.ExpiresAt = &expires
+		utils.Zlog.Info("Export completed",
+			zap.String("export_id", job.ID),
+			zap.String("chatbot_id", job.ChatbotID),
+			zap.Int64("rows", job.Rows),
+			zap.Int64("bytes", size))
+	}
+	if err := e.db.UpdateExport(context.WithoutCancel(ctx), job); err != nil {
+		utils.Zlog.Error("Failed to record export outcome", zap.String("export_id", job.ID), zap.Error(err))
+	}
+}
+
+// write streams the job's messages to path and returns the size of the file
+func (e *Exporter) write(ctx context.Context, job *loaders.ExportRecord, path string) (int64, error) {
+	if err := os.MkdirAll(e.dir, 0o755); err != nil {
+		return 0, fmt.Errorf("failed to create export directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o640)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer f.Close()
+
+	buf := bufio.NewWriterSize(f, 256<<10)
+	w := newRecordWriter(job.Format, buf)
+	err = e.db.StreamMessages(ctx, job.ChatbotID, job.From, job.To, func(m *loaders.ExportMessage) error {
+		if err := w.Write(newRecord(m)); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		job.Rows++
+		if job.Rows%progressInterval == 0 {
+			if err := e.db.UpdateExport(ctx, job); err != nil {
+				utils.Zlog.Warn("Failed to record export progress", zap.String("export_id", job.ID), zap.Error(err))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := w.Flush(); err != nil {
+		return 0, fmt.Errorf("failed to write export: %w", err)
+	}
+	if err := buf.Flush(); err != nil {
+		return 0, fmt.Errorf("failed to write export: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to sync export: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat export: %w", err)
+	}
+	return info.Size(), f.Close()
+}
+
+// path returns where the file of an export is written
+func (e *Exporter) path(job *loaders.ExportRecord) string {
+	return filepath.Join(e.dir, job.ID+"."+job.Format)
+}
+
+// janitor deletes expired exports until the exporter stops
+func (e *Exporter) janitor() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(janitorInterval)
+	defer ticker.Stop()
+	for {
+		e.purge()
+		select {
+		case <-e.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recover fails the exports this host was running when it last stopped and removes
+// their partial files. Partial files of other instances' exports are left alone.
+func (e *Exporter) recover() {
+	interrupted, err := e.db.FailInterruptedExports(e.ctx, e.host)
+	if err != nil {
+		utils.Zlog.Warn("Failed to fail interrupted exports", zap.Error(err))
+		return
+	}
+	for i := range interrupted {
+		if err := os.Remove(e.path(&interrupted[i]) + partExt); err != nil && !errors.Is(err, os.ErrNotExist) {
+			utils.Zlog.Warn("Failed to remove partial export", zap.String("export_id", interrupted[i].ID), zap.Error(err))
+		}
+	}
+	if len(interrupted) > 0 {
+		utils.Zlog.Info("Failed interrupted exports", zap.Int("exports", len(interrupted)))
+	}
+}
+
+// purge deletes the files of expired exports. Every instance runs it; deleting a file
+// another instance already deleted is not an error.
+func (e *Exporter) purge() {
+	expired, err := e.db.ListExpiredExports(e.ctx, time.Now().UTC())
+	if err != nil {
+		if e.ctx.Err() == nil {
+			utils.Zlog.Warn("Failed to list expired exports", zap.Error(err))
+		}
+		return
+	}
+	for _, job := range expired {
+		if job.FilePath != nil {
+			if err := os.Remove(filepath.Join(e.dir, filepath.Base(*job.FilePath))); err != nil && !errors.Is(err, os.ErrNotExist) {
+				utils.Zlog.Warn("Failed to delete expired export", zap.String("export_id", job.ID), zap.Error(err))
+				continue
+			}
+		}
+		if err := e.db.MarkExportExpired(e.ctx, job.ID); err != nil {
+			utils.Zlog.Warn("Failed to expire export", zap.String("export_id", job.ID), zap.Error(err))
+		}
+	}
+	if len(expired) > 0 {
+		utils.Zlog.Info("Deleted expired exports", zap.Int("exports", len(expired)))
+	}
+}
@@ -0,0 +1,143 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+)
+
+// Supported export formats
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+// csvHeader names the CSV columns; JSONL records use the same names
+var csvHeader = []string{
+	"messageId", "conversationId", "role", "content", "citations", "createdAt",
+	"topicId", "topicName", "feedback", "feedbackComment",
+}
+
+// Record is one exported message
+type Record struct {
+	MessageID       string   `json:"messageId"`
+	ConversationID  string   `json:"conversationId"`
+	Role            string   `json:"role"`
+	Content         string   `json:"content"`
+	Citations       []string `json:"citations"`
+	CreatedAt       string   `json:"createdAt"`
+	TopicID         *string  `json:"topicId"`
+	TopicName       *string  `json:"topicName"`
+	Feedback        string   `json:"feedback"` // like | dislike | neutral, empty when none
+	FeedbackComment *string  `json:"feedbackComment"`
+}
+
+func newRecord(m *loaders.ExportMessage) *Record {
+	return &Record{
+		MessageID:       m.ID,
+		ConversationID:  m.ConversationID,
+		Role:            m.Type,
+		Content:         m.Content,
+		Citations:       m.Citations,
+		CreatedAt:       m.CreatedAt.UTC().Format(time.RFC3339Nano),
+		TopicID:         m.TopicID,
+		TopicName:       m.TopicName,
+		Feedback:        loaders.FeedbackLabel(m.Feedback),
+		FeedbackComment: m.FeedbackComment,
+	}
+}
+
+// recordWriter writes records in one format
+type recordWriter interface {
+	Write(r *Record) error
+	Flush() error
+}
+
+func newRecordWriter(format string, w io.Writer) recordWriter {
+	if format == FormatCSV {
+		return &csvWriter{w: csv.NewWriter(w)}
+	}
+	return &jsonlWriter{enc: json.NewEncoder(w)}
+}
+
+// jsonlWriter writes one JSON object per line
+type jsonlWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonlWriter) Write(r *Record) error {
+	return j.enc.Encode(r)
+}
+
+func (j *jsonlWriter) Flush() error {
+	return nil
+}
+
+// csvWriter writes a header row, then one row per record. Citations are a JSON array so
+// URLs containing separators survive; missing values are empty cells. Cells a
+// spreadsheet would read as a formula are escaped.
+type csvWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (c *csvWriter) Write(r *Record) error {
+	if !c.wroteHeader {
+		c.wroteHeader = true
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	citations, err := json.Marshal(r.Citations)
+	if err != nil {
+		return err
+	}
+	row := []string{
+		r.MessageID, r.ConversationID, r.Role, r.Content, string(citations), r.CreatedAt,
+		deref(r.TopicID), deref(r.TopicName), r.Feedback, deref(r.FeedbackComment),
+	}
+	for i := range row {
+		row[i] = escapeFormula(row[i])
+	}
+	return c.w.Write(row)
+}
+
+// Flush writes the header of an empty export and any buffered rows
+func (c *csvWriter) Flush() error {
+	if !c.wroteHeader {
+		c.wroteHeader = true
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// escapeFormula prefixes a cell starting like a spreadsheet formula with a quote, so
+// visitor-written content opened in a spreadsheet is shown rather than evaluated
+func escapeFormula(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// ContentType returns the MIME type of a format
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
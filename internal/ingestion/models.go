@@ -0,0 +1,324 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/embedder"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+var ErrMigrationRunning = errors.New("an embedding migration is already running for this chatbot")
+
+const (
+	backfillBatchSize       = 100
+	maxBackfillPasses       = 5
+	defaultMigrationTimeout = 6 * time.Hour
+)
+
+// ModelMigrator moves chatbots to another embedding model without downtime. Vectors of
+// the new model are backfilled next to the primary ones while the chatbot keeps
+// answering from its current model; once every chunk is covered the chatbot's retriever
+// is flipped to the new model. Migrating to the default model flips back immediately,
+// since the primary column always covers every chunk.
+type ModelMigrator struct {
+	pipeline  *Pipeline
+	reindexer *Reindexer
+
+	mu      sync.Mutex
+	running map[string]string // chatbot id -> migration id
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// NewModelMigrator creates a migrator that stores vectors through pipeline's database.
+// Migrations do not start while reindexer rebuilds the chatbot.
+func NewModelMigrator(pipeline *Pipeline, reindexer *Reindexer) *ModelMigrator {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ModelMigrator{
+		pipeline:  pipeline,
+		reindexer: reindexer,
+		running:   make(map[string]string),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start records a migration of chatbotID to model and runs its backfill in the
+// background. dimensions of 0 uses the model's default size. It refuses while the
+// chatbot is being reindexed, since the swap replaces the rows being backfilled.
+func (m *ModelMigrator) Start(ctx context.Context, chatbotID, model string, dimensions int) (*loaders.EmbeddingMigrationRecord, error) {
+	if m.reindexer.Running(chatbotID) {
+		return nil, ErrReindexRunning
+	}
+	db := m.pipeline.db
+	embedders := m.pipeline.embedders
+
+	var emb embedder.Embedder
+	if !embedders.IsDefault(model) {
+		var err error
+		if emb, err = embedders.For(model, dimensions); err != nil {
+			return nil, err
+		}
+		dimensions = emb.Dimensions()
+	}
+
+	m.mu.Lock()
+	if _, ok := m.running[chatbotID]; ok {
+		m.mu.Unlock()
+		return nil, ErrMigrationRunning
+	}
+	if m.ctx.Err() != nil {
+		m.mu.Unlock()
+		return nil, ErrWorkerStopped
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("failed to generate migration id: %w", err)
+	}
+	m.running[chatbotID] = id.String()
+	m.mu.Unlock()
+
+	record := &loaders.EmbeddingMigrationRecord{
+		ID:         id.String(),
+		ChatbotID:  chatbotID,
+		Model:      model,
+		Dimensions: dimensions,
+		Status:     loaders.MigrationRunning,
+		StartedAt:  time.Now().UTC(),
+	}
+
+	if emb == nil {
+		defer m.finish(chatbotID)
+		record.Model = embedders.Default().Model()
+		record.Dimensions = embedders.Default().Dimensions()
+		if err := db.CreateEmbeddingMigration(ctx, record); err != nil {
+			return nil, err
+		}
+		if err := db.ActivateEmbeddingModel(ctx, chatbotID, "", 0); err != nil {
+			return nil, err
+		}
+		now := time.Now().UTC()
+		record.Status = loaders.MigrationCompleted
+		record.FinishedAt, record.ActivatedAt = &now, &now
+		if err := db.UpdateEmbeddingMigration(ctx, record); err != nil {
+			return nil, err
+		}
+		return record, nil
+	}
+
+	record.TotalChunks, err = db.CountChunksMissingModel(ctx, chatbotID, model)
+	if err == nil {
+		err = db.CreateEmbeddingMigration(ctx, record)
+	}
+	if err != nil {
+		m.finish(chatbotID)
+		return nil, err
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer m.finish(chatbotID)
+
+		jobCtx, cancel := context.WithTimeout(m.ctx, defaultMigrationTimeout)
+		defer cancel()
+		m.run(jobCtx, record, emb)
+	}()
+
+	utils.Zlog.Info("Embedding migration started",
+		zap.String("migration_id", record.ID),
+		zap.String("chatbot_id", chatbotID),
+		zap.String("model", model),
+		zap.Int("chunks", record.TotalChunks))
+	return record, nil
+}
+
+// Stop cancels running migrations and waits for them. Backfilled vectors are kept, so a
+// new migration to the same model resumes where the old one stopped.
+func (m *ModelMigrator) Stop() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+func (m *ModelMigrator) finish(chatbotID string) {
+	m.mu.Lock()
+	delete(m.running, chatbotID)
+	m.mu.Unlock()
+}
+
+// run backfills until the model covers every chunk, then flips the chatbot to it. Chunks
+// ingested during a pass are picked up by the next one.
+func (m *ModelMigrator) run(ctx context.Context, record *loaders.EmbeddingMigrationRecord, emb embedder.Embedder) {
+	db := m.pipeline.db
+
+	err := func() error {
+		for pass := 0; pass < maxBackfillPasses; pass++ {
+			// Chunks that fail are retried by the next pass; coverage stays incomplete
+			// until they succeed
+			_, failed, err := m.pipeline.backfillModel(ctx, record.ChatbotID, emb, func(n int) {
+				record.EmbeddedChunks += n
+				if err := db.UpdateEmbeddingMigration(ctx, record); err != nil {
+					utils.Zlog.Warn("Failed to record migration progress", zap.String("migration_id", record.ID), zap.Error(err))
+				}
+			})
+			if err != nil {
+				return err
+			}
+			record.FailedChunks = failed
+			// Curated answers must keep matching once the chatbot switches models
+			if err := m.pipeline.backfillFAQ(ctx, record.ChatbotID, emb); err != nil {
+				return err
+			}
+
+			err = db.ActivateEmbeddingModel(ctx, record.ChatbotID, record.Model, record.Dimensions)
+			if !errors.Is(err, loaders.ErrIncompleteCoverage) {
+				return err
+			}
+		}
+		if record.FailedChunks > 0 {
+			return fmt.Errorf("%d chunks could not be embedded with %s after %d passes",
+				record.FailedChunks, record.Model, maxBackfillPasses)
+		}
+		return fmt.Errorf("chunks kept arriving faster than they were backfilled after %d passes", maxBackfillPasses)
+	}()
+
+	finished := time.Now().UTC()
+	record.FinishedAt = &finished
+	record.Status = loaders.MigrationCompleted
+	if err != nil {
+		msg := err.Error()
+		record.Status = loaders.MigrationFailed
+		record.ErrorMessage = &msg
+		utils.Zlog.Error("Embedding migration failed",
+			zap.String("migration_id", record.ID),
+			zap.String("chatbot_id", record.ChatbotID),
+			zap.Error(err))
+	} else {
+		record.ActivatedAt = &finished
+		utils.Zlog.Info("Embedding migration completed",
+			zap.String("migration_id", record.ID),
+			zap.String("chatbot_id", record.ChatbotID),
+			zap.String("model", record.Model),
+			zap.Int("embedded", record.EmbeddedChunks))
+	}
+	if err := db.UpdateEmbeddingMigration(context.WithoutCancel(ctx), record); err != nil {
+		utils.Zlog.Error("Failed to record migration outcome", zap.String("migration_id", record.ID), zap.Error(err))
+	}
+}
+
+// ensureActiveModel backfills the vectors of the model a chatbot retrieves with, if that
+// is not the default model whose vectors ingestion already wrote
+func (p *Pipeline) ensureActiveModel(ctx context.Context, chatbotID string) error {
+	settings, err := p.db.GetChatbotSettings(ctx, chatbotID)
+	if err != nil {
+		return err
+	}
+	if settings.EmbeddingModel == nil || p.embedders.IsDefault(*settings.EmbeddingModel) {
+		return nil
+	}
+
+	dimensions := 0
+	if settings.EmbeddingDimensions != nil {
+		dimensions = *settings.EmbeddingDimensions
+	}
+	emb, err := p.embedders.For(*settings.EmbeddingModel, dimensions)
+	if err != nil {
+		return err
+	}
+	_, failed, err := p.backfillModel(ctx, chatbotID, emb, nil)
+	if err == nil && failed > 0 {
+		err = fmt.Errorf("%d chunks could not be embedded with %s", failed, emb.Model())
+	}
+	return err
+}
+
+// backfillModel embeds every chunk of a chatbot that has no vector from emb's model yet,
+// in id order and batches of backfillBatchSize. progress, if set, receives the number of
+// vectors stored after each batch. Chunks that fail to embed, alone or with their whole
+// batch, are logged and skipped; it returns how many were stored and how many failed.
+// Only database errors and cancellation stop it.
+func (p *Pipeline) backfillModel(ctx context.Context, chatbotID string, emb embedder.Embedder, progress func(int)) (int, int, error) {
+	model := emb.Model()
+	var afterID int64
+	stored, failed := 0, 0
+
+	for {
+		chunks, err := p.db.ListChunksMissingModel(ctx, chatbotID, model, afterID, backfillBatchSize)
+		if err != nil {
+			return stored, failed, err
+		}
+		if len(chunks) == 0 {
+			break
+		}
+		afterID = chunks[len(chunks)-1].ID
+
+		texts := make([]string, len(chunks))
+		for i, c := range chunks {
+			texts[i] = c.Text
+		}
+		vectors, err := emb.EmbedDocuments(ctx, texts)
+		var batchErr *embedder.BatchError
+		if err != nil && !errors.As(err, &batchErr) {
+			if ctx.Err() != nil {
+				return stored, failed, ctx.Err()
+			}
+			vectors = make([][]float64, len(chunks))
+		}
+
+		ids := make([]int64, 0, len(chunks))
+		embedded := make([][]float64, 0, len(chunks))
+		var failedIDs []int64
+		for i, c := range chunks {
+			if vectors[i] == nil {
+				failedIDs = append(failedIDs, c.ID)
+				continue
+			}
+			ids = append(ids, c.ID)
+			embedded = append(embedded, vectors[i])
+		}
+		if len(failedIDs) > 0 {
+			failed += len(failedIDs)
+			utils.Zlog.Warn("Chunks could not be embedded for backfill",
+				zap.String("chatbot_id", chatbotID),
+				zap.String("model", model),
+				zap.Int64s("chunk_ids", failedIDs),
+				zap.Error(err))
+		}
+		if err := p.db.UpsertModelVectors(ctx, chatbotID, model, ids, embedded); err != nil {
+			return stored, failed, err
+		}
+		stored += len(ids)
+		if progress != nil {
+			progress(len(ids))
+		}
+	}
+
+	return stored, failed, nil
+}
+
+// backfillFAQ embeds the chatbot's curated FAQ questions that have no vector from emb's
+// model yet. Questions are embedded as queries, like the user questions they match.
+func (p *Pipeline) backfillFAQ(ctx context.Context, chatbotID string, emb embedder.Embedder) error {
+	questions, err := p.db.ListFAQQuestionsMissingModel(ctx, chatbotID, emb.Model())
+	if err != nil || len(questions) == 0 {
+		return err
+	}
+
+	vectors := make([][]float64, len(questions))
+	for i, q := range questions {
+		if vectors[i], err = emb.EmbedQuery(ctx, q.Question); err != nil {
+			return fmt.Errorf("failed to embed faq question: %w", err)
+		}
+	}
+	return p.db.AddFAQQuestionVectors(ctx, chatbotID, emb.Model(), questions, vectors)
+}
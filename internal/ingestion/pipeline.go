@@ -0,0 +1,336 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/crawler"
+	"github.com/Conversly/lightning-response/internal/embedder"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/parsers"
+	"github.com/Conversly/lightning-response/internal/splitter"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+// Job describes one data source to ingest into a chatbot's knowledge base
+type Job struct {
+	DataSourceID int
+	ChatbotID    string
+	UserID       string
+	SourceURL    string // downloaded with FileDownloader when UploadPath is empty
+	UploadPath   string // uploaded file spooled to disk; removed once the job is done
+	ContentType  string
+	Filename     string
+	Citation     string // shown to end users; defaults to SourceURL
+	// Crawl makes this a website data source: pages found from Crawl.StartURL are
+	// ingested one by one instead of downloading SourceURL.
+	Crawl *crawler.Config
+}
+
+// Pipeline turns a data source into stored embeddings:
+// download -> parse (by content type) -> split -> diff against stored -> embed -> apply
+//
+// Chunks are embedded with the default model into the primary vector column; chatbots
+// migrated to another model additionally get that model's vectors backfilled.
+type Pipeline struct {
+	db          *loaders.PostgresClient
+	embedders   *embedder.Registry
+	embedder    embedder.Embedder
+	downloader  *utils.FileDownloader
+	crawlClient *http.Client
+	chunking    splitter.Config
+}
+
+// NewPipeline creates an ingestion pipeline. crawlClient is shared by website crawls.
+// chunking holds the default splitter settings; chatbots can override them in
+// chatbot_settings.
+func NewPipeline(db *loaders.PostgresClient, embedders *embedder.Registry, downloader *utils.FileDownloader, crawlClient *http.Client, chunking splitter.Config) *Pipeline {
+	return &Pipeline{
+		db:          db,
+		embedders:   embedders,
+		embedder:    embedders.Default(),
+		downloader:  downloader,
+		crawlClient: crawlClient,
+		chunking:    chunking,
+	}
+}
+
+// Process ingests one data source, moving its status PROCESSING -> COMPLETED or FAILED.
+// The returned error is also recorded on the data_source row.
+func (p *Pipeline) Process(ctx context.Context, job *Job) error {
+	defer removeUpload(job)
+
+	if err := p.db.SetDataSourceStatus(ctx, job.DataSourceID, loaders.DataSourceProcessing, nil); err != nil {
+		return err
+	}
+
+	summary, warning, err := p.run(ctx, job)
+	if err != nil {
+		msg := err.Error()
+		if statusErr := p.db.SetDataSourceStatus(context.WithoutCancel(ctx), job.DataSourceID, loaders.DataSourceFailed, &msg); statusErr != nil {
+			utils.Zlog.Error("Failed to mark data source as failed",
+				zap.Int("data_source_id", job.DataSourceID),
+				zap.Error(statusErr))
+		}
+		return err
+	}
+
+	// The rows are live for the primary column already; a failed backfill only delays
+	// them for a chatbot on another model and is retried by the next sync
+	if err := p.ensureActiveModel(ctx, job.ChatbotID); err != nil {
+		utils.Zlog.Warn("Failed to backfill vectors for the chatbot's embedding model",
+			zap.Int("data_source_id", job.DataSourceID),
+			zap.String("chatbot_id", job.ChatbotID),
+			zap.Error(err))
+	}
+	if err := p.db.SetDataSourceSyncSummary(ctx, job.DataSourceID, summary); err != nil {
+		utils.Zlog.Warn("Failed to record sync summary",
+			zap.Int("data_source_id", job.DataSourceID),
+			zap.Error(err))
+	}
+	if err := p.db.SetDataSourceStatus(ctx, job.DataSourceID, loaders.DataSourceCompleted, warning); err != nil {
+		return err
+	}
+
+	utils.Zlog.Info("Data source ingested",
+		zap.Int("data_source_id", job.DataSourceID),
+		zap.String("chatbot_id", job.ChatbotID),
+		zap.Int("added", summary.Added),
+		zap.Int("updated", summary.Updated),
+		zap.Int("removed", summary.Removed),
+		zap.Int("unchanged", summary.Unchanged))
+	return nil
+}
+
+// run executes the pipeline steps and returns what changed relative to the stored
+// version of the data source. A non-nil warning reports chunks that were skipped while
+// the rest of the source was ingested.
+func (p *Pipeline) run(ctx context.Context, job *Job) (loaders.SyncSummary, *string, error) {
+	if job.Crawl != nil {
+		return p.runCrawl(ctx, job)
+	}
+
+	file, err := p.fetch(ctx, job)
+	if err != nil {
+		return loaders.SyncSummary{}, nil, err
+	}
+
+	doc, err := parsers.Parse(file)
+	if err != nil {
+		return loaders.SyncSummary{}, nil, err
+	}
+
+	source := job.Citation
+	if source == "" {
+		source = job.SourceURL
+	}
+	split := splitter.New(p.chunkingFor(ctx, job.ChatbotID))
+	chunks := buildChunks(doc, split, source, strconv.Itoa(job.DataSourceID), job.DataSourceID)
+	if len(chunks) == 0 {
+		return loaders.SyncSummary{}, nil, fmt.Errorf("no text content found in data source")
+	}
+
+	summary, failed, err := p.sync(ctx, job, chunks, nil)
+	if err != nil {
+		return loaders.SyncSummary{}, nil, err
+	}
+
+	var warning *string
+	if failed > 0 {
+		msg := fmt.Sprintf("%d of %d chunks could not be embedded", failed, len(chunks))
+		warning = &msg
+	}
+	return summary, warning, nil
+}
+
+// runCrawl crawls the job's website and ingests every page as its own document, cited
+// by the page URL. Pages that fail are counted in the warning; the crawl only fails
+// when no page could be ingested.
+func (p *Pipeline) runCrawl(ctx context.Context, job *Job) (loaders.SyncSummary, *string, error) {
+	c, err := crawler.New(*job.Crawl, p.crawlClient)
+	if err != nil {
+		return loaders.SyncSummary{}, nil, err
+	}
+	split := splitter.New(p.chunkingFor(ctx, job.ChatbotID))
+
+	var chunks []loaders.EmbeddingData
+	pages := 0
+	stats, err := c.Crawl(ctx, func(ctx context.Context, page *crawler.Page) error {
+		parser, err := parsers.ForContentType(page.ContentType, "", page.Content)
+		if err != nil {
+			return err
+		}
+		doc, err := parser.Parse(page.Content)
+		if err != nil {
+			return err
+		}
+
+		documentID := fmt.Sprintf("%d:%s", job.DataSourceID, page.URL)
+		pageChunks := buildChunks(doc, split, page.URL, documentID, job.DataSourceID)
+		for i := range pageChunks {
+			pageChunks[i].Citation = &page.URL
+		}
+		if len(pageChunks) > 0 {
+			chunks = append(chunks, pageChunks...)
+			pages++
+		}
+		return nil
+	})
+	if err != nil {
+		return loaders.SyncSummary{}, nil, fmt.Errorf("crawl interrupted: %w", err)
+	}
+	if len(chunks) == 0 {
+		return loaders.SyncSummary{}, nil, fmt.Errorf("crawl found no ingestible pages (%d visited, %d skipped, %d failed)",
+			stats.Visited, stats.Skipped, stats.Failed)
+	}
+
+	utils.Zlog.Info("Website crawled",
+		zap.Int("data_source_id", job.DataSourceID),
+		zap.Int("pages", pages),
+		zap.Int("skipped", stats.Skipped),
+		zap.Int("failed", stats.Failed),
+		zap.Int("gone", len(stats.Gone)),
+		zap.Bool("truncated", stats.Truncated))
+
+	// Pages that failed transiently or were not reached before MaxPages are not evidence
+	// that they were removed, so their stored chunks stay; pages answering 404 or 410 are
+	// gone and are deleted either way
+	var keepAbsent func(*string) bool
+	if stats.Retryable > 0 || stats.Truncated {
+		gone := make(map[string]bool, len(stats.Gone))
+		for _, u := range stats.Gone {
+			gone[fmt.Sprintf("%d:%s", job.DataSourceID, u)] = true
+		}
+		keepAbsent = func(documentID *string) bool {
+			return documentID == nil || !gone[*documentID]
+		}
+	}
+	summary, failedChunks, err := p.sync(ctx, job, chunks, keepAbsent)
+	if err != nil {
+		return loaders.SyncSummary{}, nil, err
+	}
+
+	var warning *string
+	if stats.Failed > 0 || failedChunks > 0 {
+		msg := fmt.Sprintf("%d pages failed, %d chunks could not be embedded", stats.Failed, failedChunks)
+		warning = &msg
+	}
+	return summary, warning, nil
+}
+
+// chunkingFor returns the splitter settings for a chatbot, applying its overrides
+func (p *Pipeline) chunkingFor(ctx context.Context, chatbotID string) splitter.Config {
+	cfg := p.chunking
+	settings, err := p.db.GetChatbotSettings(ctx, chatbotID)
+	if err != nil {
+		utils.Zlog.Warn("Failed to load chatbot settings, using default chunking",
+			zap.String("chatbot_id", chatbotID),
+			zap.Error(err))
+		return cfg
+	}
+	if settings.ChunkStrategy != nil {
+		cfg.Strategy = *settings.ChunkStrategy
+	}
+	if settings.ChunkSize != nil {
+		cfg.ChunkSize = *settings.ChunkSize
+	}
+	if settings.ChunkOverlap != nil {
+		cfg.ChunkOverlap = *settings.ChunkOverlap
+	}
+	if settings.ChunkUnit != nil {
+		cfg.Unit = *settings.ChunkUnit
+	}
+	return cfg
+}
+
+// fetch reads the spooled upload or downloads the file from the source URL
+func (p *Pipeline) fetch(ctx context.Context, job *Job) (*utils.DownloadedFile, error) {
+	if job.UploadPath != "" {
+		content, err := os.ReadFile(job.UploadPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read uploaded file: %w", err)
+		}
+		return &utils.DownloadedFile{
+			Content:     content,
+			ContentType: job.ContentType,
+			Filename:    job.Filename,
+			Size:        int64(len(content)),
+		}, nil
+	}
+	if job.SourceURL == "" {
+		return nil, fmt.Errorf("data source has neither content nor a URL")
+	}
+
+	file, err := p.downloader.DownloadFile(ctx, job.SourceURL, job.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	if file.ContentType == "" {
+		file.ContentType = job.ContentType
+	}
+	return file, nil
+}
+
+// removeUpload deletes the job's spooled upload, if any
+func removeUpload(job *Job) {
+	if job.UploadPath == "" {
+		return
+	}
+	if err := os.Remove(job.UploadPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		utils.Zlog.Warn("Failed to remove uploaded file",
+			zap.Int("data_source_id", job.DataSourceID),
+			zap.String("path", job.UploadPath),
+			zap.Error(err))
+	}
+}
+
+// segmentSeparator joins segments into the document text that chunk offsets refer to
+const segmentSeparator = "\n\n"
+
+// buildChunks splits every parsed segment and attaches its citation, section and
+// position. Indices and offsets run across the whole document (segments joined by
+// segmentSeparator) so neighbours span segments and overlap can be trimmed on merge.
+func buildChunks(doc *parsers.Document, split splitter.Splitter, source, documentID string, dataSourceID int) []loaders.EmbeddingData {
+	var chunks []loaders.EmbeddingData
+	offset := 0
+	for _, seg := range doc.Segments {
+		var citation *string
+		if c := seg.Citation(source); c != "" {
+			citation = &c
+		}
+
+		for _, c := range split.Split(seg.Text, seg.HeadingPath, offset) {
+			var section *string
+			if s := strings.Join(c.HeadingPath, " > "); s != "" {
+				section = &s
+			} else if seg.Page > 0 {
+				s := fmt.Sprintf("page %d", seg.Page)
+				section = &s
+			}
+
+			index := len(chunks)
+			start, end := c.Start, c.End
+			chunks = append(chunks, loaders.EmbeddingData{
+				Topic:        doc.Title,
+				Text:         c.Text,
+				DataSourceID: &dataSourceID,
+				Citation:     citation,
+				DocumentID:   &documentID,
+				ChunkIndex:   &index,
+				Section:      section,
+				StartOffset:  &start,
+				EndOffset:    &end,
+			})
+		}
+		offset += len(seg.Text) + len(segmentSeparator)
+	}
+	return chunks
+}
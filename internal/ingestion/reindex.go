@@ -0,0 +1,371 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/parsers"
+	"github.com/Conversly/lightning-response/internal/splitter"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+var (
+	ErrReindexRunning   = errors.New("a reindex is already running for this chatbot")
+	ErrIngestionRunning = errors.New("data sources of this chatbot are being ingested")
+)
+
+const defaultReindexTimeout = 2 * time.Hour
+
+// Reindexer rebuilds a chatbot's index with its current chunking settings. Documents are
+// reconstructed from the stored chunks, so uploads that were never kept can be re-split
+// without being uploaded again. New rows are written invisibly and swapped in atomically
+// at the end; until then, and if the job fails, the chatbot answers from the old index.
+type Reindexer struct {
+	pipeline *Pipeline
+
+	mu      sync.Mutex
+	running map[string]string // chatbot id -> job id
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// NewReindexer creates a reindexer that embeds and chunks with pipeline's settings
+func NewReindexer(pipeline *Pipeline) *Reindexer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Reindexer{
+		pipeline: pipeline,
+		running:  make(map[string]string),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Running reports whether a reindex of chatbotID is in progress
+func (r *Reindexer) Running(chatbotID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.running[chatbotID]
+	return ok
+}
+
+// Guard runs fn unless a reindex of chatbotID is running on any instance, in which case
+// it returns ErrReindexRunning. fn holds the chatbot's reindex lock in shared mode, so no
+// reindex can start while it runs and whatever it records (a PENDING data source,
+// deleted rows) is seen by the checks of the next Start.
+func (r *Reindexer) Guard(ctx context.Context, chatbotID string, fn func() error) error {
+	if r.Running(chatbotID) {
+		return ErrReindexRunning
+	}
+	release, ok, err := r.pipeline.db.TryReindexSharedLock(ctx, chatbotID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrReindexRunning
+	}
+	defer release()
+	return fn()
+}
+
+// Start records a new job and runs it in the background. It refuses while data sources
+// of the chatbot are queued or being ingested, since the swap would drop their rows. The
+// job holds the chatbot's reindex lock until it ends, so a second Start, on this instance
+// or another, returns ErrReindexRunning; so does one racing a Guard call.
+func (r *Reindexer) Start(ctx context.Context, chatbotID string) (*loaders.ReindexJobRecord, error) {
+	r.mu.Lock()
+	if _, ok := r.running[chatbotID]; ok {
+		r.mu.Unlock()
+		return nil, ErrReindexRunning
+	}
+	if r.ctx.Err() != nil {
+		r.mu.Unlock()
+		return nil, ErrWorkerStopped
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("failed to generate job id: %w", err)
+	}
+	r.running[chatbotID] = id.String()
+	r.mu.Unlock()
+
+	release, ok, err := r.pipeline.db.TryReindexLock(ctx, chatbotID)
+	if err == nil && !ok {
+		err = ErrReindexRunning
+	}
+	if err != nil {
+		r.finish(chatbotID)
+		return nil, err
+	}
+
+	db := r.pipeline.db
+	active, err := db.HasActiveIngestion(ctx, chatbotID, time.Now().Add(-defaultJobTimeout))
+	if err == nil && active {
+		err = ErrIngestionRunning
+	}
+	var docs []string
+	if err == nil {
+		docs, err = db.ListIndexedDocuments(ctx, chatbotID)
+	}
+	if err == nil {
+		// Rows of an earlier job that died before swapping are never going to be used
+		_, err = db.DiscardPendingEmbeddings(ctx, chatbotID)
+	}
+	job := &loaders.ReindexJobRecord{
+		ID:             id.String(),
+		ChatbotID:      chatbotID,
+		Status:         loaders.ReindexRunning,
+		TotalDocuments: len(docs),
+		StartedAt:      time.Now().UTC(),
+	}
+	if err == nil {
+		err = db.CreateReindexJob(ctx, job)
+	}
+	if err != nil {
+		release()
+		r.finish(chatbotID)
+		return nil, err
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer r.finish(chatbotID)
+		defer release()
+
+		jobCtx, cancel := context.WithTimeout(r.ctx, defaultReindexTimeout)
+		defer cancel()
+		r.run(jobCtx, job, docs)
+	}()
+
+	utils.Zlog.Info("Reindex started",
+		zap.String("job_id", job.ID),
+		zap.String("chatbot_id", chatbotID),
+		zap.Int("documents", len(docs)))
+	return job, nil
+}
+
+// Stop cancels running jobs (their partial output stays invisible) and waits for them
+func (r *Reindexer) Stop() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+func (r *Reindexer) finish(chatbotID string) {
+	r.mu.Lock()
+	delete(r.running, chatbotID)
+	r.mu.Unlock()
+}
+
+// run rebuilds every document, then swaps the new rows in. Any failure leaves the old
+// index untouched.
+func (r *Reindexer) run(ctx context.Context, job *loaders.ReindexJobRecord, docs []string) {
+	db := r.pipeline.db
+	split := splitter.New(r.pipeline.chunkingFor(ctx, job.ChatbotID))
+
+	var replaced []int64
+	err := func() error {
+		for _, documentID := range docs {
+			ids, embedded, reused, err := r.reindexDocument(ctx, job, split, documentID)
+			if err != nil {
+				return fmt.Errorf("document %s: %w", documentID, err)
+			}
+			replaced = append(replaced, ids...)
+			job.ProcessedDocuments++
+			job.ChunksEmbedded += embedded
+			job.ChunksReused += reused
+			if err := db.UpdateReindexJob(ctx, job); err != nil {
+				utils.Zlog.Warn("Failed to record reindex progress", zap.String("job_id", job.ID), zap.Error(err))
+			}
+		}
+		// A chatbot on another model must not lose retrieval coverage at the swap
+		if err := r.pipeline.ensureActiveModel(ctx, job.ChatbotID); err != nil {
+			return fmt.Errorf("failed to backfill the chatbot's embedding model: %w", err)
+		}
+		return db.SwapReindex(ctx, job.ChatbotID, job.ID, replaced)
+	}()
+
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	job.Status = loaders.ReindexCompleted
+	if err != nil {
+		msg := err.Error()
+		job.Status = loaders.ReindexFailed
+		job.ErrorMessage = &msg
+		if _, discardErr := db.DiscardPendingEmbeddings(context.WithoutCancel(ctx), job.ChatbotID); discardErr != nil {
+			utils.Zlog.Warn("Failed to discard pending embeddings", zap.String("job_id", job.ID), zap.Error(discardErr))
+		}
+		utils.Zlog.Error("Reindex failed", zap.String("job_id", job.ID), zap.String("chatbot_id", job.ChatbotID), zap.Error(err))
+	} else {
+		utils.Zlog.Info("Reindex completed",
+			zap.String("job_id", job.ID),
+			zap.String("chatbot_id", job.ChatbotID),
+			zap.Int("documents", job.ProcessedDocuments),
+			zap.Int("embedded", job.ChunksEmbedded),
+			zap.Int("reused", job.ChunksReused))
+	}
+	if err := db.UpdateReindexJob(context.WithoutCancel(ctx), job); err != nil {
+		utils.Zlog.Error("Failed to record reindex outcome", zap.String("job_id", job.ID), zap.Error(err))
+	}
+}
+
+// reindexDocument re-splits one stored document and writes its pending rows. It returns
+// the ids of the rows being replaced and how many chunks were embedded or reused.
+func (r *Reindexer) reindexDocument(ctx context.Context, job *loaders.ReindexJobRecord, split splitter.Splitter, documentID string) ([]int64, int, int, error) {
+	db := r.pipeline.db
+	stored, err := db.GetIndexedDocument(ctx, job.ChatbotID, documentID)
+	if err != nil || len(stored) == 0 {
+		return nil, 0, 0, err
+	}
+
+	doc, citations := reconstructDocument(stored)
+	chunks := buildChunks(doc, split, "", documentID, 0)
+	dataSourceID := stored[0].DataSourceID
+	model := r.pipeline.embedder.Model()
+	hashes := make([]string, len(chunks))
+	for i := range chunks {
+		chunks[i].DataSourceID = dataSourceID
+		chunks[i].Citation = citations.at(*chunks[i].StartOffset)
+		hashes[i] = contentHash(chunks[i].Text)
+		chunks[i].ContentHash = &hashes[i]
+		chunks[i].Model = &model
+	}
+
+	known, err := db.GetVectorsByHash(ctx, job.ChatbotID, model, hashes)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	var missing []int
+	for i := range chunks {
+		if vec, ok := known[hashes[i]]; ok {
+			chunks[i].Vector = vec
+		} else {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) > 0 {
+		texts := make([]string, len(missing))
+		for i, idx := range missing {
+			texts[i] = chunks[idx].Text
+		}
+		// Partial results are not good enough: the new index must be complete
+		vectors, err := r.pipeline.embedder.EmbedDocuments(ctx, texts)
+		if err != nil {
+			return nil, 0, 0, fmt.Errorf("failed to embed chunks: %w", err)
+		}
+		for i, idx := range missing {
+			chunks[idx].Vector = vectors[i]
+		}
+	}
+
+	if err := db.InsertPendingEmbeddings(ctx, stored[0].UserID, job.ChatbotID, job.ID, chunks); err != nil {
+		return nil, 0, 0, err
+	}
+
+	ids := make([]int64, len(stored))
+	for i, sc := range stored {
+		ids[i] = sc.ID
+	}
+	return ids, len(missing), len(chunks) - len(missing), nil
+}
+
+// citationSpans maps offset ranges of a reconstructed document to the citation of the
+// segment they came from
+type citationSpans []struct {
+	end      int
+	citation *string
+}
+
+func (c citationSpans) at(offset int) *string {
+	for _, span := range c {
+		if offset < span.end {
+			return span.citation
+		}
+	}
+	if len(c) > 0 {
+		return c[len(c)-1].citation
+	}
+	return nil
+}
+
+// reconstructDocument rebuilds a parsed document from its stored chunks: consecutive
+// chunks of the same section become one segment, and overlap between neighbouring
+// chunks is removed using their offsets. Chunks stored before offsets existed are
+// joined as separate paragraphs.
+func reconstructDocument(stored []loaders.IndexedChunk) (*parsers.Document, citationSpans) {
+	doc := &parsers.Document{}
+	var citations citationSpans
+	offset := 0
+
+	var current strings.Builder
+	var currentSection, currentCitation *string
+	prevEnd := -1
+
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		seg := parsers.Segment{Text: current.String()}
+		if currentSection != nil {
+			if page, ok := strings.CutPrefix(*currentSection, "page "); ok {
+				seg.Page, _ = strconv.Atoi(page)
+			}
+			if seg.Page == 0 {
+				seg.HeadingPath = strings.Split(*currentSection, " > ")
+			}
+		}
+		doc.Segments = append(doc.Segments, seg)
+		offset += len(seg.Text) + len(segmentSeparator)
+		citations = append(citations, struct {
+			end      int
+			citation *string
+		}{end: offset, citation: currentCitation})
+		current.Reset()
+	}
+
+	for i, sc := range stored {
+		if i > 0 && (!equalPtr(sc.Section, currentSection) || !equalPtr(sc.Citation, currentCitation)) {
+			flush()
+			prevEnd = -1
+		}
+		currentSection, currentCitation = sc.Section, sc.Citation
+
+		text := sc.Text
+		switch {
+		case sc.StartOffset == nil || sc.EndOffset == nil || prevEnd < 0:
+			if current.Len() > 0 {
+				current.WriteString("\n\n")
+			}
+		case prevEnd > *sc.StartOffset:
+			overlap := prevEnd - *sc.StartOffset
+			if overlap >= len(text) {
+				text = ""
+			} else {
+				text = text[overlap:]
+			}
+		case *sc.StartOffset-prevEnd > 1:
+			current.WriteString("\n\n")
+		case *sc.StartOffset > prevEnd:
+			current.WriteString(" ")
+		}
+		current.WriteString(text)
+
+		prevEnd = -1
+		if sc.EndOffset != nil {
+			prevEnd = *sc.EndOffset
+		}
+	}
+	flush()
+
+	return doc, citations
+}
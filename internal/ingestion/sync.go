@@ -0,0 +1,205 @@
+package ingestion
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/Conversly/lightning-response/internal/embedder"
+	"github.com/Conversly/lightning-response/internal/loaders"
+)
+
+// syncPlan is the diff between freshly built chunks and the stored rows of a data source
+type syncPlan struct {
+	embed   []int                 // indices of chunks that need new vectors
+	replace map[int]int64         // chunk index -> stored row rewritten with new content
+	move    []loaders.ChunkUpdate // unchanged content whose position metadata changed
+	remove  []int64
+	summary loaders.SyncSummary
+}
+
+// sync diffs chunks against what is stored for the job's data source and applies the
+// difference: only new or changed chunks are embedded, vanished rows are deleted and
+// unchanged rows are left alone. Stored documents absent from chunks are deleted unless
+// keepAbsent (which may be nil) reports that their absence may be a fetch failure rather
+// than a removal. It returns the summary and the number of chunks that could not be
+// embedded.
+func (p *Pipeline) sync(ctx context.Context, job *Job, chunks []loaders.EmbeddingData, keepAbsent func(documentID *string) bool) (loaders.SyncSummary, int, error) {
+	model := p.embedder.Model()
+	for i := range chunks {
+		h := contentHash(chunks[i].Text)
+		chunks[i].ContentHash = &h
+		chunks[i].Model = &model
+	}
+
+	stored, err := p.db.GetDataSourceChunks(ctx, job.DataSourceID)
+	if err != nil {
+		return loaders.SyncSummary{}, 0, err
+	}
+	plan := planSync(stored, chunks, keepAbsent)
+
+	changes := loaders.EmbeddingSync{Update: plan.move, Delete: plan.remove}
+	summary := plan.summary
+	failed := 0
+
+	if len(plan.embed) > 0 {
+		texts := make([]string, len(plan.embed))
+		for i, idx := range plan.embed {
+			texts[i] = chunks[idx].Text
+		}
+		vectors, err := p.embedder.EmbedDocuments(ctx, texts)
+		var batchErr *embedder.BatchError
+		if err != nil && !errors.As(err, &batchErr) {
+			return loaders.SyncSummary{}, 0, fmt.Errorf("failed to embed chunks: %w", err)
+		}
+
+		for i, idx := range plan.embed {
+			chunk := chunks[idx]
+			id, replacing := plan.replace[idx]
+			// A chunk that could not be embedded keeps its old row, if any, until next sync
+			if vectors[i] == nil {
+				failed++
+				if replacing {
+					summary.Updated--
+				} else {
+					summary.Added--
+				}
+				continue
+			}
+			chunk.Vector = vectors[i]
+			if replacing {
+				changes.Update = append(changes.Update, loaders.ChunkUpdate{ID: id, Data: chunk})
+			} else {
+				changes.Insert = append(changes.Insert, chunk)
+			}
+		}
+		if failed == len(plan.embed) {
+			return loaders.SyncSummary{}, failed, fmt.Errorf("failed to embed chunks: %w", err)
+		}
+	}
+
+	if err := p.db.ApplyEmbeddingSync(ctx, job.UserID, job.ChatbotID, changes); err != nil {
+		return loaders.SyncSummary{}, failed, err
+	}
+	return summary, failed, nil
+}
+
+// planSync matches chunks to stored rows: first by identical content at the same
+// position, then by identical content elsewhere in the same document (the chunk moved),
+// then by position with different content (the chunk changed). Everything else is added
+// or removed.
+func planSync(stored []loaders.StoredChunk, chunks []loaders.EmbeddingData, keepAbsent func(documentID *string) bool) syncPlan {
+	type position struct {
+		doc   string
+		index int
+	}
+	posKey := func(doc *string, index *int) (position, bool) {
+		if doc == nil || index == nil {
+			return position{}, false
+		}
+		return position{*doc, *index}, true
+	}
+
+	byPos := make(map[position]int, len(stored))
+	byHash := make(map[string][]int, len(stored))
+	for i, sc := range stored {
+		if k, ok := posKey(sc.DocumentID, sc.ChunkIndex); ok {
+			byPos[k] = i
+		}
+		if sc.ContentHash != nil {
+			byHash[*sc.ContentHash] = append(byHash[*sc.ContentHash], i)
+		}
+	}
+
+	plan := syncPlan{replace: make(map[int]int64)}
+	used := make([]bool, len(stored))
+	matched := make([]bool, len(chunks))
+
+	keep := func(ci, si int) {
+		used[si], matched[ci] = true, true
+		plan.summary.Unchanged++
+		if !samePosition(stored[si], chunks[ci]) {
+			plan.move = append(plan.move, loaders.ChunkUpdate{ID: stored[si].ID, Data: chunks[ci]})
+		}
+	}
+
+	for ci, c := range chunks {
+		if k, ok := posKey(c.DocumentID, c.ChunkIndex); ok {
+			if si, ok := byPos[k]; ok && equalPtr(stored[si].ContentHash, c.ContentHash) {
+				keep(ci, si)
+			}
+		}
+	}
+	for ci, c := range chunks {
+		if matched[ci] {
+			continue
+		}
+		for _, si := range byHash[*c.ContentHash] {
+			if !used[si] && equalPtr(stored[si].DocumentID, c.DocumentID) {
+				keep(ci, si)
+				break
+			}
+		}
+	}
+	for ci, c := range chunks {
+		if matched[ci] {
+			continue
+		}
+		matched[ci] = true
+		plan.embed = append(plan.embed, ci)
+		if k, ok := posKey(c.DocumentID, c.ChunkIndex); ok {
+			if si, ok := byPos[k]; ok && !used[si] {
+				used[si] = true
+				plan.replace[ci] = stored[si].ID
+				plan.summary.Updated++
+				continue
+			}
+		}
+		plan.summary.Added++
+	}
+
+	seenDocs := make(map[string]bool)
+	for _, c := range chunks {
+		if c.DocumentID != nil {
+			seenDocs[*c.DocumentID] = true
+		}
+	}
+	for si, sc := range stored {
+		if used[si] {
+			continue
+		}
+		absent := sc.DocumentID == nil || !seenDocs[*sc.DocumentID]
+		if absent && keepAbsent != nil && keepAbsent(sc.DocumentID) {
+			continue
+		}
+		plan.remove = append(plan.remove, sc.ID)
+		plan.summary.Removed++
+	}
+
+	return plan
+}
+
+// samePosition reports whether a stored row already carries the chunk's metadata
+func samePosition(sc loaders.StoredChunk, c loaders.EmbeddingData) bool {
+	return equalPtr(sc.DocumentID, c.DocumentID) &&
+		equalPtr(sc.ChunkIndex, c.ChunkIndex) &&
+		equalPtr(sc.Section, c.Section) &&
+		equalPtr(sc.Citation, c.Citation) &&
+		equalPtr(sc.StartOffset, c.StartOffset) &&
+		equalPtr(sc.EndOffset, c.EndOffset)
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// contentHash identifies chunk text independent of where it sits in the document
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
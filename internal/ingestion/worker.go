@@ -0,0 +1,109 @@
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+var (
+	ErrQueueFull     = errors.New("ingestion queue is full")
+	ErrWorkerStopped = errors.New("ingestion worker is stopped")
+)
+
+const defaultJobTimeout = 30 * time.Minute
+
+// Worker runs ingestion jobs on a fixed pool of goroutines fed by a bounded queue
+type Worker struct {
+	pipeline *Pipeline
+	jobs     chan *Job
+	workers  int
+
+	mu      sync.Mutex
+	stopped bool
+	wg      sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+}
+
+// NewWorker creates a worker pool; call Start to begin processing
+func NewWorker(pipeline *Pipeline, workers, queueSize int) *Worker {
+	if workers <= 0 {
+		workers = 2
+	}
+	if queueSize <= 0 {
+		queueSize = 100
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Worker{
+		pipeline: pipeline,
+		jobs:     make(chan *Job, queueSize),
+		workers:  workers,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start launches the worker goroutines
+func (w *Worker) Start() {
+	for i := 0; i < w.workers; i++ {
+		w.wg.Add(1)
+		go w.run()
+	}
+	utils.Zlog.Info("Ingestion worker started", zap.Int("workers", w.workers))
+}
+
+// Enqueue schedules a job without blocking
+func (w *Worker) Enqueue(job *Job) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return ErrWorkerStopped
+	}
+	select {
+	case w.jobs <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Stop stops accepting jobs, cancels running ones and waits for the goroutines to exit.
+// Jobs still queued are left in PENDING so they can be resubmitted; their uploads are
+// removed, so uploads must be sent again.
+func (w *Worker) Stop() {
+	w.mu.Lock()
+	if w.stopped {
+		w.mu.Unlock()
+		return
+	}
+	w.stopped = true
+	close(w.jobs)
+	w.mu.Unlock()
+
+	w.cancel()
+	w.wg.Wait()
+}
+
+func (w *Worker) run() {
+	defer w.wg.Done()
+	for job := range w.jobs {
+		if w.ctx.Err() != nil {
+			removeUpload(job)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(w.ctx, defaultJobTimeout)
+		if err := w.pipeline.Process(ctx, job); err != nil {
+			utils.Zlog.Error("Ingestion job failed",
+				zap.Int("data_source_id", job.DataSourceID),
+				zap.String("chatbot_id", job.ChatbotID),
+				zap.Error(err))
+		}
+		cancel()
+	}
+}
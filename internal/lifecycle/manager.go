@@ -0,0 +1,183 @@
+// Package lifecycle coordinates the shutdown of background workers. Components register
+// a stop function as they start; Shutdown calls them in reverse order, so whatever
+// started first (the database pool) is stopped last.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+// shutdownGrace is how long components reached after the deadline get to stop, in total
+const shutdownGrace = 500 * time.Millisecond
+
+// StopFunc stops a component. It should return once the component has finished its
+// work or ctx is done, whichever comes first.
+type StopFunc func(ctx context.Context) error
+
+type component struct {
+	name string
+	stop StopFunc
+}
+
+// Manager tracks registered components and in-flight tasks
+type Manager struct {
+	mu         sync.Mutex
+	components []component
+	tasks      map[string]int // running tasks by name
+	tasksWG    sync.WaitGroup
+	shutdown   bool
+}
+
+// NewManager creates an empty manager
+func NewManager() *Manager {
+	return &Manager{tasks: make(map[string]int)}
+}
+
+// Register adds a component to stop on shutdown. Components are stopped in the reverse
+// of their registration order.
+func (m *Manager) Register(name string, stop StopFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.components = append(m.components, component{name: name, stop: stop})
+}
+
+// RegisterFunc adds a component whose stop function takes no context
+func (m *Manager) RegisterFunc(name string, stop func()) {
+	m.Register(name, func(context.Context) error {
+		stop()
+		return nil
+	})
+}
+
+// Track records the start of a task and returns the function marking it done
+func (m *Manager) Track(name string) (done func()) {
+	m.mu.Lock()
+	m.tasks[name]++
+	m.tasksWG.Add(1)
+	m.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			if m.tasks[name]--; m.tasks[name] == 0 {
+				delete(m.tasks, name)
+			}
+			m.mu.Unlock()
+			m.tasksWG.Done()
+		})
+	}
+}
+
+// Go runs fn in a tracked goroutine
+func (m *Manager) Go(name string, fn func()) {
+	done := m.Track(name)
+	go func() {
+		defer done()
+		fn()
+	}()
+}
+
+// WaitTasks blocks until every tracked task has finished or ctx is done. Register it
+// as a component to drain tasks at a given point of the shutdown order.
+func (m *Manager) WaitTasks(ctx context.Context) error {
+	idle := make(chan struct{})
+	go func() {
+		m.tasksWG.Wait()
+		close(idle)
+	}()
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown stops every component in reverse registration order within ctx's deadline.
+// A component still stopping when the deadline passes is abandoned and the rest are
+// stopped within a short grace period; abandoned components and unfinished tasks are
+// logged together.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if m.shutdown {
+		m.mu.Unlock()
+		return nil
+	}
+	m.shutdown = true
+	components := append([]component(nil), m.components...)
+	m.mu.Unlock()
+
+	start := time.Now()
+	var abandoned, failed []string
+	// Past the deadline, the remaining components share one grace period so ones that
+	// stop instantly (closing the pool) still run. Its Done channel stays closed once it
+	// expires, so every component after a hung one is abandoned rather than waited on.
+	var grace context.Context
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+		result := make(chan error, 1)
+		go func() { result <- c.stop(ctx) }()
+
+		if grace == nil && ctx.Err() != nil {
+			var cancel context.CancelFunc
+			grace, cancel = context.WithTimeout(context.Background(), shutdownGrace)
+			defer cancel()
+		}
+		deadline := ctx.Done()
+		if grace != nil {
+			deadline = grace.Done()
+		}
+
+		select {
+		case err := <-result:
+			if err != nil && ctx.Err() != nil && errors.Is(err, ctx.Err()) {
+				abandoned = append(abandoned, c.name)
+				continue
+			}
+			if err != nil {
+				failed = append(failed, c.name)
+				utils.Zlog.Error("Failed to stop component", zap.String("component", c.name), zap.Error(err))
+				continue
+			}
+			utils.Zlog.Info("Stopped component", zap.String("component", c.name))
+		case <-deadline:
+			abandoned = append(abandoned, c.name)
+		}
+	}
+
+	running := m.runningTasks()
+	if len(abandoned) > 0 || len(running) > 0 {
+		utils.Zlog.Warn("Shutdown deadline exceeded, abandoning unfinished work",
+			zap.Strings("abandoned_components", abandoned),
+			zap.Strings("running_tasks", running),
+			zap.Duration("elapsed", time.Since(start)))
+		return fmt.Errorf("shutdown abandoned %d components and %d tasks", len(abandoned), len(running))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to stop %v", failed)
+	}
+	utils.Zlog.Info("Shutdown complete", zap.Duration("elapsed", time.Since(start)))
+	return nil
+}
+
+// runningTasks describes the unfinished tasks as "name x count"
+func (m *Manager) runningTasks() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	running := make([]string, 0, len(m.tasks))
+	for name, n := range m.tasks {
+		running = append(running, fmt.Sprintf("%s x%d", name, n))
+	}
+	sort.Strings(running)
+	return running
+}
@@ -0,0 +1,130 @@
+package lifecycle
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	utils.Zlog = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+func TestShutdownStopsInReverseOrder(t *testing.T) {
+	m := NewManager()
+	var mu sync.Mutex
+	var stopped []string
+	for _, name := range []string{"pool", "saver", "server"} {
+		m.RegisterFunc(name, func() {
+			mu.Lock()
+			stopped = append(stopped, name)
+			mu.Unlock()
+		})
+	}
+
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	want := []string{"server", "saver", "pool"}
+	if !reflect.DeepEqual(stopped, want) {
+		t.Fatalf("stopped %v, want %v", stopped, want)
+	}
+
+	// A second shutdown is a no-op
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("second Shutdown: %v", err)
+	}
+	if len(stopped) != 3 {
+		t.Fatalf("components stopped again: %v", stopped)
+	}
+}
+
+func TestShutdownAbandonsHungComponents(t *testing.T) {
+	m := NewManager()
+
+	// None of them honours ctx. The last registered one hits the deadline, the other two
+	// are both reached after it and must share the grace period.
+	hang := make(chan struct{})
+	defer close(hang)
+	m.RegisterFunc("hung first", func() { <-hang })
+	m.RegisterFunc("hung second", func() { <-hang })
+	m.RegisterFunc("hung third", func() { <-hang })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- m.Shutdown(ctx) }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("Shutdown succeeded with hung components")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown hung after the grace period expired")
+	}
+}
+
+func TestShutdownStopsInstantComponentsAfterDeadline(t *testing.T) {
+	m := NewManager()
+	poolClosed := make(chan struct{})
+	m.RegisterFunc("pool", func() { close(poolClosed) })
+	m.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := m.Shutdown(ctx); err == nil {
+		t.Fatal("Shutdown succeeded although a component hit the deadline")
+	}
+	select {
+	case <-poolClosed:
+	default:
+		t.Fatal("pool was not stopped within the grace period")
+	}
+}
+
+func TestShutdownReportsUnfinishedTasks(t *testing.T) {
+	m := NewManager()
+	m.Register("tasks", m.WaitTasks)
+
+	release := make(chan struct{})
+	defer close(release)
+	m.Go("webhook", func() { <-release })
+	m.Go("webhook", func() { <-release })
+	finished := m.Track("finished")
+	finished()
+	finished() // marking a task done twice is harmless
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := m.Shutdown(ctx); err == nil {
+		t.Fatal("Shutdown succeeded with running tasks")
+	}
+	if got, want := m.runningTasks(), []string{"webhook x2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("running tasks %v, want %v", got, want)
+	}
+}
+
+func TestShutdownWaitsForTasks(t *testing.T) {
+	m := NewManager()
+	m.Register("tasks", m.WaitTasks)
+
+	m.Go("webhook", func() { time.Sleep(10 * time.Millisecond) })
+	if err := m.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if running := m.runningTasks(); len(running) != 0 {
+		t.Fatalf("tasks still running: %v", running)
+	}
+}
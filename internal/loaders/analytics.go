@@ -0,0 +1,313 @@
+package loaders
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// analyticsLockKey is the advisory lock held by the instance rolling up analytics
+const analyticsLockKey int64 = 0x616e616c_79746963
+
+// LatencyBucketsMS are the upper bounds of the latency histogram buckets. A rollup's
+// histogram has one more bucket, for latencies at or above the last bound.
+var LatencyBucketsMS = []int64{
+	100, 200, 300, 500, 750, 1000, 1500, 2000, 3000, 4000, 5000, 7500, 10000, 15000,
+	20000, 30000, 60000,
+}
+
+// DirtyDay is a chatbot's day whose rollup is out of date
+type DirtyDay struct {
+	ChatbotID string
+	Day       time.Time
+	Version   int64
+}
+
+// DailyRollup aggregates a chatbot's messages created on one day (UTC)
+type DailyRollup struct {
+	Day               time.Time
+	Conversations     int // conversations with a message that day
+	UserMessages      int
+	AssistantMessages int
+	Likes             int
+	Dislikes          int
+	Neutral           int
+	ToolCalls         int
+	LatencyHistogram  []int64 // assistant messages per bucket of LatencyBucketsMS
+}
+
+// TopicCount is how many user messages were tagged with a topic
+type TopicCount struct {
+	TopicID  string
+	Name     *string // nil when the topic has been deleted
+	Messages int64
+}
+
+// ToolCount is how many times a tool was called
+type ToolCount struct {
+	Tool  string
+	Calls int64
+}
+
+// TryAnalyticsLock takes the rollup lock if no other instance holds it. The returned
+// function releases it; ok is false when the lock is taken.
+func (c *PostgresClient) TryAnalyticsLock(ctx context.Context) (release func(), ok bool, err error) {
+	return c.tryAdvisoryLock(ctx, analyticsLockKey)
+}
+
+// ListDirtyDays returns up to limit days whose rollups are out of date, oldest first
+func (c *PostgresClient) ListDirtyDays(ctx context.Context, limit int) ([]DirtyDay, error) {
+	query := `
+        SELECT chatbot_id, day, version
+        FROM analytics_dirty_days
+        ORDER BY day, chatbot_id
+        LIMIT $1
+    `
+
+	rows, err := c.pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dirty days: %w", err)
+	}
+	defer rows.Close()
+
+	days := []DirtyDay{}
+	for rows.Next() {
+		var d DirtyDay
+		if err := rows.Scan(&d.ChatbotID, &d.Day, &d.Version); err != nil {
+			return nil, fmt.Errorf("failed to scan dirty day: %w", err)
+		}
+		days = append(days, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating dirty days: %w", err)
+	}
+	return days, nil
+}
+
+// RollupDay recomputes the rollups of a dirty day from its messages and clears the mark
+// unless the day was marked again meanwhile. A day without messages keeps its rollups,
+// as its messages were purged or erased.
+func (c *PostgresClient) RollupDay(ctx context.Context, d *DirtyDay) error {
+	day := d.Day.Format(time.DateOnly)
+	start := formatTimeForDB(d.Day)
+	end := formatTimeForDB(d.Day.AddDate(0, 0, 1))
+
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var r DailyRollup
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(DISTINCT unique_conv_id),
+		       COUNT(*) FILTER (WHERE "type" = 'user'),
+		       COUNT(*) FILTER (WHERE "type" = 'assistant'),
+		       COUNT(*) FILTER (WHERE feedback = 1),
+		       COUNT(*) FILTER (WHERE feedback = 2),
+		       COUNT(*) FILTER (WHERE feedback = 3),
+		       COALESCE(SUM(cardinality(tools)), 0)
+		FROM messages
+		WHERE chatbot_id = $1 AND created_at >= $2 AND created_at < $3`,
+		d.ChatbotID, start, end).Scan(&r.Conversations, &r.UserMessages, &r.AssistantMessages,
+		&r.Likes, &r.Dislikes, &r.Neutral, &r.ToolCalls); err != nil {
+		return fmt.Errorf("failed to aggregate messages: %w", err)
+	}
+
+	if r.UserMessages+r.AssistantMessages > 0 {
+		if r.LatencyHistogram, err = latencyHistogram(ctx, tx, d.ChatbotID, start, end); err != nil {
+			return err
+		}
+		if err := replaceDailyRollup(ctx, tx, d.ChatbotID, day, start, end, &r); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM analytics_dirty_days
+		WHERE chatbot_id = $1 AND day = $2::date AND version = $3`, d.ChatbotID, day, d.Version); err != nil {
+		return fmt.Errorf("failed to clear dirty day: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit rollup: %w", err)
+	}
+	return nil
+}
+
+// latencyHistogram counts the day's assistant messages per latency bucket
+func latencyHistogram(ctx context.Context, tx pgx.Tx, chatbotID, start, end string) ([]int64, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT width_bucket(latency_ms, $4::int[]), COUNT(*)
+		FROM messages
+		WHERE chatbot_id = $1 AND created_at >= $2 AND created_at < $3
+		  AND "type" = 'assistant' AND latency_ms IS NOT NULL
+		GROUP BY 1`, chatbotID, start, end, LatencyBucketsMS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate latencies: %w", err)
+	}
+	defer rows.Close()
+
+	histogram := make([]int64, len(LatencyBucketsMS)+1)
+	for rows.Next() {
+		var bucket int
+		var count int64
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan latency bucket: %w", err)
+		}
+		histogram[bucket] += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating latency buckets: %w", err)
+	}
+	return histogram, nil
+}
+
+// replaceDailyRollup writes the day's totals and replaces its topic and tool breakdowns
+func replaceDailyRollup(ctx context.Context, tx pgx.Tx, chatbotID, day, start, end string, r *DailyRollup) error {
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO analytics_daily (
+			chatbot_id, day, conversations, user_messages, assistant_messages,
+			likes, dislikes, neutral, tool_calls, latency_histogram, updated_at
+		) VALUES ($1, $2::date, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		ON CONFLICT (chatbot_id, day) DO UPDATE SET
+			conversations = EXCLUDED.conversations,
+			user_messages = EXCLUDED.user_messages,
+			assistant_messages = EXCLUDED.assistant_messages,
+			likes = EXCLUDED.likes,
+			dislikes = EXCLUDED.dislikes,
+			neutral = EXCLUDED.neutral,
+			tool_calls = EXCLUDED.tool_calls,
+			latency_histogram = EXCLUDED.latency_histogram,
+			updated_at = EXCLUDED.updated_at`,
+		chatbotID, day, r.Conversations, r.UserMessages, r.AssistantMessages,
+		r.Likes, r.Dislikes, r.Neutral, r.ToolCalls, r.LatencyHistogram,
+		formatTimeForDB(time.Now().UTC())); err != nil {
+		return fmt.Errorf("failed to store daily rollup: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM analytics_daily_topics WHERE chatbot_id = $1 AND day = $2::date`, chatbotID, day); err != nil {
+		return fmt.Errorf("failed to clear topic rollup: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO analytics_daily_topics (chatbot_id, day, topic_id, messages)
+		SELECT $1, $2::date, topic_id::text, COUNT(*)
+		FROM messages
+		WHERE chatbot_id = $1 AND created_at >= $3 AND created_at < $4
+		  AND "type" = 'user' AND topic_id IS NOT NULL
+		GROUP BY topic_id`, chatbotID, day, start, end); err != nil {
+		return fmt.Errorf("failed to store topic rollup: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM analytics_daily_tools WHERE chatbot_id = $1 AND day = $2::date`, chatbotID, day); err != nil {
+		return fmt.Errorf("failed to clear tool rollup: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO analytics_daily_tools (chatbot_id, day, tool, calls)
+		SELECT $1, $2::date, t.tool, COUNT(*)
+		FROM messages, unnest(tools) AS t (tool)
+		WHERE chatbot_id = $1 AND created_at >= $3 AND created_at < $4
+		  AND "type" = 'assistant'
+		GROUP BY t.tool`, chatbotID, day, start, end); err != nil {
+		return fmt.Errorf("failed to store tool rollup: %w", err)
+	}
+	return nil
+}
+
+// GetDailyRollups returns a chatbot's rollups for the days from first to last, both
+// included. Days without messages are left out.
+func (c *PostgresClient) GetDailyRollups(ctx context.Context, chatbotID string, first, last time.Time) ([]DailyRollup, error) {
+	query := `
+        SELECT day, conversations, user_messages, assistant_messages, likes, dislikes,
+               neutral, tool_calls, latency_histogram
+        FROM analytics_daily
+        WHERE chatbot_id = $1 AND day BETWEEN $2::date AND $3::date
+        ORDER BY day
+    `
+
+	rows, err := c.pool.Query(ctx, query, chatbotID, first.Format(time.DateOnly), last.Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load daily rollups: %w", err)
+	}
+	defer rows.Close()
+
+	rollups := []DailyRollup{}
+	for rows.Next() {
+		var r DailyRollup
+		if err := rows.Scan(&r.Day, &r.Conversations, &r.UserMessages, &r.AssistantMessages,
+			&r.Likes, &r.Dislikes, &r.Neutral, &r.ToolCalls, &r.LatencyHistogram); err != nil {
+			return nil, fmt.Errorf("failed to scan daily rollup: %w", err)
+		}
+		rollups = append(rollups, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating daily rollups: %w", err)
+	}
+	return rollups, nil
+}
+
+// GetTopicTotals returns how many user messages of the days from first to last were
+// tagged with each topic, most frequent first
+func (c *PostgresClient) GetTopicTotals(ctx context.Context, chatbotID string, first, last time.Time) ([]TopicCount, error) {
+	query := `
+        SELECT r.topic_id, t.name, SUM(r.messages)
+        FROM analytics_daily_topics r
+        LEFT JOIN chatbot_topics t ON t.id::text = r.topic_id
+        WHERE r.chatbot_id = $1 AND r.day BETWEEN $2::date AND $3::date
+        GROUP BY r.topic_id, t.name
+        ORDER BY 3 DESC, r.topic_id
+    `
+
+	rows, err := c.pool.Query(ctx, query, chatbotID, first.Format(time.DateOnly), last.Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load topic totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := []TopicCount{}
+	for rows.Next() {
+		var t TopicCount
+		if err := rows.Scan(&t.TopicID, &t.Name, &t.Messages); err != nil {
+			return nil, fmt.Errorf("failed to scan topic total: %w", err)
+		}
+		totals = append(totals, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating topic totals: %w", err)
+	}
+	return totals, nil
+}
+
+// GetToolTotals returns how many times each tool was called on the days from first to
+// last, most called first
+func (c *PostgresClient) GetToolTotals(ctx context.Context, chatbotID string, first, last time.Time) ([]ToolCount, error) {
+	query := `
+        SELECT tool, SUM(calls)
+        FROM analytics_daily_tools
+        WHERE chatbot_id = $1 AND day BETWEEN $2::date AND $3::date
+        GROUP BY tool
+        ORDER BY 2 DESC, tool
+    `
+
+	rows, err := c.pool.Query(ctx, query, chatbotID, first.Format(time.DateOnly), last.Format(time.DateOnly))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tool totals: %w", err)
+	}
+	defer rows.Close()
+
+	totals := []ToolCount{}
+	for rows.Next() {
+		var t ToolCount
+		if err := rows.Scan(&t.Tool, &t.Calls); err != nil {
+			return nil, fmt.Errorf("failed to scan tool total: %w", err)
+		}
+		totals = append(totals, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tool totals: %w", err)
+	}
+	return totals, nil
+}
@@ -0,0 +1,148 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// taggingLockKey is the advisory lock held by the instance tagging conversations
+const taggingLockKey int64 = 0x74616767_696e6721
+
+// Conversation sentiments
+const (
+	SentimentPositive = "positive"
+	SentimentNeutral  = "neutral"
+	SentimentNegative = "negative"
+)
+
+// Conversation resolutions
+const (
+	ResolutionResolved   = "resolved"
+	ResolutionUnresolved = "unresolved"
+	ResolutionEscalated  = "escalated"
+)
+
+// IdleConversation is a conversation that has had no message for a while
+type IdleConversation struct {
+	ChatbotID      string
+	ConversationID string
+	MessageCount   int
+	LastMessageAt  time.Time
+}
+
+// ConversationTags is the classification of a whole conversation
+type ConversationTags struct {
+	ChatbotID      string
+	ConversationID string
+	TopicIDs       []string
+	Tags           []string
+	Sentiment      *string
+	Resolution     *string
+	Method         string // llm | heuristic
+	Model          *string
+	MessageCount   int
+	LastMessageAt  time.Time
+	ClassifiedAt   time.Time
+}
+
+// TryTaggingLock takes the tagging lock if no other instance holds it. The returned
+// function releases it; ok is false when the lock is taken.
+func (c *PostgresClient) TryTaggingLock(ctx context.Context) (release func(), ok bool, err error) {
+	return c.tryAdvisoryLock(ctx, taggingLockKey)
+}
+
+// ListIdleConversations returns up to limit conversations whose last message is between
+// since and idleBefore and that are untagged or have new messages since they were
+// tagged, oldest first
+func (c *PostgresClient) ListIdleConversations(ctx context.Context, since, idleBefore time.Time, limit int) ([]IdleConversation, error) {
+	query := `
+        WITH recent AS (
+            SELECT chatbot_id, unique_conv_id, MAX(created_at) AS last_message_at
+            FROM messages
+            WHERE created_at >= $1
+            GROUP BY chatbot_id, unique_conv_id
+            HAVING MAX(created_at) < $2
+        )
+        SELECT r.chatbot_id, r.unique_conv_id, r.last_message_at,
+               (SELECT COUNT(*) FROM messages m
+                WHERE m.chatbot_id = r.chatbot_id AND m.unique_conv_id = r.unique_conv_id)
+        FROM recent r
+        LEFT JOIN conversation_tags ct
+            ON ct.chatbot_id = r.chatbot_id AND ct.unique_conv_id = r.unique_conv_id
+        WHERE ct.last_message_at IS NULL OR ct.last_message_at < r.last_message_at
+        ORDER BY r.last_message_at
+        LIMIT $3
+    `
+
+	rows, err := c.pool.Query(ctx, query, formatTimeForDB(since), formatTimeForDB(idleBefore), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list idle conversations: %w", err)
+	}
+	defer rows.Close()
+
+	var conversations []IdleConversation
+	for rows.Next() {
+		var ic IdleConversation
+		if err := rows.Scan(&ic.ChatbotID, &ic.ConversationID, &ic.LastMessageAt, &ic.MessageCount); err != nil {
+			return nil, fmt.Errorf("failed to scan idle conversation: %w", err)
+		}
+		conversations = append(conversations, ic)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating idle conversations: %w", err)
+	}
+	return conversations, nil
+}
+
+// UpsertConversationTags stores a conversation's classification, replacing any earlier one
+func (c *PostgresClient) UpsertConversationTags(ctx context.Context, t *ConversationTags) error {
+	query := `
+        INSERT INTO conversation_tags (
+            chatbot_id, unique_conv_id, topic_ids, tags, sentiment, resolution, method, model,
+            message_count, last_message_at, classified_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+        ON CONFLICT (chatbot_id, unique_conv_id) DO UPDATE
+        SET topic_ids = EXCLUDED.topic_ids,
+            tags = EXCLUDED.tags,
+            sentiment = EXCLUDED.sentiment,
+            resolution = EXCLUDED.resolution,
+            method = EXCLUDED.method,
+            model = EXCLUDED.model,
+            message_count = EXCLUDED.message_count,
+            last_message_at = EXCLUDED.last_message_at,
+            classified_at = EXCLUDED.classified_at
+    `
+
+	if _, err := c.pool.Exec(ctx, query, t.ChatbotID, t.ConversationID, t.TopicIDs, t.Tags, t.Sentiment,
+		t.Resolution, t.Method, t.Model, t.MessageCount, formatTimeForDB(t.LastMessageAt),
+		formatTimeForDB(t.ClassifiedAt)); err != nil {
+		return fmt.Errorf("failed to store conversation tags: %w", err)
+	}
+	return nil
+}
+
+// GetConversationTags loads a conversation's classification, nil if it has none yet
+func (c *PostgresClient) GetConversationTags(ctx context.Context, chatbotID, conversationID string) (*ConversationTags, error) {
+	query := `
+        SELECT chatbot_id, unique_conv_id, topic_ids, tags, sentiment, resolution, method, model,
+               message_count, last_message_at, classified_at
+        FROM conversation_tags
+        WHERE chatbot_id = $1 AND unique_conv_id = $2
+    `
+
+	var t ConversationTags
+	err := c.pool.QueryRow(ctx, query, chatbotID, conversationID).Scan(&t.ChatbotID, &t.ConversationID,
+		&t.TopicIDs, &t.Tags, &t.Sentiment, &t.Resolution, &t.Method, &t.Model, &t.MessageCount,
+		&t.LastMessageAt, &t.ClassifiedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load conversation tags: %w", err)
+	}
+	return &t, nil
+}
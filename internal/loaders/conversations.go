@@ -0,0 +1,167 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// ConversationSummary describes one conversation (the messages sharing a unique_conv_id)
+type ConversationSummary struct {
+	ConversationID string
+	MessageCount   int
+	StartedAt      time.Time
+	LastActivity   time.Time
+	// TopicID and TopicName are the topic of the latest tagged user message, if any
+	TopicID   *string
+	TopicName *string
+	Feedback  FeedbackSummary
+}
+
+// FeedbackSummary counts the feedback left on a conversation's messages
+type FeedbackSummary struct {
+	Likes    int
+	Dislikes int
+	Neutral  int
+}
+
+// ConversationCursor is the position after the last conversation of a page
+type ConversationCursor struct {
+	LastActivity   time.Time
+	ConversationID string
+}
+
+// TranscriptMessage is a stored message as read back
+type TranscriptMessage struct {
+	ID              string
+	Type            string // user | assistant
+	Content         string
+	Citations       []string
+	CreatedAt       time.Time
+	TopicID         *string
+	Feedback        int16 // 0 none, 1 like, 2 dislike, 3 neutral
+	FeedbackComment *string
+}
+
+// FeedbackLabel maps a stored feedback value to the labels /feedback accepts, or "" for
+// none
+func FeedbackLabel(v int16) string {
+	switch v {
+	case 1:
+		return "like"
+	case 2:
+		return "dislike"
+	case 3:
+		return "neutral"
+	}
+	return ""
+}
+
+// ListConversations returns up to limit conversations of a chatbot, most recently
+// active first, starting after cursor (nil for the first page). Pages are read from
+// conversation_activity by its index; only the conversations of the page are aggregated.
+func (c *PostgresClient) ListConversations(ctx context.Context, chatbotID string, cursor *ConversationCursor, limit int) ([]ConversationSummary, error) {
+	query := `
+        WITH page AS (
+            SELECT unique_conv_id, last_message_at
+            FROM conversation_activity
+            WHERE chatbot_id = $1
+              AND ($2::boolean OR (last_message_at, unique_conv_id) < ($3, $4))
+            ORDER BY last_message_at DESC, unique_conv_id DESC
+            LIMIT $5
+        )
+        SELECT page.unique_conv_id, conv.message_count, conv.started_at, page.last_message_at,
+               conv.likes, conv.dislikes, conv.neutral, conv.topic_id, t.name
+        FROM page
+        CROSS JOIN LATERAL (
+            SELECT
+                COUNT(*) AS message_count,
+                MIN(m.created_at) AS started_at,
+                COUNT(*) FILTER (WHERE m.feedback = 1) AS likes,
+                COUNT(*) FILTER (WHERE m.feedback = 2) AS dislikes,
+                COUNT(*) FILTER (WHERE m.feedback = 3) AS neutral,
+                (ARRAY_AGG(m.topic_id::text ORDER BY m.created_at DESC)
+                    FILTER (WHERE m.topic_id IS NOT NULL))[1] AS topic_id
+            FROM messages m
+            WHERE m.chatbot_id = $1 AND m.unique_conv_id = page.unique_conv_id
+        ) conv
+        LEFT JOIN chatbot_topics t ON t.id::text = conv.topic_id
+        WHERE conv.message_count > 0
+        ORDER BY page.last_message_at DESC, page.unique_conv_id DESC
+    `
+
+	first := cursor == nil
+	var after ConversationCursor
+	if cursor != nil {
+		after = *cursor
+	}
+	rows, err := c.pool.Query(ctx, query, chatbotID, first, formatTimeForDB(after.LastActivity), after.ConversationID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+	defer rows.Close()
+
+	conversations := []ConversationSummary{}
+	for rows.Next() {
+		var s ConversationSummary
+		if err := rows.Scan(&s.ConversationID, &s.MessageCount, &s.StartedAt, &s.LastActivity,
+			&s.Feedback.Likes, &s.Feedback.Dislikes, &s.Feedback.Neutral, &s.TopicID, &s.TopicName); err != nil {
+			return nil, fmt.Errorf("failed to scan conversation: %w", err)
+		}
+		conversations = append(conversations, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating conversations: %w", err)
+	}
+	return conversations, nil
+}
+
+// GetConversationMessages returns the latest limit messages of a conversation in the
+// order they were written, and whether older messages were left out
+func (c *PostgresClient) GetConversationMessages(ctx context.Context, chatbotID, conversationID string, limit int) ([]TranscriptMessage, bool, error) {
+	query := `
+        SELECT id, "type", content, citations, created_at, topic_id::text, COALESCE(feedback, 0), feedback_comment
+        FROM messages
+        WHERE chatbot_id = $1 AND unique_conv_id = $2
+        ORDER BY created_at DESC, id DESC
+        LIMIT $3
+    `
+
+	// One extra row tells whether the conversation is longer than limit
+	rows, err := c.pool.Query(ctx, query, chatbotID, conversationID, limit+1)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load conversation: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []TranscriptMessage{}
+	for rows.Next() {
+		var m TranscriptMessage
+		if err := rows.Scan(&m.ID, &m.Type, &m.Content, &m.Citations, &m.CreatedAt,
+			&m.TopicID, &m.Feedback, &m.FeedbackComment); err != nil {
+			return nil, false, fmt.Errorf("failed to scan message: %w", err)
+		}
+		if m.Citations == nil {
+			m.Citations = []string{}
+		}
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("error iterating messages: %w", err)
+	}
+	if len(messages) == 0 {
+		return nil, false, ErrConversationNotFound
+	}
+
+	truncated := len(messages) > limit
+	if truncated {
+		messages = messages[:limit]
+	}
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, truncated, nil
+}
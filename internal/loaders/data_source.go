@@ -0,0 +1,98 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrDataSourceOwner = errors.New("data source belongs to another chatbot or user")
+
+// Data source lifecycle states
+const (
+	DataSourcePending    = "PENDING"
+	DataSourceProcessing = "PROCESSING"
+	DataSourceCompleted  = "COMPLETED"
+	DataSourceFailed     = "FAILED"
+)
+
+// SetDataSourceStatus moves one data source to a new status and records the failure
+// reason (nil clears it)
+func (c *PostgresClient) SetDataSourceStatus(ctx context.Context, dataSourceID int, status string, errorMessage *string) error {
+	query := `
+		UPDATE data_source
+		SET status = $1, error_message = $2, updated_at = $3
+		WHERE id = $4
+	`
+
+	now := formatTimeForDB(time.Now().UTC())
+	result, err := c.pool.Exec(ctx, query, status, errorMessage, now, dataSourceID)
+	if err != nil {
+		return fmt.Errorf("failed to update data source status: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("data source %d not found", dataSourceID)
+	}
+
+	log.Printf("Updated status to '%s' for data source %d", status, dataSourceID)
+	return nil
+}
+
+// CheckDataSourceOwner verifies that a data source belongs to chatbotID and userID, so a
+// request cannot write embeddings into another chatbot under someone else's data source
+func (c *PostgresClient) CheckDataSourceOwner(ctx context.Context, dataSourceID int, chatbotID, userID string) error {
+	var ownerChatbot, ownerUser *string
+	err := c.pool.QueryRow(ctx, `
+		SELECT chatbot_id::text, user_id::text FROM data_source WHERE id = $1`,
+		dataSourceID).Scan(&ownerChatbot, &ownerUser)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrDataSourceNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load data source: %w", err)
+	}
+	if ownerChatbot == nil || *ownerChatbot != chatbotID || ownerUser == nil || *ownerUser != userID {
+		return ErrDataSourceOwner
+	}
+	return nil
+}
+
+// GetDataSourceChatbotID returns the chatbot a data source belongs to
+func (c *PostgresClient) GetDataSourceChatbotID(ctx context.Context, dataSourceID int) (string, error) {
+	var chatbotID *string
+	err := c.pool.QueryRow(ctx, `SELECT chatbot_id::text FROM data_source WHERE id = $1`, dataSourceID).Scan(&chatbotID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ErrDataSourceNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load data source: %w", err)
+	}
+	if chatbotID == nil {
+		return "", nil
+	}
+	return *chatbotID, nil
+}
+
+// HasActiveIngestion reports whether a data source of the chatbot is PENDING or
+// PROCESSING. Rows not updated since before staleBefore are leftovers of a restart and
+// are ignored.
+func (c *PostgresClient) HasActiveIngestion(ctx context.Context, chatbotID string, staleBefore time.Time) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM data_source
+			WHERE chatbot_id::text = $1 AND status IN ($2, $3) AND updated_at > $4
+		)
+	`
+
+	var active bool
+	err := c.pool.QueryRow(ctx, query, chatbotID, DataSourcePending, DataSourceProcessing,
+		formatTimeForDB(staleBefore.UTC())).Scan(&active)
+	if err != nil {
+		return false, fmt.Errorf("failed to check data source ingestion: %w", err)
+	}
+	return active, nil
+}
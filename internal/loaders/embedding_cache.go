@@ -0,0 +1,64 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pgvector/pgvector-go"
+)
+
+// GetCachedEmbedding looks up a vector in the shared embedding cache
+func (c *PostgresClient) GetCachedEmbedding(ctx context.Context, key string) ([]float64, bool, error) {
+	var stored pgvector.Vector
+	err := c.pool.QueryRow(ctx, `SELECT vector FROM embedding_cache WHERE cache_key = $1`, key).Scan(&stored)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read embedding cache: %w", err)
+	}
+
+	raw := stored.Slice()
+	vec := make([]float64, len(raw))
+	for i, v := range raw {
+		vec[i] = float64(v)
+	}
+	return vec, true, nil
+}
+
+// PutCachedEmbedding stores a vector in the shared embedding cache
+func (c *PostgresClient) PutCachedEmbedding(ctx context.Context, key, model, taskType string, vector []float64) error {
+	vec32 := make([]float32, len(vector))
+	for i, v := range vector {
+		vec32[i] = float32(v)
+	}
+
+	query := `
+        INSERT INTO embedding_cache (cache_key, model, task_type, vector, created_at)
+        VALUES ($1, $2, $3, $4, NOW())
+        ON CONFLICT (cache_key) DO NOTHING
+    `
+	if _, err := c.pool.Exec(ctx, query, key, model, taskType, pgvector.NewVector(vec32)); err != nil {
+		return fmt.Errorf("failed to write embedding cache: %w", err)
+	}
+	return nil
+}
+
+// PruneEmbeddingCache deletes the oldest shared cache rows so at most maxEntries remain
+func (c *PostgresClient) PruneEmbeddingCache(ctx context.Context, maxEntries int) (int64, error) {
+	query := `
+        DELETE FROM embedding_cache
+        WHERE cache_key IN (
+            SELECT cache_key FROM embedding_cache
+            ORDER BY created_at DESC
+            OFFSET $1
+        )
+    `
+	result, err := c.pool.Exec(ctx, query, maxEntries)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune embedding cache: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
@@ -0,0 +1,238 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrEmbeddingMigrationNotFound = errors.New("embedding migration not found")
+	ErrIncompleteCoverage         = errors.New("model does not cover every chunk yet")
+)
+
+// Embedding migration states
+const (
+	MigrationRunning   = "RUNNING"
+	MigrationCompleted = "COMPLETED"
+	MigrationFailed    = "FAILED"
+)
+
+// EmbeddingMigrationRecord represents a row of the embedding_migrations table
+type EmbeddingMigrationRecord struct {
+	ID             string
+	ChatbotID      string
+	Model          string
+	Dimensions     int
+	Status         string
+	TotalChunks    int
+	EmbeddedChunks int
+	FailedChunks   int
+	ErrorMessage   *string
+	StartedAt      time.Time
+	FinishedAt     *time.Time
+	ActivatedAt    *time.Time
+}
+
+// ChunkText is the id and text of a stored chunk
+type ChunkText struct {
+	ID   int64
+	Text string
+}
+
+// CountChunksMissingModel counts a chatbot's chunks, including rows of a running
+// reindex, that have no vector from model yet
+func (c *PostgresClient) CountChunksMissingModel(ctx context.Context, chatbotID, model string) (int, error) {
+	query := `
+        SELECT COUNT(*)
+        FROM embeddings e
+        WHERE e.chatbot_id = $1
+          AND NOT EXISTS (
+              SELECT 1 FROM embedding_vectors v WHERE v.embedding_id = e.id AND v.model = $2
+          )
+    `
+
+	var count int
+	if err := c.pool.QueryRow(ctx, query, chatbotID, model).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count chunks missing model vectors: %w", err)
+	}
+	return count, nil
+}
+
+// ListChunksMissingModel pages through chunks without a vector from model in id order
+func (c *PostgresClient) ListChunksMissingModel(ctx context.Context, chatbotID, model string, afterID int64, limit int) ([]ChunkText, error) {
+	query := `
+        SELECT e.id, e.text
+        FROM embeddings e
+        WHERE e.chatbot_id = $1 AND e.id > $3
+          AND NOT EXISTS (
+              SELECT 1 FROM embedding_vectors v WHERE v.embedding_id = e.id AND v.model = $2
+          )
+        ORDER BY e.id
+        LIMIT $4
+    `
+
+	rows, err := c.pool.Query(ctx, query, chatbotID, model, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunks missing model vectors: %w", err)
+	}
+	defer rows.Close()
+
+	var chunks []ChunkText
+	for rows.Next() {
+		var ct ChunkText
+		if err := rows.Scan(&ct.ID, &ct.Text); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk: %w", err)
+		}
+		chunks = append(chunks, ct)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chunks: %w", err)
+	}
+	return chunks, nil
+}
+
+// UpsertModelVectors stores vectors from model for chunks of a chatbot. Rows are only
+// written for chunks the chatbot owns, so ids that are not stored for it are an error.
+func (c *PostgresClient) UpsertModelVectors(ctx context.Context, chatbotID, model string, ids []int64, vectors [][]float64) error {
+	if len(ids) != len(vectors) {
+		return fmt.Errorf("got %d ids for %d vectors", len(ids), len(vectors))
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	batch := &pgx.Batch{}
+	for i, id := range ids {
+		batch.Queue(`
+			INSERT INTO embedding_vectors (embedding_id, model, vector)
+			SELECT id, $2::text, $3::vector FROM embeddings WHERE id = $1 AND chatbot_id = $4
+			ON CONFLICT (embedding_id, model) DO UPDATE SET vector = EXCLUDED.vector, created_at = NOW()`,
+			id, model, toPgVector(vectors[i]), chatbotID)
+	}
+
+	results := c.pool.SendBatch(ctx, batch)
+	defer results.Close()
+	for _, id := range ids {
+		tag, err := results.Exec()
+		if err != nil {
+			return fmt.Errorf("failed to store model vector of embedding %d: %w", id, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("embedding %d is not stored for chatbot %s", id, chatbotID)
+		}
+	}
+	return nil
+}
+
+// ActivateEmbeddingModel points a chatbot's retriever at model. An empty model switches
+// back to the primary vector column. Other models are only activated when every chunk of
+// the chatbot has a vector from them (ErrIncompleteCoverage otherwise).
+func (c *PostgresClient) ActivateEmbeddingModel(ctx context.Context, chatbotID, model string, dimensions int) error {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var modelArg *string
+	var dimsArg *int
+	if model != "" {
+		var missing int
+		err := tx.QueryRow(ctx, `
+			SELECT COUNT(*)
+			FROM embeddings e
+			WHERE e.chatbot_id = $1
+			  AND NOT EXISTS (
+			      SELECT 1 FROM embedding_vectors v WHERE v.embedding_id = e.id AND v.model = $2
+			  )`, chatbotID, model).Scan(&missing)
+		if err != nil {
+			return fmt.Errorf("failed to check model coverage: %w", err)
+		}
+		if missing > 0 {
+			return fmt.Errorf("%w: %d chunks missing", ErrIncompleteCoverage, missing)
+		}
+		modelArg, dimsArg = &model, &dimensions
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO chatbot_settings (chatbot_id, embedding_model, embedding_dimensions, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (chatbot_id) DO UPDATE
+		SET embedding_model = EXCLUDED.embedding_model,
+		    embedding_dimensions = EXCLUDED.embedding_dimensions,
+		    updated_at = NOW()`, chatbotID, modelArg, dimsArg); err != nil {
+		return fmt.Errorf("failed to activate embedding model: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit model activation: %w", err)
+	}
+
+	log.Printf("Chatbot %s now retrieves with embedding model %q", chatbotID, model)
+	return nil
+}
+
+// CreateEmbeddingMigration inserts a new RUNNING migration
+func (c *PostgresClient) CreateEmbeddingMigration(ctx context.Context, m *EmbeddingMigrationRecord) error {
+	query := `
+		INSERT INTO embedding_migrations (id, chatbot_id, model, dimensions, status, total_chunks, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	if _, err := c.pool.Exec(ctx, query, m.ID, m.ChatbotID, m.Model, m.Dimensions, m.Status,
+		m.TotalChunks, formatTimeForDB(m.StartedAt)); err != nil {
+		return fmt.Errorf("failed to create embedding migration: %w", err)
+	}
+	return nil
+}
+
+// UpdateEmbeddingMigration stores the progress and outcome of a migration
+func (c *PostgresClient) UpdateEmbeddingMigration(ctx context.Context, m *EmbeddingMigrationRecord) error {
+	query := `
+		UPDATE embedding_migrations
+		SET status = $1, total_chunks = $2, embedded_chunks = $3, failed_chunks = $4,
+		    error_message = $5, finished_at = $6, activated_at = $7
+		WHERE id = $8
+	`
+
+	if _, err := c.pool.Exec(ctx, query, m.Status, m.TotalChunks, m.EmbeddedChunks, m.FailedChunks,
+		m.ErrorMessage, formatOptionalTime(m.FinishedAt), formatOptionalTime(m.ActivatedAt), m.ID); err != nil {
+		return fmt.Errorf("failed to update embedding migration: %w", err)
+	}
+	return nil
+}
+
+// GetEmbeddingMigration loads one migration
+func (c *PostgresClient) GetEmbeddingMigration(ctx context.Context, id string) (*EmbeddingMigrationRecord, error) {
+	query := `
+		SELECT id, chatbot_id, model, dimensions, status, total_chunks, embedded_chunks,
+		       failed_chunks, error_message, started_at, finished_at, activated_at
+		FROM embedding_migrations
+		WHERE id = $1
+	`
+
+	var m EmbeddingMigrationRecord
+	err := c.pool.QueryRow(ctx, query, id).Scan(&m.ID, &m.ChatbotID, &m.Model, &m.Dimensions,
+		&m.Status, &m.TotalChunks, &m.EmbeddedChunks, &m.FailedChunks, &m.ErrorMessage, &m.StartedAt,
+		&m.FinishedAt, &m.ActivatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrEmbeddingMigrationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load embedding migration: %w", err)
+	}
+	return &m, nil
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := formatTimeForDB(*t)
+	return &s
+}
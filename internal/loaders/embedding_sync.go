@@ -0,0 +1,170 @@
+package loaders
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pgvector/pgvector-go"
+)
+
+// StoredChunk is an existing embedding row of a data source, as needed to diff a
+// re-ingestion against it
+type StoredChunk struct {
+	ID          int64
+	DocumentID  *string
+	ChunkIndex  *int
+	Section     *string
+	Citation    *string
+	StartOffset *int
+	EndOffset   *int
+	ContentHash *string
+}
+
+// ChunkUpdate rewrites an existing row. When Data.Vector is nil the content is unchanged
+// and only its position metadata (document, index, section, citation, offsets) moves.
+type ChunkUpdate struct {
+	ID   int64
+	Data EmbeddingData
+}
+
+// EmbeddingSync is the set of row changes that brings a data source in line with a
+// fresh parse of its content
+type EmbeddingSync struct {
+	Insert []EmbeddingData
+	Update []ChunkUpdate
+	Delete []int64
+}
+
+// SyncSummary counts the chunks touched by a re-ingestion
+type SyncSummary struct {
+	Added     int
+	Updated   int
+	Removed   int
+	Unchanged int
+}
+
+// GetDataSourceChunks returns identity, position and hash of every stored chunk of a
+// data source (without text or vectors)
+func (c *PostgresClient) GetDataSourceChunks(ctx context.Context, dataSourceID int) ([]StoredChunk, error) {
+	query := `
+        SELECT id, document_id, chunk_index, section, citation, start_offset, end_offset, content_hash
+        FROM embeddings
+        WHERE data_source_id = $1 AND reindex_job_id IS NULL
+    `
+
+	rows, err := c.pool.Query(ctx, query, dataSourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query data source chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var chunks []StoredChunk
+	for rows.Next() {
+		var sc StoredChunk
+		if err := rows.Scan(&sc.ID, &sc.DocumentID, &sc.ChunkIndex, &sc.Section, &sc.Citation,
+			&sc.StartOffset, &sc.EndOffset, &sc.ContentHash); err != nil {
+			return nil, fmt.Errorf("failed to scan data source chunk: %w", err)
+		}
+		chunks = append(chunks, sc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating data source chunks: %w", err)
+	}
+	return chunks, nil
+}
+
+// ApplyEmbeddingSync applies inserts, updates and deletes in one transaction, so
+// retrieval sees either the old or the new version of the data source
+func (c *PostgresClient) ApplyEmbeddingSync(ctx context.Context, userID, chatbotID string, sync EmbeddingSync) error {
+	if len(sync.Insert) == 0 && len(sync.Update) == 0 && len(sync.Delete) == 0 {
+		return nil
+	}
+
+	now := formatTimeForDB(time.Now().UTC())
+	batch := &pgx.Batch{}
+
+	if len(sync.Delete) > 0 {
+		batch.Queue(`DELETE FROM embeddings WHERE id = ANY($1)`, sync.Delete)
+	}
+
+	for _, u := range sync.Update {
+		d := u.Data
+		if d.Vector == nil {
+			batch.Queue(`
+				UPDATE embeddings
+				SET document_id = $1, chunk_index = $2, section = $3, citation = $4,
+				    start_offset = $5, end_offset = $6, updated_at = $7
+				WHERE id = $8`,
+				d.DocumentID, d.ChunkIndex, d.Section, d.Citation, d.StartOffset, d.EndOffset, now, u.ID)
+			continue
+		}
+		batch.Queue(`
+			UPDATE embeddings
+			SET text = $1, vector = $2, content_hash = $3, document_id = $4, chunk_index = $5,
+			    section = $6, citation = $7, start_offset = $8, end_offset = $9, updated_at = $10,
+			    embedding_model = $11
+			WHERE id = $12`,
+			d.Text, toPgVector(d.Vector), d.ContentHash, d.DocumentID, d.ChunkIndex,
+			d.Section, d.Citation, d.StartOffset, d.EndOffset, now, d.Model, u.ID)
+		// Vectors of other models describe the old text
+		batch.Queue(`DELETE FROM embedding_vectors WHERE embedding_id = $1`, u.ID)
+	}
+
+	for _, d := range sync.Insert {
+		batch.Queue(`
+			INSERT INTO embeddings (
+				user_id, chatbot_id, text, vector,
+				created_at, updated_at, data_source_id, citation,
+				document_id, chunk_index, section, start_offset, end_offset, content_hash,
+				embedding_model
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+			userID, chatbotID, d.Text, toPgVector(d.Vector),
+			now, now, d.DataSourceID, d.Citation,
+			d.DocumentID, d.ChunkIndex, d.Section, d.StartOffset, d.EndOffset, d.ContentHash,
+			d.Model)
+	}
+
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to apply embedding changes: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit embedding changes: %w", err)
+	}
+
+	log.Printf("Synced embeddings for chatbot %s: %d inserted, %d updated, %d deleted",
+		chatbotID, len(sync.Insert), len(sync.Update), len(sync.Delete))
+	return nil
+}
+
+// SetDataSourceSyncSummary records the outcome of the last ingestion of a data source
+func (c *PostgresClient) SetDataSourceSyncSummary(ctx context.Context, dataSourceID int, summary SyncSummary) error {
+	query := `
+		UPDATE data_source
+		SET chunks_added = $1, chunks_updated = $2, chunks_removed = $3, last_synced_at = $4
+		WHERE id = $5
+	`
+
+	now := formatTimeForDB(time.Now().UTC())
+	if _, err := c.pool.Exec(ctx, query, summary.Added, summary.Updated, summary.Removed, now, dataSourceID); err != nil {
+		return fmt.Errorf("failed to record sync summary: %w", err)
+	}
+	return nil
+}
+
+// toPgVector converts a vector to pgvector's float32 representation
+func toPgVector(v []float64) pgvector.Vector {
+	vec32 := make([]float32, len(v))
+	for i, x := range v {
+		vec32[i] = float32(x)
+	}
+	return pgvector.NewVector(vec32)
+}
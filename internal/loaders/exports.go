@@ -0,0 +1,234 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrExportNotFound = errors.New("export not found")
+
+// Export job states. ExportExpired marks an export whose file has been deleted.
+const (
+	ExportRunning   = "RUNNING"
+	ExportCompleted = "COMPLETED"
+	ExportFailed    = "FAILED"
+	ExportExpired   = "EXPIRED"
+)
+
+// exportFetchSize is how many rows each FETCH of the export cursor reads
+const exportFetchSize = 1000
+
+// ExportRecord represents a row of the message_exports table
+type ExportRecord struct {
+	ID           string
+	ChatbotID    string
+	Format       string
+	From         time.Time
+	To           time.Time
+	Status       string
+	Rows         int64
+	SizeBytes    int64
+	Host         string
+	FilePath     *string
+	ErrorMessage *string
+	StartedAt    time.Time
+	FinishedAt   *time.Time
+	ExpiresAt    *time.Time
+}
+
+// ExportMessage is a stored message with the name of its topic, as written to an export
+type ExportMessage struct {
+	ID              string
+	ConversationID  string
+	Type            string // user | assistant
+	Content         string
+	Citations       []string
+	CreatedAt       time.Time
+	TopicID         *string
+	TopicName       *string
+	Feedback        int16 // 0 none, 1 like, 2 dislike, 3 neutral
+	FeedbackComment *string
+}
+
+// CreateExport records a new export job
+func (c *PostgresClient) CreateExport(ctx context.Context, e *ExportRecord) error {
+	query := `
+		INSERT INTO message_exports (id, chatbot_id, format, range_from, range_to, status, host, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	if _, err := c.pool.Exec(ctx, query, e.ID, e.ChatbotID, e.Format, formatTimeForDB(e.From),
+		formatTimeForDB(e.To), e.Status, e.Host, formatTimeForDB(e.StartedAt)); err != nil {
+		return fmt.Errorf("failed to create export: %w", err)
+	}
+	return nil
+}
+
+// UpdateExport stores the progress and outcome of an export
+func (c *PostgresClient) UpdateExport(ctx context.Context, e *ExportRecord) error {
+	query := `
+		UPDATE message_exports
+		SET status = $1, rows_exported = $2, size_bytes = $3, file_path = $4, error_message = $5,
+		    finished_at = $6, expires_at = $7
+		WHERE id = $8
+	`
+
+	if _, err := c.pool.Exec(ctx, query, e.Status, e.Rows, e.SizeBytes, e.FilePath, e.ErrorMessage,
+		formatOptionalTime(e.FinishedAt), formatOptionalTime(e.ExpiresAt), e.ID); err != nil {
+		return fmt.Errorf("failed to update export: %w", err)
+	}
+	return nil
+}
+
+// GetExport loads an export job by id
+func (c *PostgresClient) GetExport(ctx context.Context, exportID string) (*ExportRecord, error) {
+	query := `
+		SELECT id, chatbot_id, format, range_from, range_to, status, rows_exported, size_bytes,
+		       host, file_path, error_message, started_at, finished_at, expires_at
+		FROM message_exports
+		WHERE id = $1
+	`
+
+	var e ExportRecord
+	err := c.pool.QueryRow(ctx, query, exportID).Scan(&e.ID, &e.ChatbotID, &e.Format, &e.From, &e.To,
+		&e.Status, &e.Rows, &e.SizeBytes, &e.Host, &e.FilePath, &e.ErrorMessage, &e.StartedAt,
+		&e.FinishedAt, &e.ExpiresAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load export: %w", err)
+	}
+	return &e, nil
+}
+
+// ListExpiredExports returns the completed exports whose expiry is before now
+func (c *PostgresClient) ListExpiredExports(ctx context.Context, now time.Time) ([]ExportRecord, error) {
+	query := `
+		SELECT id, file_path
+		FROM message_exports
+		WHERE status = $1 AND expires_at < $2
+	`
+
+	rows, err := c.pool.Query(ctx, query, ExportCompleted, formatTimeForDB(now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired exports: %w", err)
+	}
+	defer rows.Close()
+
+	var exports []ExportRecord
+	for rows.Next() {
+		var e ExportRecord
+		if err := rows.Scan(&e.ID, &e.FilePath); err != nil {
+			return nil, fmt.Errorf("failed to scan export: %w", err)
+		}
+		exports = append(exports, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating exports: %w", err)
+	}
+	return exports, nil
+}
+
+// MarkExportExpired records that an export's file has been deleted
+func (c *PostgresClient) MarkExportExpired(ctx context.Context, exportID string) error {
+	if _, err := c.pool.Exec(ctx, `UPDATE message_exports SET status = $1 WHERE id = $2`,
+		ExportExpired, exportID); err != nil {
+		return fmt.Errorf("failed to expire export: %w", err)
+	}
+	return nil
+}
+
+// FailInterruptedExports marks the exports host was running when it stopped as failed
+// and returns their ids and formats
+func (c *PostgresClient) FailInterruptedExports(ctx context.Context, host string) ([]ExportRecord, error) {
+	query := `
+		UPDATE message_exports
+		SET status = $1, error_message = $2, finished_at = $3
+		WHERE host = $4 AND status = $5
+		RETURNING id, format
+	`
+
+	rows, err := c.pool.Query(ctx, query, ExportFailed, "interrupted by a restart",
+		formatTimeForDB(time.Now().UTC()), host, ExportRunning)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fail interrupted exports: %w", err)
+	}
+	defer rows.Close()
+
+	var exports []ExportRecord
+	for rows.Next() {
+		var e ExportRecord
+		if err := rows.Scan(&e.ID, &e.Format); err != nil {
+			return nil, fmt.Errorf("failed to scan export: %w", err)
+		}
+		exports = append(exports, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating exports: %w", err)
+	}
+	return exports, nil
+}
+
+// StreamMessages calls fn with each message of a chatbot created in [from, to), oldest
+// first. Rows are read through a server-side cursor a page at a time, so an export of
+// any size holds only one page in memory. An error from fn stops the scan and is
+// returned as is.
+func (c *PostgresClient) StreamMessages(ctx context.Context, chatbotID string, from, to time.Time, fn func(*ExportMessage) error) error {
+	// Cursors only live inside a transaction
+	tx, err := c.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	declare := `
+        DECLARE message_export NO SCROLL CURSOR FOR
+        SELECT m.id, m.unique_conv_id, m."type", m.content, m.citations, m.created_at,
+               m.topic_id::text, t.name, COALESCE(m.feedback, 0), m.feedback_comment
+        FROM messages m
+        LEFT JOIN chatbot_topics t ON t.id = m.topic_id
+        WHERE m.chatbot_id = $1 AND m.created_at >= $2 AND m.created_at < $3
+        ORDER BY m.created_at, m.id
+    `
+	if _, err := tx.Exec(ctx, declare, chatbotID, formatTimeForDB(from), formatTimeForDB(to)); err != nil {
+		return fmt.Errorf("failed to open message cursor: %w", err)
+	}
+
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM message_export", exportFetchSize)
+	for {
+		rows, err := tx.Query(ctx, fetch)
+		if err != nil {
+			return fmt.Errorf("failed to fetch messages: %w", err)
+		}
+		n := 0
+		var m ExportMessage
+		for rows.Next() {
+			n++
+			m = ExportMessage{}
+			if err := rows.Scan(&m.ID, &m.ConversationID, &m.Type, &m.Content, &m.Citations, &m.CreatedAt,
+				&m.TopicID, &m.TopicName, &m.Feedback, &m.FeedbackComment); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan message: %w", err)
+			}
+			if m.Citations == nil {
+				m.Citations = []string{}
+			}
+			if err := fn(&m); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating messages: %w", err)
+		}
+		if n < exportFetchSize {
+			return nil
+		}
+	}
+}
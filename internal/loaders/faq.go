@@ -0,0 +1,272 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrFAQNotFound = errors.New("faq entry not found")
+
+// FAQEntry is a curated answer with the question variants it answers
+type FAQEntry struct {
+	ID        int64
+	ChatbotID string
+	Questions []string
+	Answer    string
+	Citation  *string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// FAQVectors are the embeddings of an entry's questions by one model, in question order
+type FAQVectors struct {
+	Model   string
+	Vectors [][]float64
+}
+
+// FAQMatch is an entry whose question variant is close to a user question
+type FAQMatch struct {
+	FAQID    int64
+	Question string
+	Answer   string
+	Citation *string
+	Score    float64 // cosine similarity of the closest variant
+}
+
+// FAQQuestion is one question variant of an entry
+type FAQQuestion struct {
+	FAQID    int64
+	Question string
+}
+
+// CreateFAQ stores an entry and the vectors of its questions
+func (c *PostgresClient) CreateFAQ(ctx context.Context, entry *FAQEntry, vectors []FAQVectors) error {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now().UTC()
+	err = tx.QueryRow(ctx, `
+		INSERT INTO faq_entries (chatbot_id, answer, citation, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $4)
+		RETURNING id`, entry.ChatbotID, entry.Answer, entry.Citation, formatTimeForDB(now)).Scan(&entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create faq entry: %w", err)
+	}
+	if err := insertFAQQuestions(ctx, tx, entry, vectors); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit faq entry: %w", err)
+	}
+	entry.CreatedAt, entry.UpdatedAt = now, now
+	return nil
+}
+
+// UpdateFAQ replaces the answer, citation and questions of an entry of entry.ChatbotID
+func (c *PostgresClient) UpdateFAQ(ctx context.Context, entry *FAQEntry, vectors []FAQVectors) error {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now().UTC()
+	err = tx.QueryRow(ctx, `
+		UPDATE faq_entries SET answer = $1, citation = $2, updated_at = $3
+		WHERE id = $4 AND chatbot_id = $5
+		RETURNING created_at`, entry.Answer, entry.Citation, formatTimeForDB(now), entry.ID, entry.ChatbotID).
+		Scan(&entry.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrFAQNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update faq entry: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM faq_questions WHERE faq_id = $1`, entry.ID); err != nil {
+		return fmt.Errorf("failed to replace faq questions: %w", err)
+	}
+	if err := insertFAQQuestions(ctx, tx, entry, vectors); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit faq entry: %w", err)
+	}
+	entry.UpdatedAt = now
+	return nil
+}
+
+func insertFAQQuestions(ctx context.Context, tx pgx.Tx, entry *FAQEntry, vectors []FAQVectors) error {
+	batch := &pgx.Batch{}
+	for _, v := range vectors {
+		if len(v.Vectors) != len(entry.Questions) {
+			return fmt.Errorf("got %d %s vectors for %d questions", len(v.Vectors), v.Model, len(entry.Questions))
+		}
+		for i, q := range entry.Questions {
+			batch.Queue(`
+				INSERT INTO faq_questions (faq_id, chatbot_id, question, embedding_model, vector)
+				VALUES ($1, $2, $3, $4, $5)`, entry.ID, entry.ChatbotID, q, v.Model, toPgVector(v.Vectors[i]))
+		}
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to store faq questions: %w", err)
+	}
+	return nil
+}
+
+// DeleteFAQ removes an entry of a chatbot and its questions
+func (c *PostgresClient) DeleteFAQ(ctx context.Context, chatbotID string, id int64) error {
+	result, err := c.pool.Exec(ctx, `DELETE FROM faq_entries WHERE id = $1 AND chatbot_id = $2`, id, chatbotID)
+	if err != nil {
+		return fmt.Errorf("failed to delete faq entry: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrFAQNotFound
+	}
+	return nil
+}
+
+const faqEntryColumns = `
+        f.id, f.chatbot_id, f.answer, f.citation, f.created_at, f.updated_at,
+        ARRAY(
+            SELECT q.question FROM faq_questions q WHERE q.faq_id = f.id
+            GROUP BY q.question ORDER BY MIN(q.id)
+        )`
+
+// GetFAQ loads one entry of a chatbot
+func (c *PostgresClient) GetFAQ(ctx context.Context, chatbotID string, id int64) (*FAQEntry, error) {
+	query := `SELECT ` + faqEntryColumns + ` FROM faq_entries f WHERE f.id = $1 AND f.chatbot_id = $2`
+
+	var e FAQEntry
+	err := c.pool.QueryRow(ctx, query, id, chatbotID).Scan(&e.ID, &e.ChatbotID, &e.Answer, &e.Citation,
+		&e.CreatedAt, &e.UpdatedAt, &e.Questions)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrFAQNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load faq entry: %w", err)
+	}
+	return &e, nil
+}
+
+// ListFAQs returns a chatbot's entries, oldest first
+func (c *PostgresClient) ListFAQs(ctx context.Context, chatbotID string) ([]FAQEntry, error) {
+	query := `SELECT ` + faqEntryColumns + ` FROM faq_entries f WHERE f.chatbot_id = $1 ORDER BY f.id`
+
+	rows, err := c.pool.Query(ctx, query, chatbotID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list faq entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []FAQEntry{}
+	for rows.Next() {
+		var e FAQEntry
+		if err := rows.Scan(&e.ID, &e.ChatbotID, &e.Answer, &e.Citation,
+			&e.CreatedAt, &e.UpdatedAt, &e.Questions); err != nil {
+			return nil, fmt.Errorf("failed to scan faq entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating faq entries: %w", err)
+	}
+	return entries, nil
+}
+
+// SearchFAQ returns up to limit entries whose question variants embedded by model are
+// closest to queryVector, best first, each entry once
+func (c *PostgresClient) SearchFAQ(ctx context.Context, chatbotID, model string, queryVector []float64, limit int) ([]FAQMatch, error) {
+	query := `
+        SELECT q.faq_id, q.question, f.answer, f.citation, 1 - (q.vector <=> $3) AS score
+        FROM faq_questions q
+        JOIN faq_entries f ON f.id = q.faq_id
+        WHERE q.chatbot_id = $1 AND q.embedding_model = $2
+        ORDER BY q.vector <=> $3
+        LIMIT $4
+    `
+
+	// Over-fetch so entries with several close variants still leave room for others
+	rows, err := c.pool.Query(ctx, query, chatbotID, model, toPgVector(queryVector), limit*4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search faq: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []FAQMatch
+	seen := make(map[int64]bool)
+	for rows.Next() {
+		var m FAQMatch
+		if err := rows.Scan(&m.FAQID, &m.Question, &m.Answer, &m.Citation, &m.Score); err != nil {
+			return nil, fmt.Errorf("failed to scan faq match: %w", err)
+		}
+		if seen[m.FAQID] || len(matches) == limit {
+			continue
+		}
+		seen[m.FAQID] = true
+		matches = append(matches, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating faq matches: %w", err)
+	}
+	return matches, nil
+}
+
+// ListFAQQuestionsMissingModel returns the question variants of a chatbot's entries that
+// have no vector from model yet
+func (c *PostgresClient) ListFAQQuestionsMissingModel(ctx context.Context, chatbotID, model string) ([]FAQQuestion, error) {
+	query := `
+        SELECT DISTINCT q.faq_id, q.question
+        FROM faq_questions q
+        WHERE q.chatbot_id = $1
+          AND NOT EXISTS (
+              SELECT 1 FROM faq_questions m
+              WHERE m.faq_id = q.faq_id AND m.question = q.question AND m.embedding_model = $2
+          )
+        ORDER BY q.faq_id
+    `
+
+	rows, err := c.pool.Query(ctx, query, chatbotID, model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list faq questions: %w", err)
+	}
+	defer rows.Close()
+
+	var questions []FAQQuestion
+	for rows.Next() {
+		var q FAQQuestion
+		if err := rows.Scan(&q.FAQID, &q.Question); err != nil {
+			return nil, fmt.Errorf("failed to scan faq question: %w", err)
+		}
+		questions = append(questions, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating faq questions: %w", err)
+	}
+	return questions, nil
+}
+
+// AddFAQQuestionVectors stores vectors of existing question variants from another model
+func (c *PostgresClient) AddFAQQuestionVectors(ctx context.Context, chatbotID, model string, questions []FAQQuestion, vectors [][]float64) error {
+	if len(questions) != len(vectors) {
+		return fmt.Errorf("got %d questions for %d vectors", len(questions), len(vectors))
+	}
+	batch := &pgx.Batch{}
+	for i, q := range questions {
+		batch.Queue(`
+			INSERT INTO faq_questions (faq_id, chatbot_id, question, embedding_model, vector)
+			VALUES ($1, $2, $3, $4, $5)`, q.FAQID, chatbotID, q.Question, model, toPgVector(vectors[i]))
+	}
+	if err := c.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to store faq question vectors: %w", err)
+	}
+	return nil
+}
@@ -2,10 +2,14 @@ package loaders
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pgvector/pgvector-go"
 	pgxvec "github.com/pgvector/pgvector-go/pgx"
@@ -37,8 +41,15 @@ type ChatbotRecord struct {
 
 // EmbeddingResult represents a retrieved embedding document
 type EmbeddingResult struct {
-	Text     string
-	Citation *string
+	Text        string
+	Citation    *string
+	Vector      []float64 // populated only by candidate searches
+	Score       float64   // cosine similarity to the query, populated only by candidate searches
+	DocumentID  *string   // source document the chunk was split from, if known
+	ChunkIndex  *int      // position of the chunk within its document
+	Section     *string   // parent section (e.g. heading path) the chunk belongs to
+	StartOffset *int      // byte offsets of the chunk within its document's extracted text
+	EndOffset   *int
 }
 
 type EmbeddingData struct {
@@ -47,6 +58,13 @@ type EmbeddingData struct {
 	Vector       []float64
 	DataSourceID *int
 	Citation     *string
+	DocumentID   *string
+	ChunkIndex   *int
+	Section      *string
+	StartOffset  *int
+	EndOffset    *int
+	ContentHash  *string // hash of Text, used to diff re-ingestions
+	Model        *string // embedding model that produced Vector
 }
 
 func NewPostgresClient(dsn string, workerCount, batchSize int) (*PostgresClient, error) {
@@ -147,63 +165,248 @@ type MessageRow struct {
 	CreatedAt    time.Time
 	UniqueConvID string
 	TopicID      string
+	LatencyMS    int64    // assistant messages: time taken to answer, 0 when unknown
+	Tools        []string // assistant messages: tools called to answer, in call order
 }
 
-// BatchInsertMessages inserts a batch of messages into the messages table
-func (c *PostgresClient) BatchInsertMessages(ctx context.Context, rows []MessageRow) error {
-	if len(rows) == 0 {
-		return nil
-	}
+// MessageInsertResult reports what happened to each row of a BatchInsertMessages call
+type MessageInsertResult struct {
+	Inserted   int // rows written
+	Duplicates int // rows skipped because a message with the same id already exists
+	Failed     []MessageInsertError
+}
 
-	query := `
+// MessageInsertError is a row Postgres rejected; retrying it would fail again
+type MessageInsertError struct {
+	Index     int // position of the row in the batch
+	MessageID string
+	Err       error
+}
+
+const insertMessageQuery = `
         INSERT INTO messages (
-            id, chatbot_id, citations, "type", content, created_at, unique_conv_id, topic_id
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+            id, chatbot_id, citations, "type", content, created_at, unique_conv_id, topic_id,
+            latency_ms, tools
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        ON CONFLICT (id) DO NOTHING
     `
 
-	successCount := 0
-	for _, r := range rows {
-		// Convert empty topic_id to NULL for database insertion
-		var topicID interface{}
-		if r.TopicID == "" {
-			topicID = nil
-		} else {
-			topicID = r.TopicID
-		}
+// markDirtyDaysQuery marks the days of inserted messages for the analytics rollup. Days
+// are marked in order so concurrent batches lock them in the same order. Marks follow
+// the commit of the messages, so a rollup that did not see them sees a newer mark.
+const markDirtyDaysQuery = `
+        INSERT INTO analytics_dirty_days (chatbot_id, day)
+        SELECT DISTINCT chatbot_id, created_at::date
+        FROM unnest($1::text[], $2::timestamp[]) AS m (chatbot_id, created_at)
+        ORDER BY 1, 2
+        ON CONFLICT (chatbot_id, day) DO UPDATE SET version = analytics_dirty_days.version + 1
+    `
 
-		_, err := c.pool.Exec(ctx, query,
-			r.UniqueMsgID,
-			r.ChatbotID,
-			r.Citations,
-			r.Type,
-			r.Content,
-			r.CreatedAt.UTC(),
-			r.UniqueConvID,
-			topicID,
-		)
+// touchConversationsQuery records the newest message of each conversation for
+// ListConversations. Conversations are touched in order so concurrent batches lock
+// them in the same order.
+const touchConversationsQuery = `
+        INSERT INTO conversation_activity (chatbot_id, unique_conv_id, last_message_at)
+        SELECT chatbot_id, unique_conv_id, MAX(created_at)
+        FROM unnest($1::text[], $2::text[], $3::timestamp[]) AS m (chatbot_id, unique_conv_id, created_at)
+        GROUP BY 1, 2
+        ORDER BY 1, 2
+        ON CONFLICT (chatbot_id, unique_conv_id) DO UPDATE
+        SET last_message_at = GREATEST(conversation_activity.last_message_at, EXCLUDED.last_message_at)
+    `
+
+// BatchInsertMessages inserts messages in one transaction. Rows whose id already exists
+// are skipped, so a batch can be retried safely after a partial or unknown outcome. If
+// Postgres rejects a row (a data or constraint error) the transaction is rolled back
+// and the rows are inserted one by one, so the good rows are still written and the bad
+// ones reported in the result. Any other failure, such as a lost connection, fails the
+// whole batch and is returned as the error. Once the rows are committed their days are
+// marked for the analytics rollup and their conversations' activity is recorded.
+func (c *PostgresClient) BatchInsertMessages(ctx context.Context, rows []MessageRow) (*MessageInsertResult, error) {
+	result := &MessageInsertResult{}
+	if len(rows) == 0 {
+		return result, nil
+	}
+
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for i := range rows {
+		batch.Queue(insertMessageQuery, messageArgs(&rows[i])...)
+	}
+	br := tx.SendBatch(ctx, batch)
+	var batchErr error
+	for range rows {
+		tag, err := br.Exec()
 		if err != nil {
-			log.Printf("Failed to insert message for conv=%s chatbot_id=%s: %v", r.UniqueConvID, r.ChatbotID, err)
-			continue
+			batchErr = err
+			break
+		}
+		if tag.RowsAffected() == 0 {
+			result.Duplicates++
+		} else {
+			result.Inserted++
 		}
-		successCount++
+	}
+	if err := br.Close(); err != nil && batchErr == nil {
+		batchErr = err
 	}
 
-	if successCount == 0 {
-		return fmt.Errorf("failed to insert any messages")
+	if batchErr == nil {
+		if err := tx.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("failed to commit messages: %w", err)
+		}
+		if err := c.markWritten(ctx, rows); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("failed to insert messages: %w", ctx.Err())
 	}
+	// Only rows Postgres rejected are worth isolating; retrying them one by one over a
+	// broken connection would report good rows as failed
+	if !IsRowError(batchErr) {
+		return nil, fmt.Errorf("failed to insert messages: %w", batchErr)
+	}
+	tx.Rollback(ctx)
+
+	// Isolate the rejected rows
+	result = &MessageInsertResult{}
+	for i := range rows {
+		tag, err := c.pool.Exec(ctx, insertMessageQuery, messageArgs(&rows[i])...)
+		switch {
+		case err != nil && IsRowError(err):
+			result.Failed = append(result.Failed, MessageInsertError{Index: i, MessageID: rows[i].UniqueMsgID, Err: err})
+		case err != nil:
+			// Rows written so far are skipped as duplicates when the batch is retried
+			return nil, fmt.Errorf("failed to insert messages: %w", err)
+		case tag.RowsAffected() == 0:
+			result.Duplicates++
+		default:
+			result.Inserted++
+		}
+	}
+	if len(result.Failed) < len(rows) {
+		if err := c.markWritten(ctx, writtenRows(rows, result.Failed)); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
 
+// markWritten marks the days of committed rows for the analytics rollup and records the
+// activity of their conversations. It runs outside the insert transaction, so concurrent
+// batches hold a chatbot's busy day row only for one short statement. A failure fails
+// the batch; its retry skips the rows as duplicates and marks them again.
+func (c *PostgresClient) markWritten(ctx context.Context, rows []MessageRow) error {
+	if _, err := c.pool.Exec(ctx, markDirtyDaysQuery, dirtyDayArgs(rows)...); err != nil {
+		return fmt.Errorf("failed to mark analytics days: %w", err)
+	}
+	if _, err := c.pool.Exec(ctx, touchConversationsQuery, conversationArgs(rows)...); err != nil {
+		return fmt.Errorf("failed to record conversation activity: %w", err)
+	}
 	return nil
 }
 
+// IsRowError reports whether err is Postgres rejecting a row's data (class 22) or a
+// constraint (class 23). Retrying such a row fails again; other errors may not.
+func IsRowError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (strings.HasPrefix(pgErr.Code, "22") || strings.HasPrefix(pgErr.Code, "23"))
+}
+
+func messageArgs(r *MessageRow) []any {
+	// Empty topic_id is stored as NULL
+	var topicID any
+	if r.TopicID != "" {
+		topicID = r.TopicID
+	}
+	// So are an unknown latency and the tools of user messages
+	var latency, tools any
+	if r.LatencyMS > 0 {
+		latency = r.LatencyMS
+	}
+	if r.Type == "assistant" {
+		tools = r.Tools
+		if r.Tools == nil {
+			tools = []string{}
+		}
+	}
+	return []any{
+		r.UniqueMsgID,
+		r.ChatbotID,
+		r.Citations,
+		r.Type,
+		r.Content,
+		r.CreatedAt.UTC(),
+		r.UniqueConvID,
+		topicID,
+		latency,
+		tools,
+	}
+}
+
+// dirtyDayArgs returns the chatbot ids and creation times of rows for markDirtyDaysQuery
+func dirtyDayArgs(rows []MessageRow) []any {
+	chatbotIDs := make([]string, len(rows))
+	createdAt := make([]string, len(rows))
+	for i := range rows {
+		chatbotIDs[i] = rows[i].ChatbotID
+		createdAt[i] = formatTimeForDB(rows[i].CreatedAt)
+	}
+	return []any{chatbotIDs, createdAt}
+}
+
+// conversationArgs returns the chatbot ids, conversation ids and creation times of rows
+// for touchConversationsQuery
+func conversationArgs(rows []MessageRow) []any {
+	chatbotIDs := make([]string, len(rows))
+	convIDs := make([]string, len(rows))
+	createdAt := make([]string, len(rows))
+	for i := range rows {
+		chatbotIDs[i] = rows[i].ChatbotID
+		convIDs[i] = rows[i].UniqueConvID
+		createdAt[i] = formatTimeForDB(rows[i].CreatedAt)
+	}
+	return []any{chatbotIDs, convIDs, createdAt}
+}
+
+// writtenRows returns rows without those that failed
+func writtenRows(rows []MessageRow, failed []MessageInsertError) []MessageRow {
+	skip := make(map[int]bool, len(failed))
+	for _, f := range failed {
+		skip[f.Index] = true
+	}
+	written := make([]MessageRow, 0, len(rows)-len(failed))
+	for i := range rows {
+		if !skip[i] {
+			written = append(written, rows[i])
+		}
+	}
+	return written
+}
+
 func (c *PostgresClient) UpdateMessageFeedback(ctx context.Context, chatbotID string, uniqueMsgID string, feedback int16, comment *string) error {
 	if uniqueMsgID == "" {
 		return fmt.Errorf("unique message id is required")
 	}
 
+	// The message's day is rolled up again with the new feedback
 	query := `
-        UPDATE messages
-        SET feedback = $1, feedback_comment = $2
-        WHERE id = $3 AND chatbot_id = $4
+        WITH updated AS (
+            UPDATE messages
+            SET feedback = $1, feedback_comment = $2
+            WHERE id = $3 AND chatbot_id = $4
+            RETURNING chatbot_id, created_at
+        )
+        INSERT INTO analytics_dirty_days (chatbot_id, day)
+        SELECT chatbot_id, created_at::date FROM updated
+        ON CONFLICT (chatbot_id, day) DO UPDATE SET version = analytics_dirty_days.version + 1
     `
 
 	_, err := c.pool.Exec(ctx, query, feedback, comment, uniqueMsgID, chatbotID)
@@ -213,7 +416,9 @@ func (c *PostgresClient) UpdateMessageFeedback(ctx context.Context, chatbotID st
 	return nil
 }
 
-// BatchInsertEmbeddings inserts a batch of embeddings into the database
+// BatchInsertEmbeddings inserts a batch of embeddings in one transaction. A row that
+// fails to insert fails the whole batch, so callers never report a partially stored
+// data source as complete.
 func (c *PostgresClient) BatchInsertEmbeddings(ctx context.Context, userID, chatbotID string, chunks []EmbeddingData) error {
 	if len(chunks) == 0 {
 		return nil
@@ -222,43 +427,48 @@ func (c *PostgresClient) BatchInsertEmbeddings(ctx context.Context, userID, chat
 	query := `
 		INSERT INTO embeddings (
 			user_id, chatbot_id, text, vector, 
-			created_at, updated_at, data_source_id, citation
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			created_at, updated_at, data_source_id, citation,
+			document_id, chunk_index, section, start_offset, end_offset, content_hash,
+			embedding_model
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 	`
 
 	now := formatTimeForDB(time.Now().UTC())
-	successCount := 0
-
+	batch := &pgx.Batch{}
 	for _, chunk := range chunks {
-		// Convert []float64 to []float32 for pgvector
-		vec32 := make([]float32, len(chunk.Vector))
-		for i, v := range chunk.Vector {
-			vec32[i] = float32(v)
-		}
-		vec := pgvector.NewVector(vec32)
-
-		_, err := c.pool.Exec(ctx, query,
+		batch.Queue(query,
 			userID,
 			chatbotID,
 			chunk.Text,
-			vec,
+			toPgVector(chunk.Vector),
 			now,
 			now,
 			chunk.DataSourceID,
 			chunk.Citation,
+			chunk.DocumentID,
+			chunk.ChunkIndex,
+			chunk.Section,
+			chunk.StartOffset,
+			chunk.EndOffset,
+			chunk.ContentHash,
+			chunk.Model,
 		)
-		if err != nil {
-			log.Printf("Failed to insert embedding for data_source_id=%d: %v", chunk.DataSourceID, err)
-			continue
-		}
-		successCount++
 	}
 
-	if successCount == 0 {
-		return fmt.Errorf("failed to insert any embeddings")
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to insert embeddings: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit embeddings: %w", err)
 	}
 
-	log.Printf("Successfully inserted %d/%d embeddings", successCount, len(chunks))
+	log.Printf("Successfully inserted %d embeddings", len(chunks))
 	return nil
 }
 
@@ -415,7 +625,7 @@ func (c *PostgresClient) SearchEmbeddings(ctx context.Context, chatbotID string,
 	query := `
         SELECT text, citation
         FROM embeddings 
-        WHERE chatbot_id = $1
+        WHERE chatbot_id = $1 AND reindex_job_id IS NULL
         ORDER BY vector <=> $2
         LIMIT $3
     `
@@ -442,3 +652,206 @@ func (c *PostgresClient) SearchEmbeddings(ctx context.Context, chatbotID string,
 	log.Printf("Retrieved %d embeddings for chatbot_id=%s", len(results), chatbotID)
 	return results, nil
 }
+
+// SearchFilter narrows a vector search within one chatbot. Empty fields match everything.
+type SearchFilter struct {
+	// Model selects the vectors searched: empty for the primary vector column, otherwise
+	// that model's rows in embedding_vectors
+	Model         string
+	DataSourceIDs []int
+	DocumentIDs   []string
+}
+
+// SearchEmbeddingCandidates returns the nearest embeddings together with their stored
+// vectors and cosine similarity so callers can re-rank them (e.g. MMR)
+func (c *PostgresClient) SearchEmbeddingCandidates(ctx context.Context, chatbotID string, queryVector []float64, limit int, filter SearchFilter) ([]EmbeddingResult, error) {
+	vec32 := make([]float32, len(queryVector))
+	for i, v := range queryVector {
+		vec32[i] = float32(v)
+	}
+	vec := pgvector.NewVector(vec32)
+
+	from := `embeddings e`
+	vector := `e.vector`
+	where := []string{`e.chatbot_id = $1`, `e.reindex_job_id IS NULL`}
+	args := []interface{}{chatbotID, vec, limit}
+	if filter.Model != "" {
+		// The untyped column is indexed per size (migration 007); the cast and the size
+		// filter must match an index expression for it to be used
+		dims := len(queryVector)
+		from = `embedding_vectors v JOIN embeddings e ON e.id = v.embedding_id`
+		vector = fmt.Sprintf(`(v.vector::vector(%d))`, dims)
+		args = append(args, filter.Model)
+		where = append(where, fmt.Sprintf(`v.model = $%d`, len(args)),
+			fmt.Sprintf(`vector_dims(v.vector) = %d`, dims))
+	}
+	if len(filter.DataSourceIDs) > 0 {
+		ids := make([]int32, len(filter.DataSourceIDs))
+		for i, id := range filter.DataSourceIDs {
+			ids[i] = int32(id)
+		}
+		args = append(args, ids)
+		where = append(where, fmt.Sprintf(`e.data_source_id = ANY($%d)`, len(args)))
+	}
+	if len(filter.DocumentIDs) > 0 {
+		args = append(args, filter.DocumentIDs)
+		where = append(where, fmt.Sprintf(`e.document_id = ANY($%d)`, len(args)))
+	}
+
+	query := fmt.Sprintf(`
+        SELECT e.text, e.citation, %[1]s, 1 - (%[1]s <=> $2) AS score,
+               e.document_id, e.chunk_index, e.section
+        FROM %[2]s
+        WHERE %[3]s
+        ORDER BY %[1]s <=> $2
+        LIMIT $3
+    `, vector, from, strings.Join(where, " AND "))
+
+	rows, err := c.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query embedding candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var results []EmbeddingResult
+	for rows.Next() {
+		var (
+			result EmbeddingResult
+			stored pgvector.Vector
+		)
+		if err := rows.Scan(&result.Text, &result.Citation, &stored, &result.Score,
+			&result.DocumentID, &result.ChunkIndex, &result.Section); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		raw := stored.Slice()
+		result.Vector = make([]float64, len(raw))
+		for i, v := range raw {
+			result.Vector[i] = float64(v)
+		}
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	log.Printf("Retrieved %d embedding candidates for chatbot_id=%s", len(results), chatbotID)
+	return results, nil
+}
+
+// SetPrimaryVectors replaces the primary vectors of stored chunks of a chatbot. Chunks
+// are created by ingestion, so ids that are not stored are an error.
+func (c *PostgresClient) SetPrimaryVectors(ctx context.Context, chatbotID string, ids []int64, vectors [][]float64) error {
+	if len(ids) != len(vectors) {
+		return fmt.Errorf("got %d ids for %d vectors", len(ids), len(vectors))
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	now := formatTimeForDB(time.Now().UTC())
+	batch := &pgx.Batch{}
+	for i, id := range ids {
+		batch.Queue(`UPDATE embeddings SET vector = $1, updated_at = $2 WHERE id = $3 AND chatbot_id = $4`,
+			toPgVector(vectors[i]), now, id, chatbotID)
+	}
+
+	results := c.pool.SendBatch(ctx, batch)
+	defer results.Close()
+	for _, id := range ids {
+		tag, err := results.Exec()
+		if err != nil {
+			return fmt.Errorf("failed to update vector of embedding %d: %w", id, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("embedding %d is not stored for chatbot %s", id, chatbotID)
+		}
+	}
+	return nil
+}
+
+// DeleteEmbeddingsByID removes chunks of a chatbot together with all their vectors
+func (c *PostgresClient) DeleteEmbeddingsByID(ctx context.Context, chatbotID string, ids []int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result, err := c.pool.Exec(ctx, `DELETE FROM embeddings WHERE chatbot_id = $1 AND id = ANY($2)`, chatbotID, ids)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete embeddings: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// ChunkRange is an inclusive range of chunk positions within one document
+type ChunkRange struct {
+	From int
+	To   int
+}
+
+// GetDocumentChunks returns the chunks of a document whose positions fall in any of the
+// given ranges, ordered by position. Used to expand a retrieval hit with its neighbours.
+func (c *PostgresClient) GetDocumentChunks(ctx context.Context, chatbotID, documentID string, ranges []ChunkRange) ([]EmbeddingResult, error) {
+	if len(ranges) == 0 {
+		return []EmbeddingResult{}, nil
+	}
+
+	froms := make([]int32, len(ranges))
+	tos := make([]int32, len(ranges))
+	for i, r := range ranges {
+		froms[i] = int32(r.From)
+		tos[i] = int32(r.To)
+	}
+
+	query := `
+        SELECT e.text, e.citation, e.document_id, e.chunk_index, e.section,
+               e.start_offset, e.end_offset
+        FROM embeddings e
+        WHERE e.chatbot_id = $1
+          AND e.document_id = $2
+          AND e.reindex_job_id IS NULL
+          AND EXISTS (
+              SELECT 1 FROM unnest($3::int[], $4::int[]) AS r(lo, hi)
+              WHERE e.chunk_index BETWEEN r.lo AND r.hi
+          )
+        ORDER BY e.chunk_index
+    `
+
+	return c.queryDocumentChunks(ctx, query, chatbotID, documentID, froms, tos)
+}
+
+// GetSectionChunks returns every chunk of a document that belongs to the given section,
+// ordered by position. Used to expand a retrieval hit to its parent section.
+func (c *PostgresClient) GetSectionChunks(ctx context.Context, chatbotID, documentID, section string) ([]EmbeddingResult, error) {
+	query := `
+        SELECT text, citation, document_id, chunk_index, section,
+               start_offset, end_offset
+        FROM embeddings
+        WHERE chatbot_id = $1 AND document_id = $2 AND section = $3 AND reindex_job_id IS NULL
+        ORDER BY chunk_index
+    `
+
+	return c.queryDocumentChunks(ctx, query, chatbotID, documentID, section)
+}
+
+func (c *PostgresClient) queryDocumentChunks(ctx context.Context, query string, args ...interface{}) ([]EmbeddingResult, error) {
+	rows, err := c.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var results []EmbeddingResult
+	for rows.Next() {
+		var result EmbeddingResult
+		if err := rows.Scan(&result.Text, &result.Citation, &result.DocumentID, &result.ChunkIndex, &result.Section,
+			&result.StartOffset, &result.EndOffset); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,337 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pgvector/pgvector-go"
+)
+
+var (
+	ErrDataSourceNotFound = errors.New("data source not found")
+	ErrDataSourceBusy     = errors.New("data source is being ingested")
+	ErrReindexJobNotFound = errors.New("reindex job not found")
+)
+
+// Reindex job states
+const (
+	ReindexRunning   = "RUNNING"
+	ReindexCompleted = "COMPLETED"
+	ReindexFailed    = "FAILED"
+)
+
+// ReindexJobRecord represents a row of the reindex_jobs table
+type ReindexJobRecord struct {
+	ID                 string
+	ChatbotID          string
+	Status             string
+	TotalDocuments     int
+	ProcessedDocuments int
+	ChunksEmbedded     int
+	ChunksReused       int
+	ErrorMessage       *string
+	StartedAt          time.Time
+	FinishedAt         *time.Time
+}
+
+// reindexLockKey is the advisory lock of a chatbot's reindex. A job holds it exclusively;
+// writes that a reindex must not overlap hold it shared.
+func reindexLockKey(chatbotID string) int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("reindex:" + chatbotID))
+	return int64(h.Sum64())
+}
+
+// TryReindexLock takes a chatbot's reindex lock for a job. ok is false while another job
+// runs or a write holds the shared lock, on any instance.
+func (c *PostgresClient) TryReindexLock(ctx context.Context, chatbotID string) (release func(), ok bool, err error) {
+	return c.tryAdvisoryLock(ctx, reindexLockKey(chatbotID))
+}
+
+// TryReindexSharedLock takes a chatbot's reindex lock in shared mode, so no job can start
+// until it is released. ok is false while a job runs.
+func (c *PostgresClient) TryReindexSharedLock(ctx context.Context, chatbotID string) (release func(), ok bool, err error) {
+	return c.tryAdvisoryLockShared(ctx, reindexLockKey(chatbotID))
+}
+
+// IndexedChunk is a stored chunk with its text, read back to rebuild a document
+type IndexedChunk struct {
+	ID     int64
+	UserID string
+	EmbeddingData
+}
+
+// DeleteDataSourceEmbeddings removes every embedding of a data source and clears its
+// sync summary in one transaction. The data_source row is locked first so the delete
+// cannot interleave with a running ingestion.
+func (c *PostgresClient) DeleteDataSourceEmbeddings(ctx context.Context, dataSourceID int) (int64, error) {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var status *string
+	err = tx.QueryRow(ctx, `SELECT status FROM data_source WHERE id = $1 FOR UPDATE`, dataSourceID).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrDataSourceNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to lock data source: %w", err)
+	}
+	if status != nil && *status == DataSourceProcessing {
+		return 0, ErrDataSourceBusy
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM embeddings WHERE data_source_id = $1`, dataSourceID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete embeddings: %w", err)
+	}
+
+	now := formatTimeForDB(time.Now().UTC())
+	if _, err := tx.Exec(ctx, `
+		UPDATE data_source
+		SET chunks_added = NULL, chunks_updated = NULL, chunks_removed = NULL,
+		    last_synced_at = NULL, updated_at = $1
+		WHERE id = $2`, now, dataSourceID); err != nil {
+		return 0, fmt.Errorf("failed to reset data source: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit delete: %w", err)
+	}
+
+	log.Printf("Deleted %d embeddings for data source %d", result.RowsAffected(), dataSourceID)
+	return result.RowsAffected(), nil
+}
+
+// ListIndexedDocuments returns the documents of a chatbot's live index
+func (c *PostgresClient) ListIndexedDocuments(ctx context.Context, chatbotID string) ([]string, error) {
+	query := `
+        SELECT DISTINCT document_id
+        FROM embeddings
+        WHERE chatbot_id = $1 AND document_id IS NOT NULL AND reindex_job_id IS NULL
+        ORDER BY document_id
+    `
+
+	rows, err := c.pool.Query(ctx, query, chatbotID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexed documents: %w", err)
+	}
+	defer rows.Close()
+
+	var docs []string
+	for rows.Next() {
+		var doc string
+		if err := rows.Scan(&doc); err != nil {
+			return nil, fmt.Errorf("failed to scan document id: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating documents: %w", err)
+	}
+	return docs, nil
+}
+
+// GetIndexedDocument returns the live chunks of one document in position order
+func (c *PostgresClient) GetIndexedDocument(ctx context.Context, chatbotID, documentID string) ([]IndexedChunk, error) {
+	query := `
+        SELECT id, user_id, text, data_source_id, citation, document_id, chunk_index, section,
+               start_offset, end_offset, content_hash
+        FROM embeddings
+        WHERE chatbot_id = $1 AND document_id = $2 AND reindex_job_id IS NULL
+        ORDER BY chunk_index
+    `
+
+	rows, err := c.pool.Query(ctx, query, chatbotID, documentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query document chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var chunks []IndexedChunk
+	for rows.Next() {
+		var ic IndexedChunk
+		if err := rows.Scan(&ic.ID, &ic.UserID, &ic.Text, &ic.DataSourceID, &ic.Citation, &ic.DocumentID,
+			&ic.ChunkIndex, &ic.Section, &ic.StartOffset, &ic.EndOffset, &ic.ContentHash); err != nil {
+			return nil, fmt.Errorf("failed to scan document chunk: %w", err)
+		}
+		chunks = append(chunks, ic)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating document chunks: %w", err)
+	}
+	return chunks, nil
+}
+
+// GetVectorsByHash returns live primary vectors of a chatbot produced by model, keyed by
+// content hash, so unchanged text does not need to be embedded again. Untagged rows are
+// assumed to come from model.
+func (c *PostgresClient) GetVectorsByHash(ctx context.Context, chatbotID, model string, hashes []string) (map[string][]float64, error) {
+	vectors := make(map[string][]float64)
+	if len(hashes) == 0 {
+		return vectors, nil
+	}
+
+	query := `
+        SELECT DISTINCT ON (content_hash) content_hash, vector
+        FROM embeddings
+        WHERE chatbot_id = $1 AND content_hash = ANY($2) AND reindex_job_id IS NULL
+          AND (embedding_model = $3 OR embedding_model IS NULL)
+    `
+
+	rows, err := c.pool.Query(ctx, query, chatbotID, hashes, model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vectors by hash: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			hash   string
+			stored pgvector.Vector
+		)
+		if err := rows.Scan(&hash, &stored); err != nil {
+			return nil, fmt.Errorf("failed to scan vector: %w", err)
+		}
+		raw := stored.Slice()
+		vec := make([]float64, len(raw))
+		for i, v := range raw {
+			vec[i] = float64(v)
+		}
+		vectors[hash] = vec
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating vectors: %w", err)
+	}
+	return vectors, nil
+}
+
+// InsertPendingEmbeddings writes rows for a running reindex. They are invisible to
+// retrieval until SwapReindex.
+func (c *PostgresClient) InsertPendingEmbeddings(ctx context.Context, userID, chatbotID, jobID string, chunks []EmbeddingData) error {
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	now := formatTimeForDB(time.Now().UTC())
+	batch := &pgx.Batch{}
+	for _, d := range chunks {
+		batch.Queue(`
+			INSERT INTO embeddings (
+				user_id, chatbot_id, text, vector,
+				created_at, updated_at, data_source_id, citation,
+				document_id, chunk_index, section, start_offset, end_offset, content_hash,
+				embedding_model, reindex_job_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`,
+			userID, chatbotID, d.Text, toPgVector(d.Vector),
+			now, now, d.DataSourceID, d.Citation,
+			d.DocumentID, d.ChunkIndex, d.Section, d.StartOffset, d.EndOffset, d.ContentHash,
+			d.Model, jobID)
+	}
+
+	if err := c.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to insert pending embeddings: %w", err)
+	}
+	return nil
+}
+
+// SwapReindex atomically replaces the rows a reindex was built from with the rows it
+// wrote. Rows added to the live index after the reindex started are left alone.
+func (c *PostgresClient) SwapReindex(ctx context.Context, chatbotID, jobID string, replacedIDs []int64) error {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM embeddings WHERE id = ANY($1)`, replacedIDs); err != nil {
+		return fmt.Errorf("failed to delete replaced embeddings: %w", err)
+	}
+	result, err := tx.Exec(ctx, `
+		UPDATE embeddings SET reindex_job_id = NULL
+		WHERE chatbot_id = $1 AND reindex_job_id = $2`, chatbotID, jobID)
+	if err != nil {
+		return fmt.Errorf("failed to publish reindexed embeddings: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit reindex swap: %w", err)
+	}
+
+	log.Printf("Swapped reindex %s for chatbot %s: %d rows replaced by %d", jobID, chatbotID, len(replacedIDs), result.RowsAffected())
+	return nil
+}
+
+// DiscardPendingEmbeddings deletes rows left by unfinished reindexes of a chatbot
+func (c *PostgresClient) DiscardPendingEmbeddings(ctx context.Context, chatbotID string) (int64, error) {
+	result, err := c.pool.Exec(ctx, `
+		DELETE FROM embeddings WHERE chatbot_id = $1 AND reindex_job_id IS NOT NULL`, chatbotID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to discard pending embeddings: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// CreateReindexJob inserts a new RUNNING reindex job
+func (c *PostgresClient) CreateReindexJob(ctx context.Context, job *ReindexJobRecord) error {
+	query := `
+		INSERT INTO reindex_jobs (id, chatbot_id, status, total_documents, started_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	if _, err := c.pool.Exec(ctx, query, job.ID, job.ChatbotID, job.Status, job.TotalDocuments,
+		formatTimeForDB(job.StartedAt)); err != nil {
+		return fmt.Errorf("failed to create reindex job: %w", err)
+	}
+	return nil
+}
+
+// UpdateReindexJob stores the progress and outcome of a reindex job
+func (c *PostgresClient) UpdateReindexJob(ctx context.Context, job *ReindexJobRecord) error {
+	query := `
+		UPDATE reindex_jobs
+		SET status = $1, total_documents = $2, processed_documents = $3, chunks_embedded = $4,
+		    chunks_reused = $5, error_message = $6, finished_at = $7
+		WHERE id = $8
+	`
+
+	var finishedAt *string
+	if job.FinishedAt != nil {
+		f := formatTimeForDB(*job.FinishedAt)
+		finishedAt = &f
+	}
+	if _, err := c.pool.Exec(ctx, query, job.Status, job.TotalDocuments, job.ProcessedDocuments,
+		job.ChunksEmbedded, job.ChunksReused, job.ErrorMessage, finishedAt, job.ID); err != nil {
+		return fmt.Errorf("failed to update reindex job: %w", err)
+	}
+	return nil
+}
+
+// GetReindexJob loads one reindex job
+func (c *PostgresClient) GetReindexJob(ctx context.Context, jobID string) (*ReindexJobRecord, error) {
+	query := `
+		SELECT id, chatbot_id, status, total_documents, processed_documents, chunks_embedded,
+		       chunks_reused, error_message, started_at, finished_at
+		FROM reindex_jobs
+		WHERE id = $1
+	`
+
+	var job ReindexJobRecord
+	err := c.pool.QueryRow(ctx, query, jobID).Scan(&job.ID, &job.ChatbotID, &job.Status,
+		&job.TotalDocuments, &job.ProcessedDocuments, &job.ChunksEmbedded, &job.ChunksReused,
+		&job.ErrorMessage, &job.StartedAt, &job.FinishedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrReindexJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reindex job: %w", err)
+	}
+	return &job, nil
+}
@@ -0,0 +1,333 @@
+package loaders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var ErrRetentionRunNotFound = errors.New("retention run not found")
+
+// Kinds of retention run
+const (
+	RetentionPurge   = "PURGE"
+	RetentionErasure = "ERASURE"
+)
+
+// Retention run states
+const (
+	RetentionRunning   = "RUNNING"
+	RetentionCompleted = "COMPLETED"
+	RetentionFailed    = "FAILED"
+)
+
+// Erasure modes
+const (
+	ErasureDelete    = "DELETE"
+	ErasureAnonymize = "ANONYMIZE"
+)
+
+// ErasedContent replaces the content of anonymized messages
+const ErasedContent = "[erased]"
+
+// retentionLockKey is the advisory lock held by the instance running a purge
+const retentionLockKey int64 = 0x72657465_6e74696f
+
+// RetentionRunRecord represents a row of the data_retention_runs table
+type RetentionRunRecord struct {
+	ID                 string
+	Kind               string
+	ChatbotID          *string
+	SubjectHash        *string
+	Mode               *string
+	RequestedBy        *string
+	Status             string
+	MessagesDeleted    int64
+	MessagesAnonymized int64
+	Details            map[string]int64 // PURGE: messages deleted per chatbot
+	ErrorMessage       *string
+	StartedAt          time.Time
+	FinishedAt         *time.Time
+}
+
+// RetentionPolicy is a chatbot's message retention period
+type RetentionPolicy struct {
+	ChatbotID string
+	Days      int
+}
+
+// SetRetentionDays sets how long a chatbot's messages are kept; nil keeps them forever
+func (c *PostgresClient) SetRetentionDays(ctx context.Context, chatbotID string, days *int) error {
+	if _, err := c.pool.Exec(ctx, `
+		INSERT INTO chatbot_settings (chatbot_id, retention_days, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (chatbot_id) DO UPDATE
+		SET retention_days = EXCLUDED.retention_days,
+		    updated_at = NOW()`, chatbotID, days); err != nil {
+		return fmt.Errorf("failed to set retention period: %w", err)
+	}
+	return nil
+}
+
+// ListRetentionPolicies returns the chatbots that have a retention period
+func (c *PostgresClient) ListRetentionPolicies(ctx context.Context) ([]RetentionPolicy, error) {
+	rows, err := c.pool.Query(ctx, `
+		SELECT chatbot_id, retention_days
+		FROM chatbot_settings
+		WHERE retention_days > 0
+		ORDER BY chatbot_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list retention policies: %w", err)
+	}
+	defer rows.Close()
+
+	var policies []RetentionPolicy
+	for rows.Next() {
+		var p RetentionPolicy
+		if err := rows.Scan(&p.ChatbotID, &p.Days); err != nil {
+			return nil, fmt.Errorf("failed to scan retention policy: %w", err)
+		}
+		policies = append(policies, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating retention policies: %w", err)
+	}
+	return policies, nil
+}
+
+// TryRetentionLock takes the purge lock if no other instance holds it. The returned
+// function releases it; ok is false when the lock is taken.
+func (c *PostgresClient) TryRetentionLock(ctx context.Context) (release func(), ok bool, err error) {
+	return c.tryAdvisoryLock(ctx, retentionLockKey)
+}
+
+// tryAdvisoryLock takes a session-level advisory lock if it is free. Such locks belong to
+// a connection, so one is held until the lock is released.
+func (c *PostgresClient) tryAdvisoryLock(ctx context.Context, key int64) (release func(), ok bool, err error) {
+	return c.tryLock(ctx, key, false)
+}
+
+// tryAdvisoryLockShared is tryAdvisoryLock in shared mode: any number of holders can
+// share the lock, and it is free for tryAdvisoryLock only once all have released it
+func (c *PostgresClient) tryAdvisoryLockShared(ctx context.Context, key int64) (release func(), ok bool, err error) {
+	return c.tryLock(ctx, key, true)
+}
+
+func (c *PostgresClient) tryLock(ctx context.Context, key int64, shared bool) (release func(), ok bool, err error) {
+	lock, unlock := `SELECT pg_try_advisory_lock($1)`, `SELECT pg_advisory_unlock($1)`
+	if shared {
+		lock, unlock = `SELECT pg_try_advisory_lock_shared($1)`, `SELECT pg_advisory_unlock_shared($1)`
+	}
+	conn, err := c.pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	if err := conn.QueryRow(ctx, lock, key).Scan(&ok); err != nil {
+		conn.Release()
+		return nil, false, fmt.Errorf("failed to take advisory lock: %w", err)
+	}
+	if !ok {
+		conn.Release()
+		return nil, false, nil
+	}
+	return func() {
+		if _, err := conn.Exec(context.Background(), unlock, key); err != nil {
+			// Closing the connection ends the session and with it the lock
+			conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}, true, nil
+}
+
+// PurgeMessages deletes up to limit of a chatbot's messages created before cutoff and
+// returns how many were deleted. Callers repeat it until fewer than limit are deleted,
+// keeping each transaction short.
+func (c *PostgresClient) PurgeMessages(ctx context.Context, chatbotID string, cutoff time.Time, limit int) (int64, error) {
+	query := `
+        DELETE FROM messages
+        WHERE id IN (
+            SELECT id FROM messages
+            WHERE chatbot_id = $1 AND created_at < $2
+            LIMIT $3
+        )
+    `
+
+	result, err := c.pool.Exec(ctx, query, chatbotID, formatTimeForDB(cutoff), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge messages: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// PurgeConversations deletes the classifications and activity of a chatbot's
+// conversations whose last message was before cutoff, as their messages are purged
+func (c *PostgresClient) PurgeConversations(ctx context.Context, chatbotID string, cutoff time.Time) error {
+	if _, err := c.pool.Exec(ctx, `
+		DELETE FROM conversation_tags
+		WHERE chatbot_id = $1 AND last_message_at < $2`, chatbotID, formatTimeForDB(cutoff)); err != nil {
+		return fmt.Errorf("failed to purge conversation tags: %w", err)
+	}
+	if _, err := c.pool.Exec(ctx, `
+		DELETE FROM conversation_activity
+		WHERE chatbot_id = $1 AND last_message_at < $2`, chatbotID, formatTimeForDB(cutoff)); err != nil {
+		return fmt.Errorf("failed to purge conversation activity: %w", err)
+	}
+	return nil
+}
+
+// EraseClientMessages deletes or anonymizes every message of a visitor's conversation,
+// including the feedback left on them, and returns how many rows were changed. The
+// conversation's classification is deleted either way, as free-form tags may repeat
+// what the visitor wrote. Anonymized messages keep their role, time, topic, citations
+// and feedback rating for analytics; their content and feedback comment are cleared and
+// they are moved to a new conversation id that cannot be traced back to the visitor.
+func (c *PostgresClient) EraseClientMessages(ctx context.Context, chatbotID, clientID, mode, anonymousID string) (int64, error) {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var result pgconn.CommandTag
+	switch mode {
+	case ErasureDelete:
+		result, err = tx.Exec(ctx, `
+			DELETE FROM messages
+			WHERE chatbot_id = $1 AND unique_conv_id = $2`, chatbotID, clientID)
+	case ErasureAnonymize:
+		result, err = tx.Exec(ctx, `
+			UPDATE messages
+			SET content = $3, feedback_comment = NULL, unique_conv_id = $4
+			WHERE chatbot_id = $1 AND unique_conv_id = $2`, chatbotID, clientID, ErasedContent, anonymousID)
+	default:
+		return 0, fmt.Errorf("unknown erasure mode %q", mode)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to erase messages: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM conversation_tags
+		WHERE chatbot_id = $1 AND unique_conv_id = $2`, chatbotID, clientID); err != nil {
+		return 0, fmt.Errorf("failed to erase conversation tags: %w", err)
+	}
+	// Anonymized messages stay listed under their new conversation id
+	if _, err := tx.Exec(ctx, `
+		WITH erased AS (
+			DELETE FROM conversation_activity
+			WHERE chatbot_id = $1 AND unique_conv_id = $2
+			RETURNING last_message_at
+		)
+		INSERT INTO conversation_activity (chatbot_id, unique_conv_id, last_message_at)
+		SELECT $1, $3::text, last_message_at FROM erased
+		WHERE $4::boolean`, chatbotID, clientID, anonymousID, mode == ErasureAnonymize); err != nil {
+		return 0, fmt.Errorf("failed to erase conversation activity: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit erasure: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// CreateRetentionRun records the start of a purge or erasure
+func (c *PostgresClient) CreateRetentionRun(ctx context.Context, r *RetentionRunRecord) error {
+	query := `
+		INSERT INTO data_retention_runs (id, kind, chatbot_id, subject_hash, mode, requested_by, status, started_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	if _, err := c.pool.Exec(ctx, query, r.ID, r.Kind, r.ChatbotID, r.SubjectHash, r.Mode, r.RequestedBy,
+		r.Status, formatTimeForDB(r.StartedAt)); err != nil {
+		return fmt.Errorf("failed to create retention run: %w", err)
+	}
+	return nil
+}
+
+// UpdateRetentionRun stores the outcome of a purge or erasure
+func (c *PostgresClient) UpdateRetentionRun(ctx context.Context, r *RetentionRunRecord) error {
+	query := `
+		UPDATE data_retention_runs
+		SET status = $1, messages_deleted = $2, messages_anonymized = $3, details = $4,
+		    error_message = $5, finished_at = $6
+		WHERE id = $7
+	`
+
+	var details []byte
+	if r.Details != nil {
+		var err error
+		if details, err = json.Marshal(r.Details); err != nil {
+			return fmt.Errorf("failed to encode retention details: %w", err)
+		}
+	}
+	if _, err := c.pool.Exec(ctx, query, r.Status, r.MessagesDeleted, r.MessagesAnonymized, details,
+		r.ErrorMessage, formatOptionalTime(r.FinishedAt), r.ID); err != nil {
+		return fmt.Errorf("failed to update retention run: %w", err)
+	}
+	return nil
+}
+
+const retentionRunColumns = `
+	id, kind, chatbot_id, subject_hash, mode, requested_by, status, messages_deleted,
+	messages_anonymized, details, error_message, started_at, finished_at`
+
+// GetRetentionRun loads a retention run by id
+func (c *PostgresClient) GetRetentionRun(ctx context.Context, runID string) (*RetentionRunRecord, error) {
+	row := c.pool.QueryRow(ctx, `SELECT`+retentionRunColumns+` FROM data_retention_runs WHERE id = $1`, runID)
+	r, err := scanRetentionRun(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRetentionRunNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load retention run: %w", err)
+	}
+	return r, nil
+}
+
+// ListRetentionRuns returns the latest limit runs, newest first. An empty kind or
+// chatbotID matches every run.
+func (c *PostgresClient) ListRetentionRuns(ctx context.Context, kind, chatbotID string, limit int) ([]RetentionRunRecord, error) {
+	query := `SELECT` + retentionRunColumns + `
+		FROM data_retention_runs
+		WHERE ($1 = '' OR kind = $1) AND ($2 = '' OR chatbot_id = $2)
+		ORDER BY started_at DESC, id DESC
+		LIMIT $3`
+
+	rows, err := c.pool.Query(ctx, query, kind, chatbotID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list retention runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []RetentionRunRecord{}
+	for rows.Next() {
+		r, err := scanRetentionRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan retention run: %w", err)
+		}
+		runs = append(runs, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating retention runs: %w", err)
+	}
+	return runs, nil
+}
+
+func scanRetentionRun(row pgx.Row) (*RetentionRunRecord, error) {
+	var r RetentionRunRecord
+	var details []byte
+	if err := row.Scan(&r.ID, &r.Kind, &r.ChatbotID, &r.SubjectHash, &r.Mode, &r.RequestedBy, &r.Status,
+		&r.MessagesDeleted, &r.MessagesAnonymized, &details, &r.ErrorMessage, &r.StartedAt, &r.FinishedAt); err != nil {
+		return nil, err
+	}
+	if len(details) > 0 {
+		if err := json.Unmarshal(details, &r.Details); err != nil {
+			return nil, fmt.Errorf("failed to decode retention details: %w", err)
+		}
+	}
+	return &r, nil
+}
@@ -0,0 +1,44 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/Conversly/lightning-response/internal/types"
+)
+
+// GetChatbotSettings loads the per-chatbot overrides. A chatbot without a settings row
+// gets an empty ChatbotSettings so callers can fall back to service defaults.
+func (c *PostgresClient) GetChatbotSettings(ctx context.Context, chatbotID string) (*types.ChatbotSettings, error) {
+	query := `
+        SELECT context_expansion, context_neighbours, context_char_budget,
+               chunk_strategy, chunk_size, chunk_overlap, chunk_unit,
+               embedding_model, embedding_dimensions, retention_days
+        FROM chatbot_settings
+        WHERE chatbot_id = $1
+    `
+
+	settings := &types.ChatbotSettings{ChatbotID: chatbotID}
+	err := c.pool.QueryRow(ctx, query, chatbotID).Scan(
+		&settings.ContextExpansion,
+		&settings.ContextNeighbours,
+		&settings.ContextCharBudget,
+		&settings.ChunkStrategy,
+		&settings.ChunkSize,
+		&settings.ChunkOverlap,
+		&settings.ChunkUnit,
+		&settings.EmbeddingModel,
+		&settings.EmbeddingDimensions,
+		&settings.RetentionDays,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chatbot settings: %w", err)
+	}
+	return settings, nil
+}
@@ -0,0 +1,174 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/pgvector/pgvector-go"
+)
+
+var ErrTopicNotFound = errors.New("topic not found")
+
+// TopicDefinition is a chatbot topic with the text the classifier embeds for it
+type TopicDefinition struct {
+	ID          string
+	Name        string
+	Color       string
+	Description *string
+	Examples    []string // example user messages
+}
+
+// ListTopicDefinitions returns a chatbot's topics in name order
+func (c *PostgresClient) ListTopicDefinitions(ctx context.Context, chatbotID string) ([]TopicDefinition, error) {
+	query := `
+        SELECT id::text, name, COALESCE(color, ''), description, COALESCE(examples, '{}')
+        FROM chatbot_topics
+        WHERE chatbot_id = $1
+        ORDER BY name, id
+    `
+
+	rows, err := c.pool.Query(ctx, query, chatbotID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list topics: %w", err)
+	}
+	defer rows.Close()
+
+	topics := []TopicDefinition{}
+	for rows.Next() {
+		var t TopicDefinition
+		if err := rows.Scan(&t.ID, &t.Name, &t.Color, &t.Description, &t.Examples); err != nil {
+			return nil, fmt.Errorf("failed to scan topic: %w", err)
+		}
+		topics = append(topics, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating topics: %w", err)
+	}
+	return topics, nil
+}
+
+// ListRecentUserMessages returns the content of a chatbot's latest limit user messages,
+// which keyword extraction scores against
+func (c *PostgresClient) ListRecentUserMessages(ctx context.Context, chatbotID string, limit int) ([]string, error) {
+	query := `
+        SELECT content
+        FROM messages
+        WHERE chatbot_id = $1 AND "type" = 'user'
+        ORDER BY created_at DESC
+        LIMIT $2
+    `
+
+	rows, err := c.pool.Query(ctx, query, chatbotID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []string{}
+	for rows.Next() {
+		var content string
+		if err := rows.Scan(&content); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		messages = append(messages, content)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating messages: %w", err)
+	}
+	return messages, nil
+}
+
+// UpdateTopicDefinition replaces the description and examples of one of a chatbot's
+// topics
+func (c *PostgresClient) UpdateTopicDefinition(ctx context.Context, chatbotID, topicID string, description *string, examples []string) (*TopicDefinition, error) {
+	query := `
+        UPDATE chatbot_topics
+        SET description = $3, examples = $4
+        WHERE chatbot_id = $1 AND id::text = $2
+        RETURNING id::text, name, COALESCE(color, ''), description, COALESCE(examples, '{}')
+    `
+
+	var t TopicDefinition
+	err := c.pool.QueryRow(ctx, query, chatbotID, topicID, description, examples).Scan(
+		&t.ID, &t.Name, &t.Color, &t.Description, &t.Examples)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrTopicNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update topic: %w", err)
+	}
+	return &t, nil
+}
+
+// GetTopicVectors returns the stored vectors by model of the given text hashes
+func (c *PostgresClient) GetTopicVectors(ctx context.Context, chatbotID, model string, hashes []string) (map[string][]float64, error) {
+	vectors := make(map[string][]float64, len(hashes))
+	if len(hashes) == 0 {
+		return vectors, nil
+	}
+
+	query := `
+        SELECT content_hash, vector
+        FROM topic_embeddings
+        WHERE chatbot_id = $1 AND embedding_model = $2 AND content_hash = ANY($3)
+    `
+
+	rows, err := c.pool.Query(ctx, query, chatbotID, model, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query topic vectors: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			hash   string
+			stored pgvector.Vector
+		)
+		if err := rows.Scan(&hash, &stored); err != nil {
+			return nil, fmt.Errorf("failed to scan topic vector: %w", err)
+		}
+		raw := stored.Slice()
+		vec := make([]float64, len(raw))
+		for i, v := range raw {
+			vec[i] = float64(v)
+		}
+		vectors[hash] = vec
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating topic vectors: %w", err)
+	}
+	return vectors, nil
+}
+
+// StoreTopicVectors adds vectors by model keyed by text hash and removes the chatbot's
+// vectors from model whose hash is not in keep
+func (c *PostgresClient) StoreTopicVectors(ctx context.Context, chatbotID, model string, vectors map[string][]float64, keep []string) error {
+	tx, err := c.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for hash, vec := range vectors {
+		batch.Queue(`
+			INSERT INTO topic_embeddings (chatbot_id, embedding_model, content_hash, vector)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (chatbot_id, embedding_model, content_hash) DO NOTHING`,
+			chatbotID, model, hash, toPgVector(vec))
+	}
+	batch.Queue(`
+		DELETE FROM topic_embeddings
+		WHERE chatbot_id = $1 AND embedding_model = $2 AND NOT (content_hash = ANY($3))`,
+		chatbotID, model, keep)
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to store topic vectors: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit topic vectors: %w", err)
+	}
+	return nil
+}
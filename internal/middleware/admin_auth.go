@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth protects internal endpoints (ingestion, debugging, exports) that are called
+// by the dashboard backend rather than the widget. Requests must carry
+// "Authorization: Bearer <token>". An empty token rejects every request.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		provided := strings.TrimPrefix(header, "Bearer ")
+		if token == "" || provided == header || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":     "unauthorized",
+				"message":   "missing or invalid admin token",
+				"timestamp": time.Now().UTC(),
+			})
+			return
+		}
+		c.Next()
+	}
+}
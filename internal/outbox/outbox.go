@@ -0,0 +1,495 @@
+// Package outbox is a durable local queue: an append-only log split into segment files
+// that survives restarts. Producers append records and get control back once they are
+// fsynced; one consumer reads them in order and commits what it has handled, after which
+// fully consumed segments are deleted.
+package outbox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+var (
+	// ErrFull is returned by Append when the uncommitted records would exceed MaxBytes
+	ErrFull = errors.New("outbox is full")
+	// ErrClosed is returned by Append after Close
+	ErrClosed = errors.New("outbox is closed")
+)
+
+const cursorFile = "cursor"
+
+// Options size an outbox
+type Options struct {
+	// SegmentBytes is the size at which the active segment is sealed and a new one started
+	SegmentBytes int64
+	// MaxBytes caps the size of the records not yet committed. Consumed segments are
+	// deleted at the next commit, so the files can briefly exceed it.
+	MaxBytes int64
+}
+
+// Position is a place in the log: a segment and a byte offset within it
+type Position struct {
+	Segment uint64
+	Offset  int64
+}
+
+// Batch is a run of records returned by Read, to be passed to Commit once handled
+type Batch struct {
+	Records [][]byte
+	next    Position
+}
+
+// Stats describe the backlog of an outbox
+type Stats struct {
+	PendingRecords int64 // appended but not yet committed
+	PendingBytes   int64
+	DiskBytes      int64 // total size of the segment files
+	Segments       int
+}
+
+type segment struct {
+	seq  uint64
+	size int64
+}
+
+// Outbox is safe for concurrent appends and a single reader
+type Outbox struct {
+	dir  string
+	opts Options
+
+	mu       sync.Mutex
+	segments []segment // oldest first; the last one is being appended to
+	cursor   Position
+	pending  int64
+	active   *os.File
+
+	appends chan appendRequest
+	notify  chan struct{}
+	closing chan struct{}
+	done    chan struct{}
+	once    sync.Once
+}
+
+type appendRequest struct {
+	records [][]byte
+	done    chan error
+}
+
+// Open opens the outbox in dir, creating it if needed. A record torn by a crash at the
+// end of the log is discarded.
+func Open(dir string, opts Options) (*Outbox, error) {
+	if opts.SegmentBytes <= 0 {
+		opts.SegmentBytes = 64 << 20
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 1 << 30
+	}
+	if opts.MaxBytes < opts.SegmentBytes {
+		opts.SegmentBytes = opts.MaxBytes
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+
+	o := &Outbox{
+		dir:     dir,
+		opts:    opts,
+		appends: make(chan appendRequest),
+		notify:  make(chan struct{}, 1),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := o.recover(); err != nil {
+		if o.active != nil {
+			o.active.Close()
+		}
+		return nil, err
+	}
+	go o.writer()
+	return o, nil
+}
+
+// recover loads the segments and cursor, trims a torn tail and counts the backlog
+func (o *Outbox) recover() error {
+	seqs, err := listSegments(o.dir)
+	if err != nil {
+		return fmt.Errorf("failed to list outbox segments: %w", err)
+	}
+	for _, seq := range seqs {
+		info, err := os.Stat(filepath.Join(o.dir, segmentName(seq)))
+		if err != nil {
+			return fmt.Errorf("failed to stat outbox segment: %w", err)
+		}
+		o.segments = append(o.segments, segment{seq: seq, size: info.Size()})
+	}
+
+	if len(o.segments) == 0 {
+		o.segments = []segment{{seq: 1}}
+	} else {
+		last := &o.segments[len(o.segments)-1]
+		_, end, err := scanSegment(o.dir, last.seq, 0, last.size)
+		if err != nil {
+			return fmt.Errorf("failed to scan outbox segment: %w", err)
+		}
+		if end < last.size {
+			utils.Zlog.Warn("Discarding torn outbox tail",
+				zap.String("segment", segmentName(last.seq)),
+				zap.Int64("bytes", last.size-end))
+			if err := os.Truncate(filepath.Join(o.dir, segmentName(last.seq)), end); err != nil {
+				return fmt.Errorf("failed to truncate outbox segment: %w", err)
+			}
+			last.size = end
+		}
+	}
+
+	last := o.segments[len(o.segments)-1]
+	f, err := os.OpenFile(filepath.Join(o.dir, segmentName(last.seq)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open outbox segment: %w", err)
+	}
+	o.active = f
+	if err := syncDir(o.dir); err != nil {
+		return err
+	}
+
+	// Unsynced bytes read before a power loss can leave the cursor past the end of the log
+	o.cursor = o.readCursor()
+	first := o.segments[0]
+	switch {
+	case o.cursor.Segment < first.seq:
+		o.cursor = Position{Segment: first.seq}
+	case o.cursor.Segment > last.seq || (o.cursor.Segment == last.seq && o.cursor.Offset > last.size):
+		o.cursor = Position{Segment: last.seq, Offset: last.size}
+	}
+	for _, s := range o.segments {
+		if s.seq < o.cursor.Segment {
+			continue
+		}
+		from := int64(0)
+		if s.seq == o.cursor.Segment {
+			from = o.cursor.Offset
+		}
+		n, _, err := scanSegment(o.dir, s.seq, from, s.size)
+		if err != nil {
+			return fmt.Errorf("failed to scan outbox segment: %w", err)
+		}
+		o.pending += n
+	}
+	return nil
+}
+
+// readCursor returns the committed position, or the start of the log if none was saved
+func (o *Outbox) readCursor() Position {
+	data, err := os.ReadFile(filepath.Join(o.dir, cursorFile))
+	if err != nil {
+		return Position{}
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return Position{}
+	}
+	seg, err1 := strconv.ParseUint(fields[0], 10, 64)
+	off, err2 := strconv.ParseInt(fields[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		return Position{}
+	}
+	return Position{Segment: seg, Offset: off}
+}
+
+// Append writes records to the log and returns once they are on disk. The records of
+// one call are accepted or rejected together.
+func (o *Outbox) Append(records ...[]byte) error {
+	for _, r := range records {
+		if len(r) > maxRecordSize {
+			return fmt.Errorf("outbox record of %d bytes exceeds the %d byte limit", len(r), maxRecordSize)
+		}
+	}
+	req := appendRequest{records: records, done: make(chan error, 1)}
+	select {
+	case o.appends <- req:
+		return <-req.done
+	case <-o.closing:
+		return ErrClosed
+	}
+}
+
+// writer serialises appends. Requests that queue up while a write is being synced are
+// written together and share one fsync.
+func (o *Outbox) writer() {
+	defer close(o.done)
+	for {
+		select {
+		case req := <-o.appends:
+			reqs := []appendRequest{req}
+		collect:
+			for len(reqs) < 1024 {
+				select {
+				case r := <-o.appends:
+					reqs = append(reqs, r)
+				default:
+					break collect
+				}
+			}
+			o.write(reqs)
+		case <-o.closing:
+			return
+		}
+	}
+}
+
+func (o *Outbox) write(reqs []appendRequest) {
+	errs := make([]error, len(reqs))
+	written := 0
+	var buf []byte
+
+	o.mu.Lock()
+	for i, req := range reqs {
+		buf = buf[:0]
+		for _, r := range req.records {
+			buf = encodeRecord(buf, r)
+		}
+		size := int64(len(buf))
+		// Rotate before checking the budget so a fully consumed active segment does
+		// not hold it; the old segment is deleted at the next commit
+		last := &o.segments[len(o.segments)-1]
+		if last.size > 0 && last.size+size > o.opts.SegmentBytes {
+			if err := o.rotateLocked(); err != nil {
+				errs[i] = err
+				continue
+			}
+			last = &o.segments[len(o.segments)-1]
+		}
+		if o.pendingBytesLocked()+size > o.opts.MaxBytes {
+			errs[i] = ErrFull
+			continue
+		}
+		if _, err := o.active.Write(buf); err != nil {
+			// Drop whatever part of the records reached the file
+			o.active.Truncate(last.size)
+			errs[i] = fmt.Errorf("failed to write outbox segment: %w", err)
+			continue
+		}
+		last.size += size
+		o.pending += int64(len(req.records))
+		written++
+	}
+	active := o.active
+	o.mu.Unlock()
+
+	// Only this goroutine rotates, so active cannot be closed under the sync
+	if written > 0 {
+		if err := active.Sync(); err != nil {
+			err = fmt.Errorf("failed to sync outbox segment: %w", err)
+			for i := range errs {
+				if errs[i] == nil {
+					errs[i] = err
+				}
+			}
+		}
+		select {
+		case o.notify <- struct{}{}:
+		default:
+		}
+	}
+	for i, req := range reqs {
+		req.done <- errs[i]
+	}
+}
+
+// rotateLocked seals the active segment and starts the next one
+func (o *Outbox) rotateLocked() error {
+	if err := o.active.Sync(); err != nil {
+		return fmt.Errorf("failed to sync outbox segment: %w", err)
+	}
+	seq := o.segments[len(o.segments)-1].seq + 1
+	f, err := os.OpenFile(filepath.Join(o.dir, segmentName(seq)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create outbox segment: %w", err)
+	}
+	if err := syncDir(o.dir); err != nil {
+		f.Close()
+		return err
+	}
+	o.active.Close()
+	o.active = f
+	o.segments = append(o.segments, segment{seq: seq})
+	return nil
+}
+
+// pendingBytesLocked is the size of the records not yet committed
+func (o *Outbox) pendingBytesLocked() int64 {
+	var total int64
+	for _, s := range o.segments {
+		switch {
+		case s.seq > o.cursor.Segment:
+			total += s.size
+		case s.seq == o.cursor.Segment:
+			total += max(s.size-o.cursor.Offset, 0)
+		}
+	}
+	return total
+}
+
+// Notify is signalled after records are appended
+func (o *Outbox) Notify() <-chan struct{} {
+	return o.notify
+}
+
+// Read returns up to max records following the last commit, oldest first. Reading again
+// without a commit returns the same records. A corrupt record in a sealed segment makes
+// the rest of that segment unreadable; it is skipped and logged.
+func (o *Outbox) Read(max int) (*Batch, error) {
+	o.mu.Lock()
+	segments := append([]segment(nil), o.segments...)
+	pos := o.cursor
+	o.mu.Unlock()
+
+	batch := &Batch{next: pos}
+	for i, s := range segments {
+		if s.seq < pos.Segment {
+			continue
+		}
+		from := int64(0)
+		if s.seq == pos.Segment {
+			from = pos.Offset
+		}
+		sealed := i < len(segments)-1
+		if from >= s.size {
+			if sealed {
+				batch.next = Position{Segment: segments[i+1].seq}
+				continue
+			}
+			break
+		}
+
+		r, err := openSegmentReader(o.dir, s.seq, from, s.size)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open outbox segment: %w", err)
+		}
+		for len(batch.Records) < max {
+			rec, err := r.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				utils.Zlog.Error("Skipping corrupt outbox segment remainder",
+					zap.String("segment", segmentName(s.seq)),
+					zap.Int64("offset", r.offset),
+					zap.Int64("bytes", s.size-r.offset))
+				r.offset = s.size
+				break
+			}
+			batch.Records = append(batch.Records, rec)
+		}
+		batch.next = Position{Segment: s.seq, Offset: r.offset}
+		r.close()
+
+		if len(batch.Records) >= max {
+			break
+		}
+		if sealed && r.offset >= s.size {
+			batch.next = Position{Segment: segments[i+1].seq}
+		}
+	}
+	return batch, nil
+}
+
+// Commit marks the records of b as handled and deletes segments no longer needed
+func (o *Outbox) Commit(b *Batch) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	tmp := filepath.Join(o.dir, cursorFile+".tmp")
+	data := fmt.Sprintf("%d %d\n", b.next.Segment, b.next.Offset)
+	if err := writeFileSync(tmp, []byte(data)); err != nil {
+		return fmt.Errorf("failed to write outbox cursor: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(o.dir, cursorFile)); err != nil {
+		return fmt.Errorf("failed to save outbox cursor: %w", err)
+	}
+	o.cursor = b.next
+	o.pending -= int64(len(b.Records))
+	if o.pending < 0 {
+		o.pending = 0
+	}
+
+	kept := o.segments[:0]
+	for i, s := range o.segments {
+		if s.seq < o.cursor.Segment && i < len(o.segments)-1 {
+			if err := os.Remove(filepath.Join(o.dir, segmentName(s.seq))); err != nil && !os.IsNotExist(err) {
+				utils.Zlog.Warn("Failed to delete consumed outbox segment",
+					zap.String("segment", segmentName(s.seq)),
+					zap.Error(err))
+			}
+			continue
+		}
+		kept = append(kept, s)
+	}
+	o.segments = kept
+	return nil
+}
+
+// Stats reports the current backlog
+func (o *Outbox) Stats() Stats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	st := Stats{PendingRecords: o.pending, PendingBytes: o.pendingBytesLocked(), Segments: len(o.segments)}
+	for _, s := range o.segments {
+		st.DiskBytes += s.size
+	}
+	return st
+}
+
+// Close stops accepting appends and closes the active segment. Appended records stay
+// on disk for the next Open.
+func (o *Outbox) Close() error {
+	var err error
+	o.once.Do(func() {
+		close(o.closing)
+		<-o.done
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		err = o.active.Close()
+	})
+	return err
+}
+
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir makes file creations and renames in dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to open outbox directory: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync outbox directory: %w", err)
+	}
+	return nil
+}
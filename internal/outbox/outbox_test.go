@@ -0,0 +1,207 @@
+package outbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	utils.Zlog = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+func open(t *testing.T, dir string, opts Options) *Outbox {
+	t.Helper()
+	o, err := Open(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { o.Close() })
+	return o
+}
+
+func appendRecords(t *testing.T, o *Outbox, records ...string) {
+	t.Helper()
+	for _, r := range records {
+		if err := o.Append([]byte(r)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// readAll reads every pending record without committing
+func readAll(t *testing.T, o *Outbox) (*Batch, []string) {
+	t.Helper()
+	b, err := o.Read(1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, len(b.Records))
+	for i, r := range b.Records {
+		got[i] = string(r)
+	}
+	return b, got
+}
+
+func segmentPath(t *testing.T, dir string, i int) string {
+	t.Helper()
+	seqs, err := listSegments(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i >= len(seqs) {
+		t.Fatalf("want segment %d, have %d segments", i, len(seqs))
+	}
+	return filepath.Join(dir, segmentName(seqs[i]))
+}
+
+// corrupt flips the last byte of the record at offset, which is part of its payload
+func corrupt(t *testing.T, path string, offset int64, payload string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[offset+headerSize+int64(len(payload))-1] ^= 0xff
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTruncatedLastRecord(t *testing.T) {
+	dir := t.TempDir()
+	o := open(t, dir, Options{})
+	appendRecords(t, o, "one", "two", "three")
+	o.Close()
+
+	// A crash mid-write leaves the last record cut short
+	path := segmentPath(t, dir, 0)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(path, info.Size()-2); err != nil {
+		t.Fatal(err)
+	}
+
+	o = open(t, dir, Options{})
+	if st := o.Stats(); st.PendingRecords != 2 {
+		t.Errorf("pending = %d, want 2", st.PendingRecords)
+	}
+	// Records appended after the torn one must be readable
+	appendRecords(t, o, "four")
+	if _, got := readAll(t, o); fmt.Sprint(got) != "[one two four]" {
+		t.Errorf("read %v, want [one two four]", got)
+	}
+}
+
+func TestCorruptChecksum(t *testing.T) {
+	dir := t.TempDir()
+	o := open(t, dir, Options{})
+	appendRecords(t, o, "one", "two", "three")
+	o.Close()
+
+	// A record failing its checksum at the end of the log is a torn write
+	corrupt(t, segmentPath(t, dir, 0), 2*headerSize+int64(len("one")+len("two")), "three")
+
+	o = open(t, dir, Options{})
+	appendRecords(t, o, "four")
+	if _, got := readAll(t, o); fmt.Sprint(got) != "[one two four]" {
+		t.Errorf("read %v, want [one two four]", got)
+	}
+}
+
+func TestCorruptSealedSegment(t *testing.T) {
+	dir := t.TempDir()
+	// Each segment holds two records
+	o := open(t, dir, Options{SegmentBytes: 2 * (headerSize + 2)})
+	appendRecords(t, o, "r1", "r2", "r3", "r4", "r5")
+	o.Close()
+
+	// The rest of a sealed segment is skipped past a corrupt record
+	corrupt(t, segmentPath(t, dir, 0), headerSize+2, "r2")
+
+	o = open(t, dir, Options{SegmentBytes: 2 * (headerSize + 2)})
+	b, got := readAll(t, o)
+	if fmt.Sprint(got) != "[r1 r3 r4 r5]" {
+		t.Errorf("read %v, want [r1 r3 r4 r5]", got)
+	}
+	if err := o.Commit(b); err != nil {
+		t.Fatal(err)
+	}
+	if _, got := readAll(t, o); len(got) != 0 {
+		t.Errorf("read %v after commit, want nothing", got)
+	}
+}
+
+func TestReopenAfterPartialCommit(t *testing.T) {
+	dir := t.TempDir()
+	opts := Options{SegmentBytes: 2 * (headerSize + 2)}
+	o := open(t, dir, opts)
+	appendRecords(t, o, "r1", "r2", "r3", "r4", "r5")
+
+	b, err := o.Read(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Commit(b); err != nil {
+		t.Fatal(err)
+	}
+	// Read again without committing: these must be replayed after the restart
+	if _, err := o.Read(1); err != nil {
+		t.Fatal(err)
+	}
+	o.Close()
+
+	o = open(t, dir, opts)
+	if st := o.Stats(); st.PendingRecords != 2 {
+		t.Errorf("pending = %d, want 2", st.PendingRecords)
+	}
+	b, got := readAll(t, o)
+	if fmt.Sprint(got) != "[r4 r5]" {
+		t.Errorf("read %v, want [r4 r5]", got)
+	}
+	if err := o.Commit(b); err != nil {
+		t.Fatal(err)
+	}
+	// Consumed segments are deleted, the active one is kept
+	if seqs, _ := listSegments(dir); len(seqs) != 1 {
+		t.Errorf("%d segments left, want 1", len(seqs))
+	}
+	if st := o.Stats(); st.PendingRecords != 0 || st.PendingBytes != 0 {
+		t.Errorf("stats = %+v, want an empty backlog", st)
+	}
+}
+
+func TestAppendAfterDrainingFullOutbox(t *testing.T) {
+	dir := t.TempDir()
+	// The budget holds two records, and so does the single segment it allows
+	const record = headerSize + 2
+	o := open(t, dir, Options{SegmentBytes: 4 * record, MaxBytes: 2 * record})
+	appendRecords(t, o, "r1", "r2")
+	if err := o.Append([]byte("r3")); !errors.Is(err, ErrFull) {
+		t.Fatalf("append to a full outbox: err = %v, want ErrFull", err)
+	}
+
+	for round := 0; round < 3; round++ {
+		b, got := readAll(t, o)
+		if len(got) != 2 {
+			t.Fatalf("round %d: read %v, want two records", round, got)
+		}
+		if err := o.Commit(b); err != nil {
+			t.Fatal(err)
+		}
+		// Drained records no longer count against the budget
+		appendRecords(t, o, fmt.Sprintf("a%d", round), fmt.Sprintf("b%d", round))
+	}
+	if _, got := readAll(t, o); fmt.Sprint(got) != "[a2 b2]" {
+		t.Errorf("read %v, want [a2 b2]", got)
+	}
+}
@@ -0,0 +1,129 @@
+package outbox
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Records are framed as a little-endian uint32 payload length, a CRC-32C of the
+// payload, then the payload
+const (
+	headerSize    = 8
+	maxRecordSize = 16 << 20
+	segmentExt    = ".seg"
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errTorn marks a record cut short or failing its checksum, as left by a crash mid-write
+var errTorn = errors.New("torn record")
+
+func segmentName(seq uint64) string {
+	return fmt.Sprintf("%020d%s", seq, segmentExt)
+}
+
+// listSegments returns the sequence numbers of the segment files in dir, oldest first
+func listSegments(dir string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var seqs []uint64
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	return seqs, nil
+}
+
+func encodeRecord(buf []byte, payload []byte) []byte {
+	var header [headerSize]byte
+	binary.LittleEndian.PutUint32(header[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(header[4:8], crc32.Checksum(payload, crcTable))
+	buf = append(buf, header[:]...)
+	return append(buf, payload...)
+}
+
+// segmentReader reads records from one segment starting at an offset
+type segmentReader struct {
+	f      *os.File
+	r      *bufio.Reader
+	offset int64
+	limit  int64 // bytes of the segment known to be written
+}
+
+func openSegmentReader(dir string, seq uint64, offset, limit int64) (*segmentReader, error) {
+	f, err := os.Open(filepath.Join(dir, segmentName(seq)))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &segmentReader{f: f, r: bufio.NewReaderSize(f, 64<<10), offset: offset, limit: limit}, nil
+}
+
+// next returns the following record, io.EOF at the end of the written bytes, or errTorn
+func (s *segmentReader) next() ([]byte, error) {
+	if s.offset >= s.limit {
+		return nil, io.EOF
+	}
+	if s.limit-s.offset < headerSize {
+		return nil, errTorn
+	}
+	var header [headerSize]byte
+	if _, err := io.ReadFull(s.r, header[:]); err != nil {
+		return nil, errTorn
+	}
+	size := int64(binary.LittleEndian.Uint32(header[0:4]))
+	if size > maxRecordSize || s.offset+headerSize+size > s.limit {
+		return nil, errTorn
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(s.r, payload); err != nil {
+		return nil, errTorn
+	}
+	if crc32.Checksum(payload, crcTable) != binary.LittleEndian.Uint32(header[4:8]) {
+		return nil, errTorn
+	}
+	s.offset += headerSize + size
+	return payload, nil
+}
+
+func (s *segmentReader) close() error {
+	return s.f.Close()
+}
+
+// scanSegment counts the valid records of a segment from offset and returns the offset
+// just past the last of them
+func scanSegment(dir string, seq uint64, offset, size int64) (records int64, end int64, err error) {
+	r, err := openSegmentReader(dir, seq, offset, size)
+	if err != nil {
+		return 0, offset, err
+	}
+	defer r.close()
+	for {
+		if _, err := r.next(); err != nil {
+			return records, r.offset, nil
+		}
+		records++
+	}
+}
@@ -0,0 +1,66 @@
+package parsers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CSVParser renders each row as "column: value" pairs on its own line, so a row stays
+// self-describing after splitting. The first row is taken as the header.
+type CSVParser struct{}
+
+func (CSVParser) Parse(content []byte) (*Document, error) {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	r := csv.NewReader(bytes.NewReader(content))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	header, err := r.Read()
+	if errors.Is(err, io.EOF) {
+		return &Document{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	for i, h := range header {
+		header[i] = strings.TrimSpace(h)
+	}
+
+	var b strings.Builder
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV row: %w", err)
+		}
+
+		pairs := make([]string, 0, len(record))
+		for i, v := range record {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				continue
+			}
+			col := fmt.Sprintf("column %d", i+1)
+			if i < len(header) && header[i] != "" {
+				col = header[i]
+			}
+			pairs = append(pairs, col+": "+v)
+		}
+		if len(pairs) > 0 {
+			b.WriteString(strings.Join(pairs, "; "))
+			b.WriteString("\n")
+		}
+	}
+
+	text := strings.TrimSpace(b.String())
+	if text == "" {
+		return &Document{}, nil
+	}
+	return &Document{Segments: []Segment{{Text: text}}}, nil
+}
@@ -0,0 +1,165 @@
+package parsers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DOCXParser extracts paragraphs from word/document.xml, using Heading/Title paragraph
+// styles (or outline levels) to build heading paths
+type DOCXParser struct{}
+
+func (DOCXParser) Parse(content []byte) (*Document, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DOCX archive: %w", err)
+	}
+
+	var body, core *zip.File
+	for _, f := range zr.File {
+		switch f.Name {
+		case "word/document.xml":
+			body = f
+		case "docProps/core.xml":
+			core = f
+		}
+	}
+	if body == nil {
+		return nil, fmt.Errorf("DOCX archive has no word/document.xml")
+	}
+
+	doc := &Document{}
+	if core != nil {
+		doc.Title = docxTitle(core)
+	}
+
+	rc, err := body.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document.xml: %w", err)
+	}
+	defer rc.Close()
+
+	if err := parseDocxBody(rc, doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+func parseDocxBody(r io.Reader, doc *Document) error {
+	dec := xml.NewDecoder(r)
+
+	var (
+		stack   []string
+		current strings.Builder
+		para    strings.Builder
+		level   int // heading level of the current paragraph, 0 for body text
+		inText  bool
+	)
+
+	flush := func() {
+		if text := collapseBlankLines(current.String()); text != "" {
+			doc.Segments = append(doc.Segments, Segment{Text: text, HeadingPath: headingPath(stack)})
+		}
+		current.Reset()
+	}
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse document.xml: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				para.Reset()
+				level = 0
+			case "pStyle":
+				level = docxHeadingLevel(attr(t, "val"))
+			case "outlineLvl":
+				if n, err := strconv.Atoi(attr(t, "val")); err == nil && n < 9 && level == 0 {
+					level = n + 1
+				}
+			case "t":
+				inText = true
+			case "tab":
+				para.WriteString("\t")
+			case "br", "cr":
+				para.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text := strings.TrimSpace(para.String())
+				if text == "" {
+					continue
+				}
+				if level > 0 {
+					flush()
+					stack = appendHeading(stack, level, text)
+					continue
+				}
+				current.WriteString(text)
+				current.WriteString("\n")
+			case "tbl":
+				current.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				para.Write(t)
+			}
+		}
+	}
+	flush()
+	return nil
+}
+
+// docxHeadingLevel maps built-in paragraph style IDs to heading levels
+func docxHeadingLevel(style string) int {
+	s := strings.ToLower(style)
+	if s == "title" {
+		return 1
+	}
+	if strings.HasPrefix(s, "heading") {
+		if n, err := strconv.Atoi(strings.TrimPrefix(s, "heading")); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+func docxTitle(f *zip.File) string {
+	rc, err := f.Open()
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+
+	var props struct {
+		Title string `xml:"title"`
+	}
+	if err := xml.NewDecoder(rc).Decode(&props); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(props.Title)
+}
+
+func attr(el xml.StartElement, local string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
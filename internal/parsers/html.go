@@ -0,0 +1,228 @@
+package parsers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLParser extracts the main content of a page, dropping navigation, headers, footers
+// and other boilerplate, and splits it into segments at headings
+type HTMLParser struct{}
+
+// boilerplate elements never contain article content
+var boilerplateAtoms = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Nav: true,
+	atom.Header: true, atom.Footer: true, atom.Aside: true, atom.Form: true,
+	atom.Svg: true, atom.Iframe: true, atom.Template: true, atom.Button: true,
+	atom.Select: true, atom.Head: true,
+}
+
+var boilerplateRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true, "complementary": true,
+	"search": true, "dialog": true,
+}
+
+// boilerplateHints are class/id names used by common themes for non-content blocks. A
+// hint matches a whole class token or its first or last dash/underscore-separated part
+// ("toc-list", "site_sidebar"), never a substring, so "stock" does not match "toc".
+var boilerplateHints = []string{"cookie", "sidebar", "breadcrumb", "navbar", "menu", "toc", "skip-link"}
+
+var blockAtoms = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Table: true, atom.Tr: true,
+	atom.Pre: true, atom.Blockquote: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Figure: true, atom.Figcaption: true, atom.Hr: true,
+}
+
+var headingLevels = map[atom.Atom]int{
+	atom.H1: 1, atom.H2: 2, atom.H3: 3, atom.H4: 4, atom.H5: 5, atom.H6: 6,
+}
+
+func (HTMLParser) Parse(content []byte) (*Document, error) {
+	root, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	doc := &Document{}
+	if t := findFirst(root, atom.Title); t != nil {
+		doc.Title = collapseSpaces(textContent(t))
+	}
+	if doc.Title == "" {
+		if h1 := findFirst(root, atom.H1); h1 != nil {
+			doc.Title = collapseSpaces(textContent(h1))
+		}
+	}
+
+	w := &htmlWalker{doc: doc}
+	w.walk(mainContent(root), false)
+	w.flush()
+	return doc, nil
+}
+
+// mainContent returns the element most likely to hold the page's primary content
+func mainContent(root *html.Node) *html.Node {
+	for _, a := range []atom.Atom{atom.Main, atom.Article} {
+		if n := findFirst(root, a); n != nil {
+			return n
+		}
+	}
+	if n := findByRole(root, "main"); n != nil {
+		return n
+	}
+	if n := findFirst(root, atom.Body); n != nil {
+		return n
+	}
+	return root
+}
+
+type htmlWalker struct {
+	doc     *Document
+	stack   []string
+	current strings.Builder
+}
+
+func (w *htmlWalker) walk(n *html.Node, pre bool) {
+	switch n.Type {
+	case html.TextNode:
+		if pre {
+			w.current.WriteString(n.Data)
+		} else if text := collapseSpaces(n.Data); text != "" {
+			if last := w.current.String(); last != "" && !strings.HasSuffix(last, "\n") && !strings.HasSuffix(last, " ") {
+				w.current.WriteString(" ")
+			}
+			w.current.WriteString(text)
+		}
+		return
+	case html.ElementNode:
+		if isBoilerplate(n) {
+			return
+		}
+		if level, ok := headingLevels[n.DataAtom]; ok {
+			if heading := collapseSpaces(textContent(n)); heading != "" {
+				w.flush()
+				w.stack = appendHeading(w.stack, level, heading)
+			}
+			return
+		}
+		if n.DataAtom == atom.Br {
+			w.current.WriteString("\n")
+			return
+		}
+		if n.DataAtom == atom.Pre {
+			pre = true
+		}
+	}
+
+	block := n.Type == html.ElementNode && blockAtoms[n.DataAtom]
+	if block {
+		w.current.WriteString("\n")
+		if n.DataAtom == atom.Li {
+			w.current.WriteString("- ")
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.walk(c, pre)
+	}
+	if block {
+		w.current.WriteString("\n")
+	}
+}
+
+func (w *htmlWalker) flush() {
+	if text := collapseBlankLines(w.current.String()); text != "" {
+		w.doc.Segments = append(w.doc.Segments, Segment{Text: text, HeadingPath: headingPath(w.stack)})
+	}
+	w.current.Reset()
+}
+
+func isBoilerplate(n *html.Node) bool {
+	if boilerplateAtoms[n.DataAtom] {
+		return true
+	}
+	for _, a := range n.Attr {
+		switch a.Key {
+		case "role":
+			if boilerplateRoles[strings.ToLower(a.Val)] {
+				return true
+			}
+		case "aria-hidden", "hidden":
+			if a.Key == "hidden" || a.Val == "true" {
+				return true
+			}
+		case "class", "id":
+			for _, token := range strings.Fields(strings.ToLower(a.Val)) {
+				if isBoilerplateToken(token) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func isBoilerplateToken(token string) bool {
+	for _, hint := range boilerplateHints {
+		if token == hint {
+			return true
+		}
+		for _, sep := range []string{"-", "_"} {
+			if strings.HasPrefix(token, hint+sep) || strings.HasSuffix(token, sep+hint) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findFirst(c, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func findByRole(n *html.Node, role string) *html.Node {
+	if n.Type == html.ElementNode {
+		for _, a := range n.Attr {
+			if a.Key == "role" && strings.EqualFold(a.Val, role) {
+				return n
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findByRole(c, role); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(n)
+	return b.String()
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,113 @@
+package parsers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MarkdownParser splits Markdown into segments at ATX and setext headings, keeping the
+// heading hierarchy as the segment's path. Fenced code blocks are kept verbatim and
+// never treated as headings; a YAML front matter "title" becomes the document title.
+type MarkdownParser struct{}
+
+var (
+	atxHeadingRe = regexp.MustCompile(`^ {0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+	setextH1Re   = regexp.MustCompile(`^ {0,3}=+\s*$`)
+	setextH2Re   = regexp.MustCompile(`^ {0,3}-+\s*$`)
+	fenceRe      = regexp.MustCompile("^ {0,3}(```|~~~)")
+	frontTitleRe = regexp.MustCompile(`^title:\s*["']?(.*?)["']?\s*$`)
+)
+
+func (MarkdownParser) Parse(content []byte) (*Document, error) {
+	if !utf8.Valid(content) {
+		return nil, fmt.Errorf("document is not valid UTF-8 text")
+	}
+
+	doc := &Document{}
+	lines := strings.Split(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	lines = stripFrontMatter(lines, doc)
+
+	var (
+		stack   []string
+		current []string
+		fence   string
+	)
+
+	flush := func() {
+		if text := collapseBlankLines(strings.Join(current, "\n")); text != "" {
+			doc.Segments = append(doc.Segments, Segment{Text: text, HeadingPath: headingPath(stack)})
+		}
+		current = current[:0]
+	}
+	heading := func(level int, text string) {
+		flush()
+		stack = appendHeading(stack, level, text)
+		if doc.Title == "" && level == 1 {
+			doc.Title = text
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "":
+				fence = m[1]
+			case fence == m[1]:
+				fence = ""
+			}
+			current = append(current, line)
+			continue
+		}
+		if fence != "" {
+			current = append(current, line)
+			continue
+		}
+
+		if m := atxHeadingRe.FindStringSubmatch(line); m != nil {
+			heading(len(m[1]), m[2])
+			continue
+		}
+
+		// setext: a text line underlined with === or ---
+		if strings.TrimSpace(line) != "" && i+1 < len(lines) {
+			next := lines[i+1]
+			if setextH1Re.MatchString(next) {
+				heading(1, strings.TrimSpace(line))
+				i++
+				continue
+			}
+			if setextH2Re.MatchString(next) && (i == 0 || strings.TrimSpace(lines[i-1]) == "") {
+				heading(2, strings.TrimSpace(line))
+				i++
+				continue
+			}
+		}
+
+		current = append(current, line)
+	}
+	flush()
+
+	return doc, nil
+}
+
+// stripFrontMatter removes a leading YAML front matter block, capturing its title
+func stripFrontMatter(lines []string, doc *Document) []string {
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return lines
+	}
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			for _, l := range lines[1:i] {
+				if m := frontTitleRe.FindStringSubmatch(strings.TrimSpace(l)); m != nil {
+					doc.Title = m[1]
+				}
+			}
+			return lines[i+1:]
+		}
+	}
+	return lines
+}
@@ -0,0 +1,174 @@
+package parsers
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+// Segment is a piece of a document that shares one set of citation metadata
+type Segment struct {
+	Text        string
+	Page        int      // 1-based page number for paged formats, 0 otherwise
+	HeadingPath []string // enclosing headings, outermost first
+}
+
+// Document is the parsed, format-independent form of a data source
+type Document struct {
+	Title    string
+	Segments []Segment
+}
+
+// Parser extracts text and structure from one file format
+type Parser interface {
+	Parse(content []byte) (*Document, error)
+}
+
+// Parse picks a parser from the file's content type and parses it. When the content
+// type is missing or generic, the filename extension and content sniffing are used.
+func Parse(file *utils.DownloadedFile) (*Document, error) {
+	p, err := ForContentType(file.ContentType, file.Filename, file.Content)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := p.Parse(file.Content)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Title == "" {
+		doc.Title = strings.TrimSuffix(file.Filename, path.Ext(file.Filename))
+	}
+	return doc, nil
+}
+
+// ForContentType returns the parser for a MIME type, falling back to the filename
+// extension and then to sniffing the first bytes of content
+func ForContentType(contentType, filename string, content []byte) (Parser, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if p := byMediaType(mediaType); p != nil {
+		return p, nil
+	}
+
+	if ext := strings.ToLower(path.Ext(filename)); ext != "" {
+		// TypeByExtension includes parameters, e.g. "text/html; charset=utf-8"
+		byExt, _, _ := mime.ParseMediaType(mime.TypeByExtension(ext))
+		if p := byMediaType(byExt); p != nil {
+			return p, nil
+		}
+		switch ext {
+		case ".md", ".markdown":
+			return MarkdownParser{}, nil
+		case ".docx":
+			return DOCXParser{}, nil
+		case ".csv":
+			return CSVParser{}, nil
+		}
+	}
+
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(content))
+	if p := byMediaType(sniffed); p != nil {
+		return p, nil
+	}
+
+	return nil, fmt.Errorf("unsupported content type: %q", contentType)
+}
+
+func byMediaType(mediaType string) Parser {
+	switch strings.ToLower(mediaType) {
+	case "application/pdf":
+		return PDFParser{}
+	case "application/vnd.openxmlformats-officedocument.wordprocessingml.document":
+		return DOCXParser{}
+	case "text/html", "application/xhtml+xml":
+		return HTMLParser{}
+	case "text/markdown", "text/x-markdown":
+		return MarkdownParser{}
+	case "text/csv", "application/csv":
+		return CSVParser{}
+	case "text/plain":
+		return TextParser{}
+	}
+	return nil
+}
+
+// Citation builds the citation for a segment of a document found at source. Pages are
+// addressed with the standard #page= fragment and headings with their slug, which is
+// the anchor most documentation generators emit.
+func (s Segment) Citation(source string) string {
+	if source == "" {
+		return ""
+	}
+	base := source
+	if u, err := url.Parse(source); err == nil && u.Fragment != "" {
+		u.Fragment = ""
+		base = u.String()
+	}
+
+	switch {
+	case s.Page > 0:
+		return base + "#page=" + strconv.Itoa(s.Page)
+	case len(s.HeadingPath) > 0 && isURL(source):
+		return base + "#" + Slugify(s.HeadingPath[len(s.HeadingPath)-1])
+	default:
+		return source
+	}
+}
+
+// Section returns the heading path joined for storage, or "" when there are no headings
+func (s Segment) Section() string {
+	return strings.Join(s.HeadingPath, " > ")
+}
+
+// Slugify converts a heading to a GitHub-style anchor
+func Slugify(heading string) string {
+	var b strings.Builder
+	lastDash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(heading)) {
+		switch {
+		case r == ' ' || r == '-' || r == '_':
+			if !lastDash && b.Len() > 0 {
+				b.WriteRune('-')
+				lastDash = true
+			}
+		case isWordRune(r):
+			b.WriteRune(r)
+			lastDash = false
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+// appendHeading updates a heading stack for a heading of the given level (1-6)
+func appendHeading(stack []string, level int, text string) []string {
+	if level < 1 {
+		level = 1
+	}
+	if len(stack) >= level {
+		stack = stack[:level-1]
+	}
+	for len(stack) < level-1 {
+		stack = append(stack, "")
+	}
+	return append(stack, text)
+}
+
+// headingPath returns a copy of the heading stack without placeholder levels
+func headingPath(stack []string) []string {
+	out := make([]string, 0, len(stack))
+	for _, h := range stack {
+		if h != "" {
+			out = append(out, h)
+		}
+	}
+	return out
+}
@@ -0,0 +1,329 @@
+package parsers
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+func TestMarkdownParser(t *testing.T) {
+	src := "---\ntitle: \"Widget guide\"\nauthor: docs\n---\n" +
+		"Intro text.\n\n" +
+		"# Install\n\nAdd the script tag.\n\n\n\nThen reload.\n\n" +
+		"## Domains\n\nList every domain.\n\n" +
+		"```\n# not a heading\n```\n\n" +
+		"Keys\n----\n\nRotate them monthly.\n\n" +
+		"#### Deep\n\nSkipped levels.\n\n" +
+		"Billing\n=======\n\nSent monthly.\n"
+
+	doc, err := MarkdownParser{}.Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title != "Widget guide" {
+		t.Errorf("title = %q, want the front matter title", doc.Title)
+	}
+	want := []Segment{
+		{Text: "Intro text.", HeadingPath: []string{}},
+		{Text: "Add the script tag.\n\nThen reload.", HeadingPath: []string{"Install"}},
+		{Text: "List every domain.\n\n```\n# not a heading\n```", HeadingPath: []string{"Install", "Domains"}},
+		{Text: "Rotate them monthly.", HeadingPath: []string{"Install", "Keys"}},
+		{Text: "Skipped levels.", HeadingPath: []string{"Install", "Keys", "Deep"}},
+		{Text: "Sent monthly.", HeadingPath: []string{"Billing"}},
+	}
+	assertSegments(t, doc, want)
+}
+
+func TestMarkdownTitleFromHeading(t *testing.T) {
+	doc, err := MarkdownParser{}.Parse([]byte("# Pricing\n\nPlans start free.\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title != "Pricing" {
+		t.Errorf("title = %q, want the first level 1 heading", doc.Title)
+	}
+}
+
+func TestHTMLParser(t *testing.T) {
+	src := `<!doctype html>
+<html><head><title> Help  Center </title><style>p { color: red }</style></head>
+<body>
+  <nav><a href="/">Home</a></nav>
+  <div class="cookie-banner">We use cookies</div>
+  <main>
+    <p>Welcome to the   help center.</p>
+    <h2>Accounts</h2>
+    <p>Create an account<br>in two steps.</p>
+    <div class="toc-list">Contents</div>
+    <div class="stock-level">In stock</div>
+    <h3>Passwords</h3>
+    <ul><li>Open settings</li><li>Choose reset</li></ul>
+    <aside>Related articles</aside>
+    <h2>Billing</h2>
+    <pre>plan:  pro
+seats: 5</pre>
+    <p hidden>Internal note</p>
+  </main>
+  <footer>Copyright</footer>
+</body></html>`
+
+	doc, err := HTMLParser{}.Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title != "Help Center" {
+		t.Errorf("title = %q, want %q", doc.Title, "Help Center")
+	}
+	want := []Segment{
+		{Text: "Welcome to the help center.", HeadingPath: []string{}},
+		{Text: "Create an account\nin two steps.\n\nIn stock", HeadingPath: []string{"Accounts"}},
+		{Text: "- Open settings\n\n- Choose reset", HeadingPath: []string{"Accounts", "Passwords"}},
+		{Text: "plan:  pro\nseats: 5", HeadingPath: []string{"Billing"}},
+	}
+	assertSegments(t, doc, want)
+}
+
+func TestCSVParser(t *testing.T) {
+	src := "\xef\xbb\xbfquestion, answer ,\n" +
+		"How do I reset?,From settings,extra\n" +
+		",,\n" +
+		"\"Refunds, returns\",Within 30 days\n"
+
+	doc, err := CSVParser{}.Parse([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Segment{{
+		Text: "question: How do I reset?; answer: From settings; column 3: extra\n" +
+			"question: Refunds, returns; answer: Within 30 days",
+	}}
+	assertSegments(t, doc, want)
+}
+
+func TestTextParser(t *testing.T) {
+	doc, err := TextParser{}.Parse([]byte("\n  Plain notes.\nSecond line.  \n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertSegments(t, doc, []Segment{{Text: "Plain notes.\nSecond line."}})
+
+	if _, err := (TextParser{}).Parse([]byte{0xff, 0xfe, 0x00}); err == nil {
+		t.Error("invalid UTF-8 was accepted")
+	}
+}
+
+func TestDOCXParser(t *testing.T) {
+	body := `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Title"/></w:pPr><w:r><w:t>Handbook</w:t></w:r></w:p>
+<w:p><w:r><w:t>Welcome </w:t></w:r><w:r><w:t>aboard.</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t>Leave</w:t></w:r></w:p>
+<w:p><w:r><w:t>Ask</w:t><w:tab/><w:t>early.</w:t></w:r></w:p>
+<w:p><w:pPr><w:outlineLvl w:val="2"/></w:pPr><w:r><w:t>Sick days</w:t></w:r></w:p>
+<w:p><w:r><w:t>Tell your lead.</w:t><w:br/><w:t>Rest.</w:t></w:r></w:p>
+<w:p></w:p>
+</w:body></w:document>`
+	core := `<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title> Staff handbook </dc:title></cp:coreProperties>`
+
+	doc, err := DOCXParser{}.Parse(docxFixture(t, body, core))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title != "Staff handbook" {
+		t.Errorf("title = %q, want the core properties title", doc.Title)
+	}
+	want := []Segment{
+		{Text: "Welcome aboard.", HeadingPath: []string{"Handbook"}},
+		{Text: "Ask\tearly.", HeadingPath: []string{"Handbook", "Leave"}},
+		{Text: "Tell your lead.\nRest.", HeadingPath: []string{"Handbook", "Leave", "Sick days"}},
+	}
+	assertSegments(t, doc, want)
+
+	if _, err := (DOCXParser{}).Parse([]byte("not a zip")); err == nil {
+		t.Error("a non-zip file was accepted")
+	}
+}
+
+func TestPDFParser(t *testing.T) {
+	doc, err := PDFParser{}.Parse(pdfFixture("Refund policy", []string{"Refunds take five days.", "", "Contact support."}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title != "Refund policy" {
+		t.Errorf("title = %q, want the Info title", doc.Title)
+	}
+	// The blank second page produces no segment but keeps the numbering
+	want := []Segment{
+		{Text: "Refunds take five days.", Page: 1},
+		{Text: "Contact support.", Page: 3},
+	}
+	assertSegments(t, doc, want)
+
+	if _, err := (PDFParser{}).Parse([]byte("%PDF-1.4 truncated")); err == nil {
+		t.Error("a truncated PDF was accepted")
+	}
+}
+
+func TestForContentType(t *testing.T) {
+	tests := []struct {
+		contentType, filename string
+		content               string
+		want                  Parser
+	}{
+		{"application/pdf", "", "", PDFParser{}},
+		{"text/html; charset=utf-8", "", "", HTMLParser{}},
+		{"application/xhtml+xml", "", "", HTMLParser{}},
+		{"text/markdown", "", "", MarkdownParser{}},
+		{"text/csv", "", "", CSVParser{}},
+		{"text/plain; charset=utf-8", "notes.md", "", TextParser{}},
+		{"application/vnd.openxmlformats-officedocument.wordprocessingml.document", "", "", DOCXParser{}},
+		// Generic or missing types fall back to the extension
+		{"application/octet-stream", "guide.MD", "", MarkdownParser{}},
+		{"", "handbook.docx", "", DOCXParser{}},
+		{"", "faq.csv", "", CSVParser{}},
+		{"", "page.html", "", HTMLParser{}},
+		// and then to the content
+		{"", "download", "<html><body>hi</body></html>", HTMLParser{}},
+		{"", "download", "%PDF-1.7\n", PDFParser{}},
+		{"", "", "just some text", TextParser{}},
+	}
+	for _, tt := range tests {
+		got, err := ForContentType(tt.contentType, tt.filename, []byte(tt.content))
+		if err != nil {
+			t.Errorf("ForContentType(%q, %q): %v", tt.contentType, tt.filename, err)
+			continue
+		}
+		if reflect.TypeOf(got) != reflect.TypeOf(tt.want) {
+			t.Errorf("ForContentType(%q, %q) = %T, want %T", tt.contentType, tt.filename, got, tt.want)
+		}
+	}
+
+	if _, err := ForContentType("image/png", "logo.png", []byte("\x89PNG\r\n\x1a\n")); err == nil {
+		t.Error("an image was given a parser")
+	}
+}
+
+func TestParseDefaultsTitleToFilename(t *testing.T) {
+	doc, err := Parse(&utils.DownloadedFile{Filename: "release-notes.txt", ContentType: "text/plain", Content: []byte("v2 is out")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Title != "release-notes" {
+		t.Errorf("title = %q, want the filename without extension", doc.Title)
+	}
+}
+
+func TestSegmentCitation(t *testing.T) {
+	tests := []struct {
+		seg    Segment
+		source string
+		want   string
+	}{
+		{Segment{Page: 3}, "https://example.com/policy.pdf#old", "https://example.com/policy.pdf#page=3"},
+		{Segment{HeadingPath: []string{"Setup", "API Keys & Tokens"}}, "https://example.com/docs", "https://example.com/docs#api-keys-tokens"},
+		{Segment{HeadingPath: []string{"Setup"}}, "handbook.docx", "handbook.docx"},
+		{Segment{}, "https://example.com/docs", "https://example.com/docs"},
+		{Segment{Page: 1}, "", ""},
+	}
+	for _, tt := range tests {
+		if got := tt.seg.Citation(tt.source); got != tt.want {
+			t.Errorf("Citation(%q) of %+v = %q, want %q", tt.source, tt.seg, got, tt.want)
+		}
+	}
+	if got := (Segment{HeadingPath: []string{"Setup", "Keys"}}).Section(); got != "Setup > Keys" {
+		t.Errorf("Section() = %q", got)
+	}
+}
+
+func assertSegments(t *testing.T, doc *Document, want []Segment) {
+	t.Helper()
+	if len(doc.Segments) != len(want) {
+		for _, s := range doc.Segments {
+			t.Logf("page %d %q: %q", s.Page, s.HeadingPath, s.Text)
+		}
+		t.Fatalf("got %d segments, want %d", len(doc.Segments), len(want))
+	}
+	for i, w := range want {
+		got := doc.Segments[i]
+		if got.Text != w.Text {
+			t.Errorf("segment %d text = %q, want %q", i, got.Text, w.Text)
+		}
+		if got.Page != w.Page {
+			t.Errorf("segment %d page = %d, want %d", i, got.Page, w.Page)
+		}
+		if len(got.HeadingPath) != 0 || len(w.HeadingPath) != 0 {
+			if !reflect.DeepEqual(got.HeadingPath, w.HeadingPath) {
+				t.Errorf("segment %d heading path = %q, want %q", i, got.HeadingPath, w.HeadingPath)
+			}
+		}
+	}
+}
+
+// docxFixture zips a document body and core properties into a DOCX file
+func docxFixture(t *testing.T, body, core string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{"word/document.xml": body, "docProps/core.xml": core} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pdfFixture writes a PDF with one page per entry of pages, each showing its text in
+// Helvetica. An empty entry makes a page without a text layer.
+func pdfFixture(title string, pages []string) []byte {
+	var objects []string
+	add := func(obj string) int {
+		objects = append(objects, obj)
+		return len(objects)
+	}
+
+	catalog := add("") // filled in once the page tree exists
+	tree := add("")
+	font := add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	info := add(fmt.Sprintf("<< /Title (%s) >>", title))
+
+	var kids []string
+	for _, text := range pages {
+		stream := ""
+		if text != "" {
+			stream = fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		}
+		content := add(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream))
+		page := add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+			tree, font, content))
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+	objects[catalog-1] = fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", tree)
+	objects[tree-1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(objects)+1, catalog, info, xref)
+	return b.Bytes()
+}
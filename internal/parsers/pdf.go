@@ -0,0 +1,56 @@
+package parsers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// PDFParser extracts the text layer of a PDF, one segment per page. Scanned PDFs without
+// a text layer produce no segments.
+type PDFParser struct{}
+
+func (PDFParser) Parse(content []byte) (doc *Document, err error) {
+	// the pdf package panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			doc, err = nil, fmt.Errorf("failed to parse PDF: %v", r)
+		}
+	}()
+
+	r, err := pdf.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+
+	doc = &Document{
+		Title: strings.TrimSpace(r.Trailer().Key("Info").Key("Title").Text()),
+	}
+
+	fonts := make(map[string]*pdf.Font)
+	for i := 1; i <= r.NumPage(); i++ {
+		page := r.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		for _, name := range page.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				f := page.Font(name)
+				fonts[name] = &f
+			}
+		}
+		text, err := page.GetPlainText(fonts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract text from page %d: %w", i, err)
+		}
+		text = collapseBlankLines(text)
+		if text == "" {
+			continue
+		}
+		doc.Segments = append(doc.Segments, Segment{Text: text, Page: i})
+	}
+
+	return doc, nil
+}
@@ -0,0 +1,46 @@
+package parsers
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TextParser handles plain text as a single segment
+type TextParser struct{}
+
+func (TextParser) Parse(content []byte) (*Document, error) {
+	if !utf8.Valid(content) {
+		return nil, fmt.Errorf("document is not valid UTF-8 text")
+	}
+	text := strings.TrimSpace(string(content))
+	if text == "" {
+		return &Document{}, nil
+	}
+	return &Document{Segments: []Segment{{Text: text}}}, nil
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+// collapseBlankLines trims each line and squeezes runs of blank lines to one
+func collapseBlankLines(text string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	blank := false
+	for _, line := range lines {
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		if strings.TrimSpace(line) == "" {
+			if !blank && len(out) > 0 {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
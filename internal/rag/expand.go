@@ -0,0 +1,245 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+const (
+	ExpandNone       = "none"
+	ExpandNeighbours = "neighbours"
+	ExpandParent     = "parent"
+
+	defaultCharBudget = 6000
+)
+
+// chunkSource is the subset of PostgresClient used for context expansion
+type chunkSource interface {
+	GetDocumentChunks(ctx context.Context, chatbotID, documentID string, ranges []loaders.ChunkRange) ([]loaders.EmbeddingResult, error)
+	GetSectionChunks(ctx context.Context, chatbotID, documentID, section string) ([]loaders.EmbeddingResult, error)
+}
+
+// contextWindow is one contiguous piece of a document built from one or more hits
+type contextWindow struct {
+	rank     int // best (lowest) rank of the hits it contains
+	hits     []loaders.EmbeddingResult
+	expanded []loaders.EmbeddingResult
+}
+
+// ExpandContext replaces each hit with its surrounding context: the n neighbouring chunks
+// on each side (ExpandNeighbours) or its whole parent section (ExpandParent). Hits from the
+// same document whose windows overlap are merged into one result. Expanded text is added
+// in rank order until charBudget is spent; once a window no longer fits, the original hit
+// text is used instead so expansion never drops a hit. Hits without position data pass
+// through unchanged, as do all hits when mode is unknown.
+func ExpandContext(ctx context.Context, src chunkSource, chatbotID string, hits []loaders.EmbeddingResult, mode string, n int, charBudget int) ([]loaders.EmbeddingResult, error) {
+	if len(hits) == 0 || mode == "" || mode == ExpandNone {
+		return hits, nil
+	}
+	if charBudget <= 0 {
+		charBudget = defaultCharBudget
+	}
+
+	var windows []*contextWindow
+	var err error
+	switch mode {
+	case ExpandNeighbours:
+		windows, err = neighbourWindows(ctx, src, chatbotID, hits, n)
+	case ExpandParent:
+		windows, err = parentWindows(ctx, src, chatbotID, hits)
+	default:
+		// A bad settings row must not break retrieval for the chatbot
+		utils.Zlog.Warn("Unknown context expansion mode, not expanding",
+			zap.String("chatbot_id", chatbotID),
+			zap.String("mode", mode))
+		return hits, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(windows, func(i, j int) bool { return windows[i].rank < windows[j].rank })
+
+	remaining := charBudget
+	results := make([]loaders.EmbeddingResult, 0, len(windows))
+	for _, w := range windows {
+		best := w.hits[0]
+		for _, h := range w.hits[1:] {
+			if h.Score > best.Score {
+				best = h
+			}
+		}
+
+		text := joinChunks(w.hits)
+		if len(w.expanded) > 0 {
+			if expandedText := joinChunks(w.expanded); utf8.RuneCountInString(expandedText) <= remaining {
+				text = expandedText
+			}
+		}
+		remaining -= utf8.RuneCountInString(text)
+		if remaining < 0 {
+			remaining = 0
+		}
+
+		best.Text = text
+		best.Vector = nil
+		results = append(results, best)
+	}
+
+	return results, nil
+}
+
+// neighbourWindows groups hits per document and merges overlapping [i-n, i+n] windows
+func neighbourWindows(ctx context.Context, src chunkSource, chatbotID string, hits []loaders.EmbeddingResult, n int) ([]*contextWindow, error) {
+	if n < 0 {
+		n = 0
+	}
+
+	type positioned struct {
+		rank int
+		hit  loaders.EmbeddingResult
+	}
+
+	var windows []*contextWindow
+	byDoc := make(map[string][]positioned)
+	var docOrder []string
+	for rank, h := range hits {
+		if h.DocumentID == nil || h.ChunkIndex == nil {
+			windows = append(windows, &contextWindow{rank: rank, hits: []loaders.EmbeddingResult{h}})
+			continue
+		}
+		if _, ok := byDoc[*h.DocumentID]; !ok {
+			docOrder = append(docOrder, *h.DocumentID)
+		}
+		byDoc[*h.DocumentID] = append(byDoc[*h.DocumentID], positioned{rank: rank, hit: h})
+	}
+
+	for _, docID := range docOrder {
+		docHits := byDoc[docID]
+		sort.Slice(docHits, func(i, j int) bool { return *docHits[i].hit.ChunkIndex < *docHits[j].hit.ChunkIndex })
+
+		var ranges []loaders.ChunkRange
+		var docWindows []*contextWindow
+		for _, p := range docHits {
+			idx := *p.hit.ChunkIndex
+			lo, hi := idx-n, idx+n
+			if lo < 0 {
+				lo = 0
+			}
+			last := len(ranges) - 1
+			if last >= 0 && lo <= ranges[last].To+1 {
+				if hi > ranges[last].To {
+					ranges[last].To = hi
+				}
+				w := docWindows[last]
+				w.hits = append(w.hits, p.hit)
+				if p.rank < w.rank {
+					w.rank = p.rank
+				}
+				continue
+			}
+			ranges = append(ranges, loaders.ChunkRange{From: lo, To: hi})
+			docWindows = append(docWindows, &contextWindow{rank: p.rank, hits: []loaders.EmbeddingResult{p.hit}})
+		}
+
+		chunks, err := src.GetDocumentChunks(ctx, chatbotID, docID, ranges)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load neighbouring chunks: %w", err)
+		}
+		for _, c := range chunks {
+			if c.ChunkIndex == nil {
+				continue
+			}
+			for i, r := range ranges {
+				if *c.ChunkIndex >= r.From && *c.ChunkIndex <= r.To {
+					docWindows[i].expanded = append(docWindows[i].expanded, c)
+					break
+				}
+			}
+		}
+		windows = append(windows, docWindows...)
+	}
+
+	return windows, nil
+}
+
+// parentWindows groups hits by (document, section) and loads each section once
+func parentWindows(ctx context.Context, src chunkSource, chatbotID string, hits []loaders.EmbeddingResult) ([]*contextWindow, error) {
+	var windows []*contextWindow
+	bySection := make(map[string]*contextWindow)
+	for rank, h := range hits {
+		if h.DocumentID == nil || h.Section == nil || *h.Section == "" {
+			windows = append(windows, &contextWindow{rank: rank, hits: []loaders.EmbeddingResult{h}})
+			continue
+		}
+		key := *h.DocumentID + "\x00" + *h.Section
+		if w, ok := bySection[key]; ok {
+			w.hits = append(w.hits, h)
+			continue
+		}
+		w := &contextWindow{rank: rank, hits: []loaders.EmbeddingResult{h}}
+		bySection[key] = w
+		windows = append(windows, w)
+	}
+
+	for _, w := range windows {
+		h := w.hits[0]
+		if h.DocumentID == nil || h.Section == nil || *h.Section == "" {
+			continue
+		}
+		chunks, err := src.GetSectionChunks(ctx, chatbotID, *h.DocumentID, *h.Section)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load parent section: %w", err)
+		}
+		w.expanded = chunks
+	}
+
+	return windows, nil
+}
+
+// joinChunks concatenates chunk texts in document order. When offsets are known, text
+// that overlaps the previous chunk (from splitter overlap) is dropped.
+func joinChunks(chunks []loaders.EmbeddingResult) string {
+	ordered := make([]loaders.EmbeddingResult, len(chunks))
+	copy(ordered, chunks)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].ChunkIndex == nil || ordered[j].ChunkIndex == nil {
+			return false
+		}
+		return *ordered[i].ChunkIndex < *ordered[j].ChunkIndex
+	})
+
+	var b strings.Builder
+	prevEnd := -1
+	for _, c := range ordered {
+		text := c.Text
+		if c.StartOffset != nil && c.EndOffset != nil {
+			if prevEnd > *c.StartOffset {
+				overlap := prevEnd - *c.StartOffset
+				if overlap >= len(text) {
+					continue
+				}
+				text = text[overlap:]
+				b.WriteString(text)
+				prevEnd = *c.EndOffset
+				continue
+			}
+			prevEnd = *c.EndOffset
+		} else {
+			prevEnd = -1
+		}
+		if b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(strings.TrimSpace(text))
+	}
+	return b.String()
+}
@@ -0,0 +1,233 @@
+package rag
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+func TestMain(m *testing.M) {
+	utils.Zlog = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// sentences make up the test document. Chunk i holds sentences i and i+1, so
+// consecutive chunks overlap by one sentence like splitter overlap does.
+var sentences = []string{"Zero one.", "One two.", "Two three.", "Three four.", "Four five.", "Five six.", "Six seven.", "Seven eight."}
+
+const testDoc = "doc"
+
+// fakeChunks serves the chunks of one document and records the requested ranges
+type fakeChunks struct {
+	chunks   []loaders.EmbeddingResult
+	sections map[string][]loaders.EmbeddingResult
+	ranges   [][]loaders.ChunkRange
+	loads    []string
+}
+
+func newFakeChunks() *fakeChunks {
+	doc := strings.Join(sentences, " ")
+	f := &fakeChunks{sections: make(map[string][]loaders.EmbeddingResult)}
+	start := 0
+	for i := 0; i+1 < len(sentences); i++ {
+		end := start + len(sentences[i]) + 1 + len(sentences[i+1])
+		c := chunk(i, start, end, doc[start:end])
+		f.chunks = append(f.chunks, c)
+		start += len(sentences[i]) + 1
+	}
+	return f
+}
+
+func chunk(index, start, end int, text string) loaders.EmbeddingResult {
+	doc := testDoc
+	return loaders.EmbeddingResult{
+		Text:        text,
+		DocumentID:  &doc,
+		ChunkIndex:  &index,
+		StartOffset: &start,
+		EndOffset:   &end,
+	}
+}
+
+// hit returns chunk i as a search result
+func (f *fakeChunks) hit(i int, score float64) loaders.EmbeddingResult {
+	h := f.chunks[i]
+	h.Score = score
+	h.Vector = []float64{score}
+	return h
+}
+
+func (f *fakeChunks) GetDocumentChunks(ctx context.Context, chatbotID, documentID string, ranges []loaders.ChunkRange) ([]loaders.EmbeddingResult, error) {
+	f.ranges = append(f.ranges, ranges)
+	var out []loaders.EmbeddingResult
+	for _, c := range f.chunks {
+		for _, r := range ranges {
+			if *c.ChunkIndex >= r.From && *c.ChunkIndex <= r.To {
+				out = append(out, c)
+				break
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeChunks) GetSectionChunks(ctx context.Context, chatbotID, documentID, section string) ([]loaders.EmbeddingResult, error) {
+	f.loads = append(f.loads, section)
+	return f.sections[section], nil
+}
+
+// joined is sentences[from..to] as one text
+func joined(from, to int) string {
+	return strings.Join(sentences[from:to+1], " ")
+}
+
+func TestExpandNeighboursMergesOverlappingWindows(t *testing.T) {
+	src := newFakeChunks()
+	// Windows [1, 3] and [2, 4] overlap, so both hits become one result
+	hits := []loaders.EmbeddingResult{src.hit(3, 0.8), src.hit(2, 0.9)}
+
+	got, err := ExpandContext(context.Background(), src, testChatbot, hits, ExpandNeighbours, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("got %d results, want 1: %+v", len(got), got)
+	}
+	// Chunks 1 to 4 cover sentences 1 to 5, each once despite the chunk overlap
+	if want := joined(1, 5); got[0].Text != want {
+		t.Errorf("text = %q, want %q", got[0].Text, want)
+	}
+	if got[0].Score != 0.9 || *got[0].ChunkIndex != 2 {
+		t.Errorf("merged result is chunk %d with score %v, want the best hit", *got[0].ChunkIndex, got[0].Score)
+	}
+	if got[0].Vector != nil {
+		t.Error("expanded result kept the hit's vector")
+	}
+	if want := [][]loaders.ChunkRange{{{From: 1, To: 4}}}; !reflect.DeepEqual(src.ranges, want) {
+		t.Errorf("requested ranges %v, want %v", src.ranges, want)
+	}
+}
+
+func TestExpandNeighboursAdjacentWindowsMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		hits     []int
+		n        int
+		wantText string
+		want     []loaders.ChunkRange
+	}{
+		{"adjacent hits", []int{1, 2}, 0, joined(1, 3), []loaders.ChunkRange{{From: 1, To: 2}}},
+		// Windows [0, 1] and [2, 4] touch, so they load and read as one
+		{"touching windows", []int{0, 3}, 1, joined(0, 5), []loaders.ChunkRange{{From: 0, To: 4}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := newFakeChunks()
+			var hits []loaders.EmbeddingResult
+			for _, i := range tt.hits {
+				hits = append(hits, src.hit(i, 0.5))
+			}
+			got, err := ExpandContext(context.Background(), src, testChatbot, hits, ExpandNeighbours, tt.n, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{tt.wantText}; !reflect.DeepEqual(texts(got), want) {
+				t.Errorf("got %q, want %q", texts(got), want)
+			}
+			if want := [][]loaders.ChunkRange{tt.want}; !reflect.DeepEqual(src.ranges, want) {
+				t.Errorf("requested ranges %v, want %v", src.ranges, want)
+			}
+		})
+	}
+}
+
+func TestExpandNeighboursSeparateWindowsKeepRankOrder(t *testing.T) {
+	src := newFakeChunks()
+	hits := []loaders.EmbeddingResult{src.hit(6, 0.9), src.hit(0, 0.8)}
+
+	got, err := ExpandContext(context.Background(), src, testChatbot, hits, ExpandNeighbours, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{joined(5, 7), joined(0, 2)}
+	if !reflect.DeepEqual(texts(got), want) {
+		t.Errorf("got %q, want %q", texts(got), want)
+	}
+}
+
+func TestExpandRespectsBudget(t *testing.T) {
+	src := newFakeChunks()
+	hits := []loaders.EmbeddingResult{src.hit(1, 0.9), src.hit(5, 0.8)}
+
+	// Room for the first window only; the second hit keeps its own text
+	budget := len(joined(0, 3)) + len(joined(5, 6))
+	got, err := ExpandContext(context.Background(), src, testChatbot, hits, ExpandNeighbours, 1, budget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{joined(0, 3), joined(5, 6)}
+	if !reflect.DeepEqual(texts(got), want) {
+		t.Errorf("got %q, want %q", texts(got), want)
+	}
+}
+
+func TestExpandPassesThroughUnpositionedHits(t *testing.T) {
+	src := newFakeChunks()
+	plain := loaders.EmbeddingResult{Text: "legacy chunk", Score: 0.95}
+	hits := []loaders.EmbeddingResult{plain, src.hit(2, 0.5)}
+
+	got, err := ExpandContext(context.Background(), src, testChatbot, hits, ExpandNeighbours, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"legacy chunk", joined(1, 4)}
+	if !reflect.DeepEqual(texts(got), want) {
+		t.Errorf("got %q, want %q", texts(got), want)
+	}
+}
+
+func TestExpandUnknownModeReturnsHits(t *testing.T) {
+	src := newFakeChunks()
+	hits := []loaders.EmbeddingResult{src.hit(2, 0.9)}
+
+	for _, mode := range []string{"", ExpandNone, "siblings"} {
+		got, err := ExpandContext(context.Background(), src, testChatbot, hits, mode, 1, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, hits) {
+			t.Errorf("mode %q changed the hits: %+v", mode, got)
+		}
+	}
+	if len(src.ranges) != 0 {
+		t.Errorf("chunks loaded for modes that do not expand: %v", src.ranges)
+	}
+}
+
+func TestExpandParentLoadsEachSectionOnce(t *testing.T) {
+	src := newFakeChunks()
+	section := "Guide > Setup"
+	src.sections[section] = src.chunks[2:5]
+
+	first, second := src.hit(3, 0.9), src.hit(2, 0.7)
+	first.Section, second.Section = &section, &section
+	hits := []loaders.EmbeddingResult{first, second}
+
+	got, err := ExpandContext(context.Background(), src, testChatbot, hits, ExpandParent, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Text != joined(2, 5) {
+		t.Errorf("got %q, want the section once", texts(got))
+	}
+	if !reflect.DeepEqual(src.loads, []string{section}) {
+		t.Errorf("loaded sections %q, want %q once", src.loads, section)
+	}
+}
@@ -0,0 +1,311 @@
+package rag
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// hnswIndex is a hierarchical navigable small world graph over cosine distance. Vectors
+// are normalised on insert so distance is 1 - dot product. Removed nodes stay in the
+// graph as tombstones to keep it connected and are skipped in results.
+type hnswIndex struct {
+	m              int
+	efConstruction int
+	levelMult      float64
+	rng            *rand.Rand
+
+	nodes    []hnswNode
+	byID     map[int64]int32
+	entry    int32
+	maxLevel int
+	deleted  int
+}
+
+type hnswNode struct {
+	id      int64
+	vector  []float64
+	friends [][]int32 // neighbours per layer
+	deleted bool
+}
+
+type hnswCandidate struct {
+	node int32
+	dist float64
+}
+
+func newHNSWIndex(m, efConstruction int) *hnswIndex {
+	if m < 2 {
+		m = 16
+	}
+	if efConstruction < m {
+		efConstruction = 200
+	}
+	return &hnswIndex{
+		m:              m,
+		efConstruction: efConstruction,
+		levelMult:      1 / math.Log(float64(m)),
+		rng:            rand.New(rand.NewSource(1)),
+		byID:           make(map[int64]int32),
+		entry:          -1,
+	}
+}
+
+// live returns the number of searchable vectors
+func (h *hnswIndex) live() int {
+	return len(h.byID)
+}
+
+// add inserts a vector, replacing any earlier vector with the same id
+func (h *hnswIndex) add(id int64, vector []float64) {
+	h.remove(id)
+
+	level := int(math.Floor(-math.Log(1-h.rng.Float64()) * h.levelMult))
+	n := int32(len(h.nodes))
+	h.nodes = append(h.nodes, hnswNode{
+		id:      id,
+		vector:  normalize(vector),
+		friends: make([][]int32, level+1),
+	})
+	h.byID[id] = n
+
+	if h.entry < 0 {
+		h.entry, h.maxLevel = n, level
+		return
+	}
+
+	q := h.nodes[n].vector
+	ep := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedy(q, ep, l)
+	}
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(q, []int32{ep}, h.efConstruction, l)
+		neighbours := h.selectNeighbours(candidates, h.maxFriends(l))
+		h.nodes[n].friends[l] = neighbours
+		for _, nb := range neighbours {
+			h.connect(nb, n, l)
+		}
+		ep = candidates[0].node
+	}
+	if level > h.maxLevel {
+		h.entry, h.maxLevel = n, level
+	}
+}
+
+// remove tombstones the vector with id; the graph is rebuilt once most nodes are dead
+func (h *hnswIndex) remove(id int64) {
+	n, ok := h.byID[id]
+	if !ok {
+		return
+	}
+	delete(h.byID, id)
+	h.nodes[n].deleted = true
+	h.deleted++
+	if h.deleted > 64 && h.deleted > len(h.nodes)/2 {
+		h.rebuild()
+	}
+}
+
+func (h *hnswIndex) rebuild() {
+	old := h.nodes
+	h.nodes, h.byID = nil, make(map[int64]int32, len(old)-h.deleted)
+	h.entry, h.maxLevel, h.deleted = -1, 0, 0
+	for _, node := range old {
+		if !node.deleted {
+			h.add(node.id, node.vector)
+		}
+	}
+}
+
+// search returns up to k live nodes nearest to query, nearest first
+func (h *hnswIndex) search(query []float64, k, ef int) []hnswCandidate {
+	if h.entry < 0 || k <= 0 {
+		return nil
+	}
+	if ef < k {
+		ef = k
+	}
+	q := normalize(query)
+	ep := h.entry
+	for l := h.maxLevel; l > 0; l-- {
+		ep = h.greedy(q, ep, l)
+	}
+
+	var results []hnswCandidate
+	for _, c := range h.searchLayer(q, []int32{ep}, ef, 0) {
+		if h.nodes[c.node].deleted {
+			continue
+		}
+		results = append(results, c)
+		if len(results) == k {
+			break
+		}
+	}
+	return results
+}
+
+func (h *hnswIndex) maxFriends(level int) int {
+	if level == 0 {
+		return 2 * h.m
+	}
+	return h.m
+}
+
+func (h *hnswIndex) distance(q []float64, n int32) float64 {
+	return 1 - dot(q, h.nodes[n].vector)
+}
+
+// greedy walks layer l towards q until no neighbour is closer
+func (h *hnswIndex) greedy(q []float64, ep int32, l int) int32 {
+	best := h.distance(q, ep)
+	for changed := true; changed; {
+		changed = false
+		for _, nb := range h.nodes[ep].friends[l] {
+			if d := h.distance(q, nb); d < best {
+				ep, best, changed = nb, d, true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer returns the ef nearest nodes found from eps on layer l, nearest first
+func (h *hnswIndex) searchLayer(q []float64, eps []int32, ef, l int) []hnswCandidate {
+	visited := make(map[int32]bool, ef*4)
+	candidates := &candidateHeap{}
+	results := &candidateHeap{max: true}
+	for _, ep := range eps {
+		c := hnswCandidate{ep, h.distance(q, ep)}
+		visited[ep] = true
+		heap.Push(candidates, c)
+		heap.Push(results, c)
+	}
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(hnswCandidate)
+		if results.Len() >= ef && c.dist > results.items[0].dist {
+			break
+		}
+		for _, nb := range h.nodes[c.node].friends[l] {
+			if visited[nb] {
+				continue
+			}
+			visited[nb] = true
+			d := h.distance(q, nb)
+			if results.Len() < ef || d < results.items[0].dist {
+				heap.Push(candidates, hnswCandidate{nb, d})
+				heap.Push(results, hnswCandidate{nb, d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	out := results.items
+	sort.Slice(out, func(i, j int) bool { return out[i].dist < out[j].dist })
+	return out
+}
+
+// selectNeighbours keeps the closest candidates that are not closer to an already kept
+// neighbour than to the new node, which keeps the graph navigable across clusters
+func (h *hnswIndex) selectNeighbours(candidates []hnswCandidate, max int) []int32 {
+	selected := make([]int32, 0, max)
+	for _, c := range candidates {
+		if len(selected) == max {
+			break
+		}
+		keep := true
+		for _, s := range selected {
+			if 1-dot(h.nodes[c.node].vector, h.nodes[s].vector) < c.dist {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			selected = append(selected, c.node)
+		}
+	}
+	// Top up with the nearest skipped candidates so sparse regions stay connected
+	for _, c := range candidates {
+		if len(selected) == max {
+			break
+		}
+		if !containsNode(selected, c.node) {
+			selected = append(selected, c.node)
+		}
+	}
+	return selected
+}
+
+// connect adds a link from node to nb on layer l, pruning node's list if it overflows
+func (h *hnswIndex) connect(node, nb int32, l int) {
+	friends := append(h.nodes[node].friends[l], nb)
+	if max := h.maxFriends(l); len(friends) > max {
+		q := h.nodes[node].vector
+		candidates := make([]hnswCandidate, len(friends))
+		for i, f := range friends {
+			candidates[i] = hnswCandidate{f, 1 - dot(q, h.nodes[f].vector)}
+		}
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].dist < candidates[j].dist })
+		friends = h.selectNeighbours(candidates, max)
+	}
+	h.nodes[node].friends[l] = friends
+}
+
+func containsNode(nodes []int32, n int32) bool {
+	for _, x := range nodes {
+		if x == n {
+			return true
+		}
+	}
+	return false
+}
+
+// candidateHeap is a min-heap by distance, or a max-heap when max is set
+type candidateHeap struct {
+	items []hnswCandidate
+	max   bool
+}
+
+func (c *candidateHeap) Len() int { return len(c.items) }
+func (c *candidateHeap) Less(i, j int) bool {
+	if c.max {
+		return c.items[i].dist > c.items[j].dist
+	}
+	return c.items[i].dist < c.items[j].dist
+}
+func (c *candidateHeap) Swap(i, j int) { c.items[i], c.items[j] = c.items[j], c.items[i] }
+func (c *candidateHeap) Push(x any)    { c.items = append(c.items, x.(hnswCandidate)) }
+func (c *candidateHeap) Pop() any {
+	last := c.items[len(c.items)-1]
+	c.items = c.items[:len(c.items)-1]
+	return last
+}
+
+func normalize(v []float64) []float64 {
+	var norm float64
+	for _, x := range v {
+		norm += x * x
+	}
+	out := make([]float64, len(v))
+	if norm == 0 {
+		return out
+	}
+	norm = math.Sqrt(norm)
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
+
+func dot(a, b []float64) float64 {
+	n := min(len(a), len(b))
+	var sum float64
+	for i := 0; i < n; i++ {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,145 @@
+package rag
+
+import (
+	"context"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+)
+
+const testChatbot = "bot"
+
+func randomVector(rng *rand.Rand, dims int) []float64 {
+	v := make([]float64, dims)
+	for i := range v {
+		v[i] = rng.NormFloat64()
+	}
+	return v
+}
+
+// newTestStores fills an HNSW store and an exact store with the same random vectors.
+// Records alternate between data sources 1 and 2.
+func newTestStores(t *testing.T, rng *rand.Rand, n, dims int) (hnsw, exact *MemoryStore) {
+	t.Helper()
+	records := make([]VectorRecord, n)
+	for i := range records {
+		source := i%2 + 1
+		records[i] = VectorRecord{
+			ID:           int64(i),
+			ChatbotID:    testChatbot,
+			Vector:       randomVector(rng, dims),
+			DataSourceID: &source,
+			Chunk:        loaders.EmbeddingResult{Text: strconv.Itoa(i)},
+		}
+	}
+
+	hnsw = NewMemoryStore(MemoryStoreConfig{HNSW: true})
+	exact = NewMemoryStore(MemoryStoreConfig{})
+	for _, s := range []*MemoryStore{hnsw, exact} {
+		if err := s.Upsert(context.Background(), records); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return hnsw, exact
+}
+
+// recall returns the fraction of the exact results the approximate search found
+func recall(t *testing.T, approx, exact *MemoryStore, queries [][]float64, k int, filter loaders.SearchFilter) float64 {
+	t.Helper()
+	ctx := context.Background()
+	found, total := 0, 0
+	for _, q := range queries {
+		want, err := exact.Search(ctx, testChatbot, q, k, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := approx.Search(ctx, testChatbot, q, k, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("got %d results, want %d", len(got), len(want))
+		}
+		for i := 1; i < len(got); i++ {
+			if got[i].Score > got[i-1].Score {
+				t.Fatalf("results not ordered by score: %v > %v", got[i].Score, got[i-1].Score)
+			}
+		}
+
+		ids := make(map[string]bool, len(got))
+		for _, r := range got {
+			ids[r.Text] = true
+		}
+		for _, r := range want {
+			if ids[r.Text] {
+				found++
+			}
+		}
+		total += len(want)
+	}
+	return float64(found) / float64(total)
+}
+
+func TestHNSWMatchesExactSearch(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	hnsw, exact := newTestStores(t, rng, 2000, 32)
+
+	queries := make([][]float64, 50)
+	for i := range queries {
+		queries[i] = randomVector(rng, 32)
+	}
+
+	if r := recall(t, hnsw, exact, queries, 10, loaders.SearchFilter{}); r < 0.9 {
+		t.Errorf("recall@10 = %.2f, want >= 0.9", r)
+	}
+	filter := loaders.SearchFilter{DataSourceIDs: []int{2}}
+	if r := recall(t, hnsw, exact, queries, 10, filter); r < 0.9 {
+		t.Errorf("filtered recall@10 = %.2f, want >= 0.9", r)
+	}
+}
+
+func TestHNSWSearchAfterDelete(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(7))
+	hnsw, exact := newTestStores(t, rng, 1000, 16)
+
+	deleted := make([]int64, 0, 500)
+	for id := int64(0); id < 1000; id += 2 {
+		deleted = append(deleted, id)
+	}
+	for _, s := range []*MemoryStore{hnsw, exact} {
+		if err := s.Delete(ctx, testChatbot, deleted); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	queries := make([][]float64, 30)
+	for i := range queries {
+		queries[i] = randomVector(rng, 16)
+		results, err := hnsw.Search(ctx, testChatbot, queries[i], 10, loaders.SearchFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range results {
+			if id, _ := strconv.Atoi(r.Text); id%2 == 0 {
+				t.Fatalf("deleted record %d returned", id)
+			}
+		}
+	}
+	if r := recall(t, hnsw, exact, queries, 10, loaders.SearchFilter{}); r < 0.9 {
+		t.Errorf("recall@10 after deletes = %.2f, want >= 0.9", r)
+	}
+}
+
+func TestHNSWExactOnSmallSets(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	hnsw, exact := newTestStores(t, rng, 20, 8)
+
+	queries := [][]float64{randomVector(rng, 8), randomVector(rng, 8)}
+	// Asking for every vector must return all of them
+	if r := recall(t, hnsw, exact, queries, 20, loaders.SearchFilter{}); r != 1 {
+		t.Errorf("recall@20 of 20 vectors = %.2f, want 1", r)
+	}
+}
@@ -0,0 +1,245 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+)
+
+// MemoryStoreConfig selects how a MemoryStore searches
+type MemoryStoreConfig struct {
+	// HNSW builds an approximate graph index per chatbot and model. Without it every
+	// search compares the query with every vector, which is exact and fine for small
+	// knowledge bases.
+	HNSW bool
+	// M is the number of graph links per node (default 16)
+	M int
+	// EfConstruction and EfSearch size the candidate lists while building and searching
+	// (defaults 200 and 64); larger is more accurate and slower
+	EfConstruction int
+	EfSearch       int
+}
+
+// MemoryStore is a VectorStore held in process memory. It serves tests and chatbots
+// small enough to keep in memory, and also answers context expansion queries.
+type MemoryStore struct {
+	cfg MemoryStoreConfig
+
+	mu     sync.RWMutex
+	spaces map[memorySpaceKey]*memorySpace
+}
+
+type memorySpaceKey struct {
+	chatbotID string
+	model     string
+}
+
+// memorySpace holds the vectors of one chatbot from one model
+type memorySpace struct {
+	records map[int64]*VectorRecord
+	index   *hnswIndex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore(cfg MemoryStoreConfig) *MemoryStore {
+	if cfg.M <= 0 {
+		cfg.M = 16
+	}
+	if cfg.EfConstruction <= 0 {
+		cfg.EfConstruction = 200
+	}
+	if cfg.EfSearch <= 0 {
+		cfg.EfSearch = 64
+	}
+	return &MemoryStore{
+		cfg:    cfg,
+		spaces: make(map[memorySpaceKey]*memorySpace),
+	}
+}
+
+// Upsert adds records or replaces those with the same chatbot, model and id
+func (s *MemoryStore) Upsert(ctx context.Context, records []VectorRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range records {
+		r := records[i]
+		if r.ChatbotID == "" {
+			return fmt.Errorf("vector record %d has no chatbot", r.ID)
+		}
+		if len(r.Vector) == 0 {
+			return fmt.Errorf("vector record %d has no vector", r.ID)
+		}
+		r.Vector = append([]float64(nil), r.Vector...)
+
+		key := memorySpaceKey{r.ChatbotID, r.Model}
+		space, ok := s.spaces[key]
+		if !ok {
+			space = &memorySpace{records: make(map[int64]*VectorRecord)}
+			if s.cfg.HNSW {
+				space.index = newHNSWIndex(s.cfg.M, s.cfg.EfConstruction)
+			}
+			s.spaces[key] = space
+		}
+		space.records[r.ID] = &r
+		if space.index != nil {
+			space.index.add(r.ID, r.Vector)
+		}
+	}
+	return nil
+}
+
+// Delete removes the chunks from every model's space of the chatbot
+func (s *MemoryStore) Delete(ctx context.Context, chatbotID string, ids []int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, space := range s.spaces {
+		if key.chatbotID != chatbotID {
+			continue
+		}
+		for _, id := range ids {
+			delete(space.records, id)
+			if space.index != nil {
+				space.index.remove(id)
+			}
+		}
+		if len(space.records) == 0 {
+			delete(s.spaces, key)
+		}
+	}
+	return nil
+}
+
+// Search returns the nearest vectors of the chatbot that pass filter. With HNSW the
+// index is over-fetched to leave room for filtered-out hits, and searched exhaustively
+// if that still does not yield limit results.
+func (s *MemoryStore) Search(ctx context.Context, chatbotID string, query []float64, limit int, filter loaders.SearchFilter) ([]loaders.EmbeddingResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	space, ok := s.spaces[memorySpaceKey{chatbotID, filter.Model}]
+	if !ok || limit <= 0 {
+		return []loaders.EmbeddingResult{}, nil
+	}
+	match := newRecordFilter(filter)
+
+	if space.index != nil {
+		fetch := limit
+		if match != nil {
+			fetch = limit * 4
+		}
+		var results []loaders.EmbeddingResult
+		for _, c := range space.index.search(query, fetch, max(s.cfg.EfSearch, fetch)) {
+			r := space.records[space.index.nodes[c.node].id]
+			if match == nil || match(r) {
+				results = append(results, searchResult(r, 1-c.dist))
+				if len(results) == limit {
+					break
+				}
+			}
+		}
+		if len(results) == limit || len(results) == space.index.live() {
+			return results, nil
+		}
+	}
+
+	results := make([]loaders.EmbeddingResult, 0, len(space.records))
+	for _, r := range space.records {
+		if match == nil || match(r) {
+			results = append(results, searchResult(r, CosineSimilarity(query, r.Vector)))
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// GetDocumentChunks returns the chunks of a document within the ranges, in order
+func (s *MemoryStore) GetDocumentChunks(ctx context.Context, chatbotID, documentID string, ranges []loaders.ChunkRange) ([]loaders.EmbeddingResult, error) {
+	return s.documentChunks(chatbotID, documentID, func(c *loaders.EmbeddingResult) bool {
+		if c.ChunkIndex == nil {
+			return false
+		}
+		for _, r := range ranges {
+			if *c.ChunkIndex >= r.From && *c.ChunkIndex <= r.To {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// GetSectionChunks returns the chunks of a document's section, in order
+func (s *MemoryStore) GetSectionChunks(ctx context.Context, chatbotID, documentID, section string) ([]loaders.EmbeddingResult, error) {
+	return s.documentChunks(chatbotID, documentID, func(c *loaders.EmbeddingResult) bool {
+		return c.Section != nil && *c.Section == section
+	}), nil
+}
+
+// documentChunks collects matching chunks of a document once each, whichever models
+// they are stored for
+func (s *MemoryStore) documentChunks(chatbotID, documentID string, keep func(*loaders.EmbeddingResult) bool) []loaders.EmbeddingResult {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[int64]bool)
+	var chunks []loaders.EmbeddingResult
+	for key, space := range s.spaces {
+		if key.chatbotID != chatbotID {
+			continue
+		}
+		for id, r := range space.records {
+			c := r.Chunk
+			if seen[id] || c.DocumentID == nil || *c.DocumentID != documentID || !keep(&c) {
+				continue
+			}
+			seen[id] = true
+			c.Vector, c.Score = nil, 0
+			chunks = append(chunks, c)
+		}
+	}
+	sort.SliceStable(chunks, func(i, j int) bool {
+		if chunks[i].ChunkIndex == nil || chunks[j].ChunkIndex == nil {
+			return chunks[j].ChunkIndex == nil && chunks[i].ChunkIndex != nil
+		}
+		return *chunks[i].ChunkIndex < *chunks[j].ChunkIndex
+	})
+	return chunks
+}
+
+// newRecordFilter returns nil when filter matches every record of a space
+func newRecordFilter(filter loaders.SearchFilter) func(*VectorRecord) bool {
+	if len(filter.DataSourceIDs) == 0 && len(filter.DocumentIDs) == 0 {
+		return nil
+	}
+	sources := make(map[int]bool, len(filter.DataSourceIDs))
+	for _, id := range filter.DataSourceIDs {
+		sources[id] = true
+	}
+	docs := make(map[string]bool, len(filter.DocumentIDs))
+	for _, id := range filter.DocumentIDs {
+		docs[id] = true
+	}
+	return func(r *VectorRecord) bool {
+		if len(sources) > 0 && (r.DataSourceID == nil || !sources[*r.DataSourceID]) {
+			return false
+		}
+		if len(docs) > 0 && (r.Chunk.DocumentID == nil || !docs[*r.Chunk.DocumentID]) {
+			return false
+		}
+		return true
+	}
+}
+
+func searchResult(r *VectorRecord, score float64) loaders.EmbeddingResult {
+	res := r.Chunk
+	res.Vector = r.Vector
+	res.Score = score
+	return res
+}
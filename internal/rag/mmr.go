@@ -0,0 +1,169 @@
+package rag
+
+import (
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+)
+
+// SelectMMR picks k results from candidates using maximal marginal relevance.
+// Each step chooses the candidate maximising
+//
+//	lambda*sim(query, d) - (1-lambda)*max(sim(d, selected))
+//
+// so lambda=1 reproduces plain similarity ranking and lambda=0 maximises diversity.
+// Candidates without vectors are treated as having no similarity to anything.
+func SelectMMR(queryVector []float64, candidates []loaders.EmbeddingResult, k int, lambda float64) []loaders.EmbeddingResult {
+	if k <= 0 || len(candidates) == 0 {
+		return []loaders.EmbeddingResult{}
+	}
+	if k >= len(candidates) {
+		k = len(candidates)
+	}
+	if lambda < 0 {
+		lambda = 0
+	} else if lambda > 1 {
+		lambda = 1
+	}
+
+	relevance := make([]float64, len(candidates))
+	for i, c := range candidates {
+		relevance[i] = CosineSimilarity(queryVector, c.Vector)
+	}
+
+	// maxSim[i] tracks the highest similarity between candidate i and any selected result
+	maxSim := make([]float64, len(candidates))
+	used := make([]bool, len(candidates))
+	selected := make([]loaders.EmbeddingResult, 0, k)
+
+	for len(selected) < k {
+		best := -1
+		bestScore := math.Inf(-1)
+		for i := range candidates {
+			if used[i] {
+				continue
+			}
+			score := lambda*relevance[i] - (1-lambda)*maxSim[i]
+			if score > bestScore {
+				bestScore = score
+				best = i
+			}
+		}
+		if best == -1 {
+			break
+		}
+
+		used[best] = true
+		selected = append(selected, candidates[best])
+
+		for i := range candidates {
+			if used[i] {
+				continue
+			}
+			if sim := CosineSimilarity(candidates[i].Vector, candidates[best].Vector); sim > maxSim[i] {
+				maxSim[i] = sim
+			}
+		}
+	}
+
+	return selected
+}
+
+// CollapseDuplicates removes results whose text is identical or nearly identical to an
+// earlier (higher ranked) result. Near-duplicates are detected with word-shingle Jaccard
+// similarity; threshold <= 0 disables near-duplicate detection and only exact matches
+// (after whitespace/case normalisation) are collapsed.
+func CollapseDuplicates(results []loaders.EmbeddingResult, threshold float64) []loaders.EmbeddingResult {
+	if len(results) < 2 {
+		return results
+	}
+
+	kept := make([]loaders.EmbeddingResult, 0, len(results))
+	seen := make(map[string]bool, len(results))
+	keptShingles := make([]map[string]bool, 0, len(results))
+
+	for _, r := range results {
+		norm := normalizeText(r.Text)
+		if seen[norm] {
+			continue
+		}
+
+		var shingles map[string]bool
+		if threshold > 0 {
+			shingles = wordShingles(norm, 3)
+			duplicate := false
+			for _, other := range keptShingles {
+				if jaccard(shingles, other) >= threshold {
+					duplicate = true
+					break
+				}
+			}
+			if duplicate {
+				continue
+			}
+		}
+
+		seen[norm] = true
+		keptShingles = append(keptShingles, shingles)
+		kept = append(kept, r)
+	}
+
+	return kept
+}
+
+var nonWordRe = regexp.MustCompile(`[^\pL\pN]+`)
+
+// normalizeText lowercases text and collapses punctuation and whitespace runs
+func normalizeText(text string) string {
+	return strings.TrimSpace(nonWordRe.ReplaceAllString(strings.ToLower(text), " "))
+}
+
+// wordShingles returns the set of n-word shingles of already normalised text
+func wordShingles(text string, n int) map[string]bool {
+	words := strings.Fields(text)
+	shingles := make(map[string]bool)
+	if len(words) < n {
+		if len(words) > 0 {
+			shingles[strings.Join(words, " ")] = true
+		}
+		return shingles
+	}
+	for i := 0; i <= len(words)-n; i++ {
+		shingles[strings.Join(words[i:i+n], " ")] = true
+	}
+	return shingles
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	intersection := 0
+	for k := range a {
+		if b[k] {
+			intersection++
+		}
+	}
+	union := len(a) + len(b) - intersection
+	return float64(intersection) / float64(union)
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors, or 0 when either is empty
+// or their dimensions differ
+func CosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,100 @@
+package rag
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+)
+
+// mmrCandidates are ranked by similarity to [1 0 0]: a, its near copy, then b and c
+func mmrCandidates() []loaders.EmbeddingResult {
+	return []loaders.EmbeddingResult{
+		{Text: "a", Vector: []float64{1, 0, 0}},
+		{Text: "a copy", Vector: []float64{0.99, 0.1, 0}},
+		{Text: "b", Vector: []float64{0.6, 0.8, 0}},
+		{Text: "c", Vector: []float64{0, 0, 1}},
+	}
+}
+
+func texts(results []loaders.EmbeddingResult) []string {
+	out := make([]string, len(results))
+	for i, r := range results {
+		out[i] = r.Text
+	}
+	return out
+}
+
+func TestSelectMMR(t *testing.T) {
+	query := []float64{1, 0, 0}
+	tests := []struct {
+		name   string
+		k      int
+		lambda float64
+		want   []string
+	}{
+		// Pure relevance keeps the similarity order, near copy included
+		{"lambda 1", 3, 1, []string{"a", "a copy", "b"}},
+		{"lambda above 1 is clamped", 3, 2, []string{"a", "a copy", "b"}},
+		// Pure diversity takes the first candidate, then whatever is least like the picks
+		{"lambda 0", 3, 0, []string{"a", "c", "b"}},
+		{"lambda below 0 is clamped", 3, -1, []string{"a", "c", "b"}},
+		{"k above candidates", 10, 1, []string{"a", "a copy", "b", "c"}},
+		{"k 0", 0, 1, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := texts(SelectMMR(query, mmrCandidates(), tt.k, tt.lambda))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectMMR(k=%d, lambda=%v) = %q, want %q", tt.k, tt.lambda, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectMMRWithoutVectors(t *testing.T) {
+	candidates := append(mmrCandidates(), loaders.EmbeddingResult{Text: "no vector"})
+	got := texts(SelectMMR([]float64{1, 0, 0}, candidates, 5, 1))
+	if want := []string{"a", "a copy", "b", "c", "no vector"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCollapseDuplicates(t *testing.T) {
+	results := []loaders.EmbeddingResult{
+		{Text: "Reset your password from the account page.", Score: 0.9},
+		{Text: "reset your   password from the ACCOUNT page", Score: 0.8},
+		{Text: "You can reset your password from the account page.", Score: 0.7},
+		{Text: "Shipping to Canada takes five business days.", Score: 0.6},
+	}
+	tests := []struct {
+		name      string
+		threshold float64
+		want      []string
+	}{
+		// Spacing, case and punctuation alone never make a result distinct
+		{"exact only", 0, []string{results[0].Text, results[2].Text, results[3].Text}},
+		// The reworded copy has a shingle Jaccard similarity of 5/7 with the first result
+		{"near duplicates", 0.5, []string{results[0].Text, results[3].Text}},
+		{"high threshold", 0.9, []string{results[0].Text, results[2].Text, results[3].Text}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := texts(CollapseDuplicates(results, tt.threshold))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CollapseDuplicates(%v) = %q, want %q", tt.threshold, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollapseDuplicatesKeepsHigherRanked(t *testing.T) {
+	results := []loaders.EmbeddingResult{
+		{Text: "Plans are billed monthly", Score: 0.9},
+		{Text: "plans are billed monthly!", Score: 0.95},
+	}
+	got := CollapseDuplicates(results, 0.8)
+	if len(got) != 1 || got[0].Score != 0.9 {
+		t.Errorf("got %+v, want only the first result", got)
+	}
+}
@@ -3,15 +3,44 @@ package rag
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/Conversly/lightning-response/internal/embedder"
 	"github.com/Conversly/lightning-response/internal/loaders"
 )
 
+const (
+	ModeSimilarity = "similarity"
+	ModeMMR        = "mmr"
+)
+
 // RetrieverConfig holds configuration for RAG retrievers (e.g., chatbot ID and TopK).
 type RetrieverConfig struct {
 	ChatbotID string
 	TopK      int
+
+	// Mode selects how the final TopK is picked: ModeSimilarity or ModeMMR
+	Mode string
+	// MMRLambda trades relevance (1.0) against diversity (0.0) in ModeMMR
+	MMRLambda float64
+	// FetchK is how many candidates are over-fetched before MMR selection and de-duplication
+	FetchK int
+	// DedupeThreshold is the shingle Jaccard similarity above which two chunks are collapsed
+	DedupeThreshold float64
+
+	// Expansion widens each hit with surrounding context: ExpandNone, ExpandNeighbours or ExpandParent
+	Expansion string
+	// Neighbours is the number of chunks on each side of a hit added by ExpandNeighbours
+	Neighbours int
+	// CharBudget caps the total characters of expanded context per retrieval
+	CharBudget int
+
+	// DataSourceIDs restricts retrieval to these data sources; empty searches all of them
+	DataSourceIDs []int
+
+	// EmbeddingModel selects the vectors searched: empty for the primary column, otherwise
+	// the model the chatbot was migrated to. The embedder must produce the same model.
+	EmbeddingModel string
 }
 
 type Retriever interface {
@@ -26,35 +55,104 @@ func (n *NoopRetriever) Retrieve(ctx context.Context, query string) ([]loaders.E
 	return []loaders.EmbeddingResult{}, nil
 }
 
-// PgVectorRetriever retrieves documents from PostgreSQL using pgvector
-type PgVectorRetriever struct {
-	db        *loaders.PostgresClient
-	embedder  *embedder.GeminiEmbedder
-	chatbotID string
-	topK      int
+// VectorRetriever retrieves chunks from a VectorStore
+type VectorRetriever struct {
+	store    VectorStore
+	embedder embedder.Embedder
+	cfg      RetrieverConfig
 }
 
-// NewPgVectorRetriever creates a new retriever with chatbotID and topK configured at initialization
-func NewPgVectorRetriever(db *loaders.PostgresClient, embedder *embedder.GeminiEmbedder, chatbotID string, topK int) *PgVectorRetriever {
-	return &PgVectorRetriever{
-		db:        db,
-		embedder:  embedder,
-		chatbotID: chatbotID,
-		topK:      topK,
+// NewVectorRetriever creates a retriever over store with chatbotID, topK and selection
+// mode configured at initialization. Context expansion needs a store that can also load
+// a document's chunks (PgVectorStore and MemoryStore can); with other stores hits are
+// returned unexpanded.
+func NewVectorRetriever(store VectorStore, embedder embedder.Embedder, cfg RetrieverConfig) *VectorRetriever {
+	if cfg.Mode == "" {
+		cfg.Mode = ModeSimilarity
+	}
+	if cfg.FetchK < cfg.TopK {
+		cfg.FetchK = cfg.TopK * 4
 	}
+	return &VectorRetriever{
+		store:    store,
+		embedder: embedder,
+		cfg:      cfg,
+	}
+}
+
+// NewPgVectorRetriever creates a retriever over the embeddings table
+func NewPgVectorRetriever(db *loaders.PostgresClient, embedder embedder.Embedder, cfg RetrieverConfig) *VectorRetriever {
+	return NewVectorRetriever(NewPgVectorStore(db), embedder, cfg)
 }
 
-// Retrieve searches for relevant documents using the query
-func (r *PgVectorRetriever) Retrieve(ctx context.Context, query string) ([]loaders.EmbeddingResult, error) {
-	queryEmbedding, err := r.embedder.EmbedText(ctx, query)
+// Retrieve searches for relevant documents using the query. Candidates are over-fetched,
+// near-duplicates collapsed, the final TopK picked by similarity or MMR, and each hit
+// optionally expanded with its neighbouring chunks or parent section.
+func (r *VectorRetriever) Retrieve(ctx context.Context, query string) ([]loaders.EmbeddingResult, error) {
+	return r.retrieve(ctx, query, nil)
+}
+
+// Inspect runs Retrieve and records every step: the filter, each candidate with its
+// score and fate, and the final results
+func (r *VectorRetriever) Inspect(ctx context.Context, query string) (*Trace, error) {
+	trace := &Trace{Query: query, Config: r.cfg}
+	results, err := r.retrieve(ctx, query, trace)
+	if err != nil {
+		return nil, err
+	}
+	trace.Results = results
+	return trace, nil
+}
+
+func (r *VectorRetriever) retrieve(ctx context.Context, query string, trace *Trace) ([]loaders.EmbeddingResult, error) {
+	start := time.Now()
+	queryEmbedding, err := r.embedder.EmbedQuery(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to embed query: %w", err)
 	}
 
-	results, err := r.db.SearchEmbeddings(ctx, r.chatbotID, queryEmbedding, r.topK)
+	filter := loaders.SearchFilter{
+		Model:         r.cfg.EmbeddingModel,
+		DataSourceIDs: r.cfg.DataSourceIDs,
+	}
+	searchStart := time.Now()
+	candidates, err := r.store.Search(ctx, r.cfg.ChatbotID, queryEmbedding, r.cfg.FetchK, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search embeddings: %w", err)
 	}
+	if trace != nil {
+		trace.Filter = filter
+		trace.EmbedDuration = searchStart.Sub(start)
+		trace.SearchDuration = time.Since(searchStart)
+		trace.addCandidates(candidates)
+	}
+
+	candidates = CollapseDuplicates(candidates, r.cfg.DedupeThreshold)
+	if trace != nil {
+		trace.markKept(candidates)
+	}
+
+	var results []loaders.EmbeddingResult
+	switch r.cfg.Mode {
+	case ModeMMR:
+		results = SelectMMR(queryEmbedding, candidates, r.cfg.TopK, r.cfg.MMRLambda)
+	default:
+		if len(candidates) > r.cfg.TopK {
+			candidates = candidates[:r.cfg.TopK]
+		}
+		results = candidates
+	}
+	if trace != nil {
+		trace.markSelected(results)
+	}
+
+	src, canExpand := r.store.(chunkSource)
+	if canExpand && r.cfg.Expansion != "" && r.cfg.Expansion != ExpandNone {
+		results, err = ExpandContext(ctx, src, r.cfg.ChatbotID, results, r.cfg.Expansion, r.cfg.Neighbours, r.cfg.CharBudget)
+		if err != nil {
+			return nil, fmt.Errorf("failed to expand context: %w", err)
+		}
+	}
 
 	return results, nil
 }
@@ -0,0 +1,70 @@
+package rag
+
+import (
+	"time"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+)
+
+// Trace is the record of one retrieval produced by VectorRetriever.Inspect
+type Trace struct {
+	// Query is the text that was embedded and searched
+	Query string
+	// Config is the retriever configuration after defaults were applied
+	Config RetrieverConfig
+	// Filter is what the store search was restricted to
+	Filter loaders.SearchFilter
+	// Candidates are the store's nearest chunks, nearest first, before de-duplication
+	Candidates []TraceCandidate
+	// Results are the chunks returned to the caller, after context expansion
+	Results []loaders.EmbeddingResult
+
+	EmbedDuration  time.Duration
+	SearchDuration time.Duration
+}
+
+// TraceCandidate is a fetched chunk and what became of it
+type TraceCandidate struct {
+	loaders.EmbeddingResult
+	// Duplicate is set when the chunk was collapsed into a higher ranked near-duplicate
+	Duplicate bool
+	// Rank is the 1-based position in the final top K, or 0 if the chunk was not picked
+	Rank int
+}
+
+func (t *Trace) addCandidates(candidates []loaders.EmbeddingResult) {
+	t.Candidates = make([]TraceCandidate, len(candidates))
+	for i, c := range candidates {
+		t.Candidates[i] = TraceCandidate{EmbeddingResult: c}
+	}
+}
+
+// markKept flags the candidates missing from kept as duplicates. CollapseDuplicates keeps
+// candidates in order, so kept is walked alongside them.
+func (t *Trace) markKept(kept []loaders.EmbeddingResult) {
+	j := 0
+	for i := range t.Candidates {
+		if j < len(kept) && sameCandidate(&t.Candidates[i].EmbeddingResult, &kept[j]) {
+			j++
+			continue
+		}
+		t.Candidates[i].Duplicate = true
+	}
+}
+
+// markSelected ranks the candidates picked for the final top K
+func (t *Trace) markSelected(selected []loaders.EmbeddingResult) {
+	for rank := range selected {
+		for i := range t.Candidates {
+			c := &t.Candidates[i]
+			if !c.Duplicate && c.Rank == 0 && sameCandidate(&c.EmbeddingResult, &selected[rank]) {
+				c.Rank = rank + 1
+				break
+			}
+		}
+	}
+}
+
+func sameCandidate(a, b *loaders.EmbeddingResult) bool {
+	return a.Text == b.Text && a.Score == b.Score
+}
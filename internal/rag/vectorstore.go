@@ -0,0 +1,89 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+)
+
+// VectorRecord is one stored vector and the chunk it was computed from
+type VectorRecord struct {
+	ID        int64
+	ChatbotID string
+	// Model that produced Vector; empty for the primary vectors
+	Model        string
+	Vector       []float64
+	DataSourceID *int
+	// Chunk carries the text and position metadata returned by searches. Its Vector and
+	// Score fields are ignored.
+	Chunk loaders.EmbeddingResult
+}
+
+// VectorStore stores chunk vectors per chatbot and finds the nearest ones to a query.
+// Searches return results ordered by descending cosine similarity with Vector and Score
+// populated.
+type VectorStore interface {
+	Upsert(ctx context.Context, records []VectorRecord) error
+	// Delete removes chunks of a chatbot with the vectors of every model
+	Delete(ctx context.Context, chatbotID string, ids []int64) error
+	Search(ctx context.Context, chatbotID string, query []float64, limit int, filter loaders.SearchFilter) ([]loaders.EmbeddingResult, error)
+}
+
+// PgVectorStore is the VectorStore over the embeddings table. Chunk rows are written by
+// ingestion, so Upsert only replaces vectors of chunks that already exist; the chunk
+// metadata of records is ignored.
+type PgVectorStore struct {
+	*loaders.PostgresClient
+}
+
+// NewPgVectorStore creates a store backed by db. It also serves context expansion.
+func NewPgVectorStore(db *loaders.PostgresClient) *PgVectorStore {
+	return &PgVectorStore{PostgresClient: db}
+}
+
+// Upsert writes primary vectors into the embeddings rows and other models' vectors into
+// embedding_vectors
+func (s *PgVectorStore) Upsert(ctx context.Context, records []VectorRecord) error {
+	type group struct {
+		chatbotID, model string
+	}
+	ids := make(map[group][]int64)
+	vectors := make(map[group][][]float64)
+	var order []group
+	for _, r := range records {
+		if r.ChatbotID == "" {
+			return fmt.Errorf("vector record %d has no chatbot", r.ID)
+		}
+		g := group{r.ChatbotID, r.Model}
+		if _, ok := ids[g]; !ok {
+			order = append(order, g)
+		}
+		ids[g] = append(ids[g], r.ID)
+		vectors[g] = append(vectors[g], r.Vector)
+	}
+
+	for _, g := range order {
+		var err error
+		if g.model == "" {
+			err = s.SetPrimaryVectors(ctx, g.chatbotID, ids[g], vectors[g])
+		} else {
+			err = s.UpsertModelVectors(ctx, g.chatbotID, g.model, ids[g], vectors[g])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes the chunks' rows; their other models' vectors go with them
+func (s *PgVectorStore) Delete(ctx context.Context, chatbotID string, ids []int64) error {
+	_, err := s.DeleteEmbeddingsByID(ctx, chatbotID, ids)
+	return err
+}
+
+// Search runs a pgvector nearest-neighbour query
+func (s *PgVectorStore) Search(ctx context.Context, chatbotID string, query []float64, limit int, filter loaders.SearchFilter) ([]loaders.EmbeddingResult, error) {
+	return s.SearchEmbeddingCandidates(ctx, chatbotID, query, limit, filter)
+}
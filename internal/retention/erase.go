@@ -0,0 +1,95 @@
+package retention
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+// Erasure asks for a visitor's data to be removed from one chatbot
+type Erasure struct {
+	ChatbotID   string
+	ClientID    string // the visitor's unique client id, which is their conversation id
+	Mode        string // loaders.ErasureDelete or loaders.ErasureAnonymize
+	RequestedBy string // free text for the audit trail, e.g. a ticket reference
+}
+
+// Erase deletes or anonymizes the visitor's messages and the feedback on them, drops the
+// conversation's tags, and returns the audit record. The record keeps a hash of the
+// client id, not the id.
+//
+// Messages still queued for insertion when Erase runs are written afterwards, so an
+// erasure requested while the visitor is chatting should be repeated once they stop.
+// Export files are not rewritten; they are deleted when they expire. Leads are not
+// erased: this service has no leads table, so lead data captured by the widget has to
+// be erased by the service that stores it. Erasure responses list leads as not erased.
+func Erase(ctx context.Context, db *loaders.PostgresClient, e *Erasure) (*loaders.RetentionRunRecord, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate run id: %w", err)
+	}
+	subject := SubjectHash(e.ClientID)
+	run := &loaders.RetentionRunRecord{
+		ID:          id.String(),
+		Kind:        loaders.RetentionErasure,
+		ChatbotID:   &e.ChatbotID,
+		SubjectHash: &subject,
+		Mode:        &e.Mode,
+		Status:      loaders.RetentionRunning,
+		StartedAt:   time.Now().UTC(),
+	}
+	if e.RequestedBy != "" {
+		run.RequestedBy = &e.RequestedBy
+	}
+	if err := db.CreateRetentionRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	// Anonymized messages stay grouped as one conversation under an unrelated id
+	anonymousID := "erased-" + run.ID
+	n, err := db.EraseClientMessages(ctx, e.ChatbotID, e.ClientID, e.Mode, anonymousID)
+
+	finished := time.Now().UTC()
+	run.FinishedAt = &finished
+	run.Status = loaders.RetentionCompleted
+	if e.Mode == loaders.ErasureAnonymize {
+		run.MessagesAnonymized = n
+	} else {
+		run.MessagesDeleted = n
+	}
+	if err != nil {
+		msg := err.Error()
+		run.Status = loaders.RetentionFailed
+		run.ErrorMessage = &msg
+	}
+	if updateErr := db.UpdateRetentionRun(context.WithoutCancel(ctx), run); updateErr != nil {
+		utils.Zlog.Error("Failed to record erasure", zap.String("run_id", run.ID), zap.Error(updateErr))
+		if err == nil {
+			err = updateErr
+		}
+	}
+	if err != nil {
+		return run, err
+	}
+
+	utils.Zlog.Info("Erased visitor data",
+		zap.String("run_id", run.ID),
+		zap.String("chatbot_id", e.ChatbotID),
+		zap.String("mode", e.Mode),
+		zap.Int64("messages", n))
+	return run, nil
+}
+
+// SubjectHash is the hex SHA-256 of a client id, as stored in erasure audit records
+func SubjectHash(clientID string) string {
+	sum := sha256.Sum256([]byte(clientID))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,149 @@
+// Package retention enforces chatbots' message retention periods and erases the data of
+// individual visitors on request. Every purge and erasure leaves an audit record.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+// purgeBatchSize is how many messages each delete statement removes
+const purgeBatchSize = 5000
+
+// Purger periodically deletes messages older than their chatbot's retention period.
+// Instances take an advisory lock for each run, so only one purges at a time.
+type Purger struct {
+	db       *loaders.PostgresClient
+	interval time.Duration
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewPurger creates a purger running every interval
+func NewPurger(db *loaders.PostgresClient, interval time.Duration) *Purger {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Purger{db: db, interval: interval, ctx: ctx, cancel: cancel}
+}
+
+// Start runs a purge now and then every interval until Stop
+func (p *Purger) Start() {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			if _, err := p.Run(p.ctx); err != nil && p.ctx.Err() == nil {
+				utils.Zlog.Error("Retention purge failed", zap.Error(err))
+			}
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels a purge in progress (what was deleted stays deleted and is recorded) and
+// waits for it
+func (p *Purger) Stop() {
+	p.cancel()
+	p.wg.Wait()
+}
+
+// Run purges every chatbot with a retention period once and returns the audit record.
+// It returns nil without purging when another instance holds the lock.
+func (p *Purger) Run(ctx context.Context) (*loaders.RetentionRunRecord, error) {
+	release, ok, err := p.db.TryRetentionLock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, nil
+	}
+	defer release()
+
+	policies, err := p.db.ListRetentionPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate run id: %w", err)
+	}
+	run := &loaders.RetentionRunRecord{
+		ID:        id.String(),
+		Kind:      loaders.RetentionPurge,
+		Status:    loaders.RetentionRunning,
+		Details:   make(map[string]int64),
+		StartedAt: time.Now().UTC(),
+	}
+	if err := p.db.CreateRetentionRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	for _, policy := range policies {
+		cutoff := run.StartedAt.AddDate(0, 0, -policy.Days)
+		var deleted int64
+		deleted, err = p.purgeChatbot(ctx, policy.ChatbotID, cutoff)
+		if deleted > 0 {
+			run.Details[policy.ChatbotID] = deleted
+			run.MessagesDeleted += deleted
+		}
+		if err != nil {
+			err = fmt.Errorf("chatbot %s: %w", policy.ChatbotID, err)
+			break
+		}
+	}
+
+	finished := time.Now().UTC()
+	run.FinishedAt = &finished
+	run.Status = loaders.RetentionCompleted
+	if err != nil {
+		msg := err.Error()
+		run.Status = loaders.RetentionFailed
+		run.ErrorMessage = &msg
+	}
+	if updateErr := p.db.UpdateRetentionRun(context.WithoutCancel(ctx), run); updateErr != nil {
+		utils.Zlog.Error("Failed to record retention purge", zap.String("run_id", run.ID), zap.Error(updateErr))
+	}
+	if err != nil {
+		return run, err
+	}
+
+	utils.Zlog.Info("Retention purge completed",
+		zap.String("run_id", run.ID),
+		zap.Int("chatbots", len(policies)),
+		zap.Int64("messages_deleted", run.MessagesDeleted),
+		zap.Duration("elapsed", finished.Sub(run.StartedAt)))
+	return run, nil
+}
+
+// purgeChatbot deletes a chatbot's messages created before cutoff in batches, then the
+// classifications and activity of conversations that ended before it
+func (p *Purger) purgeChatbot(ctx context.Context, chatbotID string, cutoff time.Time) (int64, error) {
+	var total int64
+	for {
+		n, err := p.db.PurgeMessages(ctx, chatbotID, cutoff, purgeBatchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < purgeBatchSize {
+			return total, p.db.PurgeConversations(ctx, chatbotID, cutoff)
+		}
+	}
+}
@@ -1,15 +1,19 @@
 package routes
 
 import (
+	"expvar"
 	"net/http"
 
+	"github.com/Conversly/lightning-response/internal/config"
 	"github.com/Conversly/lightning-response/internal/controllers"
 	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
-// SetupHealthRoutes configures health check endpoints
-func SetupHealthRoutes(router *gin.Engine, db *loaders.PostgresClient) {
+// SetupHealthRoutes configures health check endpoints, and the process counters behind
+// the admin token
+func SetupHealthRoutes(router *gin.Engine, db *loaders.PostgresClient, cfg *config.Config) {
 	healthController := controllers.NewHealthController(db)
 
 	// Root endpoint
@@ -21,4 +25,8 @@ func SetupHealthRoutes(router *gin.Engine, db *loaders.PostgresClient) {
 
 	// Health check endpoint
 	router.GET("/health", healthController.HealthCheck)
+
+	// Process counters (embedding cache hits/misses, etc.). They include memory stats and
+	// the command line, so they are not public.
+	router.GET("/debug/vars", middleware.AdminAuth(cfg.AdminAPIToken), gin.WrapH(expvar.Handler()))
 }
@@ -1,19 +1,50 @@
 package routes
 
 import (
+	"github.com/Conversly/lightning-response/internal/api/analytics"
+	"github.com/Conversly/lightning-response/internal/api/conversation"
+	"github.com/Conversly/lightning-response/internal/api/datasource"
+	"github.com/Conversly/lightning-response/internal/api/faq"
 	"github.com/Conversly/lightning-response/internal/api/feedback"
+	"github.com/Conversly/lightning-response/internal/api/privacy"
 	"github.com/Conversly/lightning-response/internal/api/response"
+	"github.com/Conversly/lightning-response/internal/api/topic"
 	"github.com/Conversly/lightning-response/internal/config"
+	"github.com/Conversly/lightning-response/internal/embedder"
+	"github.com/Conversly/lightning-response/internal/lifecycle"
 	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/topics"
+	"github.com/Conversly/lightning-response/internal/utils"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
-// SetupRoutes configures all application routes
-func SetupRoutes(router *gin.Engine, db *loaders.PostgresClient, cfg *config.Config) {
+// SetupRoutes configures all application routes. Background workers started by the
+// route groups are registered with lm.
+func SetupRoutes(router *gin.Engine, db *loaders.PostgresClient, cfg *config.Config, lm *lifecycle.Manager) {
+	// One registry is shared by query and ingestion paths so each model draws from a
+	// single rate limiter. A nil *PostgresClient must not become a non-nil interface.
+	var shared embedder.SharedEmbeddingStore
+	if db != nil {
+		shared = db
+	}
+	embedders, err := embedder.NewRegistry(cfg, shared)
+	if err != nil {
+		utils.Zlog.Error("failed to create embedder", zap.Error(err))
+	}
+	// Shared so topic edits invalidate the cache messages are tagged from
+	classifier := topics.NewClassifier(db, cfg.TopicMatchThreshold, cfg.TopicCacheTTL, cfg.KeywordCorpusSize)
+
 	// Middleware is already applied in main.go
 	// Setup route groups
-	SetupHealthRoutes(router, db)
-	response.RegisterRoutes(router, db, cfg)
+	SetupHealthRoutes(router, db, cfg)
+	response.RegisterRoutes(router, db, cfg, embedders, classifier, lm)
 	feedback.RegisterRoutes(router, db, cfg)
+	datasource.RegisterRoutes(router, db, cfg, embedders, lm)
+	faq.RegisterRoutes(router, db, cfg, embedders)
+	conversation.RegisterRoutes(router, db, cfg, lm)
+	privacy.RegisterRoutes(router, db, cfg, lm)
+	topic.RegisterRoutes(router, db, cfg, classifier)
+	analytics.RegisterRoutes(router, db, cfg, lm)
 	Setup404Handler(router)
 }
@@ -0,0 +1,94 @@
+package splitter
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	headingRe = regexp.MustCompile(`^ {0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+	fenceRe   = regexp.MustCompile("^ {0,3}(```|~~~)")
+)
+
+// MarkdownSplitter first cuts text into sections at ATX headings (ignoring fenced code),
+// extending the heading path for each section, and then splits each section recursively.
+// Heading lines stay in their section's text so chunks read naturally.
+type MarkdownSplitter struct {
+	recursive *RecursiveSplitter
+}
+
+type mdSection struct {
+	start, end int
+	path       []string
+}
+
+func (m *MarkdownSplitter) Split(text string, basePath []string, offset int) []Chunk {
+	var chunks []Chunk
+	for _, sec := range markdownSections(text, basePath) {
+		for _, c := range m.recursive.Split(text[sec.start:sec.end], sec.path, offset+sec.start) {
+			c.Index = len(chunks)
+			chunks = append(chunks, c)
+		}
+	}
+	return chunks
+}
+
+// markdownSections returns contiguous sections covering text, one per heading
+func markdownSections(text string, basePath []string) []mdSection {
+	var sections []mdSection
+	stack := copyPath(basePath)
+	base := len(basePath)
+	current := mdSection{start: 0, path: copyPath(stack)}
+	fence := ""
+
+	pos := 0
+	for pos < len(text) {
+		lineEnd := strings.IndexByte(text[pos:], '\n')
+		next := len(text)
+		if lineEnd >= 0 {
+			next = pos + lineEnd + 1
+		}
+		line := strings.TrimRight(text[pos:next], "\r\n")
+
+		if f := fenceRe.FindStringSubmatch(line); f != nil {
+			switch {
+			case fence == "":
+				fence = f[1]
+			case fence == f[1]:
+				fence = ""
+			}
+		} else if fence == "" {
+			if h := headingRe.FindStringSubmatch(line); h != nil {
+				if pos > current.start {
+					current.end = pos
+					sections = append(sections, current)
+				}
+				level := base + len(h[1])
+				if len(stack) >= level {
+					stack = stack[:level-1]
+				}
+				for len(stack) < level-1 {
+					stack = append(stack, "")
+				}
+				stack = append(stack, h[2])
+				current = mdSection{start: pos, path: nonEmpty(stack)}
+			}
+		}
+		pos = next
+	}
+	current.end = len(text)
+	if current.end > current.start {
+		sections = append(sections, current)
+	}
+	return sections
+}
+
+func nonEmpty(path []string) []string {
+	out := make([]string, 0, len(path))
+	for _, p := range path {
+		if p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
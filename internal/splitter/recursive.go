@@ -0,0 +1,127 @@
+package splitter
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// RecursiveSplitter splits on the coarsest separator present, recursing into pieces that
+// are still too large with the next separator, then packs adjacent pieces into chunks of
+// at most ChunkSize with ChunkOverlap carried between consecutive chunks.
+type RecursiveSplitter struct {
+	cfg Config
+}
+
+// span is a [start, end) byte range of the text being split
+type span struct {
+	start, end int
+}
+
+func (s *RecursiveSplitter) Split(text string, basePath []string, offset int) []Chunk {
+	spans := s.splitSpan(text, span{0, len(text)}, s.cfg.Separators)
+
+	chunks := make([]Chunk, 0, len(spans))
+	for _, sp := range spans {
+		start, end := trimSpan(text, sp.start, sp.end)
+		if start == end {
+			continue
+		}
+		chunks = append(chunks, Chunk{
+			Text:        text[start:end],
+			Index:       len(chunks),
+			HeadingPath: copyPath(basePath),
+			Start:       offset + start,
+			End:         offset + end,
+		})
+	}
+	return chunks
+}
+
+// splitSpan returns chunk spans covering sp
+func (s *RecursiveSplitter) splitSpan(text string, sp span, seps []string) []span {
+	if s.cfg.length(text[sp.start:sp.end]) <= s.cfg.ChunkSize {
+		return []span{sp}
+	}
+
+	sep, rest := "", []string(nil)
+	for i, candidate := range seps {
+		if candidate == "" || strings.Contains(text[sp.start:sp.end], candidate) {
+			sep, rest = candidate, seps[i+1:]
+			break
+		}
+	}
+
+	var atoms []span
+	for _, piece := range splitKeepingSeparator(text, sp, sep) {
+		if s.cfg.length(text[piece.start:piece.end]) > s.cfg.ChunkSize && len(rest) > 0 {
+			atoms = append(atoms, s.splitSpan(text, piece, rest)...)
+			continue
+		}
+		atoms = append(atoms, piece)
+	}
+	return s.merge(text, atoms)
+}
+
+// splitKeepingSeparator cuts sp after every occurrence of sep so the pieces are
+// contiguous and their concatenation is the original text. An empty sep splits runes.
+func splitKeepingSeparator(text string, sp span, sep string) []span {
+	var pieces []span
+	if sep == "" {
+		for i := sp.start; i < sp.end; {
+			_, size := utf8.DecodeRuneInString(text[i:sp.end])
+			pieces = append(pieces, span{i, i + size})
+			i += size
+		}
+		return pieces
+	}
+
+	start := sp.start
+	for start < sp.end {
+		idx := strings.Index(text[start:sp.end], sep)
+		if idx < 0 {
+			pieces = append(pieces, span{start, sp.end})
+			break
+		}
+		end := start + idx + len(sep)
+		pieces = append(pieces, span{start, end})
+		start = end
+	}
+	return pieces
+}
+
+// merge packs contiguous atoms into chunks no larger than ChunkSize, starting each new
+// chunk with trailing atoms of the previous one worth up to ChunkOverlap
+func (s *RecursiveSplitter) merge(text string, atoms []span) []span {
+	var chunks []span
+	var window []span
+
+	size := func(w []span) int {
+		if len(w) == 0 {
+			return 0
+		}
+		return s.cfg.length(text[w[0].start:w[len(w)-1].end])
+	}
+
+	for _, a := range atoms {
+		candidate := append(window, a)
+		if len(window) > 0 && size(candidate) > s.cfg.ChunkSize {
+			chunks = append(chunks, span{window[0].start, window[len(window)-1].end})
+
+			// keep a tail of the emitted chunk as overlap, as long as the next atom still fits
+			keep := len(window)
+			for keep > 0 {
+				tail := window[keep-1:]
+				if size(tail) > s.cfg.ChunkOverlap || size(append(append([]span{}, tail...), a)) > s.cfg.ChunkSize {
+					break
+				}
+				keep--
+			}
+			window = append([]span{}, window[keep:]...)
+		}
+		window = append(window, a)
+	}
+	if len(window) > 0 {
+		chunks = append(chunks, span{window[0].start, window[len(window)-1].end})
+	}
+	return chunks
+}
@@ -0,0 +1,133 @@
+package splitter
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	UnitChars  = "chars"
+	UnitTokens = "tokens"
+
+	StrategyRecursive = "recursive"
+	StrategyMarkdown  = "markdown"
+
+	DefaultChunkSize    = 1000
+	DefaultChunkOverlap = 150
+)
+
+// DefaultSeparators are tried in order, from the coarsest structure to single runes
+var DefaultSeparators = []string{"\n\n", "\n", ". ", "? ", "! ", "; ", ", ", " ", ""}
+
+// Config controls how text is split. Sizes are measured in Unit.
+type Config struct {
+	Strategy     string // recursive | markdown
+	ChunkSize    int
+	ChunkOverlap int
+	Unit         string // chars | tokens
+	Separators   []string
+}
+
+// Chunk is a piece of text with its position in the source text
+type Chunk struct {
+	Text        string
+	Index       int
+	HeadingPath []string
+	Start       int // byte offset of Text in the source
+	End         int // byte offset just past Text
+}
+
+// Splitter splits text into chunks
+type Splitter interface {
+	// Split splits text whose headings so far are basePath. offset is added to every
+	// chunk's Start/End so positions can refer to a larger document.
+	Split(text string, basePath []string, offset int) []Chunk
+}
+
+// New returns the splitter for cfg.Strategy, filling unset fields with defaults
+func New(cfg Config) Splitter {
+	cfg = cfg.withDefaults()
+	if cfg.Strategy == StrategyMarkdown {
+		return &MarkdownSplitter{recursive: &RecursiveSplitter{cfg: cfg}}
+	}
+	return &RecursiveSplitter{cfg: cfg}
+}
+
+func (c Config) withDefaults() Config {
+	if c.ChunkSize <= 0 {
+		c.ChunkSize = DefaultChunkSize
+	}
+	if c.ChunkOverlap < 0 {
+		c.ChunkOverlap = 0
+	}
+	if c.ChunkOverlap >= c.ChunkSize {
+		c.ChunkOverlap = c.ChunkSize / 5
+	}
+	if c.Unit != UnitTokens {
+		c.Unit = UnitChars
+	}
+	if len(c.Separators) == 0 {
+		c.Separators = DefaultSeparators
+	}
+	return c
+}
+
+// length measures text in the configured unit
+func (c Config) length(text string) int {
+	if c.Unit == UnitTokens {
+		return CountTokens(text)
+	}
+	return utf8.RuneCountInString(text)
+}
+
+// CountTokens approximates the number of BPE tokens in text: one per short word or
+// punctuation mark, and roughly one per four characters of longer words. It tracks
+// provider tokenizers closely enough for sizing chunks without a vocabulary file.
+func CountTokens(text string) int {
+	tokens := 0
+	wordLen := 0
+	endWord := func() {
+		if wordLen > 0 {
+			tokens += (wordLen + 3) / 4
+			wordLen = 0
+		}
+	}
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			wordLen++
+		case unicode.IsSpace(r):
+			endWord()
+		default:
+			endWord()
+			tokens++
+		}
+	}
+	endWord()
+	return tokens
+}
+
+// trimSpan shrinks [start, end) to exclude surrounding whitespace
+func trimSpan(text string, start, end int) (int, int) {
+	for start < end {
+		r, size := utf8.DecodeRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += size
+	}
+	for end > start {
+		r, size := utf8.DecodeLastRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		end -= size
+	}
+	return start, end
+}
+
+func copyPath(path []string) []string {
+	out := make([]string, len(path))
+	copy(out, path)
+	return out
+}
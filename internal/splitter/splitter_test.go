@@ -0,0 +1,222 @@
+package splitter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const prose = `Conversly answers questions from your knowledge base. Upload documents, crawl a website or add curated answers.
+
+Each data source is split into chunks. Chunks keep their position in the source so answers can cite it. Overlap carries context between neighbouring chunks!
+
+Pricing depends on the plan; the free plan includes one chatbot, 500 messages a month and community support. Paid plans add more chatbots, more messages, analytics and priority support.`
+
+const markdownDoc = "Intro before any heading.\n\n" +
+	"# Setup\n\nInstall the widget on your site.\n\n" +
+	"## Domains\n\nAdd every domain that embeds the widget.\n\n" +
+	"```sh\n# not a heading\ncurl https://example.com\n```\n\n" +
+	"### Localhost\n\nLocal development only needs the API key.\n\n" +
+	"## Keys\n\nRotate keys from the dashboard.\n\n" +
+	"# Billing ##\n\nInvoices are sent monthly.\n"
+
+func TestSplitOffsetsMatchSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    Config
+		text   string
+		offset int
+	}{
+		{"recursive chars", Config{ChunkSize: 80, ChunkOverlap: 20}, prose, 0},
+		{"recursive without overlap", Config{ChunkSize: 60}, prose, 0},
+		{"recursive with offset", Config{ChunkSize: 80, ChunkOverlap: 20}, prose, 1234},
+		{"recursive tokens", Config{ChunkSize: 20, ChunkOverlap: 5, Unit: UnitTokens}, prose, 0},
+		{"rune split", Config{ChunkSize: 7, ChunkOverlap: 2}, "Grüße aus Köln, straße 12 — näher am Zentrum", 0},
+		{"multibyte", Config{ChunkSize: 10, ChunkOverlap: 3}, "मुझे अपना पासवर्ड बदलना है। मेरे ऑर्डर का invoice कहाँ है?", 0},
+		{"markdown", Config{Strategy: StrategyMarkdown, ChunkSize: 60, ChunkOverlap: 10}, markdownDoc, 0},
+		{"markdown with offset", Config{Strategy: StrategyMarkdown, ChunkSize: 60, ChunkOverlap: 10}, markdownDoc, 42},
+		{"single chunk", Config{}, "  short text \n", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := tt.cfg.withDefaults()
+			chunks := New(tt.cfg).Split(tt.text, nil, tt.offset)
+			if len(chunks) == 0 {
+				t.Fatal("no chunks")
+			}
+			prevStart := -1
+			for i, c := range chunks {
+				start, end := c.Start-tt.offset, c.End-tt.offset
+				if start < 0 || end > len(tt.text) || start >= end {
+					t.Fatalf("chunk %d has span [%d, %d) outside the text", i, c.Start, c.End)
+				}
+				if got := tt.text[start:end]; got != c.Text {
+					t.Errorf("chunk %d: source[%d:%d] = %q, Text = %q", i, c.Start, c.End, got, c.Text)
+				}
+				if !utf8.ValidString(c.Text) {
+					t.Errorf("chunk %d cuts a rune: %q", i, c.Text)
+				}
+				if c.Text != strings.TrimSpace(c.Text) {
+					t.Errorf("chunk %d is not trimmed: %q", i, c.Text)
+				}
+				if n := cfg.length(c.Text); n > cfg.ChunkSize {
+					t.Errorf("chunk %d is %d %s, over %d", i, n, cfg.Unit, cfg.ChunkSize)
+				}
+				if c.Index != i {
+					t.Errorf("chunk %d has index %d", i, c.Index)
+				}
+				if c.Start <= prevStart {
+					t.Errorf("chunk %d starts at %d, not after the previous start %d", i, c.Start, prevStart)
+				}
+				prevStart = c.Start
+			}
+		})
+	}
+}
+
+func TestSplitCoversText(t *testing.T) {
+	chunks := New(Config{ChunkSize: 50}).Split(prose, nil, 0)
+
+	// Without overlap the chunks are the words of the text, in order, once each
+	var words []string
+	for _, c := range chunks {
+		words = append(words, strings.Fields(c.Text)...)
+	}
+	if want := strings.Fields(prose); !reflect.DeepEqual(words, want) {
+		t.Errorf("chunk words = %q, want %q", words, want)
+	}
+	for i := 1; i < len(chunks); i++ {
+		if chunks[i].Start < chunks[i-1].End {
+			t.Errorf("chunks %d and %d overlap without ChunkOverlap", i-1, i)
+		}
+	}
+}
+
+func TestSplitOverlap(t *testing.T) {
+	// Overlap is made of whole atoms, here sentences, so they are kept short
+	text := "Open the dashboard. Select your chatbot. Click data sources. Add a website. " +
+		"Enter its address. Start the crawl. Wait for the pages. Review the chunks. " +
+		"Test a question. Publish the widget."
+	const overlap = 40
+	chunks := New(Config{ChunkSize: 80, ChunkOverlap: overlap}).Split(text, nil, 0)
+	if len(chunks) < 3 {
+		t.Fatalf("got %d chunks, want several", len(chunks))
+	}
+
+	overlapping := 0
+	for i := 1; i < len(chunks); i++ {
+		prev, c := chunks[i-1], chunks[i]
+		if c.End <= prev.End {
+			t.Errorf("chunk %d [%d, %d) does not extend past chunk %d [%d, %d)", i, c.Start, c.End, i-1, prev.Start, prev.End)
+		}
+		if c.Start >= prev.End {
+			continue
+		}
+		overlapping++
+		shared := text[c.Start:prev.End]
+		if n := utf8.RuneCountInString(strings.TrimSpace(shared)); n > overlap {
+			t.Errorf("chunks %d and %d share %d chars (%q), over %d", i-1, i, n, shared, overlap)
+		}
+		if !strings.HasPrefix(c.Text, strings.TrimSpace(shared)) {
+			t.Errorf("chunk %d does not start with the tail of chunk %d: %q", i, i-1, shared)
+		}
+	}
+	if overlapping == 0 {
+		t.Error("no consecutive chunks overlap")
+	}
+}
+
+func TestSplitTokenUnit(t *testing.T) {
+	const size = 16
+	tokens := New(Config{ChunkSize: size, ChunkOverlap: 4, Unit: UnitTokens}).Split(prose, nil, 0)
+	chars := New(Config{ChunkSize: size, ChunkOverlap: 4}).Split(prose, nil, 0)
+
+	for i, c := range tokens {
+		if n := CountTokens(c.Text); n > size {
+			t.Errorf("chunk %d has %d tokens, over %d", i, n, size)
+		}
+	}
+	// A token is several characters, so the same size in tokens gives fewer chunks
+	if len(tokens) >= len(chars) {
+		t.Errorf("got %d token chunks and %d char chunks, want fewer token chunks", len(tokens), len(chars))
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"   ", 0},
+		{"the cat sat", 3},
+		{"hello, world!", 6},
+		{"internationalization", 5}, // 20 letters
+		{"v2.5", 3},
+		{"naïve café", 3},
+	}
+	for _, tt := range tests {
+		if got := CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownHeadingPaths(t *testing.T) {
+	chunks := New(Config{Strategy: StrategyMarkdown, ChunkSize: 1000}).Split(markdownDoc, []string{"Guide"}, 0)
+
+	want := []struct {
+		prefix string
+		path   []string
+	}{
+		{"Intro before any heading.", []string{"Guide"}},
+		{"# Setup", []string{"Guide", "Setup"}},
+		{"## Domains", []string{"Guide", "Setup", "Domains"}},
+		{"### Localhost", []string{"Guide", "Setup", "Domains", "Localhost"}},
+		{"## Keys", []string{"Guide", "Setup", "Keys"}},
+		{"# Billing ##", []string{"Guide", "Billing"}},
+	}
+	if len(chunks) != len(want) {
+		for _, c := range chunks {
+			t.Logf("%v %q", c.HeadingPath, c.Text)
+		}
+		t.Fatalf("got %d chunks, want %d", len(chunks), len(want))
+	}
+	for i, w := range want {
+		c := chunks[i]
+		if !strings.HasPrefix(c.Text, w.prefix) {
+			t.Errorf("chunk %d starts %q, want %q", i, c.Text, w.prefix)
+		}
+		if !reflect.DeepEqual(c.HeadingPath, w.path) {
+			t.Errorf("chunk %d has heading path %q, want %q", i, c.HeadingPath, w.path)
+		}
+	}
+	// The commented line in the fence stays in its section
+	if !strings.Contains(chunks[2].Text, "# not a heading") {
+		t.Errorf("fenced code left its section: %q", chunks[2].Text)
+	}
+}
+
+func TestMarkdownSkippedLevels(t *testing.T) {
+	chunks := New(Config{Strategy: StrategyMarkdown}).Split("# Top\n\ntext\n\n### Deep\n\nmore\n", nil, 0)
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(chunks))
+	}
+	if want := []string{"Top", "Deep"}; !reflect.DeepEqual(chunks[1].HeadingPath, want) {
+		t.Errorf("heading path %q, want %q", chunks[1].HeadingPath, want)
+	}
+}
+
+func TestMarkdownSplitsLongSections(t *testing.T) {
+	text := "# FAQ\n\n" + prose
+	chunks := New(Config{Strategy: StrategyMarkdown, ChunkSize: 80, ChunkOverlap: 10}).Split(text, nil, 0)
+	if len(chunks) < 3 {
+		t.Fatalf("got %d chunks, want the section split further", len(chunks))
+	}
+	for i, c := range chunks {
+		if !reflect.DeepEqual(c.HeadingPath, []string{"FAQ"}) {
+			t.Errorf("chunk %d has heading path %q", i, c.HeadingPath)
+		}
+	}
+}
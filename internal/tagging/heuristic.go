@@ -0,0 +1,108 @@
+package tagging
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+// Classification methods
+const (
+	methodLLM       = "llm"
+	methodHeuristic = "heuristic"
+)
+
+const (
+	maxTopics = 3
+	maxTags   = 5
+)
+
+// result is a conversation's classification before it is stored
+type result struct {
+	topicIDs   []string
+	tags       []string
+	sentiment  *string
+	resolution *string
+}
+
+// classifyHeuristic classifies a conversation from what is already known about its
+// messages. Topics are those of its user messages, most frequent first, and tags their
+// keywords, scored against corpus when it is not nil. Sentiment and resolution come from
+// the feedback left on it and stay unknown without any.
+func classifyHeuristic(messages []loaders.TranscriptMessage, defs []loaders.TopicDefinition, corpus *utils.KeywordCorpus) *result {
+	res := &result{topicIDs: []string{}, tags: []string{}}
+
+	otherID := ""
+	for _, d := range defs {
+		if strings.EqualFold(d.Name, "other") {
+			otherID = d.ID
+		}
+	}
+
+	counts := make(map[string]int)
+	var order []string
+	var text strings.Builder
+	likes, dislikes, neutral := 0, 0, 0
+	var lastFeedback int16
+	for _, m := range messages {
+		if m.Type == "user" {
+			if m.TopicID != nil && *m.TopicID != "" {
+				if counts[*m.TopicID] == 0 {
+					order = append(order, *m.TopicID)
+				}
+				counts[*m.TopicID]++
+			}
+			text.WriteString(m.Content)
+			text.WriteString("\n")
+		}
+		switch m.Feedback {
+		case 1:
+			likes++
+		case 2:
+			dislikes++
+		case 3:
+			neutral++
+		}
+		if m.Feedback != 0 && m.Type == "assistant" {
+			lastFeedback = m.Feedback
+		}
+	}
+
+	// Stable so ties keep the order topics came up in
+	sort.SliceStable(order, func(i, j int) bool { return counts[order[i]] > counts[order[j]] })
+	for _, id := range order {
+		// "other" only describes a conversation that matched nothing else
+		if id == otherID && len(order) > 1 {
+			continue
+		}
+		if len(res.topicIDs) == maxTopics {
+			break
+		}
+		res.topicIDs = append(res.topicIDs, id)
+	}
+
+	res.tags = append(res.tags, utils.ExtractKeywordsWith(text.String(), maxTags, utils.KeywordOptions{Corpus: corpus})...)
+
+	switch {
+	case likes > dislikes:
+		res.sentiment = ptr(loaders.SentimentPositive)
+	case dislikes > likes:
+		res.sentiment = ptr(loaders.SentimentNegative)
+	case likes+dislikes+neutral > 0:
+		res.sentiment = ptr(loaders.SentimentNeutral)
+	}
+
+	switch lastFeedback {
+	case 1:
+		res.resolution = ptr(loaders.ResolutionResolved)
+	case 2:
+		res.resolution = ptr(loaders.ResolutionUnresolved)
+	}
+	return res
+}
+
+func ptr(s string) *string {
+	return &s
+}
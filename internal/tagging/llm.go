@@ -0,0 +1,153 @@
+package tagging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+
+	"github.com/Conversly/lightning-response/internal/llm"
+	"github.com/Conversly/lightning-response/internal/loaders"
+)
+
+const (
+	// llmTimeout bounds one classification request
+	llmTimeout = 30 * time.Second
+	// maxTranscriptChars is how much of a transcript is sent, keeping its latest messages
+	maxTranscriptChars = 12000
+	llmMaxTokens       = 300
+)
+
+const systemPrompt = `You classify customer support conversations between a visitor and an assistant.
+Reply with a single JSON object and nothing else, with these fields:
+- "topics": names of at most 3 topics from the given list that the conversation is about, most relevant first; [] if none apply
+- "tags": at most 5 short lowercase tags describing what the visitor asked about
+- "sentiment": the visitor's overall sentiment, one of "positive", "neutral", "negative"
+- "resolution": "resolved" if the visitor got what they needed, "escalated" if they were handed to or asked for a human, "unresolved" otherwise`
+
+// llmClassifier classifies conversations with a chat model
+type llmClassifier struct {
+	chat  *llm.MultiKeyChatModel
+	model string
+}
+
+func newLLMClassifier(ctx context.Context, keys []string, model string) (*llmClassifier, error) {
+	temp := float32(0)
+	maxTokens := llmMaxTokens
+	chat, err := llm.NewMultiKeyChatModel(ctx, keys, model, &temp, &maxTokens)
+	if err != nil {
+		return nil, err
+	}
+	return &llmClassifier{chat: chat, model: model}, nil
+}
+
+// llmReply is the JSON the model is asked for
+type llmReply struct {
+	Topics     []string `json:"topics"`
+	Tags       []string `json:"tags"`
+	Sentiment  string   `json:"sentiment"`
+	Resolution string   `json:"resolution"`
+}
+
+// classify asks the model to classify the conversation. Topic names it returns that are
+// not among defs, and fields with unexpected values, are dropped.
+func (l *llmClassifier) classify(ctx context.Context, messages []loaders.TranscriptMessage, defs []loaders.TopicDefinition) (*result, error) {
+	ctx, cancel := context.WithTimeout(ctx, llmTimeout)
+	defer cancel()
+
+	reply, err := l.chat.Generate(ctx, []*schema.Message{
+		schema.SystemMessage(systemPrompt),
+		schema.UserMessage(buildPrompt(messages, defs)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate classification: %w", err)
+	}
+	parsed, err := parseReply(reply.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &result{topicIDs: []string{}, tags: []string{}}
+	ids := make(map[string]string, len(defs))
+	for _, d := range defs {
+		ids[strings.ToLower(strings.TrimSpace(d.Name))] = d.ID
+	}
+	seen := make(map[string]bool)
+	for _, name := range parsed.Topics {
+		id, ok := ids[strings.ToLower(strings.TrimSpace(name))]
+		if !ok || seen[id] || len(res.topicIDs) == maxTopics {
+			continue
+		}
+		seen[id] = true
+		res.topicIDs = append(res.topicIDs, id)
+	}
+	seen = make(map[string]bool)
+	for _, tag := range parsed.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] || len(res.tags) == maxTags {
+			continue
+		}
+		seen[tag] = true
+		res.tags = append(res.tags, tag)
+	}
+	switch s := strings.ToLower(parsed.Sentiment); s {
+	case loaders.SentimentPositive, loaders.SentimentNeutral, loaders.SentimentNegative:
+		res.sentiment = &s
+	}
+	switch r := strings.ToLower(parsed.Resolution); r {
+	case loaders.ResolutionResolved, loaders.ResolutionUnresolved, loaders.ResolutionEscalated:
+		res.resolution = &r
+	}
+	return res, nil
+}
+
+// buildPrompt lists the chatbot's topics and the transcript, dropping its oldest
+// messages when it is too long
+func buildPrompt(messages []loaders.TranscriptMessage, defs []loaders.TopicDefinition) string {
+	var b strings.Builder
+	b.WriteString("Topics:\n")
+	for _, d := range defs {
+		b.WriteString("- ")
+		b.WriteString(d.Name)
+		if d.Description != nil && strings.TrimSpace(*d.Description) != "" {
+			b.WriteString(": ")
+			b.WriteString(strings.TrimSpace(*d.Description))
+		}
+		b.WriteString("\n")
+	}
+
+	lines := make([]string, 0, len(messages))
+	size := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		line := messages[i].Type + ": " + strings.TrimSpace(messages[i].Content) + "\n"
+		if size+len(line) > maxTranscriptChars && len(lines) > 0 {
+			break
+		}
+		lines = append(lines, line)
+		size += len(line)
+	}
+	b.WriteString("\nConversation:\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		b.WriteString(lines[i])
+	}
+	return b.String()
+}
+
+// parseReply extracts the JSON object from the model's reply, which may be wrapped in
+// prose or a code fence
+func parseReply(content string) (*llmReply, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("classification reply has no JSON object")
+	}
+	var reply llmReply
+	if err := json.Unmarshal([]byte(content[start:end+1]), &reply); err != nil {
+		return nil, fmt.Errorf("failed to parse classification reply: %w", err)
+	}
+	return &reply, nil
+}
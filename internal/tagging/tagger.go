@@ -0,0 +1,261 @@
+// Package tagging classifies whole conversations once they go idle: the topics they
+// cover, free-form tags, the visitor's sentiment and whether they got their answer.
+package tagging
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+const (
+	// lookback bounds how far back idle conversations are searched for; conversations
+	// that went idle earlier and were never tagged are skipped
+	lookback = 24 * time.Hour
+	// tagBatchSize is how many conversations are listed at a time
+	tagBatchSize = 100
+	// maxBatchesPerRun caps the work of one run so a backlog drains over several
+	maxBatchesPerRun = 20
+	// maxTranscriptMessages is how many of a conversation's latest messages are read
+	maxTranscriptMessages = 100
+	// maxRetryBackoff caps how long a conversation that failed to be tagged is skipped
+	maxRetryBackoff = time.Hour
+)
+
+// Options configures a Tagger
+type Options struct {
+	IdleAfter time.Duration // silence after which a conversation is tagged
+	Interval  time.Duration // time between runs
+	// LLMModel classifies conversations when set; heuristics are used otherwise and
+	// whenever the model fails
+	LLMModel      string
+	GeminiAPIKeys []string
+	// KeywordCorpusSize is how many of a chatbot's latest user messages heuristic tags
+	// are scored against by TF-IDF; 0 scores them by frequency
+	KeywordCorpusSize int
+}
+
+// chatbotData is what a run loads once per chatbot
+type chatbotData struct {
+	topics []loaders.TopicDefinition
+	corpus *utils.KeywordCorpus
+}
+
+// tagFailure is a conversation that failed to be tagged and is skipped until retryAt
+type tagFailure struct {
+	attempts      int
+	retryAt       time.Time
+	lastMessageAt time.Time
+}
+
+// Tagger periodically classifies conversations that have gone idle and stores the
+// results in conversation_tags. A conversation that resumes is classified again once it
+// is idle again. Instances take an advisory lock for each run, so only one tags at a time.
+type Tagger struct {
+	db   *loaders.PostgresClient
+	opts Options
+	llm  *llmClassifier
+
+	mu       sync.Mutex
+	failures map[string]*tagFailure // chatbot id | conversation id -> failure
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewTagger creates a tagger. If the LLM cannot be set up, heuristics are used.
+func NewTagger(db *loaders.PostgresClient, opts Options) *Tagger {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &Tagger{db: db, opts: opts, failures: make(map[string]*tagFailure), ctx: ctx, cancel: cancel}
+	if opts.LLMModel != "" {
+		classifier, err := newLLMClassifier(ctx, opts.GeminiAPIKeys, opts.LLMModel)
+		if err != nil {
+			utils.Zlog.Warn("Failed to set up LLM conversation tagging, using heuristics", zap.Error(err))
+		} else {
+			t.llm = classifier
+		}
+	}
+	return t
+}
+
+// Start runs the tagger every interval until Stop
+func (t *Tagger) Start() {
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+
+		ticker := time.NewTicker(t.opts.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := t.Run(t.ctx); err != nil && t.ctx.Err() == nil {
+				utils.Zlog.Error("Conversation tagging failed", zap.Error(err))
+			}
+		}
+	}()
+}
+
+// Stop cancels a run in progress (conversations already tagged stay tagged) and waits
+// for it
+func (t *Tagger) Stop() {
+	t.cancel()
+	t.wg.Wait()
+}
+
+// Run tags the conversations that have gone idle since they were last tagged. It does
+// nothing when another instance holds the lock. A conversation that fails to be tagged
+// is logged and skipped, with a backoff, so it cannot hold up the others.
+func (t *Tagger) Run(ctx context.Context) error {
+	release, ok, err := t.db.TryTaggingLock(ctx)
+	if err != nil || !ok {
+		return err
+	}
+	defer release()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now().UTC()
+	idleBefore := now.Add(-t.opts.IdleAfter)
+	since := idleBefore.Add(-lookback)
+	chatbots := make(map[string]*chatbotData)
+	tagged, failed := 0, 0
+	defer func() {
+		if tagged > 0 || failed > 0 {
+			utils.Zlog.Info("Tagged idle conversations",
+				zap.Int("conversations", tagged),
+				zap.Int("failed", failed),
+				zap.Duration("elapsed", time.Since(now)))
+		}
+	}()
+
+	// Conversations that went idle before the lookback are never listed again
+	for key, f := range t.failures {
+		if f.lastMessageAt.Before(since) {
+			delete(t.failures, key)
+		}
+	}
+
+	for range maxBatchesPerRun {
+		idle, err := t.db.ListIdleConversations(ctx, since, idleBefore, tagBatchSize)
+		if err != nil {
+			return err
+		}
+		attempted := 0
+		for _, ic := range idle {
+			key := ic.ChatbotID + "|" + ic.ConversationID
+			f := t.failures[key]
+			if f != nil && now.Before(f.retryAt) {
+				continue
+			}
+			attempted++
+			// Classification falls back to heuristics, so errors here are database
+			// errors
+			if err := t.tag(ctx, &ic, chatbots); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				if f == nil {
+					f = &tagFailure{}
+					t.failures[key] = f
+				}
+				f.attempts++
+				f.lastMessageAt = ic.LastMessageAt
+				backoff := min(max(t.opts.Interval, time.Minute)<<min(f.attempts-1, 16), maxRetryBackoff)
+				f.retryAt = now.Add(backoff)
+				failed++
+				utils.Zlog.Warn("Failed to tag conversation",
+					zap.String("chatbot_id", ic.ChatbotID),
+					zap.String("conversation_id", ic.ConversationID),
+					zap.Int("attempts", f.attempts),
+					zap.Duration("retry_in", backoff),
+					zap.Error(err))
+				continue
+			}
+			delete(t.failures, key)
+			tagged++
+		}
+		// Skipped conversations are listed again, so a batch of them is the end
+		if len(idle) < tagBatchSize || attempted == 0 {
+			break
+		}
+	}
+	return nil
+}
+
+// tag classifies one conversation and stores the result. chatbots caches what is loaded
+// for each chatbot for the run.
+func (t *Tagger) tag(ctx context.Context, ic *loaders.IdleConversation, chatbots map[string]*chatbotData) error {
+	messages, _, err := t.db.GetConversationMessages(ctx, ic.ChatbotID, ic.ConversationID, maxTranscriptMessages)
+	if errors.Is(err, loaders.ErrConversationNotFound) {
+		// Erased or purged since it was listed
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	data, ok := chatbots[ic.ChatbotID]
+	if !ok {
+		if data, err = t.load(ctx, ic.ChatbotID); err != nil {
+			return err
+		}
+		chatbots[ic.ChatbotID] = data
+	}
+
+	tags := &loaders.ConversationTags{
+		ChatbotID:      ic.ChatbotID,
+		ConversationID: ic.ConversationID,
+		MessageCount:   ic.MessageCount,
+		LastMessageAt:  ic.LastMessageAt,
+	}
+	res := classifyHeuristic(messages, data.topics, data.corpus)
+	tags.Method = methodHeuristic
+	if t.llm != nil {
+		llmRes, err := t.llm.classify(ctx, messages, data.topics)
+		if err == nil {
+			res = llmRes
+			tags.Method = methodLLM
+			tags.Model = &t.llm.model
+		} else if ctx.Err() == nil {
+			utils.Zlog.Warn("LLM conversation tagging failed, using heuristics",
+				zap.String("chatbot_id", ic.ChatbotID),
+				zap.String("conversation_id", ic.ConversationID),
+				zap.Error(err))
+		}
+	}
+	tags.TopicIDs = res.topicIDs
+	tags.Tags = res.tags
+	tags.Sentiment = res.sentiment
+	tags.Resolution = res.resolution
+	tags.ClassifiedAt = time.Now().UTC()
+	return t.db.UpsertConversationTags(ctx, tags)
+}
+
+// load reads a chatbot's topics and, when enabled, its keyword corpus
+func (t *Tagger) load(ctx context.Context, chatbotID string) (*chatbotData, error) {
+	defs, err := t.db.ListTopicDefinitions(ctx, chatbotID)
+	if err != nil {
+		return nil, err
+	}
+	data := &chatbotData{topics: defs}
+	if t.opts.KeywordCorpusSize > 0 {
+		messages, err := t.db.ListRecentUserMessages(ctx, chatbotID, t.opts.KeywordCorpusSize)
+		if err != nil {
+			return nil, err
+		}
+		data.corpus = utils.NewKeywordCorpus(messages)
+	}
+	return data, nil
+}
@@ -30,18 +30,18 @@ type RAGToolOutput struct {
 // RAGTool implements the Eino InvokableTool interface for knowledge base retrieval
 type RAGTool struct {
 	db        *loaders.PostgresClient
-	embedder  *embedder.GeminiEmbedder
+	embedder  embedder.Embedder
 	chatbotID string
-	topK      int
+	retrCfg   rag.RetrieverConfig
 }
 
 // NewRAGTool creates a new RAG tool instance
-func NewRAGTool(db *loaders.PostgresClient, embedder *embedder.GeminiEmbedder, chatbotID string, topK int) *RAGTool {
+func NewRAGTool(db *loaders.PostgresClient, embedder embedder.Embedder, retrCfg rag.RetrieverConfig) *RAGTool {
 	return &RAGTool{
 		db:        db,
 		embedder:  embedder,
-		chatbotID: chatbotID,
-		topK:      topK,
+		chatbotID: retrCfg.ChatbotID,
+		retrCfg:   retrCfg,
 	}
 }
 
@@ -82,7 +82,7 @@ func (r *RAGTool) InvokableRun(ctx context.Context, argumentsInJSON string, opts
 		zap.String("query", input.Query))
 
 	// Create retriever and perform search
-	retriever := rag.NewPgVectorRetriever(r.db, r.embedder, r.chatbotID, r.topK)
+	retriever := rag.NewPgVectorRetriever(r.db, r.embedder, r.retrCfg)
 	results, err := retriever.Retrieve(ctx, input.Query)
 	if err != nil {
 		utils.Zlog.Error("RAG retrieval failed",
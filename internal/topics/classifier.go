@@ -0,0 +1,280 @@
+// Package topics tags user messages with the chatbot topic they are about.
+package topics
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/Conversly/lightning-response/internal/embedder"
+	"github.com/Conversly/lightning-response/internal/loaders"
+	"github.com/Conversly/lightning-response/internal/rag"
+	"github.com/Conversly/lightning-response/internal/types"
+	"github.com/Conversly/lightning-response/internal/utils"
+)
+
+// retryInterval is how long a chatbot whose topics could not be embedded is classified
+// by keywords before embedding is tried again
+const retryInterval = time.Minute
+
+// Classifier tags messages by embedding similarity to each topic's name, description
+// and example messages. A chatbot's topics and their vectors are cached per embedding
+// model. When the topics or the message cannot be embedded the keyword matcher is used.
+type Classifier struct {
+	db         *loaders.PostgresClient
+	threshold  float64
+	ttl        time.Duration
+	corpusSize int
+
+	mu    sync.Mutex
+	cache map[string]*topicSet // chatbot id | model -> topics
+}
+
+// topicSet is a chatbot's topics as cached
+type topicSet struct {
+	topics  []types.ChatbotTopic // for the keyword fallback
+	otherID string
+	// vectors of each topic's texts; nil when they could not be embedded
+	vectors []topicVector
+	// recent user messages keywords are scored against; nil when not used
+	corpus  *utils.KeywordCorpus
+	expires time.Time
+}
+
+type topicVector struct {
+	topicID string
+	vector  []float64
+}
+
+// NewClassifier creates a classifier tagging a message with the closest topic scoring at
+// least threshold, caching topics for ttl. Chatbots classified by keywords have them
+// scored by TF-IDF against their latest corpusSize user messages; 0 scores by frequency.
+func NewClassifier(db *loaders.PostgresClient, threshold float64, ttl time.Duration, corpusSize int) *Classifier {
+	return &Classifier{
+		db:         db,
+		threshold:  threshold,
+		ttl:        ttl,
+		corpusSize: corpusSize,
+		cache:      make(map[string]*topicSet),
+	}
+}
+
+// Classify returns the id of the topic of message, the "other" topic when none is close
+// enough, or "" when the chatbot has no topics or they could not be loaded. emb is the
+// chatbot's embedder; nil classifies by keywords. vec is message as embedded by emb, or
+// nil to embed it here.
+func (c *Classifier) Classify(ctx context.Context, chatbotID string, emb embedder.Embedder, message string, vec []float64) string {
+	set, err := c.topicsFor(ctx, chatbotID, emb)
+	if err != nil {
+		utils.Zlog.Debug("Failed to load chatbot topics for topic tagging",
+			zap.String("chatbot_id", chatbotID),
+			zap.Error(err))
+		return ""
+	}
+	if len(set.topics) == 0 {
+		return ""
+	}
+
+	if set.vectors != nil && strings.TrimSpace(message) != "" {
+		var err error
+		if vec == nil {
+			vec, err = emb.EmbedQuery(ctx, message)
+		}
+		if err == nil {
+			return set.closest(vec, c.threshold)
+		}
+		utils.Zlog.Debug("Failed to embed message for topic tagging, matching keywords",
+			zap.String("chatbot_id", chatbotID),
+			zap.Error(err))
+	}
+	lang := utils.DetectLanguage(message)
+	keywords := utils.ExtractKeywordsWith(message, 4, utils.KeywordOptions{Language: lang, Corpus: set.corpus})
+	return utils.MatchTopicFromKeywords(keywords, lang, set.topics)
+}
+
+// Invalidate drops the cached topics of a chatbot so the next message reloads them
+func (c *Classifier) Invalidate(chatbotID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.cache {
+		if strings.HasPrefix(key, chatbotID+"|") {
+			delete(c.cache, key)
+		}
+	}
+}
+
+// closest returns the topic with the most similar text, or the "other" topic when the
+// best score is below threshold
+func (s *topicSet) closest(vec []float64, threshold float64) string {
+	best, bestScore := "", -1.0
+	for _, tv := range s.vectors {
+		if score := rag.CosineSimilarity(vec, tv.vector); score > bestScore {
+			best, bestScore = tv.topicID, score
+		}
+	}
+	if bestScore < threshold {
+		return s.otherID
+	}
+	return best
+}
+
+// topicsFor returns the chatbot's cached topics, loading and embedding them when the
+// cache is stale
+func (c *Classifier) topicsFor(ctx context.Context, chatbotID string, emb embedder.Embedder) (*topicSet, error) {
+	model := ""
+	if emb != nil {
+		model = emb.Model()
+	}
+	key := chatbotID + "|" + model
+
+	c.mu.Lock()
+	set, ok := c.cache[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(set.expires) {
+		return set, nil
+	}
+
+	set, err := c.load(ctx, chatbotID, emb)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.cache[key] = set
+	c.mu.Unlock()
+	return set, nil
+}
+
+// load reads the chatbot's topics and their vectors, embedding texts that have none
+func (c *Classifier) load(ctx context.Context, chatbotID string, emb embedder.Embedder) (*topicSet, error) {
+	defs, err := c.db.ListTopicDefinitions(ctx, chatbotID)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &topicSet{topics: make([]types.ChatbotTopic, len(defs)), expires: time.Now().Add(c.ttl)}
+	for i, d := range defs {
+		set.topics[i] = types.ChatbotTopic{ID: d.ID, Name: d.Name, Color: d.Color}
+	}
+	set.otherID = utils.OtherTopicID(set.topics)
+	if len(defs) == 0 {
+		return set, nil
+	}
+	if emb == nil {
+		set.corpus = c.loadCorpus(ctx, chatbotID)
+		return set, nil
+	}
+
+	vectors, err := c.embedTopics(ctx, chatbotID, emb, defs)
+	if err != nil {
+		utils.Zlog.Warn("Failed to embed chatbot topics, tagging by keywords",
+			zap.String("chatbot_id", chatbotID),
+			zap.Error(err))
+		set.expires = time.Now().Add(min(c.ttl, retryInterval))
+		set.corpus = c.loadCorpus(ctx, chatbotID)
+		return set, nil
+	}
+	set.vectors = vectors
+	return set, nil
+}
+
+// loadCorpus returns the keyword corpus of a chatbot classified by keywords, or nil to
+// score keywords by frequency
+func (c *Classifier) loadCorpus(ctx context.Context, chatbotID string) *utils.KeywordCorpus {
+	if c.corpusSize <= 0 {
+		return nil
+	}
+	messages, err := c.db.ListRecentUserMessages(ctx, chatbotID, c.corpusSize)
+	if err != nil {
+		utils.Zlog.Warn("Failed to load keyword corpus, scoring keywords by frequency",
+			zap.String("chatbot_id", chatbotID),
+			zap.Error(err))
+		return nil
+	}
+	return utils.NewKeywordCorpus(messages)
+}
+
+// embedTopics returns a vector for each text of each topic other than the "other" topic.
+// Vectors are stored by text hash, so only new or changed text is embedded.
+func (c *Classifier) embedTopics(ctx context.Context, chatbotID string, emb embedder.Embedder, defs []loaders.TopicDefinition) ([]topicVector, error) {
+	type topicText struct {
+		topicID string
+		text    string
+		hash    string
+	}
+	var texts []topicText
+	var hashes []string
+	for _, d := range defs {
+		if strings.EqualFold(d.Name, "other") {
+			continue
+		}
+		for _, text := range topicTexts(&d) {
+			h := textHash(text)
+			texts = append(texts, topicText{topicID: d.ID, text: text, hash: h})
+			hashes = append(hashes, h)
+		}
+	}
+	if len(texts) == 0 {
+		return []topicVector{}, nil
+	}
+
+	stored, err := c.db.GetTopicVectors(ctx, chatbotID, emb.Model(), hashes)
+	if err != nil {
+		return nil, err
+	}
+	added := make(map[string][]float64)
+	for _, t := range texts {
+		if _, ok := stored[t.hash]; ok {
+			continue
+		}
+		vec, err := emb.EmbedQuery(ctx, t.text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed topic text: %w", err)
+		}
+		stored[t.hash] = vec
+		added[t.hash] = vec
+	}
+	if len(added) > 0 {
+		// Vectors are usable even if they could not be stored; they are embedded again
+		// on the next load
+		if err := c.db.StoreTopicVectors(ctx, chatbotID, emb.Model(), added, hashes); err != nil {
+			utils.Zlog.Warn("Failed to store topic vectors", zap.String("chatbot_id", chatbotID), zap.Error(err))
+		}
+	}
+
+	vectors := make([]topicVector, len(texts))
+	for i, t := range texts {
+		vectors[i] = topicVector{topicID: t.topicID, vector: stored[t.hash]}
+	}
+	return vectors, nil
+}
+
+// topicTexts returns the texts embedded for a topic: its name, with its description when
+// it has one, and each distinct example message
+func topicTexts(d *loaders.TopicDefinition) []string {
+	head := strings.TrimSpace(d.Name)
+	if d.Description != nil && strings.TrimSpace(*d.Description) != "" {
+		head += ": " + strings.TrimSpace(*d.Description)
+	}
+	texts := []string{head}
+	seen := map[string]bool{strings.ToLower(head): true}
+	for _, ex := range d.Examples {
+		ex = strings.TrimSpace(ex)
+		if ex == "" || seen[strings.ToLower(ex)] {
+			continue
+		}
+		seen[strings.ToLower(ex)] = true
+		texts = append(texts, ex)
+	}
+	return texts
+}
+
+func textHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
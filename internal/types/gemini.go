@@ -1,10 +1,10 @@
 package types
 
 type EmbeddingRequest struct {
-	Model               string           `json:"model"`
-	Content             EmbeddingContent `json:"content"`
-	TaskType            string           `json:"taskType,omitempty"`
-	OutputDimensionality int             `json:"outputDimensionality,omitempty"`
+	Model                string           `json:"model"`
+	Content              EmbeddingContent `json:"content"`
+	TaskType             string           `json:"taskType,omitempty"`
+	OutputDimensionality int              `json:"outputDimensionality,omitempty"`
 }
 
 // EmbeddingContent represents the content structure for embedding
@@ -25,4 +25,14 @@ type EmbeddingResponse struct {
 // Embedding represents the embedding vector
 type Embedding struct {
 	Values []float64 `json:"values"`
-}
\ No newline at end of file
+}
+
+// BatchEmbeddingRequest is the body of a batchEmbedContents call
+type BatchEmbeddingRequest struct {
+	Requests []EmbeddingRequest `json:"requests"`
+}
+
+// BatchEmbeddingResponse holds one embedding per request, in request order
+type BatchEmbeddingResponse struct {
+	Embeddings []Embedding `json:"embeddings"`
+}
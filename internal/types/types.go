@@ -35,3 +35,23 @@ type BaseResponse struct {
 	RequestID string `json:"request_id,omitempty"`
 	Success   bool   `json:"success"`
 }
+
+// ChatbotSettings holds per-chatbot overrides from the chatbot_settings table.
+// Nil fields mean the service default applies.
+type ChatbotSettings struct {
+	ChatbotID         string
+	ContextExpansion  *string // none | neighbours | parent
+	ContextNeighbours *int
+	ContextCharBudget *int
+	ChunkStrategy     *string // recursive | markdown
+	ChunkSize         *int
+	ChunkOverlap      *int
+	ChunkUnit         *string // chars | tokens
+
+	// Embedding model the retriever searches; nil means the primary vector column
+	EmbeddingModel      *string
+	EmbeddingDimensions *int
+
+	// Days messages are kept before being purged; nil keeps them indefinitely
+	RetentionDays *int
+}
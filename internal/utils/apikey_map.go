@@ -18,6 +18,7 @@ type ApiKeyManager struct {
 
 	// controls background refresh lifecycle
 	refreshCancel context.CancelFunc
+	refreshDone   chan struct{}
 }
 
 type DomainInfo struct {
@@ -193,10 +194,13 @@ func (akm *ApiKeyManager) StartAutoRefresh(ctx context.Context, pgClient *loader
 		return
 	}
 	refreshCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
 	akm.refreshCancel = cancel
+	akm.refreshDone = done
 	akm.mu.Unlock()
 
 	go func() {
+		defer close(done)
 		// initial load with timeout
 		func() {
 			loadCtx, loadCancel := context.WithTimeout(refreshCtx, 15*time.Second)
@@ -223,12 +227,18 @@ func (akm *ApiKeyManager) StartAutoRefresh(ctx context.Context, pgClient *loader
 	}()
 }
 
-// StopAutoRefresh stops the background refresh goroutine if running.
+// StopAutoRefresh stops the background refresh goroutine if running and waits for
+// an in-progress load to return.
 func (akm *ApiKeyManager) StopAutoRefresh() {
 	akm.mu.Lock()
+	done := akm.refreshDone
 	if akm.refreshCancel != nil {
 		akm.refreshCancel()
 		akm.refreshCancel = nil
+		akm.refreshDone = nil
 	}
 	akm.mu.Unlock()
+	if done != nil {
+		<-done
+	}
 }
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
+	"path"
 	"time"
 
 	"go.uber.org/zap"
@@ -94,8 +96,16 @@ func (d *FileDownloader) DownloadFile(ctx context.Context, url string, expectedC
 	}, nil
 }
 
-// extractFilenameFromURL extracts filename from URL (simple implementation)
+// extractFilenameFromURL returns the last path segment of the URL, which parsers use
+// to pick a format when the server sends a generic content type
 func extractFilenameFromURL(url string) string {
-	// Simple extraction - can be improved
-	return "downloaded_file"
+	u, err := neturl.Parse(url)
+	if err != nil {
+		return "downloaded_file"
+	}
+	name := path.Base(u.Path)
+	if name == "." || name == "/" || name == "" {
+		return "downloaded_file"
+	}
+	return name
 }
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrInvalidRequest marks a request rejected before touching any data. Services wrap it
+// with the reason and controllers answer it with 400.
+var ErrInvalidRequest = errors.New("invalid request")
+
+// RequestID returns the id the RequestID middleware gave the request
+func RequestID(ctx *gin.Context) string {
+	if idVal, exists := ctx.Get("request_id"); exists {
+		if rid, ok := idVal.(string); ok {
+			return rid
+		}
+	}
+	return ""
+}
+
+// WriteError answers with the error body shared by the API endpoints
+func WriteError(ctx *gin.Context, status int, code string, err error) {
+	ctx.JSON(status, gin.H{
+		"error":     code,
+		"message":   err.Error(),
+		"timestamp": time.Now().UTC(),
+	})
+}
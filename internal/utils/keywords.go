@@ -1,97 +1,113 @@
 package utils
 
 import (
-	"regexp"
+	"math"
 	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-var stopwords = map[string]bool{
-	"i": true, "me": true, "my": true, "myself": true, "we": true, "our": true,
-	"ours": true, "ourselves": true, "you": true, "your": true, "yours": true,
-	"yourself": true, "yourselves": true, "he": true, "him": true, "his": true,
-	"himself": true, "she": true, "her": true, "hers": true, "herself": true,
-	"it": true, "its": true, "itself": true, "they": true, "them": true,
-	"their": true, "theirs": true, "themselves": true, "what": true, "which": true,
-	"who": true, "whom": true, "this": true, "that": true, "these": true,
-	"those": true, "am": true, "is": true, "are": true, "was": true, "were": true,
-	"be": true, "been": true, "being": true, "have": true, "has": true, "had": true,
-	"having": true, "do": true, "does": true, "did": true, "doing": true,
-	"a": true, "an": true, "the": true, "and": true, "but": true, "if": true,
-	"or": true, "because": true, "as": true, "until": true, "while": true,
-	"of": true, "at": true, "by": true, "for": true, "with": true, "about": true,
-	"against": true, "between": true, "into": true, "through": true, "during": true,
-	"before": true, "after": true, "above": true, "below": true, "to": true,
-	"from": true, "up": true, "down": true, "in": true, "out": true, "on": true,
-	"off": true, "over": true, "under": true, "again": true, "further": true,
-	"then": true, "once": true, "here": true, "there": true, "when": true,
-	"where": true, "why": true, "how": true, "all": true, "both": true,
-	"each": true, "few": true, "more": true, "most": true, "other": true,
-	"some": true, "such": true, "no": true, "nor": true, "not": true,
-	"only": true, "own": true, "same": true, "so": true, "than": true,
-	"too": true, "very": true, "can": true, "will": true, "just": true,
-	"should": true, "now": true, "want": true, "need": true, "would": true,
-	"could": true, "get": true, "got": true, "please": true, "help": true,
-}
-
 type keywordScore struct {
 	word  string
 	score float64
 }
 
-// ExtractKeywords extracts the top N keywords from a message using
-// stopword filtering and frequency-based scoring
+// minCorpusDocs is how many messages a corpus needs before keywords are scored against it
+const minCorpusDocs = 20
+
+// KeywordOptions tunes ExtractKeywordsWith
+type KeywordOptions struct {
+	// Language of the message; empty detects it
+	Language string
+	// Corpus scores keywords by TF-IDF against a chatbot's messages, so words every
+	// visitor uses rank below the ones particular to this message. Nil, or a corpus of
+	// fewer than minCorpusDocs messages, scores by frequency, length and position.
+	Corpus *KeywordCorpus
+}
+
+// ExtractKeywords extracts the top N keywords from a message in its detected language,
+// using stopword filtering and frequency-based scoring
 func ExtractKeywords(message string, topN int) []string {
+	return ExtractKeywordsWith(message, topN, KeywordOptions{})
+}
+
+// ExtractKeywordsWith extracts the top N keywords from a message. Forms of a word with
+// the same stem count as one keyword, returned in the form the message uses most.
+func ExtractKeywordsWith(message string, topN int, opts KeywordOptions) []string {
 	if message == "" {
 		return []string{}
 	}
+	lang := opts.Language
+	if lang == LangUnknown {
+		lang = DetectLanguage(message)
+	}
+	if lang == LangUnknown && opts.Corpus != nil {
+		// Short messages are most likely in the language the chatbot is used in
+		lang = opts.Corpus.lang
+	}
 
 	// Normalize and tokenize
-	words := tokenize(message)
+	words := tokenize(strings.ToLower(message))
 	if len(words) == 0 {
 		return []string{}
 	}
 
-	// Count frequency and track first position
-	wordFreq := make(map[string]int)
-	wordFirstPos := make(map[string]int)
-
+	// Count frequency, first position and surface forms of each stem
+	type stemStats struct {
+		freq     int
+		firstPos int
+		forms    map[string]int
+		form     string // most frequent form, first seen on ties
+	}
+	stems := make(map[string]*stemStats)
 	for i, word := range words {
-		word = strings.ToLower(word)
-
 		// Skip if stopword or too short
-		if stopwords[word] || len(word) < 3 {
+		if isStopword(word, lang) || utf8.RuneCountInString(word) < minWordRunes(lang) {
 			continue
 		}
 
-		wordFreq[word]++
-		if _, exists := wordFirstPos[word]; !exists {
-			wordFirstPos[word] = i
+		stem := Stem(word, lang)
+		st, ok := stems[stem]
+		if !ok {
+			st = &stemStats{firstPos: i, forms: make(map[string]int)}
+			stems[stem] = st
+		}
+		st.freq++
+		st.forms[word]++
+		if st.forms[word] > st.forms[st.form] {
+			st.form = word
 		}
 	}
 
-	if len(wordFreq) == 0 {
+	if len(stems) == 0 {
 		return []string{}
 	}
 
 	// Calculate scores
-	scores := make([]keywordScore, 0, len(wordFreq))
+	scores := make([]keywordScore, 0, len(stems))
 	totalWords := float64(len(words))
-
-	for word, freq := range wordFreq {
-		// Scoring factors:
-		// 1. Frequency (more frequent = higher score)
-		// 2. Length bonus (longer words often more meaningful)
-		// 3. Position weight (earlier words slightly higher)
-
-		freqScore := float64(freq) / totalWords
-		lengthBonus := float64(len(word)) / 10.0
-		positionWeight := 1.0 - (float64(wordFirstPos[word]) / totalWords * 0.3)
-
-		score := (freqScore * 3.0) + lengthBonus + positionWeight
+	useCorpus := opts.Corpus != nil && opts.Corpus.docs >= minCorpusDocs
+
+	for stem, st := range stems {
+		freqScore := float64(st.freq) / totalWords
+		positionWeight := 1.0 - (float64(st.firstPos) / totalWords * 0.3)
+
+		var score float64
+		if useCorpus {
+			// TF-IDF, with position breaking ties between equally rare words
+			score = freqScore*opts.Corpus.idf(stem) + positionWeight*0.001
+		} else {
+			// Scoring factors:
+			// 1. Frequency (more frequent = higher score)
+			// 2. Length bonus (longer words often more meaningful)
+			// 3. Position weight (earlier words slightly higher)
+			lengthBonus := float64(utf8.RuneCountInString(st.form)) / 10.0
+			score = (freqScore * 3.0) + lengthBonus + positionWeight
+		}
 
 		scores = append(scores, keywordScore{
-			word:  word,
+			word:  st.form,
 			score: score,
 		})
 	}
@@ -110,18 +126,75 @@ func ExtractKeywords(message string, topN int) []string {
 	return result
 }
 
-// tokenize splits text into words
-func tokenize(text string) []string {
-	// Simple regex to split on non-alphanumeric characters
-	re := regexp.MustCompile(`[^\w]+`)
-	words := re.Split(text, -1)
-
-	// Filter empty strings
-	result := make([]string, 0, len(words))
-	for _, w := range words {
-		if w != "" {
-			result = append(result, w)
+// KeywordCorpus counts the messages each keyword stem occurs in, for TF-IDF scoring
+type KeywordCorpus struct {
+	docs int
+	df   map[string]int
+	lang string // most common language of the messages
+}
+
+// NewKeywordCorpus builds a corpus from messages, each in its detected language or, when
+// that cannot be told, the most common one
+func NewKeywordCorpus(messages []string) *KeywordCorpus {
+	langs := make([]string, len(messages))
+	counts := make(map[string]int)
+	for i, msg := range messages {
+		langs[i] = DetectLanguage(msg)
+		if langs[i] != LangUnknown {
+			counts[langs[i]]++
 		}
 	}
-	return result
+
+	c := &KeywordCorpus{df: make(map[string]int)}
+	for lang, n := range counts {
+		if n > counts[c.lang] || (n == counts[c.lang] && lang < c.lang) {
+			c.lang = lang
+		}
+	}
+	for i, msg := range messages {
+		lang := langs[i]
+		if lang == LangUnknown {
+			lang = c.lang
+		}
+		seen := make(map[string]bool)
+		for _, word := range tokenize(strings.ToLower(msg)) {
+			if isStopword(word, lang) || utf8.RuneCountInString(word) < minWordRunes(lang) {
+				continue
+			}
+			stem := Stem(word, lang)
+			if !seen[stem] {
+				seen[stem] = true
+				c.df[stem]++
+			}
+		}
+		c.docs++
+	}
+	return c
+}
+
+// Size returns the number of messages in the corpus
+func (c *KeywordCorpus) Size() int {
+	return c.docs
+}
+
+// idf is the smoothed inverse document frequency of a stem
+func (c *KeywordCorpus) idf(stem string) float64 {
+	return math.Log(float64(c.docs+1)/float64(c.df[stem]+1)) + 1
+}
+
+// minWordRunes is the length below which words are not keywords. Hindi words are
+// shorter, as vowel signs are written as part of the letters.
+func minWordRunes(lang string) int {
+	if lang == LangHindi {
+		return 2
+	}
+	return 3
+}
+
+// tokenize splits text into words: runs of letters, digits and combining marks, so
+// accented and Devanagari words stay whole
+func tokenize(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && !unicode.IsMark(r)
+	})
 }
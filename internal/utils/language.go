@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Languages keywords can be extracted in, as ISO 639-1 codes
+const (
+	LangEnglish = "en"
+	LangGerman  = "de"
+	LangSpanish = "es"
+	LangHindi   = "hi"
+	// LangUnknown is returned when no language stands out; keywords are then filtered
+	// with every stopword list and not stemmed
+	LangUnknown = ""
+)
+
+// latinLanguages are told apart by their stopwords
+var latinLanguages = []string{LangEnglish, LangGerman, LangSpanish}
+
+// DetectLanguage guesses the language of a short text. Hindi is recognized by its
+// script; English, German and Spanish by how many of their stopwords and letters the
+// text uses. Texts too short or too mixed to tell return LangUnknown.
+func DetectLanguage(text string) string {
+	var letters, devanagari int
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.Is(unicode.Devanagari, r) {
+			devanagari++
+		}
+	}
+	if letters == 0 {
+		return LangUnknown
+	}
+	if devanagari*2 > letters {
+		return LangHindi
+	}
+
+	scores := make(map[string]int, len(latinLanguages))
+	for _, word := range tokenize(strings.ToLower(text)) {
+		for _, lang := range latinLanguages {
+			if stopwords[lang][word] {
+				scores[lang] += 2
+			}
+		}
+		for _, r := range word {
+			switch r {
+			case 'ä', 'ö', 'ü', 'ß':
+				scores[LangGerman]++
+			case 'ñ', 'á', 'é', 'í', 'ó', 'ú':
+				scores[LangSpanish]++
+			}
+		}
+	}
+	// '¿' and '¡' are punctuation, so tokenize drops them
+	scores[LangSpanish] += strings.Count(text, "¿") + strings.Count(text, "¡")
+
+	best, bestScore, tied := LangUnknown, 0, false
+	for _, lang := range latinLanguages {
+		switch {
+		case scores[lang] > bestScore:
+			best, bestScore, tied = lang, scores[lang], false
+		case scores[lang] == bestScore && bestScore > 0:
+			tied = true
+		}
+	}
+	if tied {
+		return LangUnknown
+	}
+	return best
+}
+
+// isStopword reports whether word is a stopword of lang, or of any language when lang
+// is unknown
+func isStopword(word, lang string) bool {
+	if lang != LangUnknown {
+		return stopwords[lang][word]
+	}
+	for _, list := range stopwords {
+		if list[word] {
+			return true
+		}
+	}
+	return false
+}
+
+var stopwords = map[string]map[string]bool{
+	LangEnglish: wordSet(`
+		i me my myself we our ours ourselves you your yours yourself yourselves he him his
+		himself she her hers herself it its itself they them their theirs themselves what
+		which who whom this that these those am is are was were be been being have has had
+		having do does did doing a an the and but if or because as until while of at by for
+		with about against between into through during before after above below to from up
+		down in out on off over under again further then once here there when where why how
+		all both each few more most other some such no nor not only own same so than too
+		very can will just should now want need would could get got please help`),
+	LangGerman: wordSet(`
+		ich mich mir mein meine meinen meinem meiner du dich dir dein deine er ihn ihm sein
+		seine sie ihr ihre ihren ihrem es wir uns unser unsere euch euer der die das den dem
+		des ein eine einen einem einer eines und oder aber wenn weil als wie was wer wo wann
+		warum ist sind war waren bin bist seid sein haben habe hast hat hatte hatten werden
+		wird wurde wurden kann kannst können könnte möchte möchten will wollen muss müssen
+		soll sollte nicht kein keine keinen noch auch nur schon sehr so zu zum zur im in an
+		am auf aus bei mit nach von vor über unter für gegen ohne um durch bis hier dort da
+		dann jetzt dass ob man mal bitte danke hallo hilfe brauche gibt`),
+	LangSpanish: wordSet(`
+		yo me mi mis mío tú te ti tu tus usted ustedes él ella ello nos nosotros nosotras
+		vosotros ellos ellas les le lo la los las el un una unos unas y o u pero si porque
+		como que qué quien quién cual cuál donde dónde cuando cuándo cómo es son era eran
+		ser soy eres somos fue estar estoy está están estaba he has ha han hay tener tengo
+		tiene tienen puedo puede pueden quiero quiere necesito de del a al en con por para
+		sin sobre entre hasta desde hacia no ni ya muy más menos también solo este esta
+		estos estas ese esa eso esto aquí allí se su sus hola gracias favor ayuda`),
+	LangHindi: wordSet(`
+		मैं मुझे मेरा मेरी मेरे हम हमें हमारा हमारी हमारे आप आपका आपकी आपके तुम तुम्हारा वह
+		वे वो यह ये इस इसे इसका उस उसे उसका उनका उनकी उनके का के की को से में पर तक और या
+		लेकिन अगर तो भी ही है हैं था थी थे हो होता होती होते होगा होगी हूँ हूं कर करना करें
+		करते करता करती किया कि जो जब तब क्या कैसे क्यों कहाँ कहां कौन कब नहीं ना न एक लिए
+		साथ बहुत अब रहा रही रहे गया गई गए दें दो चाहिए चाहता चाहती सकता सकती सकते कृपया
+		धन्यवाद मदद जी`),
+}
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
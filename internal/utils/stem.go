@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Suffixes stripped by Stem, longest first. The stemmers are deliberately light: they
+// fold plurals and common inflections so that forms of a word count together, and never
+// cut a word below minStemRunes.
+var suffixes = map[string][]string{
+	LangEnglish: {"ations", "ation", "ings", "ing", "ies", "ied", "es", "ed", "s"},
+	LangGerman: {"ungen", "heiten", "keiten", "heit", "keit", "ung", "ern", "em", "en",
+		"er", "es", "e", "n", "s"},
+	LangSpanish: {"amientos", "imientos", "amiento", "imiento", "aciones", "acion", "mente",
+		"idades", "idad", "ando", "iendo", "ados", "idos", "adas", "idas", "ado", "ido",
+		"ada", "ida", "es", "os", "as", "o", "a", "e", "s"},
+	LangHindi: {"ाएंगी", "ाएंगे", "ाऊंगी", "ाऊंगा", "ाइयाँ", "ाइयों", "ाइयां", "ियाँ", "ियों",
+		"ियां", "ाएगी", "ाएगा", "ाओगी", "ाओगे", "ाएँ", "ाएं", "ाओं", "ाना", "ाने", "ानी",
+		"ाता", "ाती", "ाते", "ीं", "ों", "ें", "ाँ", "ां", "ो", "े", "ू", "ु", "ी", "ि", "ा"},
+}
+
+var minStemRunes = map[string]int{
+	LangEnglish: 3,
+	LangGerman:  3,
+	LangSpanish: 3,
+	LangHindi:   2,
+}
+
+// accentFolder removes the accents Spanish and German spelling varies on, so "acción"
+// and "accion" or "Straße" and "Strasse" stem alike
+var accentFolder = strings.NewReplacer(
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+	"ä", "a", "ö", "o", "ß", "ss",
+)
+
+// Stem returns the stem of a lowercase word in lang. Words of an unknown language are
+// returned unchanged.
+func Stem(word, lang string) string {
+	list, ok := suffixes[lang]
+	if !ok {
+		return word
+	}
+	if lang == LangGerman || lang == LangSpanish {
+		word = accentFolder.Replace(word)
+	}
+
+	minRunes := minStemRunes[lang]
+	for _, suffix := range list {
+		if !strings.HasSuffix(word, suffix) {
+			continue
+		}
+		stem := word[:len(word)-len(suffix)]
+		if utf8.RuneCountInString(stem) < minRunes {
+			continue
+		}
+		if lang == LangEnglish {
+			// "es" is only a plural after a sibilant ("boxes"), "ss" is not a plural
+			// ("class"), and "ies"/"ied" end in "y" ("policies")
+			switch suffix {
+			case "es":
+				if !hasAnySuffix(stem, "x", "z", "ch", "sh", "ss") {
+					continue
+				}
+			case "s":
+				if hasAnySuffix(stem, "s", "u") {
+					continue
+				}
+			case "ies", "ied":
+				stem += "y"
+			}
+		}
+		word = stem
+		break
+	}
+	// A silent final "e" comes and goes with English inflection ("price", "pricing")
+	if lang == LangEnglish && utf8.RuneCountInString(word) > minRunes && strings.HasSuffix(word, "e") {
+		word = strings.TrimSuffix(word, "e")
+	}
+	return word
+}
+
+func hasAnySuffix(s string, suffixes ...string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
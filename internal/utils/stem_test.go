@@ -0,0 +1,69 @@
+package utils
+
+import "testing"
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		word, lang, want string
+	}{
+		// Inflections of a word stem alike
+		{"pricing", LangEnglish, "pric"},
+		{"price", LangEnglish, "pric"},
+		{"policies", LangEnglish, "policy"},
+		{"policy", LangEnglish, "policy"},
+		{"boxes", LangEnglish, "box"},
+		{"shipped", LangEnglish, "shipp"},
+		{"shipping", LangEnglish, "shipp"},
+		// Words that only look inflected are kept
+		{"class", LangEnglish, "class"},
+		{"status", LangEnglish, "status"},
+		{"family", LangEnglish, "family"},
+		{"quickly", LangEnglish, "quickly"},
+		{"is", LangEnglish, "is"},
+
+		{"rechnungen", LangGerman, "rechn"},
+		{"rechnung", LangGerman, "rechn"},
+		{"straße", LangGerman, "strass"},
+		{"strasse", LangGerman, "strass"},
+
+		{"acción", LangSpanish, "accion"},
+		{"acciones", LangSpanish, "accion"},
+		{"facturas", LangSpanish, "factur"},
+		{"factura", LangSpanish, "factur"},
+
+		{"किताबें", LangHindi, "किताब"},
+		{"किताब", LangHindi, "किताब"},
+		{"लड़कों", LangHindi, "लड़क"},
+		{"लड़का", LangHindi, "लड़क"},
+
+		{"running", LangUnknown, "running"},
+	}
+	for _, tt := range tests {
+		if got := Stem(tt.word, tt.lang); got != tt.want {
+			t.Errorf("Stem(%q, %q) = %q, want %q", tt.word, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"How do I reset my password?", LangEnglish},
+		{"Can you help me with the invoice for my order", LangEnglish},
+		{"Wie kann ich mein Passwort ändern?", LangGerman},
+		{"Ich habe keine Rechnung für meine Bestellung bekommen", LangGerman},
+		{"¿Cómo puedo cambiar mi contraseña?", LangSpanish},
+		{"No tengo la factura de mi pedido", LangSpanish},
+		{"मुझे अपना पासवर्ड बदलना है", LangHindi},
+		{"मेरे ऑर्डर का invoice कहाँ है", LangHindi},
+		{"", LangUnknown},
+		{"12345 !!", LangUnknown},
+		{"password", LangUnknown},
+	}
+	for _, tt := range tests {
+		if got := DetectLanguage(tt.text); got != tt.want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"strings"
+	"unicode/utf8"
 
 	"github.com/Conversly/lightning-response/internal/types"
 )
@@ -10,13 +11,15 @@ const (
 	MinSimilarityThreshold = 0.3
 )
 
-func MatchTopicFromKeywords(keywords []string, topics []types.ChatbotTopic) string {
+// MatchTopicFromKeywords returns the topic whose name best matches keywords extracted in
+// lang, or the "other" topic when none matches well enough
+func MatchTopicFromKeywords(keywords []string, lang string, topics []types.ChatbotTopic) string {
 	if len(topics) == 0 {
 		return ""
 	}
 
 	if len(keywords) == 0 {
-		return findOtherTopicID(topics)
+		return OtherTopicID(topics)
 	}
 
 	bestScore := 0.0
@@ -24,7 +27,7 @@ func MatchTopicFromKeywords(keywords []string, topics []types.ChatbotTopic) stri
 
 	// Compare keywords against each topic
 	for _, topic := range topics {
-		score := calculateTopicScore(keywords, topic.Name)
+		score := calculateTopicScore(keywords, topic.Name, lang)
 		if score > bestScore {
 			bestScore = score
 			bestTopicID = topic.ID
@@ -33,14 +36,14 @@ func MatchTopicFromKeywords(keywords []string, topics []types.ChatbotTopic) stri
 
 	// Return "other" topic ID if best score is below threshold
 	if bestScore < MinSimilarityThreshold {
-		return findOtherTopicID(topics)
+		return OtherTopicID(topics)
 	}
 
 	return bestTopicID
 }
 
-// findOtherTopicID finds and returns the ID of the "other" topic
-func findOtherTopicID(topics []types.ChatbotTopic) string {
+// OtherTopicID returns the ID of the "other" topic, or "" when the chatbot has none
+func OtherTopicID(topics []types.ChatbotTopic) string {
 	for _, topic := range topics {
 		if strings.ToLower(topic.Name) == "other" {
 			return topic.ID
@@ -51,9 +54,9 @@ func findOtherTopicID(topics []types.ChatbotTopic) string {
 }
 
 // calculateTopicScore calculates similarity score between keywords and topic name
-func calculateTopicScore(keywords []string, topicName string) float64 {
+func calculateTopicScore(keywords []string, topicName, lang string) float64 {
 	topicNameLower := strings.ToLower(topicName)
-	topicWords := strings.Fields(topicNameLower)
+	topicWords := tokenize(topicNameLower)
 
 	if len(topicWords) == 0 {
 		return 0.0
@@ -67,8 +70,14 @@ func calculateTopicScore(keywords []string, topicName string) float64 {
 		keywordLower := strings.ToLower(keyword)
 		bestWordScore := 0.0
 
+		keywordStem := Stem(keywordLower, lang)
+
 		for _, topicWord := range topicWords {
 			similarity := calculateSimilarity(keywordLower, topicWord)
+			// Different forms of the same word match fully
+			if keywordStem == Stem(topicWord, lang) {
+				similarity = 1.0
+			}
 			if similarity > bestWordScore {
 				bestWordScore = similarity
 			}
@@ -101,11 +110,10 @@ func calculateSimilarity(s1, s2 string) float64 {
 
 	// Check if one is substring of the other (high score)
 	if strings.Contains(s1, s2) || strings.Contains(s2, s1) {
-		shorter := len(s2)
-		longer := len(s1)
-		if len(s1) < len(s2) {
-			shorter = len(s1)
-			longer = len(s2)
+		shorter := utf8.RuneCountInString(s2)
+		longer := utf8.RuneCountInString(s1)
+		if shorter > longer {
+			shorter, longer = longer, shorter
 		}
 		return 0.8 * (float64(shorter) / float64(longer))
 	}
@@ -116,7 +124,7 @@ func calculateSimilarity(s1, s2 string) float64 {
 
 // jaccardSimilarity calculates Jaccard similarity using character n-grams
 func jaccardSimilarity(s1, s2 string, n int) float64 {
-	if len(s1) < n || len(s2) < n {
+	if utf8.RuneCountInString(s1) < n || utf8.RuneCountInString(s2) < n {
 		// For very short strings, use simple character overlap
 		return simpleCharOverlap(s1, s2)
 	}
@@ -149,12 +157,13 @@ func jaccardSimilarity(s1, s2 string, n int) float64 {
 // extractNGrams extracts character n-grams from a string
 func extractNGrams(s string, n int) map[string]bool {
 	ngrams := make(map[string]bool)
-	if len(s) < n {
+	runes := []rune(s)
+	if len(runes) < n {
 		return ngrams
 	}
 
-	for i := 0; i <= len(s)-n; i++ {
-		ngrams[s[i:i+n]] = true
+	for i := 0; i <= len(runes)-n; i++ {
+		ngrams[string(runes[i:i+n])] = true
 	}
 	return ngrams
 }
@@ -0,0 +1,17 @@
+-- Chunk ordering and parent references used for context expansion at retrieval time.
+ALTER TABLE embeddings
+    ADD COLUMN IF NOT EXISTS document_id TEXT,
+    ADD COLUMN IF NOT EXISTS chunk_index INTEGER,
+    ADD COLUMN IF NOT EXISTS section TEXT;
+
+CREATE INDEX IF NOT EXISTS embeddings_document_position_idx
+    ON embeddings (chatbot_id, document_id, chunk_index);
+
+-- Per-chatbot overrides for service behaviour. A missing row means "use service defaults".
+CREATE TABLE IF NOT EXISTS chatbot_settings (
+    chatbot_id          TEXT PRIMARY KEY,
+    context_expansion   TEXT,     -- none | neighbours | parent
+    context_neighbours  INTEGER,  -- chunks on each side of a hit for neighbours expansion
+    context_char_budget INTEGER,  -- total characters of expanded context per retrieval
+    updated_at          TIMESTAMP NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,10 @@
+-- Shared tier of the query embedding cache, keyed by sha256(model | task type | normalised text).
+CREATE TABLE IF NOT EXISTS embedding_cache (
+    cache_key  TEXT PRIMARY KEY,
+    model      TEXT NOT NULL,
+    task_type  TEXT NOT NULL,
+    vector     vector NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS embedding_cache_created_at_idx ON embedding_cache (created_at);
@@ -0,0 +1,3 @@
+-- Failure reason surfaced to the dashboard when ingestion of a data source fails.
+ALTER TABLE data_source
+    ADD COLUMN IF NOT EXISTS error_message TEXT;
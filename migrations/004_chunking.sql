@@ -0,0 +1,11 @@
+-- Byte offsets of each chunk within its document's extracted text.
+ALTER TABLE embeddings
+    ADD COLUMN IF NOT EXISTS start_offset INTEGER,
+    ADD COLUMN IF NOT EXISTS end_offset INTEGER;
+
+-- Per-chatbot chunking settings, applied at ingestion time.
+ALTER TABLE chatbot_settings
+    ADD COLUMN IF NOT EXISTS chunk_strategy TEXT,    -- recursive | markdown
+    ADD COLUMN IF NOT EXISTS chunk_size INTEGER,
+    ADD COLUMN IF NOT EXISTS chunk_overlap INTEGER,
+    ADD COLUMN IF NOT EXISTS chunk_unit TEXT;        -- chars | tokens
@@ -0,0 +1,13 @@
+-- Content hash of each chunk's text, used to diff re-ingestions against stored rows.
+ALTER TABLE embeddings
+    ADD COLUMN IF NOT EXISTS content_hash TEXT;
+
+CREATE INDEX IF NOT EXISTS embeddings_data_source_idx
+    ON embeddings (data_source_id);
+
+-- Outcome of the last (re-)ingestion of a data source.
+ALTER TABLE data_source
+    ADD COLUMN IF NOT EXISTS chunks_added INTEGER,
+    ADD COLUMN IF NOT EXISTS chunks_updated INTEGER,
+    ADD COLUMN IF NOT EXISTS chunks_removed INTEGER,
+    ADD COLUMN IF NOT EXISTS last_synced_at TIMESTAMP;
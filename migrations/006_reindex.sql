@@ -0,0 +1,23 @@
+-- Rows written by a running reindex carry its job id and stay invisible to retrieval
+-- until the job swaps them in, so chatbots keep answering from the old index.
+ALTER TABLE embeddings
+    ADD COLUMN IF NOT EXISTS reindex_job_id TEXT;
+
+CREATE INDEX IF NOT EXISTS embeddings_reindex_job_idx
+    ON embeddings (chatbot_id, reindex_job_id)
+    WHERE reindex_job_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS reindex_jobs (
+    id                  TEXT PRIMARY KEY,
+    chatbot_id          TEXT NOT NULL,
+    status              TEXT NOT NULL,    -- RUNNING | COMPLETED | FAILED
+    total_documents     INTEGER NOT NULL DEFAULT 0,
+    processed_documents INTEGER NOT NULL DEFAULT 0,
+    chunks_embedded     INTEGER NOT NULL DEFAULT 0,
+    chunks_reused       INTEGER NOT NULL DEFAULT 0,
+    error_message       TEXT,
+    started_at          TIMESTAMP NOT NULL,
+    finished_at         TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS reindex_jobs_chatbot_idx ON reindex_jobs (chatbot_id, started_at DESC);
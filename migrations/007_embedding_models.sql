@@ -0,0 +1,48 @@
+-- Model that produced embeddings.vector. NULL marks rows written before tagging, which
+-- came from the service's default model.
+ALTER TABLE embeddings
+    ADD COLUMN IF NOT EXISTS embedding_model TEXT;
+
+-- Vectors of the same chunks from other embedding models, used while migrating a
+-- chatbot to a new model and afterwards. The column is untyped so models of any size
+-- can coexist; each size gets its own typed partial HNSW index below.
+CREATE TABLE IF NOT EXISTS embedding_vectors (
+    embedding_id BIGINT NOT NULL REFERENCES embeddings (id) ON DELETE CASCADE,
+    model        TEXT NOT NULL,
+    vector       vector NOT NULL,
+    created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (embedding_id, model)
+);
+
+CREATE INDEX IF NOT EXISTS embedding_vectors_model_idx ON embedding_vectors (model);
+
+-- Searches cast to the query's size and filter on it, matching these indexes. Sizes
+-- without an index are scanned; vectors over 2000 dimensions need a halfvec index, e.g.
+--   CREATE INDEX ON embedding_vectors USING hnsw ((vector::halfvec(3072)) halfvec_cosine_ops)
+--       WHERE vector_dims(vector) = 3072;
+CREATE INDEX IF NOT EXISTS embedding_vectors_768_hnsw_idx ON embedding_vectors
+    USING hnsw ((vector::vector(768)) vector_cosine_ops) WHERE vector_dims(vector) = 768;
+CREATE INDEX IF NOT EXISTS embedding_vectors_1536_hnsw_idx ON embedding_vectors
+    USING hnsw ((vector::vector(1536)) vector_cosine_ops) WHERE vector_dims(vector) = 1536;
+
+-- Model a chatbot's retriever searches. NULL means the primary embeddings.vector column.
+ALTER TABLE chatbot_settings
+    ADD COLUMN IF NOT EXISTS embedding_model TEXT,
+    ADD COLUMN IF NOT EXISTS embedding_dimensions INTEGER;
+
+CREATE TABLE IF NOT EXISTS embedding_migrations (
+    id              TEXT PRIMARY KEY,
+    chatbot_id      TEXT NOT NULL,
+    model           TEXT NOT NULL,
+    dimensions      INTEGER NOT NULL,
+    status          TEXT NOT NULL,    -- RUNNING | COMPLETED | FAILED
+    total_chunks    INTEGER NOT NULL DEFAULT 0,
+    embedded_chunks INTEGER NOT NULL DEFAULT 0,
+    failed_chunks   INTEGER NOT NULL DEFAULT 0,  -- chunks the last pass could not embed
+    error_message   TEXT,
+    started_at      TIMESTAMP NOT NULL,
+    finished_at     TIMESTAMP,
+    activated_at    TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS embedding_migrations_chatbot_idx ON embedding_migrations (chatbot_id, started_at DESC);
@@ -0,0 +1,25 @@
+-- Curated answers pinned by a tenant. A close enough match to one of an entry's question
+-- variants answers with the curated text instead of a generated one.
+CREATE TABLE IF NOT EXISTS faq_entries (
+    id         BIGSERIAL PRIMARY KEY,
+    chatbot_id TEXT NOT NULL,
+    answer     TEXT NOT NULL,
+    citation   TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS faq_entries_chatbot_idx ON faq_entries (chatbot_id);
+
+-- One row per question variant and embedding model. The vector column is untyped so
+-- chatbots on different models can share the table.
+CREATE TABLE IF NOT EXISTS faq_questions (
+    id              BIGSERIAL PRIMARY KEY,
+    faq_id          BIGINT NOT NULL REFERENCES faq_entries (id) ON DELETE CASCADE,
+    chatbot_id      TEXT NOT NULL,
+    question        TEXT NOT NULL,
+    embedding_model TEXT NOT NULL,
+    vector          vector NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS faq_questions_chatbot_idx ON faq_questions (chatbot_id, embedding_model);
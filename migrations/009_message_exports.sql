@@ -0,0 +1,27 @@
+-- Bulk exports of a chatbot's messages. Files are written to storage shared by every
+-- instance and deleted once expires_at passes; host is the instance that ran the job.
+CREATE TABLE IF NOT EXISTS message_exports (
+    id            TEXT PRIMARY KEY,
+    chatbot_id    TEXT NOT NULL,
+    format        TEXT NOT NULL,    -- jsonl | csv
+    range_from    TIMESTAMP NOT NULL,
+    range_to      TIMESTAMP NOT NULL,
+    status        TEXT NOT NULL,    -- RUNNING | COMPLETED | FAILED | EXPIRED
+    rows_exported BIGINT NOT NULL DEFAULT 0,
+    size_bytes    BIGINT NOT NULL DEFAULT 0,
+    host          TEXT NOT NULL,
+    file_path     TEXT,
+    error_message TEXT,
+    started_at    TIMESTAMP NOT NULL,
+    finished_at   TIMESTAMP,
+    expires_at    TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS message_exports_chatbot_idx ON message_exports (chatbot_id, started_at DESC);
+-- Any instance deletes expired exports
+CREATE INDEX IF NOT EXISTS message_exports_expires_idx ON message_exports (expires_at)
+    WHERE status = 'COMPLETED';
+
+-- Exports scan a chatbot's messages by creation time, as do retention purges (010)
+-- and analytics rollups (013)
+CREATE INDEX IF NOT EXISTS messages_chatbot_created_idx ON messages (chatbot_id, created_at);
@@ -0,0 +1,32 @@
+-- Days a chatbot's messages are kept before the purge job deletes them. NULL keeps
+-- them indefinitely.
+ALTER TABLE chatbot_settings
+    ADD COLUMN IF NOT EXISTS retention_days INTEGER;
+
+-- Audit trail of retention purges and end-user erasures. Erasures record a SHA-256 of
+-- the visitor's unique client id rather than the id itself.
+CREATE TABLE IF NOT EXISTS data_retention_runs (
+    id                  TEXT PRIMARY KEY,
+    kind                TEXT NOT NULL,    -- PURGE | ERASURE
+    chatbot_id          TEXT,             -- ERASURE only
+    subject_hash        TEXT,             -- ERASURE only
+    mode                TEXT,             -- ERASURE only: DELETE | ANONYMIZE
+    requested_by        TEXT,
+    status              TEXT NOT NULL,    -- RUNNING | COMPLETED | FAILED
+    messages_deleted    BIGINT NOT NULL DEFAULT 0,
+    messages_anonymized BIGINT NOT NULL DEFAULT 0,
+    details             JSONB,            -- PURGE: messages deleted per chatbot
+    error_message       TEXT,
+    started_at          TIMESTAMP NOT NULL,
+    finished_at         TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS data_retention_runs_started_idx ON data_retention_runs (kind, started_at DESC);
+CREATE INDEX IF NOT EXISTS data_retention_runs_chatbot_idx ON data_retention_runs (chatbot_id, started_at DESC)
+    WHERE chatbot_id IS NOT NULL;
+
+-- Purges delete a chatbot's messages by creation time through
+-- messages_chatbot_created_idx, created by 009
+
+-- Erasures look up a visitor's messages by conversation
+CREATE INDEX IF NOT EXISTS messages_chatbot_conv_idx ON messages (chatbot_id, unique_conv_id);
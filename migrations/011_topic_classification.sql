@@ -0,0 +1,17 @@
+-- Text that tells the classifier what a topic covers, alongside its name: a short
+-- description and example user messages.
+ALTER TABLE chatbot_topics
+    ADD COLUMN IF NOT EXISTS description TEXT,
+    ADD COLUMN IF NOT EXISTS examples TEXT[];
+
+-- Vectors of topic names, descriptions and examples per embedding model, keyed by the
+-- hash of the embedded text so unchanged text is never embedded again. Rows of text no
+-- longer used by any topic are removed when the chatbot's topics are next embedded.
+CREATE TABLE IF NOT EXISTS topic_embeddings (
+    chatbot_id      TEXT NOT NULL,
+    embedding_model TEXT NOT NULL,
+    content_hash    TEXT NOT NULL,
+    vector          vector NOT NULL,
+    created_at      TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chatbot_id, embedding_model, content_hash)
+);
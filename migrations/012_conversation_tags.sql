@@ -0,0 +1,23 @@
+-- Conversation-level classification, written once a conversation has gone idle and
+-- rewritten if it resumes. last_message_at is the newest message the row accounts for.
+CREATE TABLE IF NOT EXISTS conversation_tags (
+    chatbot_id      TEXT NOT NULL,
+    unique_conv_id  TEXT NOT NULL,
+    topic_ids       TEXT[] NOT NULL DEFAULT '{}',
+    tags            TEXT[] NOT NULL DEFAULT '{}',
+    sentiment       TEXT,             -- positive | neutral | negative
+    resolution      TEXT,             -- resolved | unresolved | escalated
+    method          TEXT NOT NULL,    -- llm | heuristic
+    model           TEXT,             -- LLM that classified the conversation
+    message_count   INTEGER NOT NULL,
+    last_message_at TIMESTAMP NOT NULL,
+    classified_at   TIMESTAMP NOT NULL,
+    PRIMARY KEY (chatbot_id, unique_conv_id)
+);
+
+CREATE INDEX IF NOT EXISTS conversation_tags_chatbot_idx ON conversation_tags (chatbot_id, last_message_at DESC);
+CREATE INDEX IF NOT EXISTS conversation_tags_topics_idx ON conversation_tags USING GIN (topic_ids);
+CREATE INDEX IF NOT EXISTS conversation_tags_tags_idx ON conversation_tags USING GIN (tags);
+
+-- The tagger finds conversations that went idle recently
+CREATE INDEX IF NOT EXISTS messages_created_idx ON messages (created_at);
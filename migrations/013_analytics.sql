@@ -0,0 +1,53 @@
+-- Response latency and the tools called for each assistant message
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS latency_ms INTEGER;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS tools TEXT[];
+
+-- Days whose rollups are out of date. Message inserts and feedback mark the day of the
+-- message; version changes on every mark, so a rollup only clears the mark it read.
+CREATE TABLE IF NOT EXISTS analytics_dirty_days (
+    chatbot_id TEXT NOT NULL,
+    day        DATE NOT NULL,
+    version    BIGINT NOT NULL DEFAULT 1,
+    PRIMARY KEY (chatbot_id, day)
+);
+
+-- Per-chatbot daily rollups of the messages created that day (UTC). Rollups are
+-- aggregates and are kept when messages are purged or erased.
+CREATE TABLE IF NOT EXISTS analytics_daily (
+    chatbot_id         TEXT NOT NULL,
+    day                DATE NOT NULL,
+    conversations      INTEGER NOT NULL,  -- conversations with a message that day
+    user_messages      INTEGER NOT NULL,
+    assistant_messages INTEGER NOT NULL,
+    likes              INTEGER NOT NULL,
+    dislikes           INTEGER NOT NULL,
+    neutral            INTEGER NOT NULL,
+    tool_calls         INTEGER NOT NULL,
+    latency_histogram  BIGINT[] NOT NULL, -- counts per bucket of loaders.LatencyBucketsMS
+    updated_at         TIMESTAMP NOT NULL,
+    PRIMARY KEY (chatbot_id, day)
+);
+
+CREATE TABLE IF NOT EXISTS analytics_daily_topics (
+    chatbot_id TEXT NOT NULL,
+    day        DATE NOT NULL,
+    topic_id   TEXT NOT NULL,
+    messages   INTEGER NOT NULL,  -- user messages tagged with the topic
+    PRIMARY KEY (chatbot_id, day, topic_id)
+);
+
+CREATE TABLE IF NOT EXISTS analytics_daily_tools (
+    chatbot_id TEXT NOT NULL,
+    day        DATE NOT NULL,
+    tool       TEXT NOT NULL,
+    calls      INTEGER NOT NULL,
+    PRIMARY KEY (chatbot_id, day, tool)
+);
+
+-- Rollups aggregate a chatbot's messages of one day through messages_chatbot_created_idx,
+-- created by 009
+
+-- Roll up the messages written before this migration
+INSERT INTO analytics_dirty_days (chatbot_id, day)
+SELECT DISTINCT chatbot_id, created_at::date FROM messages
+ON CONFLICT DO NOTHING;
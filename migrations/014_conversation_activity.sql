@@ -0,0 +1,19 @@
+-- The newest message of each conversation, kept up to date by the message writer so
+-- conversations can be listed a page at a time without aggregating every message
+CREATE TABLE IF NOT EXISTS conversation_activity (
+    chatbot_id      TEXT NOT NULL,
+    unique_conv_id  TEXT NOT NULL,
+    last_message_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (chatbot_id, unique_conv_id)
+);
+
+-- Conversation listings page through it by (last_message_at, unique_conv_id)
+CREATE INDEX IF NOT EXISTS conversation_activity_recent_idx
+    ON conversation_activity (chatbot_id, last_message_at DESC, unique_conv_id DESC);
+
+-- Account for the messages written before this migration
+INSERT INTO conversation_activity (chatbot_id, unique_conv_id, last_message_at)
+SELECT chatbot_id, unique_conv_id, MAX(created_at) FROM messages
+WHERE unique_conv_id IS NOT NULL
+GROUP BY chatbot_id, unique_conv_id
+ON CONFLICT DO NOTHING;